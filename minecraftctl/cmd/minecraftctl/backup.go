@@ -1,17 +1,31 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/paul/minecraftctl/internal/commands"
 	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
 	"github.com/paul/minecraftctl/pkg/worlds"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 // BackupCmd is an alias for the command defined in internal/commands
 var BackupCmd = commands.BackupCmd
 
+// backupRepo is the --repo flag shared by the backup subcommands
+var backupRepo string
+
 // backupWorldCompletionFunc provides tab completion for world names
 func backupWorldCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) != 0 {
@@ -26,56 +40,322 @@ func backupWorldCompletionFunc(cmd *cobra.Command, args []string, toComplete str
 	return names, cobra.ShellCompDirectiveNoFileComp
 }
 
+// backupWorldsCompletionFunc completes any number of world names, or "all"
+// as the only argument
+func backupWorldsCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return backupWorldCompletionFunc(cmd, args, toComplete)
+	}
+	if args[0] == "all" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := worlds.GetWorldNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+var (
+	listWorld  string
+	listSince  string
+	listBefore string
+	listOutput string
+)
+
 var backupListCmd = &cobra.Command{
-	Use:   "list [tag]",
+	Use:   "list [world]",
 	Short: "List available backup snapshots",
-	Long:  "List all available backup snapshots. Optionally filter by tag (world name or 'all').",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `List backup snapshots, oldest first.
+
+Filter by world tag (a world name or 'all') with --world or the argument, and
+by time with --since and --before. Times are dates (2006-01-02), dates with
+times (2006-01-02 15:04), RFC 3339 timestamps, or durations before now such
+as 36h or 7d.
+
+Examples:
+  minecraftctl backup list
+  minecraftctl backup list survival --since 7d
+  minecraftctl backup list --world survival --before 2024-06-01 -o json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: backupWorldCompletionFunc,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !backup.IsResticInstalled() {
 			return fmt.Errorf("restic is not installed")
 		}
 
-		cfg, err := backup.LoadConfig()
+		filter := backup.SnapshotFilter{World: listWorld}
+		if len(args) > 0 {
+			if listWorld != "" && listWorld != args[0] {
+				return fmt.Errorf("world given both as argument (%s) and --world (%s)", args[0], listWorld)
+			}
+			filter.World = args[0]
+		}
+		now := time.Now()
+		var err error
+		if listSince != "" {
+			if filter.Since, err = backup.ParseTime(listSince, now); err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+		}
+		if listBefore != "" {
+			if filter.Before, err = backup.ParseTime(listBefore, now); err != nil {
+				return fmt.Errorf("--before: %w", err)
+			}
+		}
+
+		switch listOutput {
+		case "json", "table", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", listOutput)
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
 		if err != nil {
 			return err
 		}
 
-		tag := ""
-		if len(args) > 0 {
-			tag = args[0]
+		snapshots, err := cfg.Snapshots(filter)
+		if err != nil {
+			return err
 		}
 
-		return cfg.List(tag)
+		if listOutput == "json" {
+			return printJSON(snapshots)
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tHOST\tTAGS\tSIZE\tADDED")
+		for _, snap := range snapshots {
+			size, added := "-", "-"
+			if snap.Summary != nil {
+				size = formatSize(snap.Summary.TotalBytesProcessed)
+				added = formatSize(snap.Summary.DataAdded)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", snap.ShortID, snap.Time.Local().Format("2006-01-02 15:04:05"),
+				snap.Hostname, strings.Join(snap.Tags, ","), size, added)
+		}
+		w.Flush()
+		fmt.Printf("\n%d snapshot(s)\n", len(snapshots))
+		return nil
 	},
 }
 
+var showOutput string
+
+var backupShowCmd = &cobra.Command{
+	Use:   "show <snapshot>",
+	Short: "Show details of a backup snapshot",
+	Long: `Show a snapshot's time, host, tags, paths and backup summary, and the
+size of a restore of it. Use 'latest' for the most recent snapshot.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+		switch showOutput {
+		case "json", "text", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: text, json)", showOutput)
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+
+		snap, err := cfg.Snapshot(args[0])
+		if err != nil {
+			return err
+		}
+		stats, err := cfg.RepoStats("restore-size", snap.ID)
+		if err != nil {
+			return err
+		}
+
+		if showOutput == "json" {
+			return printJSON(struct {
+				*backup.Snapshot
+				RestoreSize  int64 `json:"restore_size"`
+				RestoreFiles int   `json:"restore_files"`
+			}{snap, stats.TotalSize, stats.TotalFileCount})
+		}
+
+		fmt.Printf("Snapshot: %s\n", snap.ID)
+		fmt.Printf("Time: %s\n", snap.Time.Local().Format("2006-01-02 15:04:05 MST"))
+		fmt.Printf("Host: %s\n", snap.Hostname)
+		fmt.Printf("Tags: %s\n", strings.Join(snap.Tags, ", "))
+		fmt.Printf("Paths: %s\n", strings.Join(snap.Paths, ", "))
+		if snap.Parent != "" {
+			fmt.Printf("Parent: %s\n", snap.Parent)
+		}
+		fmt.Printf("Restore Size: %s (%d files)\n", formatSize(stats.TotalSize), stats.TotalFileCount)
+		if sum := snap.Summary; sum != nil {
+			fmt.Printf("Duration: %s\n", sum.BackupEnd.Sub(sum.BackupStart).Round(time.Second))
+			fmt.Printf("Files: %d new, %d changed, %d unmodified\n", sum.FilesNew, sum.FilesChanged, sum.FilesUnmodified)
+			fmt.Printf("Data Added: %s (%s packed)\n", formatSize(sum.DataAdded), formatSize(sum.DataAddedPacked))
+		}
+		return nil
+	},
+}
+
+var (
+	createParallel   bool
+	createMaxWorkers int
+)
+
 var backupCreateCmd = &cobra.Command{
-	Use:               "create [world]",
-	Short:             "Create a new backup",
-	Long:              "Create a new backup of the specified world, or all worlds if no world is specified.",
-	Args:              cobra.MaximumNArgs(1),
-	ValidArgsFunction: backupWorldCompletionFunc,
+	Use:   "create [world|pattern...]",
+	Short: "Create a new backup",
+	Long: `Create a new backup of the specified worlds, or all worlds if no world is specified.
+
+Worlds can be given as glob patterns such as 'survival*'. Each world matched
+gets its own snapshot tagged with the world name; with --parallel up to
+--max-workers worlds are backed up at once. A summary of every world's
+result is printed at the end. Without arguments, or with 'all', the whole
+worlds directory is backed up as a single snapshot tagged "all".
+
+Running worlds are quiesced through their own RCON endpoint (rcon.port and
+rcon.password from server.properties): saving is turned off with save-off,
+the world is flushed with save-all flush, and once the server confirms
+"Saved the game" restic runs. save-on is always sent afterwards, also when
+restic fails or the backup is interrupted. Worlds that are stopped are
+backed up without quiescing.
+
+Examples:
+  minecraftctl backup create
+  minecraftctl backup create survival
+  minecraftctl backup create 'survival*' creative --parallel --max-workers 2`,
+	ValidArgsFunction: backupWorldsCompletionFunc,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !backup.IsResticInstalled() {
 			return fmt.Errorf("restic is not installed")
 		}
 
-		cfg, err := backup.LoadConfig()
+		cfg, err := backup.LoadRepoConfig(backupRepo)
 		if err != nil {
 			return err
 		}
 
-		world := "all"
-		if len(args) > 0 {
-			world = args[0]
+		if len(args) == 0 || (len(args) == 1 && args[0] == "all") {
+			return cfg.Create("all")
 		}
 
-		return cfg.Create(world)
+		// Expand all patterns to world names, each backed up once when
+		// patterns overlap
+		worldNames := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, pattern := range args {
+			expanded, err := worlds.ExpandWorldPattern(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to expand pattern %s: %w", pattern, err)
+			}
+			for _, name := range expanded {
+				if !seen[name] {
+					seen[name] = true
+					worldNames = append(worldNames, name)
+				}
+			}
+		}
+
+		if len(worldNames) == 1 && !createParallel {
+			// Single world, show restic's progress
+			return cfg.Create(worldNames[0])
+		}
+
+		if err := cfg.InitRepository(); err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+		return backupBatch(cfg, worldNames, createParallel, createMaxWorkers)
 	},
 }
 
-var restoreTarget string
+// backupBatch backs up multiple worlds, each to its own snapshot, and prints
+// a summary of the results
+func backupBatch(cfg *backup.Config, worldNames []string, parallel bool, maxWorkers int) error {
+	results := make([]*backup.CreateResult, len(worldNames))
+	errs := make([]error, len(worldNames))
+
+	if !parallel {
+		// Sequential processing
+		for i, worldName := range worldNames {
+			fmt.Printf("Backing up world: %s...\n", worldName)
+			results[i], errs[i] = cfg.CreateWorld(worldName)
+			if errs[i] != nil {
+				log.Error().Err(errs[i]).Str("world", worldName).Msg("failed to back up world")
+			}
+		}
+	} else {
+		// Parallel processing with worker pool
+		if maxWorkers <= 0 {
+			maxWorkers = runtime.NumCPU()
+		}
+		if maxWorkers > len(worldNames) {
+			maxWorkers = len(worldNames)
+		}
+
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, maxWorkers)
+
+		for i, worldName := range worldNames {
+			wg.Add(1)
+			go func(idx int, name string) {
+				defer wg.Done()
+				semaphore <- struct{}{}        // Acquire
+				defer func() { <-semaphore }() // Release
+
+				fmt.Printf("Backing up world: %s...\n", name)
+				results[idx], errs[idx] = cfg.CreateWorld(name)
+				if errs[idx] != nil {
+					log.Error().Err(errs[idx]).Str("world", name).Msg("failed to back up world")
+				}
+			}(i, worldName)
+		}
+
+		wg.Wait()
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORLD\tSTATUS\tSNAPSHOT\tADDED\tDURATION\tERROR")
+	failed := 0
+	for i, worldName := range worldNames {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(w, "%s\tFAILED\t-\t-\t-\t%v\n", worldName, errs[i])
+			continue
+		}
+		r := results[i]
+		fmt.Fprintf(w, "%s\tOK\t%s\t%s\t%s\t\n", worldName, r.Snapshot, formatSize(r.DataAdded), r.Duration)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d world backup(s) failed", failed, len(worldNames))
+	}
+	return nil
+}
+
+var (
+	restoreTarget  string
+	restoreWorld   string
+	restoreInPlace bool
+)
 
 var backupRestoreCmd = &cobra.Command{
 	Use:   "restore <snapshot>",
@@ -84,23 +364,62 @@ var backupRestoreCmd = &cobra.Command{
 
 Use 'latest' to restore the most recent snapshot.
 Use --target to restore to a custom location instead of the original path.
+Use --world to restore only one world's world/ directory, into --target or,
+with --in-place, over the live world.
+
+An in-place restore stops the world's service, moves the current world/
+aside to world.pre-restore-<timestamp>, restores the world from the
+snapshot, fixes ownership and starts the service again if it was running.
 
 Examples:
   minecraftctl backup restore latest
   minecraftctl backup restore abc123
-  minecraftctl backup restore latest --target /tmp/restore-test`,
+  minecraftctl backup restore latest --target /tmp/restore-test
+  minecraftctl backup restore latest --world survival --target /tmp/survival
+  minecraftctl backup restore abc123 --world survival --in-place`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !backup.IsResticInstalled() {
 			return fmt.Errorf("restic is not installed")
 		}
+		if restoreInPlace && restoreWorld == "" {
+			return fmt.Errorf("--in-place requires --world")
+		}
+		if restoreInPlace && restoreTarget != "" {
+			return fmt.Errorf("--in-place and --target cannot be used together")
+		}
+		if restoreWorld != "" && !restoreInPlace && restoreTarget == "" {
+			return fmt.Errorf("--world requires --target or --in-place")
+		}
 
-		cfg, err := backup.LoadConfig()
+		cfg, err := backup.LoadRepoConfig(backupRepo)
 		if err != nil {
 			return err
 		}
 
 		snapshot := args[0]
+
+		if restoreInPlace {
+			result, err := worlds.RestoreWorld(cfg, snapshot, restoreWorld)
+			if result != nil {
+				printRestoreResult(result)
+			}
+			return err
+		}
+
+		if restoreWorld != "" {
+			snap, err := cfg.WorldSnapshot(restoreWorld, snapshot)
+			if err != nil {
+				return err
+			}
+			path, err := backup.WorldPath(snap, restoreWorld)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restoring world %s from snapshot %s to %s...\n", restoreWorld, snap.ShortID, restoreTarget)
+			return cfg.RestorePath(snap.ID, path, restoreTarget)
+		}
+
 		target := restoreTarget
 		if target == "" {
 			target = "/"
@@ -110,48 +429,221 @@ Examples:
 	},
 }
 
+// printRestoreResult reports an in-place restore and how to undo it
+func printRestoreResult(result *worlds.RestoreResult) {
+	fmt.Printf("\nRestored world %s from snapshot %s\n", result.WorldName, result.Snapshot)
+	if result.Restarted {
+		fmt.Println("Service restarted")
+	} else if !result.WasRunning {
+		fmt.Println("Service was not running and has not been started")
+	}
+
+	if result.PreRestorePath == "" {
+		return
+	}
+	fmt.Printf("Previous world kept at %s\n", result.PreRestorePath)
+	fmt.Println("\nTo undo the restore:")
+	fmt.Printf("  minecraftctl world stop %s\n", result.WorldName)
+	fmt.Printf("  rm -rf %s\n", result.WorldPath)
+	fmt.Printf("  mv %s %s\n", result.PreRestorePath, result.WorldPath)
+	fmt.Printf("  minecraftctl world start %s\n", result.WorldName)
+	fmt.Println("\nOnce the restore is confirmed, remove the previous world:")
+	fmt.Printf("  rm -rf %s\n", result.PreRestorePath)
+}
+
+var restorePlayerSnapshot string
+
+var backupRestorePlayerCmd = &cobra.Command{
+	Use:   "restore-player <world> <player|uuid>",
+	Short: "Restore a player's data from a backup",
+	Long: `Restore a player's inventory and position (playerdata/<uuid>.dat), stats
+and advancements from a snapshot, leaving the rest of the world as it is.
+
+The world must be stopped. Players are given by UUID or by name, which is
+looked up in the world's usercache.json. Current files are kept next to the
+restored ones with a .pre-restore-<timestamp> suffix.
+
+Examples:
+  minecraftctl backup restore-player survival Steve
+  minecraftctl backup restore-player survival 069a79f4-44e9-4726-a5be-fca90e38aaf5 --snapshot abc123`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+
+		result, err := worlds.RestorePlayer(cfg, restorePlayerSnapshot, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Restored player %s in world %s from snapshot %s:\n", result.UUID, result.WorldName, result.Snapshot)
+		for _, rel := range result.Restored {
+			if previous, ok := result.Previous[rel]; ok {
+				fmt.Printf("  %s (previous: %s)\n", rel, previous)
+			} else {
+				fmt.Printf("  %s\n", rel)
+			}
+		}
+		return nil
+	},
+}
+
+var (
+	restoreAreaSnapshot  string
+	restoreAreaDimension string
+	restoreAreaBox       string
+)
+
+var backupRestoreAreaCmd = &cobra.Command{
+	Use:   "restore-area <world> --box x1,z1,x2,z2",
+	Short: "Restore the chunks in an area from a backup",
+	Long: `Restore the chunks overlapping an area of a dimension from a snapshot, for
+example to undo griefing without rolling back the whole world.
+
+--box gives two opposite corners in block coordinates. For each region,
+entities and poi file the area touches, only the chunks inside the box are
+replaced with their copy from the snapshot; the rest of each file is left
+as it is. The world must be stopped. Replaced files are kept in
+world.pre-restore-area-<timestamp> in the world directory.
+
+Examples:
+  minecraftctl backup restore-area survival --box -120,80,40,260
+  minecraftctl backup restore-area survival --dimension nether --box 0,0,100,100 --snapshot abc123`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+		box, err := region.ParseBox(restoreAreaBox)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+
+		result, err := worlds.RestoreArea(cfg, restoreAreaSnapshot, args[0], restoreAreaDimension, box)
+		if err != nil {
+			return err
+		}
+
+		if len(result.Modified)+len(result.Created) == 0 {
+			fmt.Printf("No chunks in %s differ from snapshot %s\n", box, result.Snapshot)
+			return nil
+		}
+		fmt.Printf("Restored %d chunk(s) in %s of world %s from snapshot %s\n", result.Chunks, box, result.WorldName, result.Snapshot)
+		for _, rel := range result.Modified {
+			fmt.Printf("  updated %s\n", rel)
+		}
+		for _, rel := range result.Created {
+			fmt.Printf("  created %s\n", rel)
+		}
+		if result.BackupDir != "" {
+			worldPath := filepath.Join(filepath.Dir(result.BackupDir), "world")
+			fmt.Printf("\nTo undo, with the world stopped:\n")
+			fmt.Printf("  cp -a %s/. %s/\n", result.BackupDir, worldPath)
+			for _, rel := range result.Created {
+				fmt.Printf("  rm %s\n", filepath.Join(worldPath, rel))
+			}
+		}
+		return nil
+	},
+}
+
+var backupPruneDryRun bool
+
 var backupPruneCmd = &cobra.Command{
 	Use:   "prune",
 	Short: "Remove old backup snapshots",
 	Long: `Remove old backup snapshots according to the retention policy.
 
-Retention policy:
+Snapshots are grouped by world tag and each world's policy from
+backup.retention in minecraftctl.yml is applied to its snapshots. Worlds
+without their own policy use backup.retention.defaults, or if none is set:
   - Keep 7 daily snapshots
   - Keep 4 weekly snapshots
   - Keep 3 monthly snapshots
 
-This command also verifies repository integrity after pruning.`,
+Snapshots carrying one of the policy's keep_tags (e.g. pre-upgrade) are
+always kept. This command also verifies repository integrity after pruning.
+
+Examples:
+  minecraftctl backup prune --dry-run
+  minecraftctl backup prune`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !backup.IsResticInstalled() {
 			return fmt.Errorf("restic is not installed")
 		}
 
-		cfg, err := backup.LoadConfig()
+		cfg, err := backup.LoadRepoConfig(backupRepo)
 		if err != nil {
 			return err
 		}
 
-		return cfg.Prune()
+		return cfg.Prune(backup.PruneOptions{
+			Retention: config.Get().Backup.Retention,
+			DryRun:    backupPruneDryRun,
+		})
 	},
 }
 
+var (
+	statsMode   string
+	statsOutput string
+)
+
 var backupStatsCmd = &cobra.Command{
-	Use:   "stats",
+	Use:   "stats [snapshot...]",
 	Short: "Show backup repository statistics",
-	Long:  "Display statistics about the backup repository including size and deduplication.",
-	Args:  cobra.NoArgs,
+	Long: `Display statistics about the backup repository, or the given snapshots.
+
+--mode selects how restic counts: restore-size (the default) is the size of
+restoring every snapshot, raw-data the deduplicated and compressed data
+actually stored in the repository.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !backup.IsResticInstalled() {
 			return fmt.Errorf("restic is not installed")
 		}
+		switch statsOutput {
+		case "json", "text", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: text, json)", statsOutput)
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
 
-		cfg, err := backup.LoadConfig()
+		stats, err := cfg.RepoStats(statsMode, args...)
 		if err != nil {
 			return err
 		}
 
-		return cfg.Stats()
+		if statsOutput == "json" {
+			return printJSON(stats)
+		}
+
+		fmt.Printf("Repository: %s\n", cfg.Name)
+		fmt.Printf("Snapshots: %d\n", stats.SnapshotsCount)
+		fmt.Printf("Total Size: %s\n", formatSize(stats.TotalSize))
+		fmt.Printf("Total Files: %d\n", stats.TotalFileCount)
+		if stats.TotalUncompressedSize > 0 {
+			fmt.Printf("Uncompressed Size: %s\n", formatSize(stats.TotalUncompressedSize))
+			fmt.Printf("Compression Ratio: %.2fx (%.1f%% saved)\n", stats.CompressionRatio, stats.CompressionSpaceSaving)
+		}
+		return nil
 	},
 }
 
@@ -165,7 +657,7 @@ var backupCheckCmd = &cobra.Command{
 			return fmt.Errorf("restic is not installed")
 		}
 
-		cfg, err := backup.LoadConfig()
+		cfg, err := backup.LoadRepoConfig(backupRepo)
 		if err != nil {
 			return err
 		}
@@ -174,15 +666,429 @@ var backupCheckCmd = &cobra.Command{
 	},
 }
 
+var (
+	verifyWorld    string
+	verifySnapshot string
+)
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify that worlds can be restored from a backup",
+	Long: `Test-restore worlds from a snapshot into a temporary directory and check
+that the result is usable: level.dat parses, every region file has a valid
+header, and the number and size of the restored files match what the backup
+recorded. The temporary directory is removed afterwards.
+
+Without --world every world is verified against its own latest snapshot.
+Results are recorded in each world's backup-verify.json for 'backup status'.
+The command fails if any world fails verification.
+
+Examples:
+  minecraftctl backup verify
+  minecraftctl backup verify --world survival --snapshot abc123`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+
+		results, err := cfg.VerifyWorlds(verifyWorld, verifySnapshot)
+		failed := 0
+		for _, result := range results {
+			status := "OK"
+			if !result.OK {
+				status = "FAILED"
+				failed++
+			}
+			fmt.Printf("\n%s: %s (snapshot %s, %d files, %s, %d region files, %s)\n", result.World, status,
+				result.Snapshot, result.Files, formatSize(result.Bytes), result.Regions, result.Duration)
+			if result.LevelName != "" {
+				fmt.Printf("  level.dat: %s, %s\n", result.LevelName, result.Version)
+			}
+			for _, problem := range result.Problems {
+				fmt.Printf("  problem: %s\n", problem)
+			}
+			for _, note := range result.Notes {
+				fmt.Printf("  note: %s\n", note)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d world(s) failed verification", failed, len(results))
+		}
+		return nil
+	},
+}
+
+var (
+	statusMaxAge time.Duration
+	statusOutput string
+)
+
+// Exit codes of backup status
+const (
+	statusExitStale        = 2
+	statusExitVerifyFailed = 3
+)
+
+var backupStatusCmd = &cobra.Command{
+	Use:   "status [world...]",
+	Short: "Show how current each world's backups are",
+	Long: `Show, per world, the newest snapshot containing it and its age, the
+world's size and its change since the previous snapshot, the last backup
+verification, and the next run of the backup timer.
+
+A world is STALE when it has been played since its newest snapshot and that
+snapshot is older than --max-age (backup.max_age in minecraftctl.yml, default
+36h). A world nobody has played since its last backup is never stale.
+
+Exit codes, for monitoring:
+  0  every world is backed up
+  1  the status could not be determined
+  2  a world is stale or has no snapshot
+  3  no world is stale, but a world's last verification failed`,
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+		switch statusOutput {
+		case "json", "table", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", statusOutput)
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+		maxAge := statusMaxAge
+		if !cmd.Flags().Changed("max-age") {
+			maxAge = config.Get().Backup.MaxAge
+		}
+
+		statuses, err := cfg.Status(maxAge, args...)
+		if err != nil {
+			return err
+		}
+
+		if statusOutput == "json" {
+			if err := printJSON(statuses); err != nil {
+				return err
+			}
+		} else {
+			printBackupStatus(statuses)
+		}
+
+		code := 0
+		for _, status := range statuses {
+			if status.State != backup.StateOK {
+				code = statusExitStale
+				break
+			}
+			if status.LastVerify != nil && !status.LastVerify.OK {
+				code = statusExitVerifyFailed
+			}
+		}
+		if code != 0 {
+			// The table already shows why; only the exit status is needed
+			cmd.SilenceErrors = true
+			return &exitCodeError{code: code}
+		}
+		return nil
+	},
+}
+
+// printBackupStatus prints backup statuses as a table
+func printBackupStatus(statuses []backup.WorldStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORLD\tSTATE\tSNAPSHOT\tAGE\tSIZE\tDELTA\tLAST PLAYED\tVERIFIED\tNEXT BACKUP")
+	for _, s := range statuses {
+		snapshot, age := "-", "-"
+		if s.Snapshot != "" {
+			snapshot = s.Snapshot
+			age = formatAge(s.Age)
+		}
+		size, delta := "-", "-"
+		if s.Size != nil {
+			size = formatSize(*s.Size)
+		}
+		if s.SizeDelta != nil {
+			if *s.SizeDelta < 0 {
+				delta = "-" + formatSize(-*s.SizeDelta)
+			} else {
+				delta = "+" + formatSize(*s.SizeDelta)
+			}
+		}
+		verified := "never"
+		if s.LastVerify != nil && !s.LastVerify.OK {
+			verified = "FAILED " + s.LastVerify.Time.Local().Format("2006-01-02 15:04")
+		} else if !s.LastVerified.IsZero() {
+			verified = s.LastVerified.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.World, strings.ToUpper(s.State), snapshot, age,
+			size, delta, formatStatusTime(s.LastPlayed), verified, formatStatusTime(s.NextBackup))
+	}
+	w.Flush()
+}
+
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatAge formats a duration in the largest whole units, e.g. 2d3h or 45m
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
+
+var (
+	diffWorld  string
+	diffOutput string
+)
+
+var backupDiffCmd = &cobra.Command{
+	Use:   "diff <snapshot-a> <snapshot-b> --world <world>",
+	Short: "Show what changed in a world between two backups",
+	Long: `Compare a world in two snapshots and report the changes in world terms:
+the region files changed in each dimension and the block areas they cover,
+the players whose data changed, and whether level.dat, datapacks or
+server.properties changed. server.properties is only included in snapshots
+of all worlds.
+
+Areas are printed as x1,z1,x2,z2 block coordinates, as taken by
+'backup restore-area --box'. Use -o json to feed the changes to other tools.
+
+Examples:
+  minecraftctl backup diff abc123 def456 --world survival
+  minecraftctl backup diff abc123 latest --world survival -o json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+		switch diffOutput {
+		case "json", "text", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: text, json)", diffOutput)
+		}
+
+		cfg, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+
+		diff, err := cfg.Diff(args[0], args[1], diffWorld)
+		if err != nil {
+			return err
+		}
+
+		if diffOutput == "json" {
+			return printJSON(diff)
+		}
+		printWorldDiff(diff)
+		return nil
+	},
+}
+
+// printWorldDiff prints a world diff as text
+func printWorldDiff(diff *backup.WorldDiff) {
+	fmt.Printf("Changes in %s from %s (%s) to %s (%s)\n", diff.World,
+		diff.From, diff.FromTime.Local().Format("2006-01-02 15:04"),
+		diff.To, diff.ToTime.Local().Format("2006-01-02 15:04"))
+
+	if len(diff.Dimensions) == 0 {
+		fmt.Println("\nNo region files changed")
+	}
+	for _, d := range diff.Dimensions {
+		fmt.Printf("\n%s: %d region file(s) changed", d.Dimension, len(d.Regions))
+		if d.Area != nil {
+			fmt.Printf(", area %s", d.Area)
+		}
+		if d.Entities > 0 || d.POI > 0 {
+			fmt.Printf(" (%d entities, %d poi file(s))", d.Entities, d.POI)
+		}
+		fmt.Println()
+		for _, r := range d.Regions {
+			fmt.Printf("  %-14s %-8s %s\n", region.FileName(r.X, r.Z), r.Change, r.Area)
+		}
+	}
+
+	if len(diff.Players) > 0 {
+		fmt.Printf("\nPlayers: %d changed\n", len(diff.Players))
+		for _, p := range diff.Players {
+			name := p.UUID
+			if p.Name != "" {
+				name = fmt.Sprintf("%s (%s)", p.Name, p.UUID)
+			}
+			var files []string
+			for _, f := range p.Files {
+				files = append(files, fmt.Sprintf("%s %s", strings.SplitN(f.Path, "/", 2)[0], f.Change))
+			}
+			fmt.Printf("  %s: %s\n", name, strings.Join(files, ", "))
+		}
+	}
+
+	fmt.Println()
+	for _, f := range []struct{ name, change string }{
+		{"level.dat", diff.LevelDat},
+		{"server.properties", diff.ServerProperties},
+	} {
+		if f.change == "" {
+			f.change = "unchanged"
+		}
+		fmt.Printf("%s: %s\n", f.name, f.change)
+	}
+	if len(diff.Datapacks) == 0 {
+		fmt.Println("datapacks: unchanged")
+	} else {
+		fmt.Printf("datapacks: %d file(s) changed\n", len(diff.Datapacks))
+		for _, f := range diff.Datapacks {
+			fmt.Printf("  %-8s %s\n", f.Change, f.Path)
+		}
+	}
+	if diff.Other > 0 {
+		fmt.Printf("other files: %d changed\n", diff.Other)
+	}
+}
+
+var backupReposCmd = &cobra.Command{
+	Use:   "repos",
+	Short: "List configured backup repositories",
+	Long: `List the restic repositories configured under backup.repositories in
+minecraftctl.yml. Without configured repositories the "default" repository
+is derived from MC_WORLD_BUCKET.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backupCfg := config.Get().Backup
+		if len(backupCfg.Repositories) == 0 {
+			cfg, err := backup.LoadRepoConfig("")
+			if err != nil {
+				return fmt.Errorf("no repositories configured in minecraftctl.yml: %w", err)
+			}
+			fmt.Printf("%s (from MC_WORLD_BUCKET): %s\n", cfg.Name, cfg.Repository)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tREPOSITORY\tDEFAULT")
+		for _, name := range backup.RepositoryNames(backupCfg) {
+			repo := backupCfg.Repositories[name]
+			url, err := backup.RepositoryURL(repo)
+			if err != nil {
+				url = "invalid: " + err.Error()
+			}
+			defaultStr := ""
+			if name == backupCfg.DefaultRepo || len(backupCfg.Repositories) == 1 {
+				defaultStr = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, repo.Type, url, defaultStr)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var (
+	copyTo  string
+	copyTag string
+)
+
+var backupCopyCmd = &cobra.Command{
+	Use:   "copy [snapshot...] --to <repo>",
+	Short: "Copy snapshots to another repository",
+	Long: `Copy snapshots from the --repo repository (default: the default
+repository) to the --to repository using restic copy.
+
+Without snapshot IDs all snapshots are copied, optionally only those with
+--tag (a world name or 'all'). Snapshots already in the destination are
+skipped, so the command can run repeatedly to keep an offsite copy in sync.
+The destination is initialized with the source's chunker parameters if it
+does not exist yet.
+
+Examples:
+  minecraftctl backup copy --to offsite
+  minecraftctl backup copy --repo local --to offsite --tag survival
+  minecraftctl backup copy abc123 --to offsite`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !backup.IsResticInstalled() {
+			return fmt.Errorf("restic is not installed")
+		}
+
+		src, err := backup.LoadRepoConfig(backupRepo)
+		if err != nil {
+			return err
+		}
+		dst, err := backup.LoadRepoConfig(copyTo)
+		if err != nil {
+			return err
+		}
+
+		return dst.Copy(src, copyTag, args...)
+	},
+}
+
 func init() {
 	// Add flags
+	BackupCmd.PersistentFlags().StringVar(&backupRepo, "repo", "", "Backup repository from minecraftctl.yml (default: backup.default_repo)")
+	backupCopyCmd.Flags().StringVar(&copyTo, "to", "", "Repository to copy snapshots to")
+	backupCopyCmd.MarkFlagRequired("to")
+	backupCopyCmd.Flags().StringVar(&copyTag, "tag", "", "Only copy snapshots with this tag (world name or 'all')")
+	backupListCmd.Flags().StringVarP(&listWorld, "world", "w", "", "Only list snapshots of this world (or 'all')")
+	backupListCmd.Flags().StringVar(&listSince, "since", "", "Only list snapshots taken at or after this time")
+	backupListCmd.Flags().StringVar(&listBefore, "before", "", "Only list snapshots taken before this time")
+	backupListCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format (table, json)")
+	backupShowCmd.Flags().StringVarP(&showOutput, "output", "o", "text", "Output format (text, json)")
+	backupStatsCmd.Flags().StringVar(&statsMode, "mode", "", "Counting mode (restore-size, files-by-contents, raw-data, blobs-per-file)")
+	backupStatsCmd.Flags().StringVarP(&statsOutput, "output", "o", "text", "Output format (text, json)")
+	backupRestorePlayerCmd.Flags().StringVarP(&restorePlayerSnapshot, "snapshot", "s", "latest", "Snapshot to restore from")
+	backupRestoreAreaCmd.Flags().StringVarP(&restoreAreaSnapshot, "snapshot", "s", "latest", "Snapshot to restore from")
+	backupRestoreAreaCmd.Flags().StringVarP(&restoreAreaDimension, "dimension", "d", "overworld", "Dimension (overworld, nether, end)")
+	backupRestoreAreaCmd.Flags().StringVar(&restoreAreaBox, "box", "", "Area as x1,z1,x2,z2 block coordinates")
+	backupRestoreAreaCmd.MarkFlagRequired("box")
+	backupCreateCmd.Flags().BoolVar(&createParallel, "parallel", false, "Back up multiple worlds in parallel")
+	backupCreateCmd.Flags().IntVar(&createMaxWorkers, "max-workers", runtime.NumCPU(), "Maximum number of parallel workers")
+	backupDiffCmd.Flags().StringVarP(&diffWorld, "world", "w", "", "World to compare")
+	backupDiffCmd.MarkFlagRequired("world")
+	backupDiffCmd.Flags().StringVarP(&diffOutput, "output", "o", "text", "Output format (text, json)")
+	backupVerifyCmd.Flags().StringVarP(&verifyWorld, "world", "w", "", "Only verify this world")
+	backupVerifyCmd.Flags().StringVarP(&verifySnapshot, "snapshot", "s", "latest", "Snapshot to verify")
+	backupStatusCmd.Flags().DurationVar(&statusMaxAge, "max-age", backup.DefaultMaxAge, "Age after which a played world's newest snapshot is stale (default: backup.max_age)")
+	backupStatusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "Output format (table, json)")
+	backupPruneCmd.Flags().BoolVar(&backupPruneDryRun, "dry-run", false, "Show which snapshots would be forgotten without removing any")
 	backupRestoreCmd.Flags().StringVarP(&restoreTarget, "target", "t", "", "Target directory for restore (default: original location)")
+	backupRestoreCmd.Flags().StringVarP(&restoreWorld, "world", "w", "", "Only restore this world's world/ directory")
+	backupRestoreCmd.Flags().BoolVar(&restoreInPlace, "in-place", false, "Replace the live world, stopping and restarting its service")
 
 	// Add subcommands to backup command
 	BackupCmd.AddCommand(backupListCmd)
+	BackupCmd.AddCommand(backupShowCmd)
 	BackupCmd.AddCommand(backupCreateCmd)
 	BackupCmd.AddCommand(backupRestoreCmd)
+	BackupCmd.AddCommand(backupRestorePlayerCmd)
+	BackupCmd.AddCommand(backupRestoreAreaCmd)
 	BackupCmd.AddCommand(backupPruneCmd)
 	BackupCmd.AddCommand(backupStatsCmd)
 	BackupCmd.AddCommand(backupCheckCmd)
+	BackupCmd.AddCommand(backupVerifyCmd)
+	BackupCmd.AddCommand(backupStatusCmd)
+	BackupCmd.AddCommand(backupDiffCmd)
+	BackupCmd.AddCommand(backupReposCmd)
+	BackupCmd.AddCommand(backupCopyCmd)
 }
@@ -46,7 +46,7 @@ func TestWorldSubcommands(t *testing.T) {
 	subcommands := []string{
 		"list", "info", "create", "register", "upgrade",
 		"status", "start", "stop", "restart", "enable", "disable", "logs",
-		"backup",
+		"backup", "java", "jvm",
 	}
 
 	for _, name := range subcommands {
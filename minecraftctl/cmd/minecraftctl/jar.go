@@ -1,19 +1,40 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/paul/minecraftctl/pkg/config"
 	"github.com/paul/minecraftctl/pkg/jars"
+	"github.com/paul/minecraftctl/pkg/worlds"
 	"github.com/spf13/cobra"
 )
 
 var jarCmd = &cobra.Command{
 	Use:   "jar",
 	Short: "Manage Minecraft server JARs",
-	Long:  "Commands for downloading, listing, and verifying Minecraft server JAR files",
+	Long: `Commands for downloading, listing, and verifying Minecraft server JAR files.
+
+Besides vanilla, Paper, Purpur and Fabric server JARs are supported with
+--flavor. Flavored JARs are stored as <flavor>_server_<version>-<build>.jar.`,
+}
+
+// jarFlavor is the --flavor flag shared by the jar subcommands
+var jarFlavor string
+
+// selectedFlavor parses --flavor, defaulting to vanilla
+func selectedFlavor() (jars.Flavor, error) {
+	return jars.ParseFlavor(jarFlavor)
 }
 
 var jarListCmd = &cobra.Command{
@@ -26,18 +47,62 @@ var jarListCmd = &cobra.Command{
 			return err
 		}
 
+		// Only filter by flavor when one was requested explicitly
+		if jarFlavor != "" {
+			flavor, err := selectedFlavor()
+			if err != nil {
+				return err
+			}
+			var filtered []jars.JarInfo
+			for _, jar := range jarList {
+				if jar.Flavor == flavor {
+					filtered = append(filtered, jar)
+				}
+			}
+			jarList = filtered
+		}
+
+		switch jarListOutput {
+		case "json":
+			if jarList == nil {
+				jarList = []jars.JarInfo{}
+			}
+			data, err := json.MarshalIndent(jarList, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal jar list: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		case "table", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", jarListOutput)
+		}
+
 		if len(jarList) == 0 {
 			fmt.Println("No JARs found")
 			return nil
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "VERSION\tSIZE\tCHECKSUM\tINSTALLED")
+		fmt.Fprintln(w, "FLAVOR\tVERSION\tDATA VERSION\tJAVA\tSIZE\tCHECKSUM\tINSTALLED")
 		for _, jar := range jarList {
+			dataVersionStr, javaStr := "-", "-"
+			if jar.Metadata != nil {
+				if jar.Metadata.WorldVersion > 0 {
+					dataVersionStr = fmt.Sprintf("%d", jar.Metadata.WorldVersion)
+				}
+				if jar.Metadata.JavaVersion > 0 {
+					javaStr = fmt.Sprintf("%d", jar.Metadata.JavaVersion)
+				}
+			}
+			versionStr := jar.Version
+			if jar.Build != "" {
+				versionStr += "-" + jar.Build
+			}
 			sizeStr := formatSize(jar.Size)
 			checksumStr := jar.Checksum[:8] + "..."
 			installedStr := jar.InstalledAt.Format("2006-01-02 15:04")
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", jar.Version, sizeStr, checksumStr, installedStr)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", jar.Flavor, versionStr, dataVersionStr, javaStr, sizeStr, checksumStr, installedStr)
 		}
 		w.Flush()
 
@@ -45,46 +110,466 @@ var jarListCmd = &cobra.Command{
 	},
 }
 
+var jarListOutput string
+
 var (
 	downloadURL    string
 	downloadSHA256 string
+	downloadBuild  string
+
+	downloadParallel int
+	downloadRetries  int
+	downloadTimeout  time.Duration
 )
 
 var jarDownloadCmd = &cobra.Command{
-	Use:   "download <version>",
-	Short: "Download a Minecraft server JAR",
-	Long:  "Download a Minecraft server JAR from a URL with optional checksum verification",
-	Args:  cobra.ExactArgs(1),
+	Use:   "download <version>...",
+	Short: "Download Minecraft server JARs",
+	Long: `Download one or more Minecraft server JARs.
+
+The version may be a release or snapshot id, or one of the aliases "latest"
+and "latest-snapshot". Without --url the download URL and SHA1 are resolved
+from the version manifest and the JAR is verified automatically.
+
+With --flavor paper, purpur or fabric the build is resolved from the
+flavor's build API (the newest stable build unless --build is given; for
+Fabric the build is the loader version) and verified with the published
+checksum: SHA256 for Paper, MD5 for Purpur. Fabric publishes no checksum.
+
+With --url the JAR is downloaded from the given URL and verified against
+--sha256 or the checksums.txt entry for the version, if any.
+
+Failed downloads are retried with exponential backoff (--retries) and resume
+where they stopped when the server supports range requests; an interrupted
+download leaves a .part file that the next run resumes. --timeout bounds each
+download including retries. Several versions are downloaded in parallel
+(--parallel at a time). Progress bars are shown on a terminal, progress log
+lines otherwise.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		version := args[0]
 		cfg := config.Get()
 
-		if downloadURL == "" {
-			return fmt.Errorf("--url is required")
+		flavor, err := selectedFlavor()
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 && (downloadURL != "" || downloadSHA256 != "" || downloadBuild != "") {
+			return fmt.Errorf("--url, --sha256 and --build can only be used when downloading a single version")
+		}
+		if downloadParallel < 1 {
+			return fmt.Errorf("--parallel must be at least 1")
 		}
 
-		// If --sha256 not provided, try to load from checksums.txt
-		expectedSHA256 := downloadSHA256
-		if expectedSHA256 == "" {
-			checksums, err := jars.LoadChecksums(cfg.JarsDir)
-			if err == nil {
-				jarFileName := fmt.Sprintf("minecraft_server_%s.jar", version)
-				if checksum, ok := checksums[jarFileName]; ok {
-					expectedSHA256 = checksum
-					fmt.Printf("Using checksum from checksums.txt: %s\n", checksum[:16]+"...")
-				}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		progress := newDownloadProgress(os.Stderr)
+		opts := jars.DownloadOptions{
+			Timeout: downloadTimeout,
+			Retries: downloadRetries,
+		}
+		if downloadRetries == 0 {
+			opts.Retries = -1
+		}
+
+		errs := make([]error, len(args))
+		results := make([]string, len(args))
+		sem := make(chan struct{}, downloadParallel)
+		var wg sync.WaitGroup
+		for i, version := range args {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i], errs[i] = downloadJar(ctx, cfg, flavor, version, opts, progress)
+			}()
+		}
+		wg.Wait()
+		progress.Finish()
+
+		if len(args) == 1 {
+			if errs[0] != nil {
+				return errs[0]
 			}
+			fmt.Printf("JAR %s downloaded successfully\n", results[0])
+			return nil
 		}
 
-		if err := jars.DownloadJar(version, downloadURL, cfg.JarsDir, expectedSHA256); err != nil {
+		var failed []string
+		for i, version := range args {
+			if errs[i] != nil {
+				fmt.Printf("JAR %s failed: %v\n", version, errs[i])
+				failed = append(failed, version)
+				continue
+			}
+			fmt.Printf("JAR %s downloaded successfully\n", results[i])
+		}
+
+		if len(failed) > 0 {
+			return fmt.Errorf("%d of %d downloads failed: %s", len(failed), len(args), strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// downloadJar resolves and downloads a single version, returning the label
+// of the downloaded JAR
+func downloadJar(ctx context.Context, cfg *config.GlobalConfig, flavor jars.Flavor, version string, opts jars.DownloadOptions, progress *downloadProgress) (string, error) {
+	expected := jars.Checksums{SHA256: downloadSHA256}
+
+	if flavor != jars.FlavorVanilla {
+		return downloadFlavorJar(ctx, flavor, version, cfg.JarsDir, expected, opts, progress)
+	}
+
+	url := downloadURL
+	if url == "" {
+		client := newManifestClient(cfg)
+		id, download, err := client.ResolveServerDownload(version)
+		if err != nil {
+			return "", err
+		}
+		if id != version {
+			fmt.Printf("Resolved %s to %s\n", version, id)
+		}
+		version = id
+		url = download.URL
+		expected.SHA1 = download.SHA1
+	}
+
+	// If --sha256 not provided, try to load from checksums.txt
+	if expected.SHA256 == "" {
+		checksums, err := jars.LoadChecksums(cfg.JarsDir)
+		if err == nil {
+			jarFileName := fmt.Sprintf("minecraft_server_%s.jar", version)
+			if checksum, ok := checksums[jarFileName]; ok {
+				expected.SHA256 = checksum
+				fmt.Printf("Using checksum from checksums.txt: %s\n", checksum[:16]+"...")
+			}
+		}
+	}
+
+	spec := jars.JarSpec{Flavor: jars.FlavorVanilla, Version: version}
+	opts.Progress = progress.Track(spec.Label())
+	if err := jars.DownloadJarContext(ctx, spec, url, cfg.JarsDir, expected, opts); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// downloadFlavorJar resolves and downloads a Paper, Purpur or Fabric JAR
+func downloadFlavorJar(ctx context.Context, flavor jars.Flavor, version, jarsDir string, expected jars.Checksums, opts jars.DownloadOptions, progress *downloadProgress) (string, error) {
+	var download *jars.FlavorDownload
+	if downloadURL != "" {
+		if downloadBuild == "" {
+			return "", fmt.Errorf("--build is required with --url for flavor %s", flavor)
+		}
+		download = &jars.FlavorDownload{
+			Spec: jars.JarSpec{Flavor: flavor, Version: version, Build: downloadBuild},
+			URL:  downloadURL,
+		}
+	} else {
+		resolved, err := jars.NewFlavorClient().Resolve(flavor, version, downloadBuild)
+		if err != nil {
+			return "", err
+		}
+		download = resolved
+		fmt.Printf("Resolved %s %s to build %s\n", flavor, version, download.Spec.Build)
+	}
+
+	if expected.SHA256 != "" {
+		download.Checksums.SHA256 = expected.SHA256
+	}
+
+	opts.Progress = progress.Track(download.Spec.Label())
+	if err := jars.DownloadJarContext(ctx, download.Spec, download.URL, jarsDir, download.Checksums, opts); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s as %s", download.Spec.Label(), download.Spec.FileName()), nil
+}
+
+var (
+	availableSnapshots bool
+	availableOffline   bool
+)
+
+var jarAvailableCmd = &cobra.Command{
+	Use:   "available",
+	Short: "List server versions available for download",
+	Long: `List server versions from the version manifest, newest first.
+
+The manifest is cached under the cache directory; when it cannot be fetched
+(or with --offline) the cached copy is used instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flavor, err := selectedFlavor(); err != nil {
 			return err
+		} else if flavor != jars.FlavorVanilla {
+			return fmt.Errorf("jar available only lists vanilla versions")
 		}
 
-		fmt.Printf("JAR %s downloaded successfully\n", version)
+		cfg := config.Get()
+		client := newManifestClient(cfg)
+		client.Offline = availableOffline
+
+		manifest, err := client.Manifest()
+		if err != nil {
+			return err
+		}
+
+		versions := manifest.Filter(availableSnapshots)
+		if len(versions) == 0 {
+			fmt.Println("No versions found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tTYPE\tRELEASED\tLATEST")
+		for _, v := range versions {
+			latestStr := ""
+			switch v.ID {
+			case manifest.Latest.Release:
+				latestStr = jars.LatestRelease
+			case manifest.Latest.Snapshot:
+				latestStr = jars.LatestSnapshot
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.ID, v.Type, v.ReleaseTime.Format("2006-01-02"), latestStr)
+		}
+		w.Flush()
+
 		return nil
 	},
 }
 
+var jarUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show which worlds use each JAR",
+	Long: `Show which worlds use each installed JAR.
+
+A JAR is in use when a world's server.jar symlink points to it. JARs a world
+ran before an upgrade (recorded in upgrade-history.json) are listed under
+ROLLBACK; 'jar prune' keeps them unless --force is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Get()
+		jarList, err := jars.ListJars(cfg.JarsDir)
+		if err != nil {
+			return err
+		}
+
+		refs, err := worlds.CollectJarReferences()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "JAR\tFLAVOR\tVERSION\tWORLDS\tROLLBACK")
+
+		listed := make(map[string]bool)
+		for _, jar := range jarList {
+			name := filepath.Base(jar.Path)
+			listed[name] = true
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, jar.Flavor, jar.Version,
+				joinOrDash(refs.InUse[name]), joinOrDash(refs.Rollback[name]))
+		}
+
+		// Worlds pointing at JARs that are no longer installed
+		var missing []string
+		for name := range refs.InUse {
+			if !listed[name] {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		for _, name := range missing {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, "-", "(missing)",
+				joinOrDash(refs.InUse[name]), joinOrDash(refs.Rollback[name]))
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+var (
+	pruneKeep   int
+	pruneDryRun bool
+	pruneForce  bool
+)
+
+var jarPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove JARs no world uses",
+	Long: `Remove JARs that no world's server.jar points to and drop their
+entries from checksums.txt.
+
+JARs a world ran before an upgrade are kept so the upgrade can be rolled
+back, unless --force is given. JARs in use are never removed. --keep also
+keeps the N newest unused JARs of each flavor.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Get()
+
+		if pruneKeep < 0 {
+			return fmt.Errorf("--keep must be >= 0")
+		}
+
+		refs, err := worlds.CollectJarReferences()
+		if err != nil {
+			return err
+		}
+
+		decisions, err := jars.PruneJars(cfg.JarsDir, jars.PruneOptions{
+			Keep:     pruneKeep,
+			DryRun:   pruneDryRun,
+			Force:    pruneForce,
+			InUse:    refs.InUse,
+			Rollback: refs.Rollback,
+		})
+
+		removeLabel := "removed"
+		if pruneDryRun {
+			removeLabel = "would remove"
+		}
+		removed := 0
+		for _, d := range decisions {
+			action := "keep"
+			if d.Remove {
+				action = removeLabel
+				removed++
+			}
+			fmt.Printf("%-12s %s (%s)\n", action, filepath.Base(d.Jar.Path), d.Reason)
+		}
+		if err != nil {
+			return err
+		}
+
+		if pruneDryRun {
+			fmt.Printf("\nDry run: %d JAR(s) would be removed\n", removed)
+		} else {
+			fmt.Printf("\n%d JAR(s) removed\n", removed)
+		}
+		return nil
+	},
+}
+
+var (
+	syncFile   string
+	syncCheck  bool
+	syncPrune  bool
+	syncOutput string
+)
+
+var jarSyncCmd = &cobra.Command{
+	Use:   "sync -f <jars.yml|vars.hcl>",
+	Short: "Sync the jars directory with a declarative JAR list",
+	Long: `Make the jars directory match a declarative list of JARs.
+
+The list is a YAML file with a top-level "jars" list, or a Packer variables
+file such as minecraft_jars.auto.pkrvars.hcl (read when the file ends in .hcl):
+
+  jars:
+    - version: 1.21.4
+      url: https://piston-data.mojang.com/v1/objects/.../server.jar
+      sha256: <checksum>
+    - version: 1.21.4
+      flavor: paper
+      build: "232"
+      url: https://api.papermc.io/v2/projects/paper/versions/1.21.4/builds/232/downloads/paper-1.21.4-232.jar
+      sha256: <checksum>
+
+Missing JARs are downloaded, existing JARs are verified and downloaded again
+if their checksum differs, and checksums.txt is rewritten to match. With
+--prune, JARs not in the list are removed unless a world uses them or needs
+them for rollback. With --check nothing is changed; the command only reports
+drift and exits non-zero if there is any.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Get()
+
+		lock, err := jars.LoadJarLock(syncFile)
+		if err != nil {
+			return err
+		}
+
+		protected := make(map[string]string)
+		if syncPrune {
+			refs, err := worlds.CollectJarReferences()
+			if err != nil {
+				return err
+			}
+			for name, names := range refs.Rollback {
+				protected[name] = fmt.Sprintf("needed for rollback by %v", names)
+			}
+			for name, names := range refs.InUse {
+				protected[name] = fmt.Sprintf("in use by %v", names)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		progress := newDownloadProgress(os.Stderr)
+		result, syncErr := jars.SyncJars(ctx, cfg.JarsDir, lock, jars.SyncOptions{
+			Check:     syncCheck,
+			Prune:     syncPrune,
+			Protected: protected,
+			Track:     progress.Track,
+		})
+		progress.Finish()
+		if result == nil {
+			return syncErr
+		}
+
+		switch syncOutput {
+		case "json":
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal sync result: %w", err)
+			}
+			fmt.Println(string(data))
+		case "table", "":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "JAR\tACTION\tDETAIL")
+			for _, item := range result.Items {
+				detail := item.Detail
+				if item.Error != "" {
+					detail = "FAILED: " + item.Error
+				}
+				if detail == "" {
+					detail = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", item.Name, item.Action, detail)
+			}
+			w.Flush()
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", syncOutput)
+		}
+
+		if syncErr != nil {
+			return syncErr
+		}
+		if syncCheck && result.Drift {
+			return fmt.Errorf("jars directory %s has drifted from %s", cfg.JarsDir, syncFile)
+		}
+		return nil
+	},
+}
+
+// joinOrDash joins names with commas, or returns "-" if there are none
+func joinOrDash(names []string) string {
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
+
+// newManifestClient creates a version manifest client from the global configuration
+func newManifestClient(cfg *config.GlobalConfig) *jars.ManifestClient {
+	return jars.NewManifestClient(cfg.VersionManifestURL, cfg.CacheDir)
+}
+
 var jarVerifyCmd = &cobra.Command{
 	Use:   "verify <version>",
 	Short: "Verify a JAR's checksum",
@@ -94,7 +579,12 @@ var jarVerifyCmd = &cobra.Command{
 		version := args[0]
 		cfg := config.Get()
 
-		if err := jars.VerifyJar(version, cfg.JarsDir); err != nil {
+		flavor, err := selectedFlavor()
+		if err != nil {
+			return err
+		}
+
+		if err := jars.VerifyFlavorJar(flavor, version, cfg.JarsDir); err != nil {
 			return err
 		}
 
@@ -111,17 +601,42 @@ var jarInfoCmd = &cobra.Command{
 		version := args[0]
 		cfg := config.Get()
 
-		info, err := jars.GetJarInfo(version, cfg.JarsDir)
+		flavor, err := selectedFlavor()
+		if err != nil {
+			return err
+		}
+
+		info, err := jars.GetFlavorJarInfo(flavor, version, cfg.JarsDir)
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("Version: %s\n", info.Version)
+		fmt.Printf("Flavor: %s\n", info.Flavor)
+		if info.Build != "" {
+			fmt.Printf("Build: %s\n", info.Build)
+		}
 		fmt.Printf("Path: %s\n", info.Path)
 		fmt.Printf("Size: %s\n", formatSize(info.Size))
 		fmt.Printf("Checksum: %s\n", info.Checksum)
 		fmt.Printf("Installed: %s\n", info.InstalledAt.Format("2006-01-02 15:04:05"))
 
+		if meta := info.Metadata; meta != nil {
+			fmt.Printf("ID: %s\n", meta.ID)
+			fmt.Printf("Name: %s\n", meta.Name)
+			fmt.Printf("Data Version: %d\n", meta.WorldVersion)
+			fmt.Printf("Protocol Version: %d\n", meta.ProtocolVersion)
+			if meta.JavaVersion > 0 {
+				fmt.Printf("Java Version: %d\n", meta.JavaVersion)
+			}
+			if !meta.BuildTime.IsZero() {
+				fmt.Printf("Build Time: %s\n", meta.BuildTime.Format(time.RFC3339))
+			}
+			fmt.Printf("Bundler: %t\n", meta.Bundler)
+		} else {
+			fmt.Println("Metadata: not available (no version.json in jar)")
+		}
+
 		return nil
 	},
 }
@@ -131,10 +646,35 @@ func init() {
 	jarCmd.AddCommand(jarDownloadCmd)
 	jarCmd.AddCommand(jarVerifyCmd)
 	jarCmd.AddCommand(jarInfoCmd)
+	jarCmd.AddCommand(jarAvailableCmd)
+	jarCmd.AddCommand(jarUsageCmd)
+	jarCmd.AddCommand(jarPruneCmd)
+	jarCmd.AddCommand(jarSyncCmd)
 
-	jarDownloadCmd.Flags().StringVar(&downloadURL, "url", "", "URL to download the JAR from (required)")
-	jarDownloadCmd.MarkFlagRequired("url")
+	jarCmd.PersistentFlags().StringVar(&jarFlavor, "flavor", "", "Server flavor: vanilla, paper, purpur, fabric (default vanilla; list shows all flavors unless set)")
+
+	jarListCmd.Flags().StringVarP(&jarListOutput, "output", "o", "table", "Output format (table, json)")
+
+	jarDownloadCmd.Flags().StringVar(&downloadURL, "url", "", "URL to download the JAR from (default: resolved from the version manifest)")
 	jarDownloadCmd.Flags().StringVar(&downloadSHA256, "sha256", "", "Expected SHA256 checksum (optional, will use checksums.txt if available)")
+
+	jarDownloadCmd.Flags().StringVar(&downloadBuild, "build", "", "Build to download for non-vanilla flavors (default: newest stable build)")
+	jarDownloadCmd.Flags().IntVar(&downloadParallel, "parallel", 3, "Number of versions to download at once")
+	jarDownloadCmd.Flags().IntVar(&downloadRetries, "retries", jars.DefaultDownloadRetries, "Retries per download after a failed attempt")
+	jarDownloadCmd.Flags().DurationVar(&downloadTimeout, "timeout", jars.DefaultDownloadTimeout, "Timeout per download, including retries")
+
+	jarPruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "Also keep the N newest unused JARs of each flavor")
+	jarPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be removed without removing anything")
+	jarPruneCmd.Flags().BoolVar(&pruneForce, "force", false, "Also remove JARs needed to roll back a world upgrade")
+
+	jarSyncCmd.Flags().StringVarP(&syncFile, "file", "f", "", "JAR list to sync with (YAML, or a Packer .hcl variables file)")
+	jarSyncCmd.MarkFlagRequired("file")
+	jarSyncCmd.Flags().BoolVar(&syncCheck, "check", false, "Only report drift; exit non-zero if the jars directory differs")
+	jarSyncCmd.Flags().BoolVar(&syncPrune, "prune", false, "Remove JARs not in the list (JARs worlds use or need for rollback are kept)")
+	jarSyncCmd.Flags().StringVarP(&syncOutput, "output", "o", "table", "Output format (table, json)")
+
+	jarAvailableCmd.Flags().BoolVar(&availableSnapshots, "snapshots", false, "Include snapshots and pre-releases")
+	jarAvailableCmd.Flags().BoolVar(&availableOffline, "offline", false, "Only use the cached version manifest")
 }
 
 // formatSize formats a file size in human-readable format
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -153,6 +154,30 @@ func TestJarListCmdExecution(t *testing.T) {
 	})
 }
 
+func TestJarListCmdJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t, dir)
+	createTestJarFile(t, dir, "1.20.1", "content1")
+
+	jarListOutput = "json"
+	defer func() { jarListOutput = "table" }()
+
+	output, err := captureStdout(t, func() error {
+		return jarListCmd.RunE(jarListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("jarListCmd.RunE() failed: %v", err)
+	}
+
+	var list []jars.JarInfo
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	if len(list) != 1 || list[0].Version != "1.20.1" {
+		t.Errorf("Unexpected jar list: %+v", list)
+	}
+}
+
 func TestJarInfoCmdExecution(t *testing.T) {
 	t.Run("shows jar info", func(t *testing.T) {
 		dir := t.TempDir()
@@ -300,7 +325,7 @@ func TestJarVerifyCmdExecution(t *testing.T) {
 
 func TestJarCmdStructure(t *testing.T) {
 	t.Run("jar cmd has correct subcommands", func(t *testing.T) {
-		subcommands := []string{"list", "download", "verify", "info"}
+		subcommands := []string{"list", "download", "verify", "info", "available", "usage", "prune", "sync"}
 		for _, name := range subcommands {
 			found := false
 			for _, cmd := range jarCmd.Commands() {
@@ -349,8 +374,8 @@ func TestJarCmdStructure(t *testing.T) {
 	})
 
 	t.Run("jar download cmd uses", func(t *testing.T) {
-		if jarDownloadCmd.Use != "download <version>" {
-			t.Errorf("jarDownloadCmd.Use = %q, want 'download <version>'", jarDownloadCmd.Use)
+		if jarDownloadCmd.Use != "download <version>..." {
+			t.Errorf("jarDownloadCmd.Use = %q, want 'download <version>...'", jarDownloadCmd.Use)
 		}
 	})
 
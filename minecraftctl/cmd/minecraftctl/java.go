@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/java"
+	"github.com/spf13/cobra"
+)
+
+var javaCmd = &cobra.Command{
+	Use:   "java",
+	Short: "Manage Java runtimes",
+	Long:  "Commands for discovering the Java runtimes installed for Minecraft servers",
+}
+
+var javaListOutput string
+
+var javaListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed Java runtimes",
+	Long: `List installed Java runtimes and their versions.
+
+Runtimes are discovered under java_search_paths (default: /usr/lib/jvm/*,
+/usr/java/*, /opt/java/*, /opt/jdk*), JAVA_HOME and the java on PATH.
+The runtime used by worlds without a per-world selection is marked DEFAULT.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Get()
+		runtimes, err := java.Discover(cfg.JavaSearchPaths)
+		if err != nil {
+			return err
+		}
+
+		switch javaListOutput {
+		case "json":
+			if runtimes == nil {
+				runtimes = []java.Runtime{}
+			}
+			data, err := json.MarshalIndent(runtimes, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal java runtimes: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		case "table", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", javaListOutput)
+		}
+
+		if len(runtimes) == 0 {
+			fmt.Println("No Java runtimes found")
+			return nil
+		}
+
+		defaultRuntime, _ := java.Inspect(cfg.JavaPath)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MAJOR\tVERSION\tVENDOR\tPATH\tDEFAULT")
+		for _, rt := range runtimes {
+			vendor := rt.Vendor
+			if vendor == "" {
+				vendor = "-"
+			}
+			defaultStr := ""
+			if defaultRuntime != nil && defaultRuntime.Home == rt.Home {
+				defaultStr = "yes"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", rt.Major, rt.Version, vendor, rt.Path, defaultStr)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	javaCmd.AddCommand(javaListCmd)
+
+	javaListCmd.Flags().StringVarP(&javaListOutput, "output", "o", "table", "Output format (table, json)")
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/paul/minecraftctl/cmd/minecraftctl/root"
-	"github.com/paul/minecraftctl/pkg/jars"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -17,7 +18,9 @@ func init() {
 	rootCmd.AddCommand(RconCmd)
 	rootCmd.AddCommand(ConfigCmd)
 	rootCmd.AddCommand(BackupCmd)
-	rootCmd.AddCommand(jars.JarCmd)
+	rootCmd.AddCommand(jarCmd)
+	rootCmd.AddCommand(javaCmd)
+	rootCmd.AddCommand(snapshotCmd)
 }
 
 func main() {
@@ -25,7 +28,22 @@ func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
 	if err := root.GetRootCmd().Execute(); err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		log.Error().Err(err).Msg("command failed")
 		os.Exit(1)
 	}
 }
+
+// exitCodeError makes the command exit with a specific code, for commands
+// whose exit status is meant for monitoring. The command has already
+// reported the details.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
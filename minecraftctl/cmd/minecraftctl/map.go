@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/paul/minecraftctl/internal/commands"
 	"github.com/paul/minecraftctl/pkg/config"
 	"github.com/paul/minecraftctl/pkg/maps"
+	"github.com/paul/minecraftctl/pkg/s3"
 	"github.com/paul/minecraftctl/pkg/systemd"
 	"github.com/paul/minecraftctl/pkg/worlds"
 	"github.com/rs/zerolog/log"
@@ -74,6 +80,7 @@ var mapBuildNowCmd = &cobra.Command{
 		parallel, _ := cmd.Flags().GetBool("parallel")
 		maxWorkers, _ := cmd.Flags().GetInt("max-workers")
 		logLevel, _ := cmd.Flags().GetString("log-level")
+		keepGoing, _ := cmd.Flags().GetBool("keep-going")
 
 		// Expand all patterns to world names
 		worldNames := make([]string, 0)
@@ -101,8 +108,11 @@ var mapBuildNowCmd = &cobra.Command{
 				NoLock:      noLock,
 				NonBlocking: nonBlocking,
 				LogLevel:    logLevel,
+				KeepGoing:   keepGoing,
 			}
-			return builder.Build(opts)
+			report, err := builder.Build(opts)
+			printBuildReports([]*maps.BuildReport{report})
+			return err
 		}
 
 		// Multiple worlds - batch processing
@@ -114,6 +124,7 @@ var mapBuildNowCmd = &cobra.Command{
 			NoLock:      noLock,
 			NonBlocking: nonBlocking,
 			LogLevel:    logLevel,
+			KeepGoing:   keepGoing,
 		}, parallel, maxWorkers)
 	},
 }
@@ -665,16 +676,96 @@ var mapBuildLogsCmd = &cobra.Command{
 	},
 }
 
+var mapPublishCmd = &cobra.Command{
+	Use:   "publish [world]",
+	Short: "Upload changed map tiles to S3-compatible storage",
+	Long: `Uploads the maps directory, or one world's maps, to an S3 bucket.
+
+Only files whose content changed since the last publish are uploaded, with
+their Content-Type and Cache-Control set. Tiles removed locally are deleted
+from the bucket, and world_manifest.json and index.html are uploaded last so
+visitors never see an index pointing at missing tiles.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: mapSingleWorldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Get()
+		bucket, _ := cmd.Flags().GetString("bucket")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+		region, _ := cmd.Flags().GetString("region")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		resync, _ := cmd.Flags().GetBool("resync")
+
+		if bucket == "" {
+			bucket = cfg.Publish.Bucket
+		}
+		if !cmd.Flags().Changed("prefix") {
+			prefix = cfg.Publish.Prefix
+		}
+		if endpoint == "" {
+			endpoint = cfg.Publish.Endpoint
+		}
+		if region == "" {
+			region = cfg.Publish.Region
+		}
+		if bucket == "" {
+			return fmt.Errorf("no bucket given: use --bucket, publish.bucket in the config or MC_MAP_BUCKET")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		creds, err := s3.LoadCredentials(ctx)
+		if err != nil {
+			return err
+		}
+		// The bucket is signed for in its own region; on EC2 that is the
+		// instance's, as minecraft.env sets no AWS_REGION
+		if region == "" && endpoint == "" {
+			region, err = s3.InstanceRegion(ctx)
+			if err != nil {
+				log.Warn().Err(err).Str("region", s3.DefaultRegion).Msg("no region configured and instance region unavailable, using default")
+			}
+		}
+		client := &s3.Client{Bucket: bucket, Region: region, Endpoint: endpoint, Credentials: creds}
+
+		var worldName string
+		if len(args) == 1 {
+			worldName = args[0]
+		}
+		opts := maps.PublishOptions{
+			WorldName:   worldName,
+			Prefix:      prefix,
+			IndexPath:   maps.PublishIndexPath(cfg.CacheDir, endpoint, bucket, prefix),
+			Concurrency: concurrency,
+			DryRun:      dryRun,
+			Resync:      resync,
+		}
+		result, err := maps.NewPublisher(client).Publish(ctx, opts)
+		if result != nil {
+			verb := "Uploaded"
+			if dryRun {
+				verb = "Would upload"
+			}
+			fmt.Printf("%s %d files (%s), deleted %d, %d unchanged\n",
+				verb, result.Uploaded, formatSize(result.UploadedBytes), result.Deleted, result.Unchanged)
+		}
+		return err
+	},
+}
+
 func init() {
 	// Flags for map build now command
 	mapBuildNowCmd.Flags().String("map", "", "Build only a specific map (by name)")
-	mapBuildNowCmd.Flags().Bool("force", false, "Force rebuild even if map is up to date")
+	mapBuildNowCmd.Flags().Bool("force", false, "Re-render whole maps instead of only the regions changed since the last build")
 	mapBuildNowCmd.Flags().String("lock-file", "", "Path to lock file (default: from config)")
 	mapBuildNowCmd.Flags().Duration("lock-timeout", 0, "Maximum time to wait for lock (0 = block forever)")
 	mapBuildNowCmd.Flags().Bool("no-lock", false, "Disable file locking")
 	mapBuildNowCmd.Flags().Bool("non-blocking", false, "Exit immediately if lock is held")
 	mapBuildNowCmd.Flags().Bool("parallel", false, "Process multiple worlds in parallel")
 	mapBuildNowCmd.Flags().Int("max-workers", runtime.NumCPU(), "Maximum number of parallel workers")
+	mapBuildNowCmd.Flags().Bool("keep-going", false, "Build the remaining maps after a map fails (still exits non-zero)")
 	mapBuildNowCmd.Flags().String("log-level", "warning", "uNmINeD log level (verbose, debug, information, warning, error, fatal)")
 
 	mapPreviewCmd.Flags().String("log-level", "warning", "uNmINeD log level (verbose, debug, information, warning, error, fatal)")
@@ -686,6 +777,14 @@ func init() {
 	mapManifestCmd.Flags().Bool("update-index", false, "Update aggregate manifest and HTML index after manifest generation")
 	mapManifestCmd.Flags().String("log-level", "warning", "uNmINeD log level (verbose, debug, information, warning, error, fatal)")
 
+	mapPublishCmd.Flags().String("bucket", "", "Bucket to publish to (default: from config or $MC_MAP_BUCKET)")
+	mapPublishCmd.Flags().String("prefix", config.DefaultPublishPrefix, "Key prefix of the published maps")
+	mapPublishCmd.Flags().String("endpoint", "", "URL of an S3-compatible service such as MinIO (default: AWS)")
+	mapPublishCmd.Flags().String("region", "", "Region to sign requests for (default: from config, $AWS_REGION or the EC2 instance's region)")
+	mapPublishCmd.Flags().Int("concurrency", 8, "Maximum number of parallel uploads")
+	mapPublishCmd.Flags().Bool("dry-run", false, "Show what would be uploaded and deleted without changing the bucket")
+	mapPublishCmd.Flags().Bool("resync", false, "Compare with a listing of the bucket instead of the local publish index")
+
 	mapConfigGenerateCmd.Flags().Bool("force", false, "Overwrite existing config file")
 	mapConfigGenerateCmd.Flags().Int("radius", 2048, "Radius of the spawn area zoom region")
 	mapConfigGenerateCmd.Flags().String("output", "", "Output path for config file (default: <worldPath>/map-config.yml)")
@@ -705,6 +804,7 @@ func init() {
 	MapCmd.AddCommand(mapPreviewCmd)
 	MapCmd.AddCommand(mapManifestCmd)
 	MapCmd.AddCommand(mapIndexCmd)
+	MapCmd.AddCommand(mapPublishCmd)
 
 	mapConfigCmd.AddCommand(mapConfigGenerateCmd)
 	mapConfigCmd.AddCommand(mapConfigGetCmd)
@@ -1026,17 +1126,21 @@ func buildBatch(worldNames []string, baseOpts maps.BuildOptions, parallel bool,
 	if !parallel {
 		// Sequential processing
 		var firstErr error
+		reports := make([]*maps.BuildReport, 0, len(worldNames))
 		for _, worldName := range worldNames {
 			builder := maps.NewBuilder()
 			opts := baseOpts
 			opts.WorldName = worldName
-			if err := builder.Build(opts); err != nil {
+			report, err := builder.Build(opts)
+			reports = append(reports, report)
+			if err != nil {
 				log.Error().Err(err).Str("world", worldName).Msg("failed to build maps")
 				if firstErr == nil {
 					firstErr = err
 				}
 			}
 		}
+		printBuildReports(reports)
 		return firstErr
 	}
 
@@ -1051,6 +1155,7 @@ func buildBatch(worldNames []string, baseOpts maps.BuildOptions, parallel bool,
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, maxWorkers)
 	errors := make([]error, len(worldNames))
+	reports := make([]*maps.BuildReport, len(worldNames))
 
 	for i, worldName := range worldNames {
 		wg.Add(1)
@@ -1062,11 +1167,12 @@ func buildBatch(worldNames []string, baseOpts maps.BuildOptions, parallel bool,
 			builder := maps.NewBuilder()
 			opts := baseOpts
 			opts.WorldName = name
-			errors[idx] = builder.Build(opts)
+			reports[idx], errors[idx] = builder.Build(opts)
 		}(i, worldName)
 	}
 
 	wg.Wait()
+	printBuildReports(reports)
 
 	// Return first error if any
 	for _, err := range errors {
@@ -1077,6 +1183,31 @@ func buildBatch(worldNames []string, baseOpts maps.BuildOptions, parallel bool,
 	return nil
 }
 
+// printBuildReports prints a table of the maps and ranges of build reports.
+// Nil reports, of builds that did not start, are left out.
+func printBuildReports(reports []*maps.BuildReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORLD\tMAP\tSTATUS\tTILES\tSIZE\tDURATION\tERROR")
+	rows := 0
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+		for _, m := range report.Maps {
+			rows++
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", report.World, m.Name, m.Status,
+				m.Tiles, formatSize(m.Size), m.Duration.Round(time.Millisecond), m.Error)
+			for _, r := range m.Ranges {
+				fmt.Fprintf(w, "%s\t%s/%s\t%s\t%d\t%s\t%s\t%s\n", report.World, m.Name, r.Name, r.Status,
+					r.Tiles, formatSize(r.Size), r.Duration.Round(time.Millisecond), r.Error)
+			}
+		}
+	}
+	if rows > 0 {
+		w.Flush()
+	}
+}
+
 // manifestBatch processes multiple worlds for manifest generation
 func manifestBatch(worldNames []string, baseOpts maps.ManifestOptions, parallel bool, maxWorkers int) error {
 	if !parallel {
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/jars"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	progressBarWidth    = 30
+	progressRedrawEvery = 100 * time.Millisecond
+	// progressLogStep is the percentage between log lines when not on a TTY
+	progressLogStep = 25
+	// progressLogBytes is the byte interval between log lines for downloads
+	// of unknown size when not on a TTY
+	progressLogBytes = 16 << 20
+)
+
+// downloadProgress renders the progress of one or more concurrent downloads.
+// On a terminal it redraws one bar per download; otherwise it logs a line
+// for every progressLogStep percent.
+type downloadProgress struct {
+	mu       sync.Mutex
+	out      *os.File
+	tty      bool
+	names    []string
+	state    map[string]*progressState
+	drawn    int
+	lastDraw time.Time
+}
+
+type progressState struct {
+	done, total int64
+	logged      int64
+}
+
+func newDownloadProgress(out *os.File) *downloadProgress {
+	return &downloadProgress{
+		out:   out,
+		tty:   isTerminal(out),
+		state: make(map[string]*progressState),
+	}
+}
+
+// Track returns a ProgressFunc that reports progress for the named download
+func (p *downloadProgress) Track(name string) jars.ProgressFunc {
+	p.mu.Lock()
+	p.names = append(p.names, name)
+	p.state[name] = &progressState{total: -1}
+	p.mu.Unlock()
+
+	return func(done, total int64) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		st := p.state[name]
+		st.done, st.total = done, total
+		if p.tty {
+			complete := total >= 0 && done >= total
+			if complete || time.Since(p.lastDraw) >= progressRedrawEvery {
+				p.draw()
+			}
+			return
+		}
+		p.logProgress(name, st)
+	}
+}
+
+// Finish draws the final state and moves past the bars
+func (p *downloadProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty && p.drawn > 0 {
+		p.draw()
+	}
+}
+
+// draw redraws all bars in place; callers hold p.mu
+func (p *downloadProgress) draw() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.drawn)
+	}
+	width := 0
+	for _, name := range p.names {
+		width = max(width, len(name))
+	}
+	for _, name := range p.names {
+		st := p.state[name]
+		fmt.Fprintf(p.out, "\033[2K%-*s %s\n", width, name, formatProgress(st.done, st.total))
+	}
+	p.drawn = len(p.names)
+	p.lastDraw = time.Now()
+}
+
+// logProgress logs a line when a download crosses a progress step; callers hold p.mu
+func (p *downloadProgress) logProgress(name string, st *progressState) {
+	if st.total > 0 {
+		step := st.done * 100 / st.total / progressLogStep * progressLogStep
+		if step > st.logged {
+			st.logged = step
+			log.Info().Str("download", name).Int64("percent", step).Str("size", formatMB(st.total)).Msg("download progress")
+		}
+		return
+	}
+	if st.done-st.logged >= progressLogBytes {
+		st.logged = st.done
+		log.Info().Str("download", name).Str("downloaded", formatMB(st.done)).Msg("download progress")
+	}
+}
+
+// formatProgress renders a bar such as "[=====>    ]  45%  12.3/27.4 MB"
+func formatProgress(done, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("[%s] %s", strings.Repeat("?", progressBarWidth), formatMB(done))
+	}
+	filled := int(done * progressBarWidth / total)
+	filled = min(filled, progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %3d%%  %.1f/%s", bar, done*100/total, float64(done)/(1<<20), formatMB(total))
+}
+
+func formatMB(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
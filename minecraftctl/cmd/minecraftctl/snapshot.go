@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/snapshot"
+	"github.com/paul/minecraftctl/pkg/worlds"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage local world snapshots",
+	Long: `Commands for local world snapshots, a fast rollback tier next to restic backups.
+
+Snapshots are full copies of a world's world/ directory under snapshots.dir
+(default: <worlds_dir>/.snapshots). Files unchanged since the previous
+snapshot are hard links to it, so each snapshot only costs the space of the
+region files that changed and is cheap enough to take every 15 minutes.
+snapshots.dir must be on the same filesystem as the worlds.`,
+}
+
+var snapshotCreatePrune bool
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <world|pattern>...",
+	Short: "Snapshot worlds",
+	Long: `Snapshot one or more worlds. Patterns are expanded to world names.
+
+Running worlds are quiesced over RCON while they are copied, as for backup
+create. With --prune, snapshots the retention policy (snapshots.retention)
+no longer keeps are removed afterwards.
+
+Examples:
+  minecraftctl snapshot create survival
+  minecraftctl snapshot create 'survival*' --prune`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worldNames := make([]string, 0)
+		for _, pattern := range args {
+			expanded, err := worlds.ExpandWorldPattern(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to expand pattern %s: %w", pattern, err)
+			}
+			worldNames = append(worldNames, expanded...)
+		}
+
+		store := snapshot.NewStore()
+		policy := snapshot.RetentionFor(config.Get().Snapshots.Retention)
+		failed := 0
+		for _, worldName := range worldNames {
+			snap, err := store.Create(worldName)
+			if err != nil {
+				failed++
+				log.Error().Err(err).Str("world", worldName).Msg("failed to snapshot world")
+				continue
+			}
+			fmt.Printf("Created snapshot %s of %s: %d files, %s copied of %s in %s\n",
+				snap.ID, worldName, snap.Files, formatSize(snap.Copied), formatSize(snap.Size), snap.Duration)
+
+			if snapshotCreatePrune {
+				removed, err := store.Prune(worldName, policy, false)
+				if err != nil {
+					failed++
+					log.Error().Err(err).Str("world", worldName).Msg("failed to prune snapshots")
+					continue
+				}
+				if len(removed) > 0 {
+					fmt.Printf("Pruned %d snapshot(s) of %s\n", len(removed), worldName)
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d world snapshot(s) failed", failed, len(worldNames))
+		}
+		return nil
+	},
+}
+
+var snapshotListOutput string
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list <world>",
+	Short: "List a world's snapshots",
+	Long: `List a world's local snapshots, oldest first.
+
+COPIED is the data the snapshot added; the rest of its size is shared with
+the snapshot before it.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch snapshotListOutput {
+		case "json", "table", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: table, json)", snapshotListOutput)
+		}
+
+		snapshots, err := snapshot.NewStore().List(args[0])
+		if err != nil {
+			return err
+		}
+
+		if snapshotListOutput == "json" {
+			return printJSON(snapshots)
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tFILES\tSIZE\tCOPIED\tDURATION")
+		for _, snap := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", snap.ID, snap.Time.Local().Format("2006-01-02 15:04:05"),
+				snap.Files, formatSize(snap.Size), formatSize(snap.Copied), snap.Duration)
+		}
+		return w.Flush()
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <world> [id|latest]",
+	Short: "Restore a world from a snapshot",
+	Long: `Replace a world's world/ directory with a copy of a local snapshot.
+
+The restore works like backup restore --in-place: the world's service is
+stopped, the current world/ is moved aside to world.pre-restore-<timestamp>,
+the snapshot is copied in, ownership is fixed and the service is started
+again if it was running. The snapshot is copied rather than linked, so it
+is left untouched by the restored world. Without an ID the newest snapshot
+is restored.
+
+Examples:
+  minecraftctl snapshot restore survival
+  minecraftctl snapshot restore survival 20240510-141500`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := "latest"
+		if len(args) > 1 {
+			id = args[1]
+		}
+
+		snap, err := snapshot.NewStore().Get(args[0], id)
+		if err != nil {
+			return err
+		}
+
+		result, err := worlds.ReplaceWorld(args[0], snap.ID, func(worldPath string) error {
+			return snapshot.CopyWorld(snap, worldPath)
+		})
+		if result != nil {
+			printRestoreResult(result)
+		}
+		return err
+	},
+}
+
+var snapshotPruneDryRun bool
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune <world|pattern>...",
+	Short: "Remove snapshots outside the retention policy",
+	Long: `Remove the snapshots of worlds that snapshots.retention no longer keeps.
+
+The policy uses the keep_last, keep_hourly, keep_daily, keep_weekly,
+keep_monthly and keep_yearly counts of the backup retention settings. If
+none is set, the last 8 snapshots, one per hour for 24 hours and one per
+day for 7 days are kept.
+
+Examples:
+  minecraftctl snapshot prune survival --dry-run
+  minecraftctl snapshot prune '*'`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worldNames := make([]string, 0)
+		for _, pattern := range args {
+			expanded, err := worlds.ExpandWorldPattern(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to expand pattern %s: %w", pattern, err)
+			}
+			worldNames = append(worldNames, expanded...)
+		}
+
+		store := snapshot.NewStore()
+		policy := snapshot.RetentionFor(config.Get().Snapshots.Retention)
+		verb := "Removed"
+		if snapshotPruneDryRun {
+			verb = "Would remove"
+		}
+		for _, worldName := range worldNames {
+			removed, err := store.Prune(worldName, policy, snapshotPruneDryRun)
+			for _, snap := range removed {
+				fmt.Printf("%s snapshot %s of %s\n", verb, snap.ID, worldName)
+			}
+			if err != nil {
+				return err
+			}
+			if len(removed) == 0 {
+				fmt.Printf("Nothing to prune for %s\n", worldName)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	snapshotCreateCmd.Flags().BoolVar(&snapshotCreatePrune, "prune", false, "Apply the retention policy after creating snapshots")
+	snapshotListCmd.Flags().StringVarP(&snapshotListOutput, "output", "o", "table", "Output format (table, json)")
+	snapshotPruneCmd.Flags().BoolVar(&snapshotPruneDryRun, "dry-run", false, "Show which snapshots would be removed without removing any")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/paul/minecraftctl/internal/commands"
+	"github.com/paul/minecraftctl/pkg/jars"
 	"github.com/paul/minecraftctl/pkg/systemd"
 	"github.com/paul/minecraftctl/pkg/worlds"
 	"github.com/spf13/cobra"
@@ -131,6 +132,18 @@ var worldInfoCmd = &cobra.Command{
 			fmt.Println("no")
 		}
 
+		check, err := worlds.CheckWorldJava(worldName, "")
+		if check != nil {
+			printJavaCheck(check)
+		}
+		if err != nil {
+			return err
+		}
+		if !check.OK() {
+			fmt.Printf("\nWarning: Java %d is required but the world runs %s\n", check.Required, check.JavaPath)
+			fmt.Printf("Select it with: minecraftctl world java set %s %d\n", worldName, check.Required)
+		}
+
 		return nil
 	},
 }
@@ -140,6 +153,8 @@ var (
 	createSeed        string
 	createNoMapConfig bool
 	createNoSystemd   bool
+	createFlavor      string
+	createHeap        string
 )
 
 var (
@@ -162,8 +177,14 @@ var (
 var worldCreateCmd = &cobra.Command{
 	Use:   "create <world-name>",
 	Short: "Create a new Minecraft world",
-	Long:  "Create a new Minecraft world directory with server configuration. Requires the Minecraft server jar to be installed at /opt/minecraft/jars/minecraft_server_<version>.jar",
-	Args:  cobra.ExactArgs(1),
+	Long: `Create a new Minecraft world directory with server configuration.
+
+Requires the server jar to be installed in the jars directory, e.g.
+minecraft_server_<version>.jar for vanilla or paper_server_<version>-<build>.jar
+for --flavor paper (use 'minecraftctl jar download --flavor paper' first).
+Paper and Purpur worlds get a plugins/ directory, Fabric worlds a mods/ directory.
+--heap writes the JVM heap size to the world's jvm.env (see 'minecraftctl world jvm').`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		worldName := args[0]
 
@@ -171,9 +192,16 @@ var worldCreateCmd = &cobra.Command{
 			return fmt.Errorf("--version is required")
 		}
 
+		flavor, err := jars.ParseFlavor(createFlavor)
+		if err != nil {
+			return err
+		}
+
 		opts := worlds.CreateWorldOptions{
 			Version:         createVersion,
+			Flavor:          flavor,
 			Seed:            createSeed,
+			Heap:            createHeap,
 			CreateMapConfig: !createNoMapConfig,
 			EnableSystemd:   !createNoSystemd,
 		}
@@ -182,7 +210,12 @@ var worldCreateCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("World '%s' created successfully with jar version %s\n", worldName, createVersion)
+		if flavor == jars.FlavorVanilla {
+			fmt.Printf("World '%s' created successfully with jar version %s\n", worldName, createVersion)
+		} else {
+			fmt.Printf("World '%s' created successfully with %s jar version %s\n", worldName, flavor, createVersion)
+			fmt.Printf("Created %s/ directory\n", flavor.ExtensionsDir())
+		}
 		if opts.CreateMapConfig {
 			fmt.Println("Default map-config.yml created")
 		}
@@ -223,10 +256,18 @@ var worldUpgradeCmd = &cobra.Command{
 
 This command:
 1. Verifies the world exists
-2. Checks that the target version is newer than the current version
-3. Verifies the target JAR exists in the jars directory
-4. Optionally stops the running server (with --stop flag)
-5. Updates the server.jar symlink to point to the new version
+2. Verifies the target JAR of the world's flavor (vanilla, paper, purpur or
+   fabric) exists in the jars directory; for flavored worlds the version may
+   include a build (e.g. 1.21.4-130), otherwise the newest installed build is used
+3. Checks that the target version is newer than the current version, using the
+   DataVersion embedded in both JARs when available (or a newer build of the
+   same version for flavored worlds)
+4. Verifies a Java runtime new enough for the target JAR is installed, and
+   selects it for the world if the world's current Java is too old
+5. Optionally stops the running server (with --stop flag)
+6. Updates the server.jar symlink to point to the new version
+7. Records the previous JAR in the world's upgrade-history.json, so
+   'minecraftctl jar prune' keeps it available for rollback
 
 Note: This only updates the JAR symlink. Minecraft will automatically upgrade
 world data when the server starts with the new version. World upgrades are
@@ -251,8 +292,17 @@ one-way and cannot be reverted.`,
 		}
 
 		fmt.Printf("World '%s' upgraded successfully\n", result.WorldName)
+		if result.Flavor != jars.FlavorVanilla {
+			fmt.Printf("  Flavor: %s\n", result.Flavor)
+		}
 		fmt.Printf("  Previous version: %s\n", result.PreviousVersion)
 		fmt.Printf("  New version: %s\n", result.NewVersion)
+		if result.PreviousDataVersion > 0 && result.NewDataVersion > 0 {
+			fmt.Printf("  Data version: %d -> %d\n", result.PreviousDataVersion, result.NewDataVersion)
+		}
+		if result.JavaSelected != "" {
+			fmt.Printf("  Java: %s (written to %s)\n", result.JavaSelected, worlds.JVMEnvFile)
+		}
 		if result.ServiceStopped {
 			fmt.Printf("\nNote: Service minecraft@%s.service was stopped.\n", worldName)
 			fmt.Println("Start it with: systemctl start minecraft@" + worldName + ".service")
@@ -466,6 +516,8 @@ func init() {
 	worldCreateCmd.Flags().StringVar(&createSeed, "seed", "", "World seed (optional)")
 	worldCreateCmd.Flags().BoolVar(&createNoMapConfig, "no-map-config", false, "Skip creating map-config.yml")
 	worldCreateCmd.Flags().BoolVar(&createNoSystemd, "no-systemd", false, "Skip enabling and starting systemd service")
+	worldCreateCmd.Flags().StringVar(&createFlavor, "flavor", "vanilla", "Server flavor (vanilla, paper, purpur, fabric)")
+	worldCreateCmd.Flags().StringVar(&createHeap, "heap", "", "JVM heap size written to the world's jvm.env (e.g. 4G; default: 1536M)")
 
 	// Upgrade command flags
 	worldUpgradeCmd.Flags().StringVar(&upgradeVersion, "version", "", "Target Minecraft server version (e.g., 1.21.11)")
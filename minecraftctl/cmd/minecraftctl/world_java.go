@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/java"
+	"github.com/paul/minecraftctl/pkg/worlds"
+	"github.com/spf13/cobra"
+)
+
+var worldJavaCmd = &cobra.Command{
+	Use:   "java",
+	Short: "Manage the Java runtime of a world",
+	Long: `Manage the Java runtime a world's server runs with.
+
+The selection is stored as JAVA=<path> in the world's jvm.env, which
+minecraft@.service reads as an EnvironmentFile. Worlds without a selection use
+java_path (default: /usr/bin/java).`,
+}
+
+var worldJavaGetCmd = &cobra.Command{
+	Use:               "get <world>",
+	Short:             "Show the Java runtime of a world",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, err := worlds.CheckWorldJava(args[0], "")
+		if check != nil {
+			printJavaCheck(check)
+		}
+		return err
+	},
+}
+
+var worldJavaSetCmd = &cobra.Command{
+	Use:   "set <world> <major|path|default>",
+	Short: "Select the Java runtime of a world",
+	Long: `Select the Java runtime of a world.
+
+The runtime may be given as a major version (e.g. 21), which picks the best
+installed runtime as shown by 'minecraftctl java list', as the path to a java
+binary, or as "default" to remove the selection.
+
+Restart the world for the change to take effect.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worldName, runtime := args[0], args[1]
+
+		if runtime == "default" {
+			if err := worlds.SetWorldJava(worldName, ""); err != nil {
+				return err
+			}
+			fmt.Printf("World %s now uses the default Java (%s)\n", worldName, config.Get().JavaPath)
+			return nil
+		}
+
+		var rt *java.Runtime
+		if major, err := strconv.Atoi(runtime); err == nil {
+			runtimes, err := java.Discover(config.Get().JavaSearchPaths)
+			if err != nil {
+				return err
+			}
+			rt, err = java.Select(runtimes, major)
+			if err != nil {
+				return err
+			}
+		} else {
+			rt, err = java.Inspect(runtime)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := worlds.SetWorldJava(worldName, rt.Path); err != nil {
+			return err
+		}
+		fmt.Printf("World %s now uses Java %s (%s)\n", worldName, rt.Version, rt.Path)
+
+		check, err := worlds.CheckWorldJava(worldName, "")
+		if err == nil && !check.OK() {
+			fmt.Printf("Warning: the world's server.jar requires Java %d\n", check.Required)
+		}
+		return nil
+	},
+}
+
+// printJavaCheck prints the Java section of world info and world java get
+func printJavaCheck(check *worlds.JavaCheck) {
+	if check.Required > 0 {
+		fmt.Printf("Required Java: %d\n", check.Required)
+	} else {
+		fmt.Println("Required Java: unknown")
+	}
+
+	source := "default"
+	if check.Selected {
+		source = "selected in " + worlds.JVMEnvFile
+	}
+	fmt.Printf("Java: %s (%s)\n", check.JavaPath, source)
+
+	if check.Current != nil {
+		fmt.Printf("Java Version: %s\n", check.Current.Version)
+	} else {
+		fmt.Println("Java Version: not installed")
+	}
+}
+
+func init() {
+	WorldCmd.AddCommand(worldJavaCmd)
+	worldJavaCmd.AddCommand(worldJavaGetCmd)
+	worldJavaCmd.AddCommand(worldJavaSetCmd)
+}
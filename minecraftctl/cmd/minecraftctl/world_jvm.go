@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/paul/minecraftctl/pkg/systemd"
+	"github.com/paul/minecraftctl/pkg/worlds"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jvmGetOutput       string
+	jvmSetHeap         string
+	jvmSetFlags        string
+	jvmSetCustomFlags  string
+	jvmSuggestReserve  string
+	jvmSuggestApply    bool
+	jvmSuggestMemTotal string
+)
+
+var worldJvmCmd = &cobra.Command{
+	Use:   "jvm",
+	Short: "Manage the JVM heap and flags of a world",
+	Long: `Manage the JVM heap size and flags a world's server runs with.
+
+Settings are stored in the world's jvm.env as JVM_OPTS, which minecraft@.service
+reads as an EnvironmentFile. Worlds without settings use the unit's default of
+-Xms1536M -Xmx1536M.`,
+}
+
+var worldJvmGetCmd = &cobra.Command{
+	Use:               "get <world>",
+	Short:             "Show the JVM settings of a world",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := worlds.GetWorldJVM(args[0])
+		if err != nil {
+			return err
+		}
+
+		switch jvmGetOutput {
+		case "json":
+			data, err := json.MarshalIndent(settings, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JVM settings: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		case "text", "":
+		default:
+			return fmt.Errorf("unsupported output format: %s (supported: text, json)", jvmGetOutput)
+		}
+
+		source := worlds.JVMEnvFile
+		if !settings.Configured {
+			source = "unit default"
+		}
+		fmt.Printf("Heap: %s (%s)\n", settings.Heap, source)
+		fmt.Printf("Flags: %s\n", settings.Flags)
+		if settings.Flags == worlds.FlagsCustom {
+			fmt.Printf("Custom Flags: %s\n", settings.CustomFlags)
+		}
+		if opts, err := settings.Options(); err == nil {
+			fmt.Printf("JVM Options: %s\n", opts)
+		}
+		return nil
+	},
+}
+
+var worldJvmSetCmd = &cobra.Command{
+	Use:   "set <world>",
+	Short: "Set the JVM heap and flags of a world",
+	Long: `Set the JVM heap and flags of a world.
+
+--heap sets both -Xms and -Xmx (e.g. 4G or 1536M). --flags selects a preset:
+  default  no GC tuning
+  aikar    Aikar's G1 flags (https://mcflags.emc.gs), sized to the heap
+  custom   the flags given with --custom-flags
+
+Settings not given keep their current value. Restart the world for the change
+to take effect.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worldName := args[0]
+		if !cmd.Flags().Changed("heap") && !cmd.Flags().Changed("flags") && !cmd.Flags().Changed("custom-flags") {
+			return fmt.Errorf("nothing to set: use --heap, --flags or --custom-flags")
+		}
+
+		settings, err := worlds.GetWorldJVM(worldName)
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("heap") {
+			settings.Heap = jvmSetHeap
+		}
+		if cmd.Flags().Changed("custom-flags") {
+			settings.CustomFlags = jvmSetCustomFlags
+			if !cmd.Flags().Changed("flags") {
+				settings.Flags = worlds.FlagsCustom
+			}
+		}
+		if cmd.Flags().Changed("flags") {
+			settings.Flags = jvmSetFlags
+		}
+
+		if err := worlds.SetWorldJVM(worldName, *settings); err != nil {
+			return err
+		}
+
+		opts, _ := settings.Options()
+		fmt.Printf("World %s now uses: %s\n", worldName, opts)
+		fmt.Printf("Restart the world to apply: minecraftctl world restart %s\n", worldName)
+		return nil
+	},
+}
+
+var worldJvmSuggestCmd = &cobra.Command{
+	Use:   "suggest [world...]",
+	Short: "Suggest heap sizes that split host memory across worlds",
+	Long: `Suggest heap sizes that split host memory evenly across worlds.
+
+Without arguments, memory is split across worlds whose minecraft@ service is
+enabled. --reserve memory is left for the system, and 512M per world is set
+aside for memory the JVM uses outside the heap. With --apply the suggested
+heaps are written to each world's jvm.env, keeping its flags.`,
+	ValidArgsFunction: worldCompletionFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worldNames := args
+		if len(worldNames) == 0 {
+			names, err := worlds.GetWorldNames()
+			if err != nil {
+				return fmt.Errorf("failed to list worlds: %w", err)
+			}
+			for _, name := range names {
+				unit := systemd.FormatUnitName("minecraft", name, systemd.UnitService)
+				if enabled, _ := systemd.IsEnabled(unit); enabled {
+					worldNames = append(worldNames, name)
+				}
+			}
+			if len(worldNames) == 0 {
+				return fmt.Errorf("no enabled worlds found (pass world names to include them)")
+			}
+		}
+		sort.Strings(worldNames)
+
+		var totalMB int
+		var err error
+		if jvmSuggestMemTotal != "" {
+			totalMB, err = worlds.ParseHeap(jvmSuggestMemTotal)
+		} else {
+			totalMB, err = worlds.HostMemoryMB()
+		}
+		if err != nil {
+			return err
+		}
+		reserveMB, err := worlds.ParseHeap(jvmSuggestReserve)
+		if err != nil {
+			return fmt.Errorf("invalid --reserve: %w", err)
+		}
+
+		heaps, err := worlds.SuggestHeaps(totalMB, reserveMB, worldNames)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Host memory: %s, reserved: %s, worlds: %d\n\n", worlds.FormatHeap(totalMB), worlds.FormatHeap(reserveMB), len(worldNames))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WORLD\tCURRENT\tSUGGESTED")
+		for _, name := range worldNames {
+			current := "-"
+			if settings, err := worlds.GetWorldJVM(name); err == nil {
+				current = settings.Heap
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, current, worlds.FormatHeap(heaps[name]))
+		}
+		w.Flush()
+
+		if !jvmSuggestApply {
+			return nil
+		}
+
+		fmt.Println()
+		for _, name := range worldNames {
+			settings, err := worlds.GetWorldJVM(name)
+			if err != nil {
+				return err
+			}
+			settings.Heap = worlds.FormatHeap(heaps[name])
+			if err := worlds.SetWorldJVM(name, *settings); err != nil {
+				return fmt.Errorf("failed to apply heap to %s: %w", name, err)
+			}
+			fmt.Printf("Applied %s heap to %s\n", settings.Heap, name)
+		}
+		fmt.Println("Restart the worlds to apply the new heap sizes")
+		return nil
+	},
+}
+
+func init() {
+	WorldCmd.AddCommand(worldJvmCmd)
+	worldJvmCmd.AddCommand(worldJvmGetCmd)
+	worldJvmCmd.AddCommand(worldJvmSetCmd)
+	worldJvmCmd.AddCommand(worldJvmSuggestCmd)
+
+	worldJvmGetCmd.Flags().StringVarP(&jvmGetOutput, "output", "o", "text", "Output format (text, json)")
+
+	worldJvmSetCmd.Flags().StringVar(&jvmSetHeap, "heap", "", "Heap size for -Xms and -Xmx (e.g. 4G, 1536M)")
+	worldJvmSetCmd.Flags().StringVar(&jvmSetFlags, "flags", "", "JVM flags preset (default, aikar, custom)")
+	worldJvmSetCmd.Flags().StringVar(&jvmSetCustomFlags, "custom-flags", "", "JVM flags for the custom preset (implies --flags custom)")
+
+	worldJvmSuggestCmd.Flags().StringVar(&jvmSuggestReserve, "reserve", "2G", "Memory to leave for the system")
+	worldJvmSuggestCmd.Flags().StringVar(&jvmSuggestMemTotal, "memory", "", "Total memory to split instead of the host's (e.g. 16G)")
+	worldJvmSuggestCmd.Flags().BoolVar(&jvmSuggestApply, "apply", false, "Write the suggested heaps to each world's jvm.env")
+}
@@ -2,12 +2,16 @@
 package backup
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/paul/minecraftctl/pkg/envfile"
+	"github.com/paul/minecraftctl/pkg/config"
 )
 
 const (
@@ -17,20 +21,25 @@ const (
 
 // Config holds the backup configuration
 type Config struct {
-	Repository string
-	Password   string
-	WorldsDir  string
+	// Name is the repository name from minecraftctl.yml, or "default" for
+	// the repository derived from MC_WORLD_BUCKET
+	Name         string
+	Repository   string
+	Password     string
+	PasswordFile string
+	WorldsDir    string
+	// Env holds extra environment variables passed to restic
+	Env []string
 }
 
-// LoadConfig loads backup configuration from environment
+// LoadConfig loads the default backup repository configuration
 func LoadConfig() (*Config, error) {
-	// Try to load from env file if not already in environment
-	if os.Getenv("MC_WORLD_BUCKET") == "" || os.Getenv("RESTIC_PASSWORD") == "" {
-		if ef, err := envfile.Load(envfile.DefaultMinecraftEnvPath); err == nil {
-			ef.ExportIfNotSet()
-		}
-	}
+	return LoadRepoConfig("")
+}
 
+// loadEnvConfig builds the repository configuration from MC_WORLD_BUCKET,
+// used when minecraftctl.yml configures no repositories
+func loadEnvConfig() (*Config, error) {
 	bucket := os.Getenv("MC_WORLD_BUCKET")
 	if bucket == "" {
 		return nil, fmt.Errorf("MC_WORLD_BUCKET not set")
@@ -52,19 +61,38 @@ func LoadConfig() (*Config, error) {
 	}
 
 	return &Config{
+		Name:       DefaultRepoName,
 		Repository: fmt.Sprintf("s3:s3.%s.amazonaws.com/%s", region, bucket),
 		Password:   password,
 		WorldsDir:  worldsDir,
 	}, nil
 }
 
+// environ returns the environment for restic commands against the
+// repository. extra is added before the repository's own variables, which
+// take precedence.
+func (c *Config) environ(extra ...string) []string {
+	env := append(os.Environ(), extra...)
+	env = append(env, c.Env...)
+	env = append(env, "RESTIC_REPOSITORY="+c.Repository)
+	if c.PasswordFile != "" {
+		env = append(env, "RESTIC_PASSWORD_FILE="+c.PasswordFile)
+	} else {
+		env = append(env, "RESTIC_PASSWORD="+c.Password)
+	}
+	return env
+}
+
+// command creates a restic command with the configured environment
+func (c *Config) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("restic", args...)
+	cmd.Env = c.environ()
+	return cmd
+}
+
 // runRestic executes a restic command with the configured environment
 func (c *Config) runRestic(args ...string) error {
-	cmd := exec.Command("restic", args...)
-	cmd.Env = append(os.Environ(),
-		"RESTIC_REPOSITORY="+c.Repository,
-		"RESTIC_PASSWORD="+c.Password,
-	)
+	cmd := c.command(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
@@ -73,12 +101,7 @@ func (c *Config) runRestic(args ...string) error {
 
 // runResticOutput executes a restic command and returns the output
 func (c *Config) runResticOutput(args ...string) (string, error) {
-	cmd := exec.Command("restic", args...)
-	cmd.Env = append(os.Environ(),
-		"RESTIC_REPOSITORY="+c.Repository,
-		"RESTIC_PASSWORD="+c.Password,
-	)
-	out, err := cmd.CombinedOutput()
+	out, err := c.command(args...).CombinedOutput()
 	return string(out), err
 }
 
@@ -94,16 +117,10 @@ func (c *Config) InitRepository() error {
 	return c.runRestic("init")
 }
 
-// List shows available snapshots, optionally filtered by tag
-func (c *Config) List(tag string) error {
-	args := []string{"snapshots"}
-	if tag != "" {
-		args = append(args, "--tag", tag)
-	}
-	return c.runRestic(args...)
-}
-
-// Create creates a new backup
+// Create creates a new backup. Running worlds are quiesced over their RCON
+// endpoints for the duration of the backup: saving is turned off and the
+// world flushed to disk first, and turned back on afterwards even if restic
+// fails or is interrupted. Stopped worlds are backed up as they are.
 func (c *Config) Create(world string) error {
 	if err := c.InitRepository(); err != nil {
 		return fmt.Errorf("failed to initialize repository: %w", err)
@@ -111,10 +128,16 @@ func (c *Config) Create(world string) error {
 
 	var backupPath string
 	var tag string
+	var worldDirs []string
 
 	if world == "" || world == "all" {
 		backupPath = c.WorldsDir
 		tag = "all"
+		dirs, err := serverDirs(c.WorldsDir)
+		if err != nil {
+			return err
+		}
+		worldDirs = dirs
 		fmt.Printf("Backing up all worlds in %s...\n", backupPath)
 	} else {
 		backupPath = fmt.Sprintf("%s/%s/world", c.WorldsDir, world)
@@ -122,21 +145,91 @@ func (c *Config) Create(world string) error {
 		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
 			return fmt.Errorf("world path not found: %s", backupPath)
 		}
+		worldDirs = []string{filepath.Join(c.WorldsDir, world)}
 		fmt.Printf("Backing up world: %s...\n", world)
 	}
 
-	err := c.runRestic("backup", backupPath,
+	var extra []string
+	if tag == "all" {
+		// Local snapshots are kept in the worlds directory by default
+		extra = append(extra, "--exclude", config.Get().Snapshots.Dir)
+	}
+	err := WithQuiescedWorlds(worldDirs, func() error {
+		return c.runRestic(backupArgs(backupPath, tag, extra...)...)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nBackup complete. Recent snapshots:")
+	return c.runRestic("snapshots", "--latest", "3", "--tag", tag)
+}
+
+// backupArgs returns the restic arguments to back up path with tag
+func backupArgs(path, tag string, extra ...string) []string {
+	args := []string{"backup", path,
 		"--tag", tag,
 		"--exclude", "*.log",
 		"--exclude", "logs/",
 		"--exclude", "crash-reports/",
-	)
+	}
+	return append(args, extra...)
+}
+
+// CreateResult describes a snapshot made by CreateWorld
+type CreateResult struct {
+	World    string
+	Snapshot string
+	// DataAdded is the new data restic stored for the snapshot
+	DataAdded int64
+	Duration  time.Duration
+}
+
+// CreateWorld backs up a single world like Create, without printing restic's
+// progress, so several worlds can be backed up at once. The repository must
+// already be initialized.
+func (c *Config) CreateWorld(world string) (*CreateResult, error) {
+	worldDir := filepath.Join(c.WorldsDir, world)
+	backupPath := filepath.Join(worldDir, "world")
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("world path not found: %s", backupPath)
+	}
+
+	start := time.Now()
+	var out []byte
+	err := WithQuiescedWorlds([]string{worldDir}, func() error {
+		var err error
+		out, err = c.command(backupArgs(backupPath, world, "--json", "--quiet")...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				return fmt.Errorf("restic backup failed: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return fmt.Errorf("restic backup failed: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Println("\nBackup complete. Recent snapshots:")
-	return c.runRestic("snapshots", "--latest", "3", "--tag", tag)
+	result := &CreateResult{World: world, Duration: time.Since(start).Round(time.Second)}
+	// restic backup --json prints one message per line and ends with a summary
+	for _, line := range strings.Split(string(out), "\n") {
+		var msg struct {
+			MessageType string `json:"message_type"`
+			SnapshotID  string `json:"snapshot_id"`
+			DataAdded   int64  `json:"data_added"`
+		}
+		if json.Unmarshal([]byte(line), &msg) == nil && msg.MessageType == "summary" {
+			result.Snapshot = msg.SnapshotID
+			if len(result.Snapshot) > 8 {
+				result.Snapshot = result.Snapshot[:8]
+			}
+			result.DataAdded = msg.DataAdded
+		}
+	}
+	return result, nil
 }
 
 // Restore restores a snapshot
@@ -159,33 +252,6 @@ func (c *Config) Restore(snapshot string, target string) error {
 	return c.runRestic(args...)
 }
 
-// Prune removes old snapshots according to retention policy
-func (c *Config) Prune() error {
-	fmt.Println("Pruning old snapshots...")
-	err := c.runRestic("forget",
-		"--keep-daily", "7",
-		"--keep-weekly", "4",
-		"--keep-monthly", "3",
-		"--prune",
-	)
-	if err != nil {
-		return err
-	}
-
-	fmt.Println("\nChecking repository integrity...")
-	if err := c.runRestic("check"); err != nil {
-		return err
-	}
-
-	fmt.Println("\nRepository statistics:")
-	return c.runRestic("stats")
-}
-
-// Stats shows repository statistics
-func (c *Config) Stats() error {
-	return c.runRestic("stats")
-}
-
 // Check verifies repository integrity
 func (c *Config) Check() error {
 	return c.runRestic("check")
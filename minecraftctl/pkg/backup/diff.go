@@ -0,0 +1,285 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// Change kinds reported by Diff
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// WorldDiff describes what changed in a world between two snapshots
+type WorldDiff struct {
+	World    string    `json:"world"`
+	From     string    `json:"from"`
+	FromTime time.Time `json:"from_time"`
+	To       string    `json:"to"`
+	ToTime   time.Time `json:"to_time"`
+	// Dimensions lists dimensions with changed region files, overworld first
+	Dimensions []DimensionDiff `json:"dimensions"`
+	Players    []PlayerChange  `json:"players"`
+	// LevelDat and ServerProperties are the change to each file, empty if
+	// unchanged. server.properties is only in snapshots of all worlds.
+	LevelDat         string       `json:"level_dat,omitempty"`
+	ServerProperties string       `json:"server_properties,omitempty"`
+	Datapacks        []FileChange `json:"datapacks"`
+	// Other counts changed files that fit none of the above
+	Other int `json:"other"`
+}
+
+// DimensionDiff lists the changed region files of one dimension
+type DimensionDiff struct {
+	// Dimension is overworld, nether, end or a custom dimension's ID
+	Dimension string `json:"dimension"`
+	// Regions are the changed terrain region files
+	Regions []RegionChange `json:"regions"`
+	// Area is the smallest box covering every changed terrain region
+	Area *region.Box `json:"area,omitempty"`
+	// Entities and POI count changed entities and poi region files
+	Entities int `json:"entities"`
+	POI      int `json:"poi"`
+}
+
+// RegionChange is a changed terrain region file
+type RegionChange struct {
+	X      int        `json:"x"`
+	Z      int        `json:"z"`
+	Change string     `json:"change"`
+	Area   region.Box `json:"area"`
+}
+
+// PlayerChange lists a player's changed files
+type PlayerChange struct {
+	UUID string `json:"uuid"`
+	// Name is looked up in the world's usercache.json, empty if unknown
+	Name  string       `json:"name,omitempty"`
+	Files []FileChange `json:"files"`
+}
+
+// FileChange is a changed file, relative to the world/ directory
+type FileChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+}
+
+// uuidPattern matches the dashed player UUIDs of playerdata, stats and
+// advancements file names
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// diffMessage is a line of restic diff --json output
+type diffMessage struct {
+	MessageType string `json:"message_type"`
+	Path        string `json:"path"`
+	Modifier    string `json:"modifier"`
+}
+
+// Diff compares a world in two snapshots, translating changed files into
+// dimensions, regions, players and configuration
+func (c *Config) Diff(from, to, world string) (*WorldDiff, error) {
+	snapFrom, err := c.WorldSnapshot(world, from)
+	if err != nil {
+		return nil, err
+	}
+	snapTo, err := c.WorldSnapshot(world, to)
+	if err != nil {
+		return nil, err
+	}
+	worldPath, err := WorldPath(snapTo, world)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.command("diff", "--json", snapFrom.ID, snapTo.ID).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff snapshots %s and %s: %w", snapFrom.ShortID, snapTo.ShortID, err)
+	}
+
+	var changes []diffMessage
+	for _, line := range strings.Split(string(out), "\n") {
+		var msg diffMessage
+		if json.Unmarshal([]byte(line), &msg) == nil && msg.MessageType == "change" {
+			changes = append(changes, msg)
+		}
+	}
+
+	diff := classifyChanges(changes, worldPath, loadPlayerNames(filepath.Join(c.WorldsDir, world)))
+	diff.World = world
+	diff.From, diff.FromTime = snapFrom.ShortID, snapFrom.Time
+	diff.To, diff.ToTime = snapTo.ShortID, snapTo.Time
+	return diff, nil
+}
+
+// classifyChanges sorts restic diff changes below worldPath, the world/
+// directory, into a WorldDiff. names maps player UUIDs to names.
+func classifyChanges(changes []diffMessage, worldPath string, names map[string]string) *WorldDiff {
+	diff := &WorldDiff{Dimensions: []DimensionDiff{}, Players: []PlayerChange{}, Datapacks: []FileChange{}}
+	dims := make(map[string]*DimensionDiff)
+	players := make(map[string]*PlayerChange)
+	serverDir := path.Dir(worldPath)
+
+	for _, msg := range changes {
+		change := changeKind(msg.Modifier)
+		// Directories end in a slash; only files are reported
+		if change == "" || strings.HasSuffix(msg.Path, "/") {
+			continue
+		}
+		if msg.Path == path.Join(serverDir, "server.properties") {
+			diff.ServerProperties = change
+			continue
+		}
+		rel, ok := strings.CutPrefix(msg.Path, worldPath+"/")
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(rel, "/")
+		switch {
+		case rel == "level.dat":
+			diff.LevelDat = change
+		case parts[0] == "datapacks":
+			diff.Datapacks = append(diff.Datapacks, FileChange{Path: rel, Change: change})
+		case len(parts) == 2 && (parts[0] == "playerdata" || parts[0] == "stats" || parts[0] == "advancements"):
+			uuid := strings.TrimSuffix(strings.TrimSuffix(parts[1], ".dat"), ".json")
+			if !uuidPattern.MatchString(uuid) {
+				diff.Other++
+				continue
+			}
+			p, ok := players[uuid]
+			if !ok {
+				p = &PlayerChange{UUID: uuid, Name: names[uuid]}
+				players[uuid] = p
+			}
+			p.Files = append(p.Files, FileChange{Path: rel, Change: change})
+		default:
+			dimension, kind, rx, rz, ok := parseRegionPath(parts)
+			if !ok {
+				diff.Other++
+				continue
+			}
+			d, ok := dims[dimension]
+			if !ok {
+				d = &DimensionDiff{Dimension: dimension, Regions: []RegionChange{}}
+				dims[dimension] = d
+			}
+			switch kind {
+			case "region":
+				area := region.RegionBox(rx, rz)
+				d.Regions = append(d.Regions, RegionChange{X: rx, Z: rz, Change: change, Area: area})
+				if d.Area == nil {
+					d.Area = &area
+				} else {
+					*d.Area = d.Area.Union(area)
+				}
+			case "entities":
+				d.Entities++
+			case "poi":
+				d.POI++
+			}
+		}
+	}
+
+	for _, d := range dims {
+		diff.Dimensions = append(diff.Dimensions, *d)
+	}
+	order := map[string]int{"overworld": 0, "nether": 1, "end": 2}
+	sort.Slice(diff.Dimensions, func(i, j int) bool {
+		a, b := diff.Dimensions[i].Dimension, diff.Dimensions[j].Dimension
+		oa, okA := order[a]
+		ob, okB := order[b]
+		switch {
+		case okA && okB:
+			return oa < ob
+		case okA != okB:
+			return okA
+		default:
+			return a < b
+		}
+	})
+	for _, p := range players {
+		diff.Players = append(diff.Players, *p)
+	}
+	sort.Slice(diff.Players, func(i, j int) bool { return diff.Players[i].UUID < diff.Players[j].UUID })
+	return diff
+}
+
+// parseRegionPath recognises <dimension>/<kind>/r.<x>.<z>.mca below world/,
+// where the dimension directory is empty for the overworld, DIM-1, DIM1 or
+// dimensions/<namespace>/<name>
+func parseRegionPath(parts []string) (dimension, kind string, rx, rz int, ok bool) {
+	n := len(parts)
+	if n < 2 {
+		return "", "", 0, 0, false
+	}
+	kind = parts[n-2]
+	if kind != "region" && kind != "entities" && kind != "poi" {
+		return "", "", 0, 0, false
+	}
+	if rx, rz, ok = region.ParseFileName(parts[n-1]); !ok {
+		return "", "", 0, 0, false
+	}
+
+	dir := parts[:n-2]
+	switch {
+	case len(dir) == 0:
+		dimension = "overworld"
+	case len(dir) == 1 && dir[0] == "DIM-1":
+		dimension = "nether"
+	case len(dir) == 1 && dir[0] == "DIM1":
+		dimension = "end"
+	case len(dir) == 3 && dir[0] == "dimensions":
+		dimension = dir[1] + ":" + dir[2]
+	default:
+		return "", "", 0, 0, false
+	}
+	return dimension, kind, rx, rz, true
+}
+
+// changeKind translates a restic diff modifier, which combines letters such
+// as "TM" when several aspects of a path changed. Metadata-only changes (U)
+// are not reported.
+func changeKind(modifier string) string {
+	switch {
+	case strings.Contains(modifier, "+"):
+		return ChangeAdded
+	case strings.Contains(modifier, "-"):
+		return ChangeRemoved
+	case strings.ContainsAny(modifier, "MT?"):
+		return ChangeModified
+	default:
+		return ""
+	}
+}
+
+// loadPlayerNames maps player UUIDs to names from a world's usercache.json.
+// A missing or unreadable cache yields no names.
+func loadPlayerNames(worldDir string) map[string]string {
+	names := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(worldDir, "usercache.json"))
+	if err != nil {
+		return names
+	}
+	var cache []struct {
+		Name string `json:"name"`
+		UUID string `json:"uuid"`
+	}
+	if json.Unmarshal(data, &cache) != nil {
+		return names
+	}
+	for _, entry := range cache {
+		names[strings.ToLower(entry.UUID)] = entry.Name
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+func TestClassifyChanges(t *testing.T) {
+	const world = "/srv/minecraft-server/survival/world"
+	steve := "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+	changes := []diffMessage{
+		{"change", world + "/region/", "M"},
+		{"change", world + "/region/r.0.0.mca", "M"},
+		{"change", world + "/region/r.-1.2.mca", "+"},
+		{"change", world + "/entities/r.0.0.mca", "M"},
+		{"change", world + "/poi/r.0.0.mca", "TM"},
+		{"change", world + "/DIM-1/region/r.3.3.mca", "MU"},
+		{"change", world + "/dimensions/mymod/mining/region/r.0.0.mca", "-"},
+		{"change", world + "/DIM1/region/r.0.0.mca", "U"},
+		{"change", world + "/playerdata/" + steve + ".dat", "M"},
+		{"change", world + "/stats/" + steve + ".json", "M"},
+		{"change", world + "/playerdata/" + steve + ".dat_old", "M"},
+		{"change", world + "/level.dat", "M"},
+		{"change", world + "/datapacks/tweaks.zip", "+"},
+		{"change", "/srv/minecraft-server/survival/server.properties", "M"},
+		{"change", "/srv/minecraft-server/creative/world/level.dat", "M"},
+	}
+
+	diff := classifyChanges(changes, world, map[string]string{steve: "Steve"})
+
+	var dims []string
+	for _, d := range diff.Dimensions {
+		dims = append(dims, d.Dimension)
+	}
+	if got := strings.Join(dims, ","); got != "overworld,nether,mymod:mining" {
+		t.Fatalf("Dimensions = %s", got)
+	}
+
+	overworld := diff.Dimensions[0]
+	if len(overworld.Regions) != 2 || overworld.Entities != 1 || overworld.POI != 1 {
+		t.Errorf("Unexpected overworld diff: %+v", overworld)
+	}
+	if r := overworld.Regions[1]; r.X != -1 || r.Z != 2 || r.Change != ChangeAdded {
+		t.Errorf("Unexpected region change: %+v", r)
+	}
+	if want := (region.Box{MinX: -512, MinZ: 0, MaxX: 511, MaxZ: 1535}); overworld.Area == nil || *overworld.Area != want {
+		t.Errorf("Area = %v, want %v", overworld.Area, want)
+	}
+	if diff.Dimensions[2].Regions[0].Change != ChangeRemoved {
+		t.Errorf("Expected removed region in custom dimension: %+v", diff.Dimensions[2])
+	}
+
+	if len(diff.Players) != 1 || diff.Players[0].Name != "Steve" || len(diff.Players[0].Files) != 2 {
+		t.Errorf("Unexpected players: %+v", diff.Players)
+	}
+	if diff.LevelDat != ChangeModified || diff.ServerProperties != ChangeModified {
+		t.Errorf("LevelDat = %q, ServerProperties = %q", diff.LevelDat, diff.ServerProperties)
+	}
+	if len(diff.Datapacks) != 1 || diff.Datapacks[0].Path != "datapacks/tweaks.zip" {
+		t.Errorf("Unexpected datapacks: %+v", diff.Datapacks)
+	}
+	if diff.Other != 1 {
+		t.Errorf("Other = %d, want 1 (playerdata backup file)", diff.Other)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	worldsDir := t.TempDir()
+	os.MkdirAll(filepath.Join(worldsDir, "survival"), 0755)
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret", WorldsDir: worldsDir}
+
+	logPath := fakeRestic(t, map[string]string{
+		"snapshots": testSnapshotsJSON,
+		"diff": `{"message_type":"change","path":"/srv/minecraft-server/survival/world/region/r.0.0.mca","modifier":"M"}
+{"message_type":"statistics","changed_files":1}`,
+	})
+
+	diff, err := cfg.Diff("aaaa1111", "cccc3333", "survival")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if diff.From != "aaaa1111" || len(diff.Dimensions) != 1 || len(diff.Dimensions[0].Regions) != 1 {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+
+	data, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(data), "diff --json aaaa1111bbbb2222 ") {
+		t.Errorf("Expected restic diff of both snapshots, got:\n%s", data)
+	}
+
+	if _, err := cfg.Diff("aaaa1111", "cccc3333", "creative"); err == nil {
+		t.Error("Expected error for world not in snapshots")
+	}
+}
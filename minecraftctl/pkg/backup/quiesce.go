@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/rcon"
+	"github.com/paul/minecraftctl/pkg/systemd"
+	"github.com/rs/zerolog/log"
+)
+
+// savedMessage is what the server prints once save-all has written the world
+const savedMessage = "Saved the game"
+
+// saveTimeout bounds how long to wait for the "Saved the game" confirmation
+var saveTimeout = 2 * time.Minute
+
+// rconConn is the part of an RCON client quiescing needs
+type rconConn interface {
+	Send(command string) (string, error)
+	Close() error
+}
+
+// dialWorld connects to the RCON endpoint of a world; replaced in tests
+var dialWorld = func(worldDir string) (rconConn, error) {
+	return rcon.NewWorldClient(worldDir)
+}
+
+// serviceActive reports whether a world's server is running; replaced in
+// tests
+var serviceActive = func(world string) (bool, error) {
+	return systemd.IsActive(systemd.FormatUnitName("minecraft", world, systemd.UnitService))
+}
+
+// quiescedWorld is a running world whose automatic saving is turned off
+type quiescedWorld struct {
+	name string
+	conn rconConn
+}
+
+// quiesce makes a running world's files consistent for a backup: it turns
+// automatic saving off and flushes the world to disk. A world whose RCON
+// endpoint cannot be reached is stopped, its files are not being written,
+// and nil is returned; if its service is running after all the backup would
+// be inconsistent and an error is returned. Callers must resume a non-nil
+// result.
+func quiesce(worldDir string) (*quiescedWorld, error) {
+	name := filepath.Base(worldDir)
+	conn, err := dialWorld(worldDir)
+	if err != nil {
+		running, activeErr := serviceActive(name)
+		switch {
+		case activeErr != nil:
+			log.Warn().Str("world", name).Err(err).AnErr("service_error", activeErr).
+				Msg("world not reachable over RCON and its service state is unknown, backing up without quiescing")
+		case running:
+			return nil, fmt.Errorf("world %s is running but not reachable over RCON, refusing an unquiesced backup (check enable-rcon and rcon.password in server.properties): %w", name, err)
+		default:
+			log.Info().Str("world", name).Err(err).Msg("world not running, backing up without quiescing")
+		}
+		return nil, nil
+	}
+	q := &quiescedWorld{name: name, conn: conn}
+
+	fmt.Printf("Quiescing world %s...\n", name)
+	if _, err := conn.Send("save-off"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to disable saving on %s: %w", name, err)
+	}
+	if err := q.flush(worldDir); err != nil {
+		q.resume()
+		return nil, err
+	}
+	return q, nil
+}
+
+// flush runs save-all flush and waits for the "Saved the game" confirmation.
+// Servers usually include it in the RCON response; otherwise it is awaited
+// in logs/latest.log.
+func (q *quiescedWorld) flush(worldDir string) error {
+	logPath := filepath.Join(worldDir, "logs", "latest.log")
+	var offset int64
+	if info, err := os.Stat(logPath); err == nil {
+		offset = info.Size()
+	}
+
+	resp, err := q.conn.Send("save-all flush")
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", q.name, err)
+	}
+	if strings.Contains(resp, savedMessage) {
+		return nil
+	}
+
+	deadline := time.Now().Add(saveTimeout)
+	for time.Now().Before(deadline) {
+		if logContains(logPath, offset, savedMessage) {
+			return nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for %s to save after %s", q.name, saveTimeout)
+}
+
+// logContains reports whether the log has text after offset. A log that
+// shrank was rotated and is searched from the start.
+func logContains(path string, offset int64, text string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return false
+	}
+	data, err := io.ReadAll(f)
+	return err == nil && strings.Contains(string(data), text)
+}
+
+// resume turns automatic saving back on and closes the connection
+func (q *quiescedWorld) resume() {
+	if q == nil {
+		return
+	}
+	if _, err := q.conn.Send("save-on"); err != nil {
+		log.Error().Str("world", q.name).Err(err).Msg("failed to re-enable saving, run 'save-on' manually")
+	} else {
+		fmt.Printf("Resumed saving on world %s\n", q.name)
+	}
+	q.conn.Close()
+}
+
+// WithQuiescedWorlds quiesces the worlds, runs fn and resumes saving on every
+// quiesced world however fn ends. SIGINT and SIGTERM are held off while
+// worlds are quiesced: fn is left to finish (a restic it runs receives them
+// too and exits), after which saving is resumed and the interruption is
+// returned as an error.
+func WithQuiescedWorlds(worldDirs []string, fn func() error) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	var quiesced []*quiescedWorld
+	defer func() {
+		for _, q := range quiesced {
+			q.resume()
+		}
+	}()
+
+	for _, dir := range worldDirs {
+		q, err := quiesce(dir)
+		if err != nil {
+			return err
+		}
+		if q != nil {
+			quiesced = append(quiesced, q)
+		}
+		select {
+		case sig := <-sigs:
+			return fmt.Errorf("interrupted by %s", sig)
+		default:
+		}
+	}
+
+	err := fn()
+	select {
+	case sig := <-sigs:
+		return fmt.Errorf("interrupted by %s", sig)
+	default:
+	}
+	return err
+}
+
+// serverDirs returns the world directories under worldsDir, i.e. those with
+// a server.properties
+func serverDirs(worldsDir string) ([]string, error) {
+	entries, err := os.ReadDir(worldsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worlds directory: %w", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		dir := filepath.Join(worldsDir, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "server.properties")); e.IsDir() && err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeConn records RCON commands. onSend may change the response.
+type fakeConn struct {
+	commands []string
+	closed   bool
+	onSend   func(command string) string
+}
+
+func (f *fakeConn) Send(command string) (string, error) {
+	f.commands = append(f.commands, command)
+	if f.onSend != nil {
+		return f.onSend(command), nil
+	}
+	if command == "save-all flush" {
+		return "Saving the game (this may take a moment!)Saved the game", nil
+	}
+	return "", nil
+}
+
+func (f *fakeConn) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCreateQuiescesWorld(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival")
+	os.MkdirAll(filepath.Join(worldDir, "world"), 0755)
+	os.MkdirAll(filepath.Join(worldDir, "logs"), 0755)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("enable-rcon=true\n"), 0644)
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret", WorldsDir: worldsDir}
+
+	origDial, origTimeout, origActive := dialWorld, saveTimeout, serviceActive
+	defer func() { dialWorld, saveTimeout, serviceActive = origDial, origTimeout, origActive }()
+
+	// useConn makes dialWorld return conn, and checks that restic runs
+	// while saving is off when backup is expected
+	useConn := func(t *testing.T, conn *fakeConn, logPath string, backup bool) {
+		dialWorld = func(dir string) (rconConn, error) {
+			if dir != worldDir {
+				t.Errorf("dialWorld(%s), want %s", dir, worldDir)
+			}
+			return conn, nil
+		}
+		onSend := conn.onSend
+		conn.onSend = func(command string) string {
+			data, _ := os.ReadFile(logPath)
+			ranBackup := strings.Contains(string(data), "backup ")
+			if command == "save-on" && backup && !ranBackup {
+				t.Error("save-on sent before restic backup")
+			}
+			if command != "save-on" && ranBackup {
+				t.Errorf("%s sent after restic backup", command)
+			}
+			if onSend != nil {
+				return onSend(command)
+			}
+			if command == "save-all flush" {
+				return "Saved the game"
+			}
+			return ""
+		}
+	}
+
+	t.Run("running world", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		conn := &fakeConn{}
+		useConn(t, conn, logPath, true)
+
+		if err := cfg.Create("survival"); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		want := []string{"save-off", "save-all flush", "save-on"}
+		if !reflect.DeepEqual(conn.commands, want) || !conn.closed {
+			t.Errorf("RCON commands = %v (closed %v), want %v", conn.commands, conn.closed, want)
+		}
+	})
+
+	t.Run("save-on after restic failure", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		t.Setenv("FAKE_RESTIC_FAIL", "1")
+		conn := &fakeConn{}
+		useConn(t, conn, logPath, true)
+
+		if err := cfg.Create("survival"); err == nil {
+			t.Fatal("Expected restic failure")
+		}
+		if len(conn.commands) == 0 || conn.commands[len(conn.commands)-1] != "save-on" {
+			t.Errorf("Expected save-on after failure, got %v", conn.commands)
+		}
+	})
+
+	t.Run("confirmation from server log", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		latest := filepath.Join(worldDir, "logs", "latest.log")
+		os.WriteFile(latest, []byte("[10:00:00] [Server thread/INFO]: Saved the game\n"), 0644)
+		conn := &fakeConn{onSend: func(command string) string {
+			if command == "save-all flush" {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					f, _ := os.OpenFile(latest, os.O_APPEND|os.O_WRONLY, 0644)
+					fmt.Fprintln(f, "[10:05:00] [Server thread/INFO]: Saved the game")
+					f.Close()
+				}()
+				return "Saving the game (this may take a moment!)"
+			}
+			return ""
+		}}
+		useConn(t, conn, logPath, true)
+
+		if err := cfg.Create("survival"); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	})
+
+	t.Run("save timeout resumes saving", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		saveTimeout = 100 * time.Millisecond
+		defer func() { saveTimeout = origTimeout }()
+		conn := &fakeConn{onSend: func(string) string { return "" }}
+		useConn(t, conn, logPath, false)
+
+		if err := cfg.Create("survival"); err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Fatalf("Expected timeout, got %v", err)
+		}
+		want := []string{"save-off", "save-all flush", "save-on"}
+		if !reflect.DeepEqual(conn.commands, want) {
+			t.Errorf("RCON commands = %v, want %v", conn.commands, want)
+		}
+		data, _ := os.ReadFile(logPath)
+		if strings.Contains(string(data), "backup ") {
+			t.Error("restic should not run when the world could not be saved")
+		}
+	})
+
+	t.Run("stopped world", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		dialWorld = func(string) (rconConn, error) {
+			return nil, fmt.Errorf("connection refused")
+		}
+		serviceActive = func(string) (bool, error) { return false, nil }
+
+		if err := cfg.Create("survival"); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		data, _ := os.ReadFile(logPath)
+		if !strings.Contains(string(data), "backup "+filepath.Join(worldDir, "world")) {
+			t.Errorf("Expected restic backup of the world, got:\n%s", data)
+		}
+	})
+
+	t.Run("running world without RCON", func(t *testing.T) {
+		logPath := fakeRestic(t, nil)
+		dialWorld = func(string) (rconConn, error) {
+			return nil, fmt.Errorf("connection refused")
+		}
+		serviceActive = func(world string) (bool, error) { return world == "survival", nil }
+
+		err := cfg.Create("survival")
+		if err == nil || !strings.Contains(err.Error(), "not reachable over RCON") {
+			t.Fatalf("Expected error for running world without RCON, got %v", err)
+		}
+		data, _ := os.ReadFile(logPath)
+		if strings.Contains(string(data), "backup ") {
+			t.Error("restic should not run for an unquiesced running world")
+		}
+	})
+}
+
+func TestCreateWorld(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival")
+	os.MkdirAll(filepath.Join(worldDir, "world"), 0755)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("enable-rcon=true\n"), 0644)
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret", WorldsDir: worldsDir}
+
+	origDial := dialWorld
+	defer func() { dialWorld = origDial }()
+	conn := &fakeConn{}
+	dialWorld = func(string) (rconConn, error) { return conn, nil }
+
+	logPath := fakeRestic(t, map[string]string{"backup": `{"message_type":"status","percent_done":0.5}
+{"message_type":"summary","files_new":3,"data_added":2048,"snapshot_id":"0123456789abcdef0123"}`})
+
+	result, err := cfg.CreateWorld("survival")
+	if err != nil {
+		t.Fatalf("CreateWorld failed: %v", err)
+	}
+	if result.World != "survival" || result.Snapshot != "01234567" || result.DataAdded != 2048 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if want := []string{"save-off", "save-all flush", "save-on"}; !reflect.DeepEqual(conn.commands, want) {
+		t.Errorf("RCON commands = %v, want %v", conn.commands, want)
+	}
+	data, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(data), "backup "+filepath.Join(worldDir, "world")+" --tag survival") {
+		t.Errorf("Expected a snapshot tagged with the world, got:\n%s", data)
+	}
+
+	if _, err := cfg.CreateWorld("creative"); err == nil {
+		t.Error("Expected error for missing world")
+	}
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+// DefaultRepoName names the repository derived from MC_WORLD_BUCKET when
+// minecraftctl.yml configures no repositories
+const DefaultRepoName = "default"
+
+// Repository types supported in minecraftctl.yml
+const (
+	RepoLocal = "local"
+	RepoS3    = "s3"
+	RepoSFTP  = "sftp"
+	RepoREST  = "rest"
+	RepoB2    = "b2"
+)
+
+// RepositoryURL builds the restic repository string for a configured repository:
+//
+//	local  <path>
+//	s3     s3:<endpoint>/<path>          (endpoint defaults to AWS in AWS_REGION)
+//	sftp   sftp:<endpoint>:<path>        (sftp://<endpoint>/<path> when endpoint has a port)
+//	rest   rest:<endpoint>/<path>
+//	b2     b2:<path>                     (path is bucket[:prefix])
+func RepositoryURL(repo config.BackupRepository) (string, error) {
+	switch repo.Type {
+	case RepoLocal, RepoS3, RepoSFTP, RepoB2:
+		if repo.Path == "" {
+			return "", fmt.Errorf("%s repository needs a path", repo.Type)
+		}
+	case RepoREST:
+	case "":
+		return "", fmt.Errorf("repository type not set (local, s3, sftp, rest, b2)")
+	default:
+		return "", fmt.Errorf("unsupported repository type: %s (supported: local, s3, sftp, rest, b2)", repo.Type)
+	}
+
+	switch repo.Type {
+	case RepoLocal:
+		return repo.Path, nil
+	case RepoS3:
+		endpoint := repo.Endpoint
+		if endpoint == "" {
+			region := os.Getenv("AWS_REGION")
+			if region == "" {
+				region = defaultRegion
+			}
+			endpoint = fmt.Sprintf("s3.%s.amazonaws.com", region)
+		}
+		return fmt.Sprintf("s3:%s/%s", strings.TrimSuffix(endpoint, "/"), strings.TrimPrefix(repo.Path, "/")), nil
+	case RepoSFTP:
+		if repo.Endpoint == "" {
+			return "", fmt.Errorf("sftp repository needs an endpoint (user@host[:port])")
+		}
+		if host := repo.Endpoint[strings.LastIndex(repo.Endpoint, "@")+1:]; strings.Contains(host, ":") {
+			return fmt.Sprintf("sftp://%s/%s", repo.Endpoint, repo.Path), nil
+		}
+		return fmt.Sprintf("sftp:%s:%s", repo.Endpoint, repo.Path), nil
+	case RepoREST:
+		if repo.Endpoint == "" {
+			return "", fmt.Errorf("rest repository needs an endpoint (server URL)")
+		}
+		url := "rest:" + strings.TrimSuffix(repo.Endpoint, "/") + "/"
+		return url + strings.TrimPrefix(repo.Path, "/"), nil
+	default: // RepoB2
+		return "b2:" + repo.Path, nil
+	}
+}
+
+// LoadRepoConfig loads the backup configuration for a named repository from
+// minecraftctl.yml. An empty name selects backup.default_repo, or the only
+// repository if just one is configured. Without configured repositories the
+// repository is derived from MC_WORLD_BUCKET as before.
+func LoadRepoConfig(name string) (*Config, error) {
+	// config.Init has exported minecraft.env, before expanding repository
+	// settings that refer to its variables
+	global := config.Get()
+	repos := global.Backup.Repositories
+	name = strings.ToLower(name)
+
+	if len(repos) == 0 {
+		if name != "" && name != DefaultRepoName {
+			return nil, fmt.Errorf("unknown repository %q: no repositories configured in minecraftctl.yml", name)
+		}
+		return loadEnvConfig()
+	}
+
+	if name == "" {
+		name = global.Backup.DefaultRepo
+	}
+	if name == "" {
+		if len(repos) != 1 {
+			return nil, fmt.Errorf("several repositories configured (%s): set backup.default_repo or use --repo", strings.Join(RepositoryNames(global.Backup), ", "))
+		}
+		for only := range repos {
+			name = only
+		}
+	}
+
+	repo, ok := repos[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown repository %q (configured: %s)", name, strings.Join(RepositoryNames(global.Backup), ", "))
+	}
+
+	url, err := RepositoryURL(repo)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %w", name, err)
+	}
+
+	cfg := &Config{
+		Name:         name,
+		Repository:   url,
+		Password:     repo.Password,
+		PasswordFile: repo.PasswordFile,
+		WorldsDir:    global.WorldsDir,
+	}
+	if cfg.Password == "" && cfg.PasswordFile == "" {
+		cfg.Password = os.Getenv("RESTIC_PASSWORD")
+		if cfg.Password == "" {
+			return nil, fmt.Errorf("repository %s: no password, password_file or RESTIC_PASSWORD set", name)
+		}
+	}
+
+	for _, key := range sortedKeys(repo.Env) {
+		cfg.Env = append(cfg.Env, key+"="+repo.Env[key])
+	}
+
+	return cfg, nil
+}
+
+// RepositoryNames returns the names of the configured repositories, sorted
+func RepositoryNames(backup config.BackupConfig) []string {
+	return sortedKeys(backup.Repositories)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Copy replicates snapshots from src into the repository with restic copy.
+// Without snapshot IDs every snapshot (with tag, if set) is copied; snapshots
+// already present in the destination are skipped. The destination is
+// initialized with src's chunker parameters if needed, so copied data
+// deduplicates.
+func (c *Config) Copy(src *Config, tag string, snapshots ...string) error {
+	if src.Repository == c.Repository {
+		return fmt.Errorf("source and destination are the same repository: %s", c.Repository)
+	}
+
+	if _, err := c.runResticOutput("snapshots", "--quiet"); err != nil {
+		fmt.Printf("Initializing restic repository %s...\n", c.Name)
+		if err := c.runResticFrom(src, "init", "--copy-chunker-params"); err != nil {
+			return fmt.Errorf("failed to initialize repository %s: %w", c.Name, err)
+		}
+	}
+
+	args := []string{"copy"}
+	if tag != "" {
+		args = append(args, "--tag", tag)
+	}
+	args = append(args, snapshots...)
+
+	fmt.Printf("Copying snapshots from %s to %s...\n", src.Name, c.Name)
+	return c.runResticFrom(src, args...)
+}
+
+// runResticFrom runs a restic command whose --from-repo is src
+func (c *Config) runResticFrom(src *Config, args ...string) error {
+	cmd := exec.Command("restic", args...)
+	// Variables of the destination win if both repositories set the same one
+	cmd.Env = c.environ(src.Env...)
+	cmd.Env = append(cmd.Env, "RESTIC_FROM_REPOSITORY="+src.Repository)
+	if src.PasswordFile != "" {
+		cmd.Env = append(cmd.Env, "RESTIC_FROM_PASSWORD_FILE="+src.PasswordFile)
+	} else {
+		cmd.Env = append(cmd.Env, "RESTIC_FROM_PASSWORD="+src.Password)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
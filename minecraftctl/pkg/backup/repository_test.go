@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/spf13/viper"
+)
+
+func TestRepositoryURL(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	tests := []struct {
+		name    string
+		repo    config.BackupRepository
+		want    string
+		wantErr bool
+	}{
+		{"local", config.BackupRepository{Type: "local", Path: "/mnt/backup"}, "/mnt/backup", false},
+		{"s3 aws", config.BackupRepository{Type: "s3", Path: "bucket/restic"}, "s3:s3.eu-west-1.amazonaws.com/bucket/restic", false},
+		{"s3 minio", config.BackupRepository{Type: "s3", Endpoint: "http://localhost:9000/", Path: "/bucket"}, "s3:http://localhost:9000/bucket", false},
+		{"sftp", config.BackupRepository{Type: "sftp", Endpoint: "backup@nas", Path: "/srv/restic"}, "sftp:backup@nas:/srv/restic", false},
+		{"sftp with port", config.BackupRepository{Type: "sftp", Endpoint: "backup@nas:2222", Path: "/srv/restic"}, "sftp://backup@nas:2222//srv/restic", false},
+		{"rest", config.BackupRepository{Type: "rest", Endpoint: "https://rest.example.com:8000", Path: "minecraft"}, "rest:https://rest.example.com:8000/minecraft", false},
+		{"rest root", config.BackupRepository{Type: "rest", Endpoint: "http://localhost:8000/"}, "rest:http://localhost:8000/", false},
+		{"b2", config.BackupRepository{Type: "b2", Path: "bucket:minecraft"}, "b2:bucket:minecraft", false},
+		{"missing path", config.BackupRepository{Type: "local"}, "", true},
+		{"sftp without endpoint", config.BackupRepository{Type: "sftp", Path: "/srv"}, "", true},
+		{"missing type", config.BackupRepository{Path: "/srv"}, "", true},
+		{"unknown type", config.BackupRepository{Type: "ftp", Path: "/srv"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RepositoryURL(tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepositoryURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RepositoryURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	setup := func(t *testing.T, content string) {
+		t.Helper()
+		viper.Reset()
+		path := filepath.Join(t.TempDir(), "minecraftctl.yml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := config.Init(path); err != nil {
+			t.Fatalf("config.Init() failed: %v", err)
+		}
+	}
+
+	t.Run("named repositories", func(t *testing.T) {
+		t.Setenv("RESTIC_PASSWORD", "fallback")
+		setup(t, `
+worlds_dir: /srv/worlds
+backup:
+  default_repo: local
+  repositories:
+    local:
+      type: local
+      path: /mnt/restic
+    offsite:
+      type: sftp
+      endpoint: backup@nas
+      path: /restic
+      password: offsite-secret
+      env:
+        SSH_AUTH_SOCK: /run/agent
+`)
+
+		cfg, err := LoadRepoConfig("")
+		if err != nil {
+			t.Fatalf("LoadRepoConfig failed: %v", err)
+		}
+		if cfg.Name != "local" || cfg.Repository != "/mnt/restic" || cfg.Password != "fallback" || cfg.WorldsDir != "/srv/worlds" {
+			t.Errorf("Unexpected default repository: %+v", cfg)
+		}
+
+		cfg, err = LoadRepoConfig("Offsite")
+		if err != nil {
+			t.Fatalf("LoadRepoConfig failed: %v", err)
+		}
+		if cfg.Repository != "sftp:backup@nas:/restic" || cfg.Password != "offsite-secret" {
+			t.Errorf("Unexpected offsite repository: %+v", cfg)
+		}
+		env := strings.Join(cfg.environ(), "\n")
+		for _, want := range []string{"SSH_AUTH_SOCK=/run/agent", "RESTIC_REPOSITORY=sftp:backup@nas:/restic", "RESTIC_PASSWORD=offsite-secret"} {
+			if !strings.Contains(env, want) {
+				t.Errorf("environ() missing %s", want)
+			}
+		}
+
+		if _, err := LoadRepoConfig("missing"); err == nil || !strings.Contains(err.Error(), "local, offsite") {
+			t.Errorf("Expected unknown repository error listing repositories, got %v", err)
+		}
+	})
+
+	t.Run("several repositories without default", func(t *testing.T) {
+		t.Setenv("RESTIC_PASSWORD", "secret")
+		setup(t, `
+backup:
+  repositories:
+    a: {type: local, path: /a}
+    b: {type: local, path: /b}
+`)
+		if _, err := LoadRepoConfig(""); err == nil {
+			t.Error("Expected error without default_repo")
+		}
+	})
+
+	t.Run("no password", func(t *testing.T) {
+		t.Setenv("RESTIC_PASSWORD", "")
+		setup(t, `
+backup:
+  repositories:
+    a: {type: local, path: /a}
+`)
+		if _, err := LoadRepoConfig("a"); err == nil {
+			t.Error("Expected error without password")
+		}
+	})
+
+	t.Run("falls back to MC_WORLD_BUCKET", func(t *testing.T) {
+		t.Setenv("MC_WORLD_BUCKET", "worlds")
+		t.Setenv("RESTIC_PASSWORD", "secret")
+		t.Setenv("AWS_REGION", "us-west-2")
+		setup(t, "worlds_dir: /srv/worlds\n")
+
+		cfg, err := LoadRepoConfig("")
+		if err != nil {
+			t.Fatalf("LoadRepoConfig failed: %v", err)
+		}
+		if cfg.Name != DefaultRepoName || cfg.Repository != "s3:s3.us-west-2.amazonaws.com/worlds" {
+			t.Errorf("Unexpected legacy repository: %+v", cfg)
+		}
+		if _, err := LoadRepoConfig("offsite"); err == nil {
+			t.Error("Expected error for named repository without configuration")
+		}
+	})
+}
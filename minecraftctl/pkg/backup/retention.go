@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+// DefaultRetention is used when minecraftctl.yml sets no retention defaults
+var DefaultRetention = config.RetentionPolicy{
+	KeepDaily:   7,
+	KeepWeekly:  4,
+	KeepMonthly: 3,
+}
+
+// PruneOptions controls Prune
+type PruneOptions struct {
+	// Retention holds the default and per-world policies
+	Retention config.RetentionConfig
+	// DryRun shows which snapshots would be forgotten without removing any
+	DryRun bool
+}
+
+// RetentionFor returns the policy for a world tag: the configured defaults
+// (or DefaultRetention) with the fields the world sets overridden
+func RetentionFor(retention config.RetentionConfig, world string) config.RetentionPolicy {
+	policy := retention.Defaults
+	if isEmptyPolicy(policy) {
+		policy = DefaultRetention
+	}
+
+	override, ok := retention.Worlds[strings.ToLower(world)]
+	if !ok {
+		return policy
+	}
+	for _, f := range []struct {
+		dst *int
+		src int
+	}{
+		{&policy.KeepLast, override.KeepLast},
+		{&policy.KeepHourly, override.KeepHourly},
+		{&policy.KeepDaily, override.KeepDaily},
+		{&policy.KeepWeekly, override.KeepWeekly},
+		{&policy.KeepMonthly, override.KeepMonthly},
+		{&policy.KeepYearly, override.KeepYearly},
+	} {
+		if f.src != 0 {
+			*f.dst = f.src
+		}
+	}
+	if override.KeepTags != nil {
+		policy.KeepTags = override.KeepTags
+	}
+	return policy
+}
+
+func isEmptyPolicy(p config.RetentionPolicy) bool {
+	return p.KeepLast == 0 && p.KeepHourly == 0 && p.KeepDaily == 0 && p.KeepWeekly == 0 &&
+		p.KeepMonthly == 0 && p.KeepYearly == 0 && len(p.KeepTags) == 0
+}
+
+// policyFlags converts a policy to restic forget flags
+func policyFlags(p config.RetentionPolicy) []string {
+	var args []string
+	for _, f := range []struct {
+		flag string
+		n    int
+	}{
+		{"--keep-last", p.KeepLast},
+		{"--keep-hourly", p.KeepHourly},
+		{"--keep-daily", p.KeepDaily},
+		{"--keep-weekly", p.KeepWeekly},
+		{"--keep-monthly", p.KeepMonthly},
+		{"--keep-yearly", p.KeepYearly},
+	} {
+		if f.n != 0 {
+			args = append(args, f.flag, strconv.Itoa(f.n))
+		}
+	}
+	for _, tag := range p.KeepTags {
+		args = append(args, "--keep-tag", tag)
+	}
+	return args
+}
+
+// DescribeRetention formats a policy for display, e.g. "daily 7, weekly 4, tags pre-upgrade"
+func DescribeRetention(p config.RetentionPolicy) string {
+	var parts []string
+	for _, f := range []struct {
+		name string
+		n    int
+	}{
+		{"last", p.KeepLast},
+		{"hourly", p.KeepHourly},
+		{"daily", p.KeepDaily},
+		{"weekly", p.KeepWeekly},
+		{"monthly", p.KeepMonthly},
+		{"yearly", p.KeepYearly},
+	} {
+		switch {
+		case f.n < 0:
+			parts = append(parts, f.name+" unlimited")
+		case f.n > 0:
+			parts = append(parts, fmt.Sprintf("%s %d", f.name, f.n))
+		}
+	}
+	if len(p.KeepTags) > 0 {
+		parts = append(parts, "tags "+strings.Join(p.KeepTags, ","))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// worldTags returns the world tag of every snapshot group in the
+// repository. A snapshot belongs to the world whose tag it carries and whose
+// <worlds_dir>/<world>/world directory it contains, so tags added by hand,
+// such as "manual", are not taken for worlds; snapshots of all worlds are
+// grouped under their "all" tag. Snapshots of no recognised world are
+// returned in unknown.
+func (c *Config) worldTags() (worlds []string, unknown []Snapshot, err error) {
+	snapshots, err := c.Snapshots(SnapshotFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, snap := range snapshots {
+		world := snapshotWorld(snap)
+		if world == "" {
+			unknown = append(unknown, snap)
+			continue
+		}
+		if !seen[world] {
+			seen[world] = true
+			worlds = append(worlds, world)
+		}
+	}
+	sort.Strings(worlds)
+	return worlds, unknown, nil
+}
+
+// snapshotWorld returns "all" for snapshots of all worlds, or the tag of
+// the world whose world/ directory the snapshot contains, or ""
+func snapshotWorld(snap Snapshot) string {
+	if snap.HasTag("all") {
+		return "all"
+	}
+	for _, tag := range snap.Tags {
+		for _, p := range snap.Paths {
+			if path.Base(p) == "world" && path.Base(path.Dir(p)) == tag {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// Prune forgets snapshots world by world, applying each world's retention
+// policy to the snapshots with its tag, then removes unreferenced data and
+// checks the repository. With DryRun only the forget plan is shown.
+// Snapshots of no known world are reported and left alone.
+func (c *Config) Prune(opts PruneOptions) error {
+	worlds, unknown, err := c.worldTags()
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		fmt.Printf("Skipping %d snapshot(s) of no known world:\n", len(unknown))
+		for _, snap := range unknown {
+			fmt.Printf("  %s  tags: %s  paths: %s\n", snap.ShortID, strings.Join(snap.Tags, ","), strings.Join(snap.Paths, ", "))
+		}
+	}
+	if len(worlds) == 0 {
+		fmt.Println("No snapshots to prune")
+		return nil
+	}
+
+	for _, world := range worlds {
+		policy := RetentionFor(opts.Retention, world)
+		fmt.Printf("\n%s: keep %s\n", world, DescribeRetention(policy))
+
+		// Group all snapshots of the world together regardless of host or path
+		args := []string{"forget", "--tag", world, "--group-by", ""}
+		args = append(args, policyFlags(policy)...)
+		if opts.DryRun {
+			args = append(args, "--dry-run")
+		}
+		if err := c.runRestic(args...); err != nil {
+			return fmt.Errorf("failed to forget snapshots of %s: %w", world, err)
+		}
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	fmt.Println("\nRemoving unreferenced data...")
+	if err := c.runRestic("prune"); err != nil {
+		return err
+	}
+
+	fmt.Println("\nChecking repository integrity...")
+	if err := c.runRestic("check"); err != nil {
+		return err
+	}
+
+	fmt.Println("\nRepository statistics:")
+	return c.runRestic("stats")
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+func TestRetentionFor(t *testing.T) {
+	retention := config.RetentionConfig{
+		Defaults: config.RetentionPolicy{KeepDaily: 14, KeepWeekly: 8, KeepTags: []string{"pre-upgrade"}},
+		Worlds: map[string]config.RetentionPolicy{
+			"survival": {KeepLast: 5, KeepDaily: 30},
+			"creative": {KeepTags: []string{}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		retention config.RetentionConfig
+		world     string
+		want      config.RetentionPolicy
+	}{
+		{"built-in default", config.RetentionConfig{}, "survival", DefaultRetention},
+		{"configured default", retention, "lobby", retention.Defaults},
+		{"world override", retention, "Survival", config.RetentionPolicy{KeepLast: 5, KeepDaily: 30, KeepWeekly: 8, KeepTags: []string{"pre-upgrade"}}},
+		{"world clears keep tags", retention, "creative", config.RetentionPolicy{KeepDaily: 14, KeepWeekly: 8, KeepTags: []string{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RetentionFor(tt.retention, tt.world)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RetentionFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFlags(t *testing.T) {
+	policy := config.RetentionPolicy{KeepLast: 3, KeepYearly: -1, KeepTags: []string{"pre-upgrade", "keep"}}
+	want := []string{"--keep-last", "3", "--keep-yearly", "-1", "--keep-tag", "pre-upgrade", "--keep-tag", "keep"}
+	if got := policyFlags(policy); !reflect.DeepEqual(got, want) {
+		t.Errorf("policyFlags() = %v, want %v", got, want)
+	}
+	if got := DescribeRetention(policy); got != "last 3, yearly unlimited, tags pre-upgrade,keep" {
+		t.Errorf("DescribeRetention() = %q", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	// Tags added by hand, such as manual, are not taken for worlds
+	snapshots := `[
+		{"id": "1", "tags": ["survival"], "paths": ["/srv/minecraft-server/survival/world"]},
+		{"id": "2", "tags": ["pre-upgrade", "survival"], "paths": ["/srv/minecraft-server/survival/world"]},
+		{"id": "3", "tags": ["manual", "creative"], "paths": ["/srv/minecraft-server/creative/world"]},
+		{"id": "4", "tags": ["all"], "paths": ["/srv/minecraft-server"]},
+		{"id": "5", "tags": ["manual"], "paths": ["/home/paul/notes"]},
+		{"id": "6"}
+	]`
+	retention := config.RetentionConfig{
+		Defaults: config.RetentionPolicy{KeepDaily: 7, KeepTags: []string{"pre-upgrade"}},
+		Worlds:   map[string]config.RetentionPolicy{"creative": {KeepLast: 2}},
+	}
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret"}
+
+	t.Run("dry run", func(t *testing.T) {
+		logPath := fakeRestic(t, map[string]string{"snapshots": snapshots})
+		if err := cfg.Prune(PruneOptions{Retention: retention, DryRun: true}); err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(logPath)
+		got := strings.Split(strings.TrimSpace(string(data)), "\n")
+		want := []string{
+			"snapshots --json",
+			"forget --tag all --group-by  --keep-daily 7 --keep-tag pre-upgrade --dry-run",
+			"forget --tag creative --group-by  --keep-last 2 --keep-daily 7 --keep-tag pre-upgrade --dry-run",
+			"forget --tag survival --group-by  --keep-daily 7 --keep-tag pre-upgrade --dry-run",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("restic calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		logPath := fakeRestic(t, map[string]string{"snapshots": snapshots})
+		if err := cfg.Prune(PruneOptions{Retention: retention}); err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(logPath)
+		log := string(data)
+		if strings.Contains(log, "--dry-run") {
+			t.Error("Prune should not pass --dry-run")
+		}
+		if !strings.HasSuffix(log, "prune\ncheck\nstats\n") {
+			t.Errorf("Expected prune, check and stats after forget, got:\n%s", log)
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// Snapshot is a restic snapshot as reported by restic snapshots --json
+type Snapshot struct {
+	ID       string    `json:"id"`
+	ShortID  string    `json:"short_id"`
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Tags     []string  `json:"tags"`
+	Paths    []string  `json:"paths"`
+	Parent   string    `json:"parent,omitempty"`
+	// Summary is recorded by restic 0.17 and later; nil for older snapshots
+	Summary *SnapshotSummary `json:"summary,omitempty"`
+}
+
+// SnapshotSummary describes the backup run that created a snapshot
+type SnapshotSummary struct {
+	BackupStart         time.Time `json:"backup_start"`
+	BackupEnd           time.Time `json:"backup_end"`
+	FilesNew            int       `json:"files_new"`
+	FilesChanged        int       `json:"files_changed"`
+	FilesUnmodified     int       `json:"files_unmodified"`
+	DirsNew             int       `json:"dirs_new"`
+	DirsChanged         int       `json:"dirs_changed"`
+	DirsUnmodified      int       `json:"dirs_unmodified"`
+	DataAdded           int64     `json:"data_added"`
+	DataAddedPacked     int64     `json:"data_added_packed"`
+	TotalFilesProcessed int       `json:"total_files_processed"`
+	TotalBytesProcessed int64     `json:"total_bytes_processed"`
+}
+
+// Stats is the output of restic stats --json. The blob and compression
+// fields are only set in raw-data mode.
+type Stats struct {
+	TotalSize              int64   `json:"total_size"`
+	TotalFileCount         int     `json:"total_file_count"`
+	SnapshotsCount         int     `json:"snapshots_count"`
+	TotalBlobCount         int     `json:"total_blob_count,omitempty"`
+	TotalUncompressedSize  int64   `json:"total_uncompressed_size,omitempty"`
+	CompressionRatio       float64 `json:"compression_ratio,omitempty"`
+	CompressionSpaceSaving float64 `json:"compression_space_saving,omitempty"`
+}
+
+// SnapshotFilter selects snapshots. Zero fields match everything.
+type SnapshotFilter struct {
+	// World matches snapshots tagged with the world name (or "all")
+	World string
+	// Since and Before bound the snapshot time: Since <= Time < Before
+	Since  time.Time
+	Before time.Time
+}
+
+// HasTag reports whether the snapshot carries tag
+func (s Snapshot) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListSnapshots returns the snapshots in the default repository that match
+// filter, oldest first
+func ListSnapshots(filter SnapshotFilter) ([]Snapshot, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Snapshots(filter)
+}
+
+// Snapshots returns the snapshots in the repository that match filter,
+// oldest first
+func (c *Config) Snapshots(filter SnapshotFilter) ([]Snapshot, error) {
+	args := []string{"snapshots", "--json"}
+	if filter.World != "" {
+		// Repeated --tag options match snapshots with either tag
+		args = append(args, "--tag", filter.World, "--tag", "all")
+	}
+
+	var all []Snapshot
+	if err := c.resticJSON(&all, args...); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, snap := range all {
+		if !filter.Since.IsZero() && snap.Time.Before(filter.Since) {
+			continue
+		}
+		if !filter.Before.IsZero() && !snap.Time.Before(filter.Before) {
+			continue
+		}
+		if filter.World != "" {
+			if _, err := WorldPath(&snap, filter.World); err != nil {
+				continue
+			}
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// Snapshot returns a single snapshot by ID, short ID or "latest"
+func (c *Config) Snapshot(id string) (*Snapshot, error) {
+	var snapshots []Snapshot
+	if err := c.resticJSON(&snapshots, "snapshots", "--json", id); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", id, err)
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return &snapshots[0], nil
+}
+
+// WorldSnapshot returns a snapshot containing a world: the snapshot with
+// the given ID, or for "latest" the newest snapshot of the world or of all
+// worlds. restic's own latest is the newest snapshot of any world.
+func (c *Config) WorldSnapshot(world, id string) (*Snapshot, error) {
+	if id != "latest" {
+		snap, err := c.Snapshot(id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := WorldPath(snap, world); err != nil {
+			return nil, err
+		}
+		return snap, nil
+	}
+
+	snapshots, err := c.Snapshots(SnapshotFilter{World: world})
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots of %s", world)
+	}
+	return &snapshots[len(snapshots)-1], nil
+}
+
+// RepoStats returns repository statistics in the given restic stats mode
+// (restore-size, files-by-contents, raw-data or blobs-per-file; empty uses
+// restic's default), limited to snapshots if any are given
+func (c *Config) RepoStats(mode string, snapshots ...string) (*Stats, error) {
+	args := []string{"stats", "--json"}
+	if mode != "" {
+		args = append(args, "--mode", mode)
+	}
+	args = append(args, snapshots...)
+
+	var stats Stats
+	if err := c.resticJSON(&stats, args...); err != nil {
+		return nil, fmt.Errorf("failed to read repository statistics: %w", err)
+	}
+	return &stats, nil
+}
+
+// resticJSON runs a restic command with JSON output and decodes it into v.
+// restic's error message is included in the returned error.
+func (c *Config) resticJSON(v any, args ...string) error {
+	out, err := c.command(args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return err
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("failed to parse restic output: %w", err)
+	}
+	return nil
+}
+
+// ParseTime parses a --since/--before value: an RFC 3339 time, a date
+// (2006-01-02), a date and time (2006-01-02 15:04), or a duration before now
+// such as 36h or 7d
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		var days int
+		if _, err := fmt.Sscanf(value, "%dd", &days); err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use 2006-01-02, 2006-01-02 15:04, RFC 3339 or a duration like 7d or 36h)", value)
+}
+
+// WorldPath returns the path of a world's world/ directory inside a snapshot.
+// World snapshots contain <worlds_dir>/<world>/world itself; snapshots of
+// all worlds contain the worlds directory.
+func WorldPath(snap *Snapshot, world string) (string, error) {
+	for _, p := range snap.Paths {
+		if path.Base(p) == "world" && path.Base(path.Dir(p)) == world {
+			return p, nil
+		}
+	}
+	if snap.HasTag("all") && len(snap.Paths) == 1 {
+		return path.Join(snap.Paths[0], world, "world"), nil
+	}
+	return "", fmt.Errorf("snapshot %s does not contain world %s (paths: %s)", snap.ShortID, world, strings.Join(snap.Paths, ", "))
+}
+
+// RestorePath restores the directory dir of a snapshot into target, so
+// target receives the directory's contents. It needs restic 0.16 or later.
+func (c *Config) RestorePath(snapshot, dir, target string) error {
+	return c.runRestic("restore", snapshot+":"+dir, "--target", target)
+}
+
+// Node is a file or directory in a snapshot, from restic ls --json
+type Node struct {
+	Name  string    `json:"name"`
+	Type  string    `json:"type"`
+	Path  string    `json:"path"`
+	Size  int64     `json:"size"`
+	MTime time.Time `json:"mtime"`
+}
+
+// ListDir returns the entries directly inside dir in a snapshot. A
+// directory missing from the snapshot has no entries.
+func (c *Config) ListDir(snapshot, dir string) ([]Node, error) {
+	cmd := c.command("ls", "--json", snapshot, dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s in snapshot %s: %w", dir, snapshot, err)
+	}
+
+	var nodes []Node
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var node Node
+		// The first line describes the snapshot and has no path
+		if err := json.Unmarshal([]byte(line), &node); err != nil || node.Path == "" {
+			continue
+		}
+		if path.Dir(node.Path) == path.Clean(dir) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// Dump writes the contents of a file in a snapshot to w
+func (c *Config) Dump(snapshot, file string, w io.Writer) error {
+	cmd := c.command("dump", snapshot, file)
+	cmd.Stdout = w
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to read %s from snapshot %s: %w: %s", file, snapshot, err, msg)
+		}
+		return fmt.Errorf("failed to read %s from snapshot %s: %w", file, snapshot, err)
+	}
+	return nil
+}
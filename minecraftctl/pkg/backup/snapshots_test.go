@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRestic puts a restic script on PATH that logs its arguments to the
+// returned file. For commands run with --json it prints outputs[command];
+// "backup" fails when FAKE_RESTIC_FAIL is set; "restore" copies the
+// directory FAKE_RESTIC_RESTORE into its --target.
+func fakeRestic(t *testing.T, outputs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "restic.log")
+	for command, output := range outputs {
+		os.WriteFile(filepath.Join(dir, command+".json"), []byte(output), 0644)
+	}
+
+	script := `#!/bin/sh
+echo "$*" >> ` + logPath + `
+case " $* " in
+*" --json "*)
+	if [ -f "` + dir + `/$1.json" ]; then
+		cat "` + dir + `/$1.json"
+	else
+		echo "unexpected restic $1" >&2
+		exit 1
+	fi
+	;;
+esac
+if [ "$1" = "backup" ] && [ -n "$FAKE_RESTIC_FAIL" ]; then
+	exit 1
+fi
+if [ "$1" = "restore" ] && [ -n "$FAKE_RESTIC_RESTORE" ]; then
+	cp -R "$FAKE_RESTIC_RESTORE/." "$4"
+fi
+`
+	if err := os.WriteFile(filepath.Join(dir, "restic"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake restic: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+const testSnapshotsJSON = `[
+	{
+		"time": "2024-05-01T03:00:00.123456789Z",
+		"tree": "t1",
+		"paths": ["/srv/minecraft-server/survival/world"],
+		"hostname": "mc1",
+		"tags": ["survival"],
+		"id": "aaaa1111bbbb2222",
+		"short_id": "aaaa1111"
+	},
+	{
+		"time": "2024-05-02T03:00:00Z",
+		"parent": "aaaa1111bbbb2222",
+		"paths": ["/srv/minecraft-server/survival/world"],
+		"hostname": "mc1",
+		"tags": ["survival", "pre-upgrade"],
+		"summary": {
+			"backup_start": "2024-05-02T03:00:00Z",
+			"backup_end": "2024-05-02T03:00:42Z",
+			"files_new": 3,
+			"files_changed": 10,
+			"files_unmodified": 200,
+			"data_added": 1048576,
+			"total_files_processed": 213,
+			"total_bytes_processed": 524288000
+		},
+		"id": "cccc3333dddd4444",
+		"short_id": "cccc3333"
+	}
+]`
+
+func TestSnapshots(t *testing.T) {
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret"}
+
+	t.Run("parse", func(t *testing.T) {
+		logPath := fakeRestic(t, map[string]string{"snapshots": testSnapshotsJSON})
+
+		snapshots, err := cfg.Snapshots(SnapshotFilter{World: "survival"})
+		if err != nil {
+			t.Fatalf("Snapshots failed: %v", err)
+		}
+		if len(snapshots) != 2 {
+			t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+		}
+
+		first, second := snapshots[0], snapshots[1]
+		if first.ShortID != "aaaa1111" || first.Hostname != "mc1" || first.Summary != nil {
+			t.Errorf("Unexpected first snapshot: %+v", first)
+		}
+		if !first.Time.Equal(time.Date(2024, 5, 1, 3, 0, 0, 123456789, time.UTC)) {
+			t.Errorf("Time = %v", first.Time)
+		}
+		if second.Parent != first.ID || !second.HasTag("pre-upgrade") || second.HasTag("creative") {
+			t.Errorf("Unexpected second snapshot: %+v", second)
+		}
+		if second.Summary == nil || second.Summary.DataAdded != 1048576 || second.Summary.TotalFilesProcessed != 213 {
+			t.Errorf("Unexpected summary: %+v", second.Summary)
+		}
+
+		data, _ := os.ReadFile(logPath)
+		if strings.TrimSpace(string(data)) != "snapshots --json --tag survival --tag all" {
+			t.Errorf("restic called with %q", data)
+		}
+	})
+
+	t.Run("time filter", func(t *testing.T) {
+		fakeRestic(t, map[string]string{"snapshots": testSnapshotsJSON})
+
+		tests := []struct {
+			name   string
+			filter SnapshotFilter
+			want   []string
+		}{
+			{"since", SnapshotFilter{Since: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}, []string{"cccc3333"}},
+			{"since is inclusive", SnapshotFilter{Since: time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)}, []string{"cccc3333"}},
+			{"before is exclusive", SnapshotFilter{Before: time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)}, []string{"aaaa1111"}},
+			{"no match", SnapshotFilter{Since: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				snapshots, err := cfg.Snapshots(tt.filter)
+				if err != nil {
+					t.Fatalf("Snapshots failed: %v", err)
+				}
+				if snapshots == nil {
+					t.Fatal("Snapshots should return an empty list, not nil")
+				}
+				var got []string
+				for _, snap := range snapshots {
+					got = append(got, snap.ShortID)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("Snapshots() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("single snapshot and stats", func(t *testing.T) {
+		logPath := fakeRestic(t, map[string]string{
+			"snapshots": testSnapshotsJSON,
+			"stats":     `{"total_size":524288000,"total_file_count":213,"snapshots_count":1}`,
+		})
+
+		snap, err := cfg.Snapshot("latest")
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		if snap.ShortID != "aaaa1111" {
+			t.Errorf("Snapshot() = %s", snap.ShortID)
+		}
+
+		stats, err := cfg.RepoStats("restore-size", snap.ID)
+		if err != nil {
+			t.Fatalf("RepoStats failed: %v", err)
+		}
+		if stats.TotalSize != 524288000 || stats.TotalFileCount != 213 || stats.SnapshotsCount != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+
+		data, _ := os.ReadFile(logPath)
+		if !strings.Contains(string(data), "stats --json --mode restore-size aaaa1111bbbb2222") {
+			t.Errorf("Unexpected restic calls:\n%s", data)
+		}
+	})
+
+	t.Run("world", func(t *testing.T) {
+		fakeRestic(t, map[string]string{"snapshots": `[
+			{"id":"aaaa1111bbbb2222","short_id":"aaaa1111","time":"2024-05-01T03:00:00Z","tags":["survival"],"paths":["/srv/minecraft-server/survival/world"]},
+			{"id":"eeee5555ffff6666","short_id":"eeee5555","time":"2024-05-02T03:00:00Z","tags":["all"],"paths":["/srv/minecraft-server"]},
+			{"id":"cccc3333dddd4444","short_id":"cccc3333","time":"2024-05-03T03:00:00Z","tags":["creative"],"paths":["/srv/minecraft-server/creative/world"]}
+		]`})
+
+		// The all snapshot contains survival; creative's doesn't
+		snapshots, err := cfg.Snapshots(SnapshotFilter{World: "survival"})
+		if err != nil {
+			t.Fatalf("Snapshots failed: %v", err)
+		}
+		if len(snapshots) != 2 || snapshots[1].ShortID != "eeee5555" {
+			t.Errorf("Expected survival's and the all snapshot, got %+v", snapshots)
+		}
+
+		// restic's latest would be creative's snapshot
+		snap, err := cfg.WorldSnapshot("survival", "latest")
+		if err != nil {
+			t.Fatalf("WorldSnapshot failed: %v", err)
+		}
+		if snap.ShortID != "eeee5555" {
+			t.Errorf("WorldSnapshot(latest) = %s, want eeee5555", snap.ShortID)
+		}
+	})
+
+	t.Run("restic error", func(t *testing.T) {
+		fakeRestic(t, nil)
+		_, err := cfg.Snapshots(SnapshotFilter{})
+		if err == nil || !strings.Contains(err.Error(), "unexpected restic snapshots") {
+			t.Errorf("Expected error with restic's message, got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		fakeRestic(t, map[string]string{"snapshots": "[]"})
+		if _, err := cfg.Snapshot("ffff"); err == nil {
+			t.Error("Expected error for unknown snapshot")
+		}
+	})
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"7d", time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC), false},
+		{"36h", time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC), false},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2024-05-01 15:04", time.Date(2024, 5, 1, 15, 4, 0, 0, time.UTC), false},
+		{"2024-05-01T15:04:05+02:00", time.Date(2024, 5, 1, 13, 4, 5, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+		{"-5d", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorldPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		snap    Snapshot
+		world   string
+		want    string
+		wantErr bool
+	}{
+		{"world snapshot", Snapshot{Tags: []string{"survival"}, Paths: []string{"/srv/minecraft-server/survival/world"}}, "survival", "/srv/minecraft-server/survival/world", false},
+		{"all worlds snapshot", Snapshot{Tags: []string{"all"}, Paths: []string{"/srv/minecraft-server"}}, "creative", "/srv/minecraft-server/creative/world", false},
+		{"other world", Snapshot{Tags: []string{"survival"}, Paths: []string{"/srv/minecraft-server/survival/world"}}, "creative", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WorldPath(&tt.snap, tt.world)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WorldPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("WorldPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/nbt"
+	"github.com/paul/minecraftctl/pkg/systemd"
+)
+
+// DefaultMaxAge is used when minecraftctl.yml sets no backup.max_age. It
+// leaves room for a daily backup timer that runs late.
+const DefaultMaxAge = 36 * time.Hour
+
+// Backup states reported by Status
+const (
+	StateOK      = "ok"
+	StateStale   = "stale"
+	StateMissing = "missing"
+)
+
+// nextTimerRun returns when a backup timer next triggers; a variable so
+// tests can stub systemd
+var nextTimerRun = systemd.NextElapse
+
+// WorldStatus describes how current a world's backups are
+type WorldStatus struct {
+	World string `json:"world"`
+	// State is ok, stale or missing (no snapshot)
+	State string `json:"state"`
+	// Snapshot is the newest snapshot containing the world
+	Snapshot     string        `json:"snapshot,omitempty"`
+	SnapshotTime time.Time     `json:"snapshot_time,omitzero"`
+	Age          time.Duration `json:"age,omitzero"`
+	// Size is the world's size in the newest world snapshot, and SizeDelta
+	// its change from the previous one. Both need snapshot summaries
+	// (restic 0.17 or later).
+	Size      *int64 `json:"size,omitempty"`
+	SizeDelta *int64 `json:"size_delta,omitempty"`
+	// LastPlayed is read from level.dat; zero if unreadable
+	LastPlayed time.Time `json:"last_played,omitzero"`
+	// LastVerify is the most recent backup verify run, LastVerified the
+	// time of the most recent successful one
+	LastVerify   *VerifyResult `json:"last_verify,omitempty"`
+	LastVerified time.Time     `json:"last_verified,omitzero"`
+	// NextBackup is the next run of the world's backup timer, or of the
+	// all-worlds timer if the world has none
+	NextBackup time.Time `json:"next_backup,omitzero"`
+}
+
+// backupState decides whether a world's newest snapshot is current enough.
+// A world is stale only if it has been played since its newest snapshot and
+// that snapshot is older than maxAge: a world nobody has played since its
+// last backup has nothing new to back up, however old the backup is.
+func backupState(snapshotTime, lastPlayed, now time.Time, maxAge time.Duration) string {
+	switch {
+	case snapshotTime.IsZero():
+		return StateMissing
+	case !lastPlayed.IsZero() && !lastPlayed.After(snapshotTime):
+		return StateOK
+	case now.Sub(snapshotTime) <= maxAge:
+		return StateOK
+	default:
+		return StateStale
+	}
+}
+
+// Status reports the backup status of every world in the worlds directory,
+// or of the given worlds
+func (c *Config) Status(maxAge time.Duration, worlds ...string) ([]WorldStatus, error) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if len(worlds) == 0 {
+		dirs, err := serverDirs(c.WorldsDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			worlds = append(worlds, filepath.Base(dir))
+		}
+	}
+
+	snapshots, err := c.Snapshots(SnapshotFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	allTimer := nextTimerRun("minecraft-world-backup.timer")
+	statuses := make([]WorldStatus, 0, len(worlds))
+	for _, world := range worlds {
+		worldDir := filepath.Join(c.WorldsDir, world)
+		status := WorldStatus{World: world}
+
+		// Snapshots are oldest first; keep the newest containing the world
+		// and the two newest of the world alone for the size delta
+		var newest, current, previous *Snapshot
+		for i := range snapshots {
+			snap := &snapshots[i]
+			if snap.HasTag(world) {
+				newest = snap
+				previous, current = current, snap
+			} else if snap.HasTag("all") {
+				newest = snap
+			}
+		}
+
+		if newest != nil {
+			status.Snapshot = newest.ShortID
+			status.SnapshotTime = newest.Time
+			status.Age = now.Sub(newest.Time).Round(time.Second)
+		}
+		if current != nil && current.Summary != nil {
+			size := current.Summary.TotalBytesProcessed
+			status.Size = &size
+			if previous != nil && previous.Summary != nil {
+				delta := size - previous.Summary.TotalBytesProcessed
+				status.SizeDelta = &delta
+			}
+		}
+
+		if info, err := nbt.ReadLevelDat(filepath.Join(worldDir, "world", "level.dat")); err == nil && info.LastPlayed > 0 {
+			status.LastPlayed = time.UnixMilli(info.LastPlayed)
+		}
+
+		state, err := LoadVerifyState(worldDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", world, err)
+		}
+		status.LastVerify = state.LastRun
+		if state.LastSuccess != nil {
+			status.LastVerified = state.LastSuccess.Time
+		}
+
+		status.NextBackup = nextTimerRun(systemd.FormatUnitName("minecraft-world-backup", world, systemd.UnitTimer))
+		if status.NextBackup.IsZero() {
+			status.NextBackup = allTimer
+		}
+
+		status.State = backupState(status.SnapshotTime, status.LastPlayed, now, maxAge)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
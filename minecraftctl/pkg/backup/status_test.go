@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupState(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	maxAge := 36 * time.Hour
+
+	tests := []struct {
+		name       string
+		snapshot   time.Time
+		lastPlayed time.Time
+		want       string
+	}{
+		{"no snapshot", time.Time{}, now.Add(-time.Hour), StateMissing},
+		{"recent snapshot", now.Add(-2 * time.Hour), now.Add(-time.Hour), StateOK},
+		{"old snapshot, played since", now.Add(-48 * time.Hour), now.Add(-time.Hour), StateStale},
+		{"old snapshot, idle since", now.Add(-30 * 24 * time.Hour), now.Add(-31 * 24 * time.Hour), StateOK},
+		{"old snapshot, last played unknown", now.Add(-48 * time.Hour), time.Time{}, StateStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backupState(tt.snapshot, tt.lastPlayed, now, maxAge); got != tt.want {
+				t.Errorf("backupState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	worldsDir := t.TempDir()
+	for _, world := range []string{"survival", "creative"} {
+		os.MkdirAll(filepath.Join(worldsDir, world), 0755)
+		os.WriteFile(filepath.Join(worldsDir, world, "server.properties"), []byte("level-name=world\n"), 0644)
+	}
+	recordVerify(filepath.Join(worldsDir, "survival"), &VerifyResult{World: "survival", Snapshot: "aaaa1111", Time: time.Now(), OK: true})
+	recordVerify(filepath.Join(worldsDir, "survival"), &VerifyResult{World: "survival", Snapshot: "cccc3333", Time: time.Now(), OK: false})
+
+	origNext := nextTimerRun
+	t.Cleanup(func() { nextTimerRun = origNext })
+	next := time.Date(2030, 1, 1, 3, 0, 0, 0, time.UTC)
+	nextTimerRun = func(timer string) time.Time {
+		if timer == "minecraft-world-backup.timer" {
+			return next
+		}
+		return time.Time{}
+	}
+
+	// testSnapshotsJSON has two survival snapshots from 2024 with a summary
+	// on the second; the world has no level.dat so its age decides
+	fakeRestic(t, map[string]string{"snapshots": testSnapshotsJSON})
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret", WorldsDir: worldsDir}
+
+	statuses, err := cfg.Status(0)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %+v", statuses)
+	}
+
+	creative, survival := statuses[0], statuses[1]
+	if creative.World != "creative" || creative.State != StateMissing || creative.Snapshot != "" {
+		t.Errorf("Unexpected creative status: %+v", creative)
+	}
+	if survival.State != StateStale || survival.Snapshot != "cccc3333" {
+		t.Errorf("Unexpected survival status: %+v", survival)
+	}
+	if survival.Size == nil || *survival.Size != 524288000 || survival.SizeDelta != nil {
+		t.Errorf("Expected size without delta (previous has no summary), got %v %v", survival.Size, survival.SizeDelta)
+	}
+	if survival.LastVerify == nil || survival.LastVerify.OK || survival.LastVerified.IsZero() {
+		t.Errorf("Expected failed last verify after a success, got %+v", survival.LastVerify)
+	}
+	if !survival.NextBackup.Equal(next) {
+		t.Errorf("NextBackup = %v, want all-worlds timer %v", survival.NextBackup, next)
+	}
+
+	statuses, err = cfg.Status(100*365*24*time.Hour, "survival")
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != StateOK {
+		t.Errorf("Expected survival to be ok with a long max age, got %+v", statuses)
+	}
+}
@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/nbt"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// VerifyStateFile is the per-world file recording backup verifications
+const VerifyStateFile = "backup-verify.json"
+
+// VerifyResult is the outcome of verifying one world in a snapshot
+type VerifyResult struct {
+	World        string        `json:"world"`
+	Repository   string        `json:"repository"`
+	Snapshot     string        `json:"snapshot"`
+	SnapshotTime time.Time     `json:"snapshot_time"`
+	Time         time.Time     `json:"time"`
+	Duration     time.Duration `json:"duration"`
+	OK           bool          `json:"ok"`
+	LevelName    string        `json:"level_name,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	Files        int           `json:"files"`
+	Bytes        int64         `json:"bytes"`
+	Regions      int           `json:"regions"`
+	// Problems lists everything that failed verification
+	Problems []string `json:"problems,omitempty"`
+	// Notes lists checks that were skipped
+	Notes []string `json:"notes,omitempty"`
+}
+
+// VerifyState is the content of a world's VerifyStateFile
+type VerifyState struct {
+	LastRun     *VerifyResult `json:"last_run,omitempty"`
+	LastSuccess *VerifyResult `json:"last_success,omitempty"`
+}
+
+// LoadVerifyState reads a world's verification state. A missing state file
+// yields an empty state.
+func LoadVerifyState(worldDir string) (*VerifyState, error) {
+	data, err := os.ReadFile(filepath.Join(worldDir, VerifyStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &VerifyState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read verify state: %w", err)
+	}
+
+	var state VerifyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse verify state: %w", err)
+	}
+	return &state, nil
+}
+
+// recordVerify stores a result as the world's last run, and as its last
+// success if it passed
+func recordVerify(worldDir string, result *VerifyResult) error {
+	state, err := LoadVerifyState(worldDir)
+	if err != nil {
+		return err
+	}
+	state.LastRun = result
+	if result.OK {
+		state.LastSuccess = result
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(worldDir, VerifyStateFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write verify state: %w", err)
+	}
+	return nil
+}
+
+// VerifyWorlds verifies worlds in a snapshot. Without a world every world in
+// the worlds directory is verified. "latest" is the newest snapshot of each
+// world rather than of the whole repository. Results are recorded in each
+// world's VerifyStateFile.
+func (c *Config) VerifyWorlds(world, snapshot string) ([]*VerifyResult, error) {
+	var worlds []string
+	if world != "" {
+		worlds = []string{world}
+	} else {
+		dirs, err := serverDirs(c.WorldsDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			worlds = append(worlds, filepath.Base(dir))
+		}
+	}
+
+	if snapshot == "" {
+		snapshot = "latest"
+	}
+	var results []*VerifyResult
+	for _, w := range worlds {
+		snap, err := c.WorldSnapshot(w, snapshot)
+		if err != nil {
+			if world == "" {
+				fmt.Printf("Skipping %s: %v\n", w, err)
+				continue
+			}
+			return results, err
+		}
+
+		fmt.Printf("Verifying %s in snapshot %s...\n", w, snap.ShortID)
+		result, err := c.Verify(snap, w)
+		if err != nil {
+			return results, err
+		}
+		if worldDir := filepath.Join(c.WorldsDir, w); dirExists(worldDir) {
+			if err := recordVerify(worldDir, result); err != nil {
+				return results, err
+			}
+		}
+		results = append(results, result)
+	}
+	if world == "" && len(results) == 0 {
+		return nil, fmt.Errorf("no worlds in %s have snapshots to verify", c.WorldsDir)
+	}
+	return results, nil
+}
+
+// Verify restores a world from a snapshot into a temporary directory and
+// checks that the restore is usable: level.dat parses, every region file has
+// a valid header, and the file count and size match what the backup recorded.
+// The temporary directory is removed afterwards. A failed check is reported
+// in the result; an error means the verification itself could not run.
+func (c *Config) Verify(snap *Snapshot, world string) (*VerifyResult, error) {
+	start := time.Now()
+	result := &VerifyResult{
+		World:        world,
+		Repository:   c.Name,
+		Snapshot:     snap.ShortID,
+		SnapshotTime: snap.Time,
+		Time:         start,
+	}
+
+	snapPath, err := WorldPath(snap, world)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "minecraftctl-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := c.RestorePath(snap.ID, snapPath, tmp); err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("restore failed: %v", err))
+		return finishVerify(result, start), nil
+	}
+
+	if info, err := nbt.ReadLevelDat(filepath.Join(tmp, "level.dat")); err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("level.dat: %v", err))
+	} else {
+		result.LevelName = info.LevelName
+		result.Version = info.GetVersionName()
+	}
+
+	err = filepath.WalkDir(tmp, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		result.Files++
+		result.Bytes += info.Size()
+
+		if strings.HasSuffix(p, ".mca") {
+			result.Regions++
+			if _, err := region.Read(p); err != nil {
+				rel, _ := filepath.Rel(tmp, p)
+				result.Problems = append(result.Problems, fmt.Sprintf("region file %s: %v", rel, errorCause(err, p)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan restored world: %w", err)
+	}
+
+	// The summary covers every path of the snapshot, so it only describes
+	// the world when the snapshot holds nothing else
+	switch {
+	case snap.Summary == nil:
+		result.Notes = append(result.Notes, "snapshot has no summary (restic < 0.17), count and size not compared")
+	case len(snap.Paths) != 1 || snap.Paths[0] != snapPath:
+		result.Notes = append(result.Notes, "snapshot holds more than this world, count and size not compared")
+	default:
+		if result.Files != snap.Summary.TotalFilesProcessed {
+			result.Problems = append(result.Problems,
+				fmt.Sprintf("restored %d files, snapshot recorded %d", result.Files, snap.Summary.TotalFilesProcessed))
+		}
+		if result.Bytes != snap.Summary.TotalBytesProcessed {
+			result.Problems = append(result.Problems,
+				fmt.Sprintf("restored %d bytes, snapshot recorded %d", result.Bytes, snap.Summary.TotalBytesProcessed))
+		}
+	}
+
+	return finishVerify(result, start), nil
+}
+
+func finishVerify(result *VerifyResult, start time.Time) *VerifyResult {
+	result.OK = len(result.Problems) == 0
+	result.Duration = time.Since(start).Round(time.Millisecond)
+	return result
+}
+
+// errorCause strips the temporary path region.Read prefixes errors with
+func errorCause(err error, path string) string {
+	return strings.TrimPrefix(err.Error(), path+": ")
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
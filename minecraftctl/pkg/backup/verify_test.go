@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+func verifySnapshotsJSON(files int, size int64) string {
+	return fmt.Sprintf(`[{"id":"aaaa1111bbbb2222","short_id":"aaaa1111","time":"2024-05-01T03:00:00Z",
+		"tags":["survival"],"paths":["/srv/minecraft-server/survival/world"],
+		"summary":{"total_files_processed":%d,"total_bytes_processed":%d}}]`, files, size)
+}
+
+func TestVerifyWorlds(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival")
+	os.MkdirAll(worldDir, 0755)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("level-name=world\n"), 0644)
+	cfg := &Config{Name: "test", Repository: "/tmp/repo", Password: "secret", WorldsDir: worldsDir}
+
+	// The snapshot restores a level.dat and a region file, and records
+	// their count and size
+	levelDat, err := os.ReadFile(filepath.Join("..", "..", "testdata", "default", "world", "level.dat"))
+	if err != nil {
+		t.Fatalf("Failed to read test level.dat: %v", err)
+	}
+	regionFile := &region.File{}
+	regionFile.Chunks[0] = &region.Chunk{Timestamp: 1, Data: []byte{2, 0x78, 0x9c}}
+	files, size := 2, int64(len(levelDat)+len(regionFile.Bytes()))
+	restoredWorld := func(t *testing.T) string {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "level.dat"), levelDat, 0644)
+		os.MkdirAll(filepath.Join(dir, "region"), 0755)
+		regionFile.Write(filepath.Join(dir, "region", "r.0.0.mca"))
+		return dir
+	}
+
+	t.Run("valid snapshot", func(t *testing.T) {
+		dir := restoredWorld(t)
+		t.Setenv("FAKE_RESTIC_RESTORE", dir)
+		logPath := fakeRestic(t, map[string]string{"snapshots": verifySnapshotsJSON(files, size)})
+
+		results, err := cfg.VerifyWorlds("", "latest")
+		if err != nil {
+			t.Fatalf("VerifyWorlds failed: %v", err)
+		}
+		if len(results) != 1 || !results[0].OK {
+			t.Fatalf("Expected one passing result, got %+v", results)
+		}
+		r := results[0]
+		if r.Files != 2 || r.Regions != 1 || r.LevelName == "" {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+
+		log, _ := os.ReadFile(logPath)
+		if !strings.Contains(string(log), "snapshots --json --tag survival") ||
+			!strings.Contains(string(log), "restore aaaa1111bbbb2222:/srv/minecraft-server/survival/world --target") {
+			t.Errorf("Unexpected restic calls:\n%s", log)
+		}
+
+		state, err := LoadVerifyState(worldDir)
+		if err != nil {
+			t.Fatalf("LoadVerifyState failed: %v", err)
+		}
+		if state.LastRun == nil || state.LastSuccess == nil || state.LastSuccess.Snapshot != "aaaa1111" {
+			t.Errorf("Unexpected verify state: %+v", state)
+		}
+	})
+
+	t.Run("size mismatch and corrupt region", func(t *testing.T) {
+		dir := restoredWorld(t)
+		os.WriteFile(filepath.Join(dir, "region", "r.1.0.mca"), []byte("garbage"), 0644)
+		t.Setenv("FAKE_RESTIC_RESTORE", dir)
+		fakeRestic(t, map[string]string{"snapshots": verifySnapshotsJSON(files, size)})
+
+		results, err := cfg.VerifyWorlds("survival", "latest")
+		if err != nil {
+			t.Fatalf("VerifyWorlds failed: %v", err)
+		}
+		r := results[0]
+		if r.OK || len(r.Problems) != 3 {
+			t.Errorf("Expected region, count and size problems, got %v", r.Problems)
+		}
+
+		state, _ := LoadVerifyState(worldDir)
+		if state.LastRun.OK || state.LastSuccess == nil || !state.LastSuccess.OK {
+			t.Errorf("Failed run must not replace the last success: %+v", state)
+		}
+	})
+
+	t.Run("missing level.dat", func(t *testing.T) {
+		dir := restoredWorld(t)
+		os.Remove(filepath.Join(dir, "level.dat"))
+		t.Setenv("FAKE_RESTIC_RESTORE", dir)
+		fakeRestic(t, map[string]string{"snapshots": verifySnapshotsJSON(files-1, size)})
+
+		results, err := cfg.VerifyWorlds("survival", "latest")
+		if err != nil {
+			t.Fatalf("VerifyWorlds failed: %v", err)
+		}
+		if results[0].OK || !strings.Contains(strings.Join(results[0].Problems, "\n"), "level.dat") {
+			t.Errorf("Expected level.dat problem, got %v", results[0].Problems)
+		}
+	})
+
+	t.Run("no snapshots", func(t *testing.T) {
+		fakeRestic(t, map[string]string{"snapshots": "[]"})
+		if _, err := cfg.VerifyWorlds("survival", "latest"); err == nil {
+			t.Error("Expected error for world without snapshots")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// BackupConfig holds the backup section of minecraftctl.yml
+type BackupConfig struct {
+	// DefaultRepo names the repository used when --repo is not given
+	DefaultRepo string `mapstructure:"default_repo"`
+	// Repositories maps repository names to restic repositories. Names are
+	// case-insensitive.
+	Repositories map[string]BackupRepository `mapstructure:"repositories"`
+	// Retention configures which snapshots backup prune keeps
+	Retention RetentionConfig `mapstructure:"retention"`
+	// MaxAge is how old a world's newest snapshot may be before backup
+	// status reports it stale, e.g. 36h
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// RetentionConfig holds the default retention policy and per-world overrides
+type RetentionConfig struct {
+	Defaults RetentionPolicy `mapstructure:"defaults"`
+	// Worlds maps world names (case-insensitive) to policies. Fields a
+	// world sets override the defaults.
+	Worlds map[string]RetentionPolicy `mapstructure:"worlds"`
+}
+
+// RetentionPolicy mirrors the keep options of restic forget. Zero means
+// unset; -1 keeps an unlimited number of snapshots.
+type RetentionPolicy struct {
+	KeepLast    int `mapstructure:"keep_last" json:"keep_last,omitempty"`
+	KeepHourly  int `mapstructure:"keep_hourly" json:"keep_hourly,omitempty"`
+	KeepDaily   int `mapstructure:"keep_daily" json:"keep_daily,omitempty"`
+	KeepWeekly  int `mapstructure:"keep_weekly" json:"keep_weekly,omitempty"`
+	KeepMonthly int `mapstructure:"keep_monthly" json:"keep_monthly,omitempty"`
+	KeepYearly  int `mapstructure:"keep_yearly" json:"keep_yearly,omitempty"`
+	// KeepTags keeps every snapshot carrying one of these tags, e.g. pre-upgrade
+	KeepTags []string `mapstructure:"keep_tags" json:"keep_tags,omitempty"`
+}
+
+// BackupRepository describes a restic repository
+type BackupRepository struct {
+	// Type is one of local, s3, sftp, rest or b2
+	Type string `mapstructure:"type"`
+	// Endpoint is the S3 endpoint, SFTP user@host[:port], or REST server URL
+	Endpoint string `mapstructure:"endpoint"`
+	// Path is the directory, bucket[/prefix] or path within the server
+	Path string `mapstructure:"path"`
+	// Password or PasswordFile unlock the repository; RESTIC_PASSWORD is
+	// used when neither is set
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+	// Env holds extra environment variables for restic, such as
+	// AWS_ACCESS_KEY_ID or B2_ACCOUNT_ID
+	Env map[string]string `mapstructure:"env"`
+}
+
+// loadBackupConfig reads the backup section from Viper, expanding environment
+// variables in repository settings
+func loadBackupConfig() BackupConfig {
+	var backup BackupConfig
+	if err := viper.UnmarshalKey("backup", &backup); err != nil {
+		return BackupConfig{}
+	}
+
+	backup.DefaultRepo = strings.ToLower(backup.DefaultRepo)
+	for name, repo := range backup.Repositories {
+		repo.Endpoint = expandEnv(repo.Endpoint)
+		repo.Path = expandEnv(repo.Path)
+		repo.Password = expandEnv(repo.Password)
+		repo.PasswordFile = expandEnv(repo.PasswordFile)
+
+		// Viper lowercases map keys; environment variable names are uppercase
+		env := make(map[string]string, len(repo.Env))
+		for key, value := range repo.Env {
+			env[strings.ToUpper(key)] = expandEnv(value)
+		}
+		repo.Env = env
+
+		backup.Repositories[name] = repo
+	}
+
+	return backup
+}
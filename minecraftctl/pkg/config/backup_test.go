@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupConfig(t *testing.T) {
+	resetViper()
+	t.Setenv("TEST_B2_KEY", "b2secret")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "minecraftctl.yaml")
+	content := `
+backup:
+  default_repo: Primary
+  repositories:
+    primary:
+      type: s3
+      path: my-bucket
+    Offsite:
+      type: b2
+      path: bucket:minecraft
+      password_file: /etc/restic/offsite.pass
+      env:
+        B2_ACCOUNT_ID: abc
+        B2_ACCOUNT_KEY: ${TEST_B2_KEY}
+  max_age: 48h
+  retention:
+    defaults:
+      keep_daily: 7
+      keep_tags: [pre-upgrade]
+    worlds:
+      Survival:
+        keep_last: 10
+        keep_yearly: -1
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := Init(configPath); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	backup := Get().Backup
+	if backup.DefaultRepo != "primary" {
+		t.Errorf("DefaultRepo = %q, want primary", backup.DefaultRepo)
+	}
+	if len(backup.Repositories) != 2 {
+		t.Fatalf("Expected 2 repositories, got %v", backup.Repositories)
+	}
+
+	offsite, ok := backup.Repositories["offsite"]
+	if !ok {
+		t.Fatalf("Repository names should be lowercased: %v", backup.Repositories)
+	}
+	if offsite.Type != "b2" || offsite.PasswordFile != "/etc/restic/offsite.pass" {
+		t.Errorf("Unexpected offsite repository: %+v", offsite)
+	}
+	if offsite.Env["B2_ACCOUNT_ID"] != "abc" || offsite.Env["B2_ACCOUNT_KEY"] != "b2secret" {
+		t.Errorf("Env should be uppercased and expanded: %v", offsite.Env)
+	}
+
+	if backup.MaxAge != 48*time.Hour {
+		t.Errorf("MaxAge = %v, want 48h", backup.MaxAge)
+	}
+
+	retention := backup.Retention
+	if retention.Defaults.KeepDaily != 7 || len(retention.Defaults.KeepTags) != 1 || retention.Defaults.KeepTags[0] != "pre-upgrade" {
+		t.Errorf("Unexpected retention defaults: %+v", retention.Defaults)
+	}
+	survival := retention.Worlds["survival"]
+	if survival.KeepLast != 10 || survival.KeepYearly != -1 || survival.KeepDaily != 0 {
+		t.Errorf("Unexpected survival retention: %+v", survival)
+	}
+}
+
+func TestBackupConfigEnvFile(t *testing.T) {
+	resetViper()
+	// Defined only in minecraft.env; t.Setenv restores the environment
+	t.Setenv("TEST_REPO_BUCKET", "")
+	t.Setenv("TEST_REPO_PASSWORD", "")
+	t.Setenv("TEST_OTHER_ENV", "")
+
+	dir := t.TempDir()
+	origEnvPath := minecraftEnvPath
+	defer func() { minecraftEnvPath = origEnvPath }()
+	minecraftEnvPath = filepath.Join(dir, "minecraft.env")
+	os.WriteFile(minecraftEnvPath, []byte("TEST_REPO_BUCKET=worlds\nTEST_REPO_PASSWORD=secret\n"), 0644)
+	otherEnvPath := filepath.Join(dir, "other.env")
+	os.WriteFile(otherEnvPath, []byte("TEST_OTHER_ENV=1\n"), 0644)
+
+	configPath := filepath.Join(dir, "minecraftctl.yaml")
+	os.WriteFile(configPath, []byte(`
+backup:
+  repositories:
+    primary:
+      type: s3
+      path: ${TEST_REPO_BUCKET}/restic
+      password: ${TEST_REPO_PASSWORD}
+`), 0644)
+
+	// Also with another env file, minecraft.env is exported before the
+	// repository settings are expanded
+	if err := InitWithEnvFile(configPath, otherEnvPath); err != nil {
+		t.Fatalf("InitWithEnvFile failed: %v", err)
+	}
+	primary := Get().Backup.Repositories["primary"]
+	if primary.Path != "worlds/restic" || primary.Password != "secret" {
+		t.Errorf("Repository settings not expanded from minecraft.env: %+v", primary)
+	}
+}
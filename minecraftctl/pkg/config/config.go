@@ -21,6 +21,14 @@ const (
 	DefaultLockFile  = "/tmp/minecraft-map-build.lock"
 	DefaultRconHost  = "127.0.0.1"
 	DefaultRconPort  = 25575
+	DefaultCacheDir  = "/var/cache/minecraftctl"
+	DefaultJavaPath  = "/usr/bin/java"
+
+	// DefaultUnminedPath is where the packer images install unmined-cli
+	DefaultUnminedPath = "/opt/unmined/unmined-cli"
+
+	// DefaultVersionManifestURL is the launcher version manifest published by Mojang
+	DefaultVersionManifestURL = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
 )
 
 // GlobalConfig holds the application-wide configuration
@@ -29,7 +37,29 @@ type GlobalConfig struct {
 	MapsDir   string
 	JarsDir   string
 	LockFile  string
+	CacheDir  string
 	Rcon      RconConfig
+
+	// VersionManifestURL points at the launcher version manifest; override
+	// it to use a mirror or a local fixture
+	VersionManifestURL string
+
+	// JavaPath is the java binary minecraft@.service uses for worlds
+	// without a per-world Java selection
+	JavaPath string
+	// JavaSearchPaths are glob patterns of JDK homes to discover; empty
+	// uses the standard locations
+	JavaSearchPaths []string
+
+	// UnminedPath is the unmined-cli binary of the unmined map renderer
+	UnminedPath string
+
+	// Backup holds backup repository settings
+	Backup BackupConfig
+	// Snapshots holds local snapshot settings
+	Snapshots SnapshotConfig
+	// Publish holds map publishing settings
+	Publish PublishConfig
 }
 
 // RconConfig holds RCON connection settings
@@ -58,10 +88,12 @@ type MapDefinition struct {
 	Name         string     `yaml:"name" mapstructure:"name"`
 	Dimension    string     `yaml:"dimension" mapstructure:"dimension"`
 	OutputSubdir string     `yaml:"output_subdir" mapstructure:"output_subdir"`
+	Renderer     string     `yaml:"renderer,omitempty" mapstructure:"renderer"` // Map backend: unmined or native (default: unmined)
 	Zoomout      *int       `yaml:"zoomout,omitempty" mapstructure:"zoomout"`
 	Zoomin       *int       `yaml:"zoomin,omitempty" mapstructure:"zoomin"`
 	Options      MapOptions `yaml:"options,omitempty" mapstructure:"options"`
 	Ranges       []MapRange `yaml:"ranges,omitempty" mapstructure:"ranges"`
+	Markers      MapMarkers `yaml:"markers,omitempty" mapstructure:"markers"`
 }
 
 // MapOptions holds optional rendering options
@@ -83,8 +115,24 @@ type MapRange struct {
 	Zoomin  *int   `yaml:"zoomin,omitempty" mapstructure:"zoomin"`
 }
 
+// MapMarkers configures the markers of a web map besides the world spawn,
+// and players with options.players
+type MapMarkers struct {
+	Signs bool     `yaml:"signs,omitempty" mapstructure:"signs"` // Signs and named banners, read from every chunk
+	POIs  []MapPOI `yaml:"pois,omitempty" mapstructure:"pois"`
+}
+
+// MapPOI is a named point of interest
+type MapPOI struct {
+	Name     string `yaml:"name" mapstructure:"name"`
+	Position [2]int `yaml:"position" mapstructure:"position"`
+}
+
 var globalConfig *GlobalConfig
 
+// minecraftEnvPath is the env file the server units and backups read
+var minecraftEnvPath = envfile.DefaultMinecraftEnvPath
+
 // Init initializes the configuration system
 func Init(cfgFile string) error {
 	return InitWithEnvFile(cfgFile, "")
@@ -93,13 +141,18 @@ func Init(cfgFile string) error {
 // InitWithEnvFile initializes the configuration system with an optional env file
 func InitWithEnvFile(cfgFile, envFilePath string) error {
 	// Auto-load minecraft.env to set RCON environment variables
-	// This makes RCON commands work without manually exporting env vars
-	if envFilePath == "" {
-		envFilePath = envfile.DefaultMinecraftEnvPath
-	}
-	if ef, err := envfile.Load(envFilePath); err == nil {
-		// Only export vars that aren't already set (env vars take precedence)
-		ef.ExportIfNotSet()
+	// This makes RCON commands work without manually exporting env vars.
+	// minecraft.env is loaded after another env file too, as repository
+	// settings below may refer to its variables, such as MC_WORLD_BUCKET.
+	envFilePaths := []string{minecraftEnvPath}
+	if envFilePath != "" && envFilePath != minecraftEnvPath {
+		envFilePaths = []string{envFilePath, minecraftEnvPath}
+	}
+	for _, path := range envFilePaths {
+		if ef, err := envfile.Load(path); err == nil {
+			// Only export vars that aren't already set (env vars take precedence)
+			ef.ExportIfNotSet()
+		}
 	}
 	// Ignore error if env file doesn't exist - it's optional
 
@@ -110,6 +163,10 @@ func InitWithEnvFile(cfgFile, envFilePath string) error {
 	viper.SetDefault("lock_file", DefaultLockFile)
 	viper.SetDefault("rcon.host", DefaultRconHost)
 	viper.SetDefault("rcon.port", DefaultRconPort)
+	viper.SetDefault("cache_dir", DefaultCacheDir)
+	viper.SetDefault("version_manifest_url", DefaultVersionManifestURL)
+	viper.SetDefault("java_path", DefaultJavaPath)
+	viper.SetDefault("unmined_path", DefaultUnminedPath)
 
 	// If config file is explicitly set, use it
 	if cfgFile != "" {
@@ -146,6 +203,10 @@ func InitWithEnvFile(cfgFile, envFilePath string) error {
 	viper.BindEnv("maps_dir", "MAPS_DIR")
 	viper.BindEnv("jars_dir", "MINECRAFT_JARS_DIR")
 	viper.BindEnv("lock_file", "LOCK_FILE")
+	viper.BindEnv("cache_dir", "MINECRAFT_CACHE_DIR")
+	viper.BindEnv("version_manifest_url", "MINECRAFT_VERSION_MANIFEST_URL")
+	viper.BindEnv("java_path", "MINECRAFT_JAVA_PATH")
+	viper.BindEnv("unmined_path", "MINECRAFT_UNMINED_PATH")
 
 	// Load global config
 	globalConfig = &GlobalConfig{
@@ -153,11 +214,17 @@ func InitWithEnvFile(cfgFile, envFilePath string) error {
 		MapsDir:   viper.GetString("maps_dir"),
 		JarsDir:   viper.GetString("jars_dir"),
 		LockFile:  viper.GetString("lock_file"),
+		CacheDir:  viper.GetString("cache_dir"),
 		Rcon: RconConfig{
 			Host:     viper.GetString("rcon.host"),
 			Port:     viper.GetInt("rcon.port"),
 			Password: viper.GetString("rcon.password"),
 		},
+		VersionManifestURL: viper.GetString("version_manifest_url"),
+		JavaPath:           viper.GetString("java_path"),
+		JavaSearchPaths:    viper.GetStringSlice("java_search_paths"),
+		UnminedPath:        viper.GetString("unmined_path"),
+		Backup:             loadBackupConfig(),
 	}
 
 	// Check environment variables directly (overrides Viper values)
@@ -191,7 +258,11 @@ func InitWithEnvFile(cfgFile, envFilePath string) error {
 	globalConfig.MapsDir = expandEnv(globalConfig.MapsDir)
 	globalConfig.JarsDir = expandEnv(globalConfig.JarsDir)
 	globalConfig.LockFile = expandEnv(globalConfig.LockFile)
+	globalConfig.CacheDir = expandEnv(globalConfig.CacheDir)
+	globalConfig.UnminedPath = expandEnv(globalConfig.UnminedPath)
 	globalConfig.Rcon.Password = expandEnv(globalConfig.Rcon.Password)
+	globalConfig.Snapshots = loadSnapshotConfig(globalConfig.WorldsDir)
+	globalConfig.Publish = loadPublishConfig()
 
 	return nil
 }
@@ -206,10 +277,16 @@ func Get() *GlobalConfig {
 			MapsDir:   DefaultMapsDir,
 			JarsDir:   DefaultJarsDir,
 			LockFile:  DefaultLockFile,
+			CacheDir:  DefaultCacheDir,
 			Rcon: RconConfig{
 				Host: DefaultRconHost,
 				Port: DefaultRconPort,
 			},
+			VersionManifestURL: DefaultVersionManifestURL,
+			JavaPath:           DefaultJavaPath,
+			UnminedPath:        DefaultUnminedPath,
+			Snapshots:          SnapshotConfig{Dir: filepath.Join(DefaultWorldsDir, ".snapshots")},
+			Publish:            PublishConfig{Prefix: DefaultPublishPrefix},
 		}
 	}
 
@@ -220,11 +297,17 @@ func Get() *GlobalConfig {
 		MapsDir:   viper.GetString("maps_dir"),
 		JarsDir:   viper.GetString("jars_dir"),
 		LockFile:  viper.GetString("lock_file"),
+		CacheDir:  viper.GetString("cache_dir"),
 		Rcon: RconConfig{
 			Host:     viper.GetString("rcon.host"),
 			Port:     viper.GetInt("rcon.port"),
 			Password: viper.GetString("rcon.password"),
 		},
+		VersionManifestURL: viper.GetString("version_manifest_url"),
+		JavaPath:           viper.GetString("java_path"),
+		JavaSearchPaths:    viper.GetStringSlice("java_search_paths"),
+		UnminedPath:        viper.GetString("unmined_path"),
+		Backup:             loadBackupConfig(),
 	}
 
 	// Expand environment variables in paths and password
@@ -232,7 +315,11 @@ func Get() *GlobalConfig {
 	cfg.MapsDir = expandEnv(cfg.MapsDir)
 	cfg.JarsDir = expandEnv(cfg.JarsDir)
 	cfg.LockFile = expandEnv(cfg.LockFile)
+	cfg.CacheDir = expandEnv(cfg.CacheDir)
+	cfg.UnminedPath = expandEnv(cfg.UnminedPath)
 	cfg.Rcon.Password = expandEnv(cfg.Rcon.Password)
+	cfg.Snapshots = loadSnapshotConfig(cfg.WorldsDir)
+	cfg.Publish = loadPublishConfig()
 
 	return cfg
 }
@@ -561,6 +648,7 @@ func ValidateMapConfig(mapConfig *MapConfig) []string {
 
 	validDimensions := map[string]bool{"overworld": true, "nether": true, "end": true}
 	validShadowValues := map[string]bool{"true": true, "false": true, "2d": true, "3d": true, "3do": true}
+	validRenderers := map[string]bool{"unmined": true, "native": true}
 
 	for i, m := range mapConfig.Maps {
 		if m.Name == "" {
@@ -572,6 +660,10 @@ func ValidateMapConfig(mapConfig *MapConfig) []string {
 			errs = append(errs, fmt.Sprintf("maps[%d].dimension must be one of: overworld, nether, end (got: %s)", i, m.Dimension))
 		}
 
+		if m.Renderer != "" && !validRenderers[m.Renderer] {
+			errs = append(errs, fmt.Sprintf("maps[%d].renderer must be one of: unmined, native (got: %s)", i, m.Renderer))
+		}
+
 		// Validate shadow value if set
 		if m.Options.Shadows != nil {
 			var shadowStr string
@@ -613,6 +705,13 @@ func ValidateMapConfig(mapConfig *MapConfig) []string {
 				errs = append(errs, fmt.Sprintf("maps[%d].ranges[%d].zoomin must be >= 0", i, j))
 			}
 		}
+
+		// Validate points of interest
+		for j, poi := range m.Markers.POIs {
+			if poi.Name == "" {
+				errs = append(errs, fmt.Sprintf("maps[%d].markers.pois[%d].name is required", i, j))
+			}
+		}
 	}
 
 	return errs
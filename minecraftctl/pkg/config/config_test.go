@@ -610,6 +610,37 @@ func TestValidateMapConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("renderer", func(t *testing.T) {
+		mapConfig := &MapConfig{
+			Defaults: MapDefaults{ChunkProcessors: 1},
+			Maps: []MapDefinition{
+				{Name: "a", Dimension: "overworld", Renderer: "unmined"},
+				{Name: "c", Dimension: "overworld", Renderer: "native"},
+				{Name: "b", Dimension: "overworld", Renderer: "bluemap"},
+			},
+		}
+		errs := ValidateMapConfig(mapConfig)
+		if len(errs) != 1 || errs[0] != "maps[2].renderer must be one of: unmined, native (got: bluemap)" {
+			t.Errorf("Expected one renderer error, got: %v", errs)
+		}
+	})
+
+	t.Run("markers", func(t *testing.T) {
+		mapConfig := &MapConfig{
+			Defaults: MapDefaults{ChunkProcessors: 1},
+			Maps: []MapDefinition{
+				{Name: "a", Dimension: "overworld", Markers: MapMarkers{
+					Signs: true,
+					POIs:  []MapPOI{{Name: "Village", Position: [2]int{120, -340}}, {Position: [2]int{0, 0}}},
+				}},
+			},
+		}
+		errs := ValidateMapConfig(mapConfig)
+		if len(errs) != 1 || errs[0] != "maps[0].markers.pois[1].name is required" {
+			t.Errorf("Expected one poi error, got: %v", errs)
+		}
+	})
+
 	t.Run("valid shadow values", func(t *testing.T) {
 		validShadows := []interface{}{"true", "false", "2d", "3d", "3do", true, false}
 		for _, shadow := range validShadows {
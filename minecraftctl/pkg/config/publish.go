@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultPublishPrefix is the key prefix maps are published under
+const DefaultPublishPrefix = "maps"
+
+// PublishConfig holds the publish section of minecraftctl.yml, the defaults
+// of map publish
+type PublishConfig struct {
+	// Bucket receives the maps. Default: $MC_MAP_BUCKET
+	Bucket string `mapstructure:"bucket"`
+	// Prefix is prepended to object keys. Default: maps
+	Prefix string `mapstructure:"prefix"`
+	// Endpoint is the URL of an S3-compatible service such as MinIO;
+	// empty publishes to AWS
+	Endpoint string `mapstructure:"endpoint"`
+	// Region signs requests. Default: $AWS_REGION, $AWS_DEFAULT_REGION or,
+	// publishing to AWS, the region of the EC2 instance
+	Region string `mapstructure:"region"`
+}
+
+// loadPublishConfig reads the publish section from Viper
+func loadPublishConfig() PublishConfig {
+	var publish PublishConfig
+	if err := viper.UnmarshalKey("publish", &publish); err != nil {
+		publish = PublishConfig{}
+	}
+	publish.Bucket = expandEnv(publish.Bucket)
+	publish.Endpoint = expandEnv(publish.Endpoint)
+
+	if publish.Bucket == "" {
+		publish.Bucket = os.Getenv("MC_MAP_BUCKET")
+	}
+	if !viper.IsSet("publish.prefix") {
+		publish.Prefix = DefaultPublishPrefix
+	}
+	if publish.Region == "" {
+		publish.Region = os.Getenv("AWS_REGION")
+	}
+	if publish.Region == "" {
+		publish.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return publish
+}
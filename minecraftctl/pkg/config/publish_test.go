@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublishConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		resetViper()
+		t.Setenv("MC_MAP_BUCKET", "minecraft-maps")
+		t.Setenv("AWS_REGION", "")
+		t.Setenv("AWS_DEFAULT_REGION", "us-east-2")
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "minecraftctl.yaml")
+		os.WriteFile(configPath, []byte("worlds_dir: /srv/worlds\n"), 0644)
+		if err := Init(configPath); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		publish := Get().Publish
+		want := PublishConfig{Bucket: "minecraft-maps", Prefix: "maps", Region: "us-east-2"}
+		if publish != want {
+			t.Errorf("Publish = %+v, want %+v", publish, want)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		resetViper()
+		t.Setenv("MC_MAP_BUCKET", "minecraft-maps")
+		t.Setenv("TEST_MINIO", "http://minio:9000")
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "minecraftctl.yaml")
+		content := `
+publish:
+  bucket: site
+  prefix: ""
+  endpoint: ${TEST_MINIO}
+  region: eu-west-1
+`
+		os.WriteFile(configPath, []byte(content), 0644)
+		if err := Init(configPath); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		publish := Get().Publish
+		want := PublishConfig{Bucket: "site", Prefix: "", Endpoint: "http://minio:9000", Region: "eu-west-1"}
+		if publish != want {
+			t.Errorf("Publish = %+v, want %+v", publish, want)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// SnapshotConfig holds the snapshots section of minecraftctl.yml, for local
+// world snapshots
+type SnapshotConfig struct {
+	// Dir holds the snapshots. It must be on the same filesystem as the
+	// worlds so unchanged files can be hard-linked. Default:
+	// <worlds_dir>/.snapshots
+	Dir string `mapstructure:"dir"`
+	// Retention decides which snapshots snapshot prune keeps; keep_tags
+	// does not apply
+	Retention RetentionPolicy `mapstructure:"retention"`
+}
+
+// loadSnapshotConfig reads the snapshots section from Viper. worldsDir is
+// the expanded worlds directory the default Dir is placed in.
+func loadSnapshotConfig(worldsDir string) SnapshotConfig {
+	var snapshots SnapshotConfig
+	if err := viper.UnmarshalKey("snapshots", &snapshots); err != nil {
+		snapshots = SnapshotConfig{}
+	}
+	snapshots.Dir = expandEnv(snapshots.Dir)
+	if snapshots.Dir == "" {
+		snapshots.Dir = filepath.Join(worldsDir, ".snapshots")
+	}
+	return snapshots
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		resetViper()
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "minecraftctl.yaml")
+		os.WriteFile(configPath, []byte("worlds_dir: /srv/worlds\n"), 0644)
+		if err := Init(configPath); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		snapshots := Get().Snapshots
+		if snapshots.Dir != "/srv/worlds/.snapshots" {
+			t.Errorf("Dir = %q, want /srv/worlds/.snapshots", snapshots.Dir)
+		}
+		if snapshots.Retention.KeepLast != 0 {
+			t.Errorf("Expected empty retention, got %+v", snapshots.Retention)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		resetViper()
+		t.Setenv("TEST_SNAPSHOT_DIR", "/mnt/fast")
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "minecraftctl.yaml")
+		content := `
+snapshots:
+  dir: ${TEST_SNAPSHOT_DIR}/snapshots
+  retention:
+    keep_last: 4
+    keep_hourly: 12
+`
+		os.WriteFile(configPath, []byte(content), 0644)
+		if err := Init(configPath); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		snapshots := Get().Snapshots
+		if snapshots.Dir != "/mnt/fast/snapshots" {
+			t.Errorf("Dir = %q, want /mnt/fast/snapshots", snapshots.Dir)
+		}
+		if snapshots.Retention.KeepLast != 4 || snapshots.Retention.KeepHourly != 12 {
+			t.Errorf("Unexpected retention: %+v", snapshots.Retention)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -44,10 +45,12 @@ func Load(path string) (*EnvFile, error) {
 		key := strings.TrimSpace(line[:idx])
 		value := strings.TrimSpace(line[idx+1:])
 
-		// Remove surrounding quotes if present
+		// Remove surrounding quotes if present, undoing the escapes systemd
+		// recognizes inside double quotes
 		if len(value) >= 2 {
-			if (value[0] == '"' && value[len(value)-1] == '"') ||
-				(value[0] == '\'' && value[len(value)-1] == '\'') {
+			if value[0] == '"' && value[len(value)-1] == '"' {
+				value = unescape(value[1 : len(value)-1])
+			} else if value[0] == '\'' && value[len(value)-1] == '\'' {
 				value = value[1 : len(value)-1]
 			}
 		}
@@ -145,3 +148,83 @@ func (e *EnvFile) Keys() []string {
 func (e *EnvFile) Len() int {
 	return len(e.vars)
 }
+
+// New returns an empty env file that will be written to path
+func New(path string) *EnvFile {
+	return &EnvFile{
+		vars: make(map[string]string),
+		path: path,
+	}
+}
+
+// Set sets a value
+func (e *EnvFile) Set(key, value string) {
+	e.vars[key] = value
+}
+
+// Delete removes a key
+func (e *EnvFile) Delete(key string) {
+	delete(e.vars, key)
+}
+
+// quoteChars are the characters that make Save double-quote a value
+const quoteChars = " \t\"'\\$`#;"
+
+// escapeChars are escaped with a backslash inside double quotes, as systemd's
+// EnvironmentFile= parser expects
+const escapeChars = "\"\\$`"
+
+// escape backslash-escapes escapeChars in a double-quoted value
+func escape(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(escapeChars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescape reverses escape, leaving other backslashes in place as systemd does
+func unescape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) && strings.IndexByte(escapeChars, value[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// Save writes the env file to its path with keys sorted, preceded by the
+// header as comment lines. Values containing whitespace, quotes or shell
+// characters are double-quoted and escaped so systemd's EnvironmentFile= and
+// Load read them back unchanged. Values spanning lines are rejected.
+func (e *EnvFile) Save(header string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(header, "\n") {
+		if line != "" {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+
+	keys := e.Keys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := e.vars[key]
+		if strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("value of %s spans multiple lines", key)
+		}
+		if strings.ContainsAny(value, quoteChars) {
+			value = `"` + escape(value) + `"`
+		}
+		fmt.Fprintf(&b, "%s=%s\n", key, value)
+	}
+
+	if err := os.WriteFile(e.path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	return nil
+}
@@ -287,3 +287,47 @@ func TestMustGet(t *testing.T) {
 	}()
 	ef.MustGet("MISSING_KEY")
 }
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.env")
+	ef := New(path)
+	values := map[string]string{
+		"PLAIN":   "value",
+		"SPACES":  "hello world",
+		"QUOTES":  `-Dfoo="a b"`,
+		"SPECIAL": `C:\path $HOME` + "`cmd`",
+		"EMPTY":   "",
+	}
+	for key, value := range values {
+		ef.Set(key, value)
+	}
+	if err := ef.Save("header"); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# header\n" +
+		"EMPTY=\n" +
+		"PLAIN=value\n" +
+		`QUOTES="-Dfoo=\"a b\""` + "\n" +
+		`SPACES="hello world"` + "\n" +
+		`SPECIAL="C:\\path \$HOME` + "\\`cmd\\`\"\n"
+	if string(data) != want {
+		t.Errorf("Saved file:\n%s\nwant:\n%s", data, want)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	for key, value := range values {
+		if got, _ := loaded.Get(key); got != value {
+			t.Errorf("Get(%s) = %q, want %q", key, got, value)
+		}
+	}
+
+	ef.Set("MULTILINE", "a\nb")
+	if err := ef.Save(""); err == nil {
+		t.Error("Save() should fail for a value spanning lines")
+	}
+}
@@ -0,0 +1,381 @@
+package jars
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultDownloadTimeout bounds a whole download, including retries
+	DefaultDownloadTimeout = 10 * time.Minute
+	// DefaultDownloadRetries is the number of retries after a failed attempt
+	DefaultDownloadRetries = 5
+	// DefaultRetryBackoff is the delay before the first retry
+	DefaultRetryBackoff = time.Second
+	// DefaultIdleTimeout bounds the wait for response headers and for each
+	// read of the body within an attempt
+	DefaultIdleTimeout = 30 * time.Second
+
+	maxRetryBackoff = 30 * time.Second
+
+	// partialSuffix marks an incomplete download. It is kept when a download
+	// fails so the next attempt can resume it with an HTTP range request.
+	partialSuffix = ".part"
+)
+
+// ProgressFunc is called as a download makes progress. total is -1 when the
+// server did not report the size.
+type ProgressFunc func(done, total int64)
+
+// DownloadOptions controls retries, timeouts and progress reporting of a download
+type DownloadOptions struct {
+	// Timeout bounds the whole download including retries (DefaultDownloadTimeout when zero)
+	Timeout time.Duration
+	// Retries after a failed attempt (DefaultDownloadRetries when zero, none when negative)
+	Retries int
+	// Backoff before the first retry, doubled for each further retry (DefaultRetryBackoff when zero)
+	Backoff time.Duration
+	// IdleTimeout fails an attempt that receives no data for this long, so
+	// a stalled server is retried (DefaultIdleTimeout when zero)
+	IdleTimeout time.Duration
+	// HTTPClient is used for requests (a client with a response header
+	// timeout of IdleTimeout when nil)
+	HTTPClient *http.Client
+	// Progress, if set, receives progress updates
+	Progress ProgressFunc
+	// Replace an existing JAR once the download is verified, instead of
+	// failing. The old JAR stays in place if the download fails.
+	Replace bool
+}
+
+func (o DownloadOptions) withDefaults() DownloadOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultDownloadTimeout
+	}
+	if o.Retries == 0 {
+		o.Retries = DefaultDownloadRetries
+	} else if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultRetryBackoff
+	}
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = DefaultIdleTimeout
+	}
+	if o.HTTPClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = o.IdleTimeout
+		o.HTTPClient = &http.Client{Transport: transport}
+	}
+	return o
+}
+
+// permanentError marks a download failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// DownloadJarContext downloads the JAR described by spec to its canonical
+// file name. Failed attempts are retried with exponential backoff, resuming
+// the partial download where the server supports range requests. The JAR is
+// verified against every provided checksum before it is moved into place.
+func DownloadJarContext(ctx context.Context, spec JarSpec, url, jarsDir string, expected Checksums, opts DownloadOptions) error {
+	opts = opts.withDefaults()
+	version := spec.Label()
+	fileName := spec.FileName()
+	jarPath := filepath.Join(jarsDir, fileName)
+
+	// Check if JAR already exists
+	if _, err := os.Stat(jarPath); err == nil && !opts.Replace {
+		return fmt.Errorf("jar already exists: %s", jarPath)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	partPath := jarPath + partialSuffix
+	log.Info().Str("version", version).Str("url", url).Msg("downloading jar")
+	if err := fetchWithRetries(ctx, url, partPath, version, opts); err != nil {
+		return err
+	}
+
+	sums, err := hashFile(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash jar file: %w", err)
+	}
+	if err := verifyChecksums(version, sums, expected); err != nil {
+		// The partial file is complete but wrong; don't resume from it
+		os.Remove(partPath)
+		return err
+	}
+
+	// Move the verified file to its final location, atomically replacing
+	// an existing JAR
+	if err := os.Rename(partPath, jarPath); err != nil {
+		return fmt.Errorf("failed to move jar to final location: %w", err)
+	}
+
+	// Update checksums.txt
+	if err := saveChecksum(fileName, sums.SHA256, jarsDir); err != nil {
+		log.Warn().Err(err).Msg("failed to update checksums.txt")
+		// Don't fail the download if checksum file update fails
+	}
+
+	log.Info().Str("version", version).Str("path", jarPath).Msg("jar downloaded successfully")
+	return nil
+}
+
+// fetchWithRetries downloads url into partPath, retrying transient failures
+func fetchWithRetries(ctx context.Context, url, partPath, version string, opts DownloadOptions) error {
+	backoff := opts.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fetchOnce(ctx, opts.HTTPClient, url, partPath, opts.IdleTimeout, opts.Progress)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= opts.Retries || ctx.Err() != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("%w (partial download kept for resume)", ctx.Err())
+			}
+			return fmt.Errorf("failed to download jar: %w", err)
+		}
+
+		log.Warn().Err(err).Str("version", version).Int("attempt", attempt+1).Dur("retry_in", backoff).Msg("download failed, retrying")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to download jar: %w (partial download kept for resume)", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// fetchOnce makes a single download attempt, appending to partPath from its
+// current size with a range request. The attempt is cancelled when no data
+// arrives for idleTimeout.
+func fetchOnce(ctx context.Context, client *http.Client, url, partPath string, idleTimeout time.Duration, progress ProgressFunc) error {
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return &permanentError{fmt.Errorf("failed to create temporary file: %w", err)}
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return &permanentError{fmt.Errorf("failed to read temporary file: %w", err)}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var idle atomic.Bool
+	timer := time.AfterFunc(idleTimeout, func() {
+		idle.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &permanentError{fmt.Errorf("invalid download URL: %w", err)}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if idle.Load() {
+			return fmt.Errorf("no response within %s", idleTimeout)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Full response: the server ignored the range, so start over
+		if offset > 0 {
+			log.Debug().Str("path", partPath).Msg("server does not support resume, restarting download")
+			if err := restartPartial(file); err != nil {
+				return err
+			}
+			offset = 0
+		}
+		total = resp.ContentLength
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			if err := restartPartial(file); err != nil {
+				return err
+			}
+			return fmt.Errorf("unexpected Content-Range %q, restarting download", resp.Header.Get("Content-Range"))
+		}
+		total = size
+		log.Debug().Str("path", partPath).Int64("offset", offset).Msg("resuming download")
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not match the remote file; start over
+		if err := restartPartial(file); err != nil {
+			return err
+		}
+		return fmt.Errorf("partial download is invalid, restarting")
+	default:
+		err := fmt.Errorf("download failed with status %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return err
+		}
+		return &permanentError{err}
+	}
+
+	pw := &progressWriter{done: offset, total: total, fn: progress}
+	pw.report()
+	body := &idleReader{r: resp.Body, timer: timer, timeout: idleTimeout}
+	if _, err := io.Copy(io.MultiWriter(file, pw), body); err != nil {
+		if idle.Load() {
+			return fmt.Errorf("no data received for %s after %d bytes", idleTimeout, pw.done)
+		}
+		return fmt.Errorf("connection lost after %d bytes: %w", pw.done, err)
+	}
+	if total >= 0 && pw.done < total {
+		return fmt.Errorf("connection lost after %d of %d bytes", pw.done, total)
+	}
+	return nil
+}
+
+// restartPartial truncates a partial download so the next attempt starts over
+func restartPartial(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return &permanentError{fmt.Errorf("failed to truncate temporary file: %w", err)}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return &permanentError{fmt.Errorf("failed to rewind temporary file: %w", err)}
+	}
+	return nil
+}
+
+// parseContentRange parses "bytes start-end/size", returning size -1 for "*"
+func parseContentRange(header string) (start, size int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rangePart, sizePart, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	startStr, _, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size = -1
+	if sizePart != "*" {
+		if size, err = strconv.ParseInt(sizePart, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// idleReader restarts an idle timer whenever data is read
+type idleReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// progressWriter counts bytes written and reports them to a ProgressFunc
+type progressWriter struct {
+	done  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	w.report()
+	return len(p), nil
+}
+
+func (w *progressWriter) report() {
+	if w.fn != nil {
+		w.fn(w.done, w.total)
+	}
+}
+
+// hashFile computes the SHA256, SHA1 and MD5 digests of a file
+func hashFile(path string) (Checksums, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Checksums{}, err
+	}
+	defer file.Close()
+
+	sha256Hash := sha256.New()
+	sha1Hash := sha1.New()
+	md5Hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha256Hash, sha1Hash, md5Hash), file); err != nil {
+		return Checksums{}, err
+	}
+
+	return Checksums{
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+		SHA1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+	}, nil
+}
+
+// verifyChecksums compares actual digests against every expected digest provided
+func verifyChecksums(version string, actual, expected Checksums) error {
+	if expected.SHA256 != "" {
+		if actual.SHA256 != expected.SHA256 {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", expected.SHA256, actual.SHA256)
+		}
+		log.Info().Str("version", version).Msg("checksum verified")
+	}
+	if expected.SHA1 != "" {
+		if actual.SHA1 != expected.SHA1 {
+			return fmt.Errorf("sha1 mismatch: expected %s, got %s", expected.SHA1, actual.SHA1)
+		}
+		log.Info().Str("version", version).Msg("sha1 verified")
+	}
+	if expected.MD5 != "" {
+		if actual.MD5 != expected.MD5 {
+			return fmt.Errorf("md5 mismatch: expected %s, got %s", expected.MD5, actual.MD5)
+		}
+		log.Info().Str("version", version).Msg("md5 verified")
+	}
+	return nil
+}
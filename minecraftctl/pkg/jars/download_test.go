@@ -0,0 +1,266 @@
+package jars
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer serves content with range support, dropping the connection
+// after dropAfter bytes on the first failures requests
+func newFlakyServer(t *testing.T, content string, dropAfter int, failures int32) (*httptest.Server, *atomic.Int32, *[]string) {
+	t.Helper()
+	var requests atomic.Int32
+	var ranges []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		start := 0
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)-start))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		}
+
+		body := content[start:]
+		if n <= failures && len(body) > dropAfter {
+			// Send part of the body, then kill the connection
+			w.Write([]byte(body[:dropAfter]))
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &ranges
+}
+
+func TestDownloadJarContext(t *testing.T) {
+	content := strings.Repeat("minecraft server jar ", 500)
+	spec := JarSpec{Flavor: FlavorVanilla, Version: "1.21.4"}
+	fastRetry := DownloadOptions{Backoff: time.Millisecond}
+
+	t.Run("resumes after dropped connections", func(t *testing.T) {
+		server, requests, ranges := newFlakyServer(t, content, 1000, 2)
+		dir := t.TempDir()
+
+		var lastDone, lastTotal int64
+		opts := fastRetry
+		opts.Progress = func(done, total int64) { lastDone, lastTotal = done, total }
+
+		err := DownloadJarContext(context.Background(), spec, server.URL, dir, Checksums{SHA256: sha256sum(content)}, opts)
+		if err != nil {
+			t.Fatalf("DownloadJarContext failed: %v", err)
+		}
+
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+		if want := []string{"", "bytes=1000-", "bytes=2000-"}; strings.Join(*ranges, ",") != strings.Join(want, ",") {
+			t.Errorf("Range headers = %q, want %q", *ranges, want)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, spec.FileName()))
+		if string(data) != content {
+			t.Error("Downloaded content mismatch")
+		}
+		if _, err := os.Stat(filepath.Join(dir, spec.FileName()+partialSuffix)); !os.IsNotExist(err) {
+			t.Error("Partial file should be gone after success")
+		}
+		if lastDone != int64(len(content)) || lastTotal != int64(len(content)) {
+			t.Errorf("Progress ended at %d/%d, want %d", lastDone, lastTotal, len(content))
+		}
+	})
+
+	t.Run("restarts when server ignores range", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write([]byte(content[:500]))
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.Write([]byte(content))
+		}))
+		defer server.Close()
+
+		dir := t.TempDir()
+		err := DownloadJarContext(context.Background(), spec, server.URL, dir, Checksums{SHA256: sha256sum(content)}, fastRetry)
+		if err != nil {
+			t.Fatalf("DownloadJarContext failed: %v", err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, spec.FileName()))
+		if string(data) != content {
+			t.Error("Downloaded content mismatch")
+		}
+	})
+
+	t.Run("gives up after retries and keeps partial file", func(t *testing.T) {
+		server, requests, _ := newFlakyServer(t, content, 100, 100)
+		dir := t.TempDir()
+
+		opts := fastRetry
+		opts.Retries = 2
+		err := DownloadJarContext(context.Background(), spec, server.URL, dir, Checksums{}, opts)
+		if err == nil {
+			t.Fatal("Expected error after exhausting retries")
+		}
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 attempts, got %d", requests.Load())
+		}
+
+		info, err := os.Stat(filepath.Join(dir, spec.FileName()+partialSuffix))
+		if err != nil || info.Size() != 300 {
+			t.Fatalf("Expected 300 byte partial file, got %v, %v", info, err)
+		}
+
+		// A later run resumes from the partial file
+		server2, _, ranges := newFlakyServer(t, content, 0, 0)
+		if err := DownloadJarContext(context.Background(), spec, server2.URL, dir, Checksums{SHA256: sha256sum(content)}, fastRetry); err != nil {
+			t.Fatalf("Resumed download failed: %v", err)
+		}
+		if (*ranges)[0] != "bytes=300-" {
+			t.Errorf("Expected resume from byte 300, got %q", (*ranges)[0])
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		err := DownloadJarContext(context.Background(), spec, server.URL, t.TempDir(), Checksums{}, fastRetry)
+		if err == nil || !strings.Contains(err.Error(), "status 403") {
+			t.Fatalf("Expected status 403 error, got %v", err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", requests.Load())
+		}
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(content))
+		}))
+		defer server.Close()
+
+		if err := DownloadJarContext(context.Background(), spec, server.URL, t.TempDir(), Checksums{}, fastRetry); err != nil {
+			t.Fatalf("DownloadJarContext failed: %v", err)
+		}
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		defer server.Close()
+
+		opts := fastRetry
+		opts.Timeout = 50 * time.Millisecond
+		start := time.Now()
+		err := DownloadJarContext(context.Background(), spec, server.URL, t.TempDir(), Checksums{}, opts)
+		if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+			t.Fatalf("Expected deadline error, got %v", err)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("Timeout not honored, took %v", time.Since(start))
+		}
+	})
+
+	t.Run("retries stalled downloads", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				// Send the headers and part of the body, then stall
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write([]byte(content[:100]))
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				return
+			}
+			w.Write([]byte(content))
+		}))
+		defer server.Close()
+
+		opts := fastRetry
+		opts.IdleTimeout = 50 * time.Millisecond
+		start := time.Now()
+		if err := DownloadJarContext(context.Background(), spec, server.URL, t.TempDir(), Checksums{}, opts); err != nil {
+			t.Fatalf("DownloadJarContext failed: %v", err)
+		}
+		if requests.Load() != 2 || time.Since(start) > 2*time.Second {
+			t.Errorf("Expected a quick retry, got %d requests in %v", requests.Load(), time.Since(start))
+		}
+	})
+
+	t.Run("checksum mismatch removes partial file", func(t *testing.T) {
+		server, _, _ := newFlakyServer(t, content, 0, 0)
+		dir := t.TempDir()
+
+		err := DownloadJarContext(context.Background(), spec, server.URL, dir, Checksums{SHA256: sha256sum("other")}, fastRetry)
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("Expected checksum mismatch, got %v", err)
+		}
+		for _, name := range []string{spec.FileName(), spec.FileName() + partialSuffix} {
+			if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+				t.Errorf("%s should not exist after checksum failure", name)
+			}
+		}
+	})
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header      string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.header)
+		if ok != tt.ok || (ok && (start != tt.start || size != tt.size)) {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v", tt.header, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package jars
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flavor identifies the server software a JAR runs
+type Flavor string
+
+const (
+	FlavorVanilla Flavor = "vanilla"
+	FlavorPaper   Flavor = "paper"
+	FlavorPurpur  Flavor = "purpur"
+	FlavorFabric  Flavor = "fabric"
+)
+
+const (
+	DefaultPaperAPIURL  = "https://api.papermc.io"
+	DefaultPurpurAPIURL = "https://api.purpurmc.org"
+	DefaultFabricAPIURL = "https://meta.fabricmc.net"
+
+	flavorJarInfix = "_server_"
+)
+
+// Flavors returns all supported flavors
+func Flavors() []Flavor {
+	return []Flavor{FlavorVanilla, FlavorPaper, FlavorPurpur, FlavorFabric}
+}
+
+// ParseFlavor parses a flavor name. An empty name means vanilla.
+func ParseFlavor(name string) (Flavor, error) {
+	if name == "" {
+		return FlavorVanilla, nil
+	}
+	for _, f := range Flavors() {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported flavor: %s (supported: vanilla, paper, purpur, fabric)", name)
+}
+
+// ExtensionsDir returns the world subdirectory holding plugins or mods for
+// the flavor, or "" for vanilla
+func (f Flavor) ExtensionsDir() string {
+	switch f {
+	case FlavorPaper, FlavorPurpur:
+		return "plugins"
+	case FlavorFabric:
+		return "mods"
+	default:
+		return ""
+	}
+}
+
+// JarSpec identifies a server JAR by flavor, Minecraft version and build.
+// Vanilla JARs have no build; for Fabric the build is the loader version.
+type JarSpec struct {
+	Flavor  Flavor
+	Version string
+	Build   string
+}
+
+// FileName returns the canonical file name: minecraft_server_<version>.jar
+// for vanilla and <flavor>_server_<version>-<build>.jar otherwise
+func (s JarSpec) FileName() string {
+	if s.Flavor == "" || s.Flavor == FlavorVanilla {
+		return jarPrefix + s.Version + jarSuffix
+	}
+	name := string(s.Flavor) + flavorJarInfix + s.Version
+	if s.Build != "" {
+		name += "-" + s.Build
+	}
+	return name + jarSuffix
+}
+
+// Label returns a short human-readable name such as "1.21.4" or "paper 1.21.4-123"
+func (s JarSpec) Label() string {
+	if s.Flavor == "" || s.Flavor == FlavorVanilla {
+		return s.Version
+	}
+	label := string(s.Flavor) + " " + s.Version
+	if s.Build != "" {
+		label += "-" + s.Build
+	}
+	return label
+}
+
+// ParseJarFileName parses a canonical JAR file name. Builds are separated
+// from the version by the last "-", so versions like 1.20.5-rc1 must always
+// carry a build in flavored file names.
+func ParseJarFileName(name string) (JarSpec, bool) {
+	if !strings.HasSuffix(name, jarSuffix) {
+		return JarSpec{}, false
+	}
+	base := strings.TrimSuffix(name, jarSuffix)
+
+	if strings.HasPrefix(base, jarPrefix) {
+		version := strings.TrimPrefix(base, jarPrefix)
+		return JarSpec{Flavor: FlavorVanilla, Version: version}, version != ""
+	}
+
+	for _, f := range Flavors() {
+		prefix := string(f) + flavorJarInfix
+		if f == FlavorVanilla || !strings.HasPrefix(base, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(base, prefix)
+		spec := JarSpec{Flavor: f, Version: rest}
+		if i := strings.LastIndex(rest, "-"); i > 0 {
+			spec.Version, spec.Build = rest[:i], rest[i+1:]
+		}
+		return spec, spec.Version != ""
+	}
+
+	return JarSpec{}, false
+}
+
+// FlavorFromJar returns the flavor of a JAR based on its file name,
+// defaulting to vanilla
+func FlavorFromJar(jarPath string) Flavor {
+	if spec, ok := ParseJarFileName(filepath.Base(jarPath)); ok {
+		return spec.Flavor
+	}
+	return FlavorVanilla
+}
+
+// FindFlavorJar returns the path of an installed JAR for a flavor. For
+// non-vanilla flavors version may be "<version>" (newest installed build)
+// or "<version>-<build>".
+func FindFlavorJar(flavor Flavor, version, jarsDir string) (string, error) {
+	if flavor == "" || flavor == FlavorVanilla {
+		return FindJar(version, jarsDir)
+	}
+
+	entries, err := os.ReadDir(jarsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read jars directory: %w", err)
+	}
+
+	var best JarSpec
+	var bestPath string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		spec, ok := ParseJarFileName(entry.Name())
+		if !ok || spec.Flavor != flavor {
+			continue
+		}
+		if spec.Version+"-"+spec.Build == version {
+			return filepath.Join(jarsDir, entry.Name()), nil
+		}
+		if spec.Version == version && (bestPath == "" || CompareBuilds(spec.Build, best.Build) > 0) {
+			best, bestPath = spec, filepath.Join(jarsDir, entry.Name())
+		}
+	}
+
+	if bestPath == "" {
+		return "", fmt.Errorf("%s jar not found for version %s in %s", flavor, version, jarsDir)
+	}
+	return bestPath, nil
+}
+
+// CompareBuilds compares two build identifiers (Paper/Purpur build numbers or
+// Fabric loader versions) numerically, returning -1, 0 or 1
+func CompareBuilds(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var an, bn int
+		if i < len(aParts) {
+			an, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bn, _ = strconv.Atoi(bParts[i])
+		}
+		if an != bn {
+			if an < bn {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// FlavorDownload is a resolved flavored JAR download
+type FlavorDownload struct {
+	Spec      JarSpec
+	URL       string
+	Checksums Checksums
+}
+
+// FlavorClient resolves builds from the Paper, Purpur and Fabric APIs
+type FlavorClient struct {
+	PaperURL   string
+	PurpurURL  string
+	FabricURL  string
+	HTTPClient *http.Client
+}
+
+// NewFlavorClient creates a flavor client using the public build APIs
+func NewFlavorClient() *FlavorClient {
+	return &FlavorClient{
+		PaperURL:   DefaultPaperAPIURL,
+		PurpurURL:  DefaultPurpurAPIURL,
+		FabricURL:  DefaultFabricAPIURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Resolve finds the download for a flavor, version and build. Version may be
+// "latest" and an empty build selects the newest stable build.
+func (c *FlavorClient) Resolve(flavor Flavor, version, build string) (*FlavorDownload, error) {
+	switch flavor {
+	case FlavorPaper:
+		return c.resolvePaper(version, build)
+	case FlavorPurpur:
+		return c.resolvePurpur(version, build)
+	case FlavorFabric:
+		return c.resolveFabric(version, build)
+	default:
+		return nil, fmt.Errorf("flavor %s has no build API", flavor)
+	}
+}
+
+func (c *FlavorClient) getJSON(rawURL string, v interface{}) error {
+	data, err := httpGet(c.HTTPClient, rawURL)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// resolvePaper uses the PaperMC v2 API:
+// /v2/projects/paper/versions/<version>/builds
+func (c *FlavorClient) resolvePaper(version, build string) (*FlavorDownload, error) {
+	base := strings.TrimSuffix(c.PaperURL, "/") + "/v2/projects/paper"
+
+	if version == LatestRelease {
+		var project struct {
+			Versions []string `json:"versions"`
+		}
+		if err := c.getJSON(base, &project); err != nil {
+			return nil, fmt.Errorf("failed to list paper versions: %w", err)
+		}
+		if len(project.Versions) == 0 {
+			return nil, fmt.Errorf("no paper versions available")
+		}
+		version = project.Versions[len(project.Versions)-1]
+	}
+
+	var builds struct {
+		Builds []struct {
+			Build     int    `json:"build"`
+			Channel   string `json:"channel"`
+			Downloads struct {
+				Application struct {
+					Name   string `json:"name"`
+					SHA256 string `json:"sha256"`
+				} `json:"application"`
+			} `json:"downloads"`
+		} `json:"builds"`
+	}
+	versionURL := base + "/versions/" + url.PathEscape(version)
+	if err := c.getJSON(versionURL+"/builds", &builds); err != nil {
+		return nil, fmt.Errorf("failed to list paper builds for %s: %w", version, err)
+	}
+
+	// Builds are listed oldest first; pick the requested or newest stable one
+	for i := len(builds.Builds) - 1; i >= 0; i-- {
+		b := builds.Builds[i]
+		buildStr := strconv.Itoa(b.Build)
+		if build != "" && buildStr != build {
+			continue
+		}
+		if build == "" && b.Channel != "" && b.Channel != "default" {
+			continue
+		}
+		app := b.Downloads.Application
+		return &FlavorDownload{
+			Spec:      JarSpec{Flavor: FlavorPaper, Version: version, Build: buildStr},
+			URL:       fmt.Sprintf("%s/builds/%s/downloads/%s", versionURL, buildStr, url.PathEscape(app.Name)),
+			Checksums: Checksums{SHA256: app.SHA256},
+		}, nil
+	}
+
+	if build != "" {
+		return nil, fmt.Errorf("paper build %s not found for version %s", build, version)
+	}
+	return nil, fmt.Errorf("no stable paper builds for version %s", version)
+}
+
+// resolvePurpur uses the Purpur v2 API: /v2/purpur/<version>/<build>.
+// Purpur publishes MD5 rather than SHA256 checksums.
+func (c *FlavorClient) resolvePurpur(version, build string) (*FlavorDownload, error) {
+	base := strings.TrimSuffix(c.PurpurURL, "/") + "/v2/purpur"
+
+	if version == LatestRelease {
+		var project struct {
+			Versions []string `json:"versions"`
+		}
+		if err := c.getJSON(base, &project); err != nil {
+			return nil, fmt.Errorf("failed to list purpur versions: %w", err)
+		}
+		if len(project.Versions) == 0 {
+			return nil, fmt.Errorf("no purpur versions available")
+		}
+		version = project.Versions[len(project.Versions)-1]
+	}
+
+	versionURL := base + "/" + url.PathEscape(version)
+	if build == "" {
+		var info struct {
+			Builds struct {
+				Latest string `json:"latest"`
+			} `json:"builds"`
+		}
+		if err := c.getJSON(versionURL, &info); err != nil {
+			return nil, fmt.Errorf("failed to list purpur builds for %s: %w", version, err)
+		}
+		if info.Builds.Latest == "" {
+			return nil, fmt.Errorf("no purpur builds for version %s", version)
+		}
+		build = info.Builds.Latest
+	}
+
+	var details struct {
+		Build  string `json:"build"`
+		Result string `json:"result"`
+		MD5    string `json:"md5"`
+	}
+	buildURL := versionURL + "/" + url.PathEscape(build)
+	if err := c.getJSON(buildURL, &details); err != nil {
+		return nil, fmt.Errorf("failed to get purpur build %s for %s: %w", build, version, err)
+	}
+	if details.Result != "" && details.Result != "SUCCESS" {
+		return nil, fmt.Errorf("purpur build %s for %s did not succeed (%s)", build, version, details.Result)
+	}
+
+	return &FlavorDownload{
+		Spec:      JarSpec{Flavor: FlavorPurpur, Version: version, Build: build},
+		URL:       buildURL + "/download",
+		Checksums: Checksums{MD5: details.MD5},
+	}, nil
+}
+
+// resolveFabric uses the Fabric meta API to build a server launcher URL:
+// /v2/versions/loader/<game>/<loader>/<installer>/server/jar.
+// The build is the loader version; the newest stable installer is used.
+// Fabric does not publish checksums for the launcher.
+func (c *FlavorClient) resolveFabric(version, build string) (*FlavorDownload, error) {
+	base := strings.TrimSuffix(c.FabricURL, "/") + "/v2/versions"
+
+	type component struct {
+		Version string `json:"version"`
+		Stable  bool   `json:"stable"`
+	}
+	newestStable := func(list []component) string {
+		for _, v := range list {
+			if v.Stable {
+				return v.Version
+			}
+		}
+		return ""
+	}
+
+	if version == LatestRelease {
+		var games []component
+		if err := c.getJSON(base+"/game", &games); err != nil {
+			return nil, fmt.Errorf("failed to list fabric game versions: %w", err)
+		}
+		if version = newestStable(games); version == "" {
+			return nil, fmt.Errorf("no stable fabric game versions available")
+		}
+	}
+
+	if build == "" {
+		var loaders []struct {
+			Loader component `json:"loader"`
+		}
+		if err := c.getJSON(base+"/loader/"+url.PathEscape(version), &loaders); err != nil {
+			return nil, fmt.Errorf("failed to list fabric loaders for %s: %w", version, err)
+		}
+		for _, l := range loaders {
+			if l.Loader.Stable {
+				build = l.Loader.Version
+				break
+			}
+		}
+		if build == "" {
+			return nil, fmt.Errorf("no stable fabric loader for version %s", version)
+		}
+	}
+
+	var installers []component
+	if err := c.getJSON(base+"/installer", &installers); err != nil {
+		return nil, fmt.Errorf("failed to list fabric installers: %w", err)
+	}
+	installer := newestStable(installers)
+	if installer == "" {
+		return nil, fmt.Errorf("no stable fabric installer available")
+	}
+
+	return &FlavorDownload{
+		Spec: JarSpec{Flavor: FlavorFabric, Version: version, Build: build},
+		URL: fmt.Sprintf("%s/loader/%s/%s/%s/server/jar", base,
+			url.PathEscape(version), url.PathEscape(build), url.PathEscape(installer)),
+	}, nil
+}
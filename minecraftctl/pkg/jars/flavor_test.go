@@ -0,0 +1,233 @@
+package jars
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJarFileNames(t *testing.T) {
+	tests := []struct {
+		spec JarSpec
+		name string
+	}{
+		{JarSpec{Flavor: FlavorVanilla, Version: "1.21.4"}, "minecraft_server_1.21.4.jar"},
+		{JarSpec{Flavor: FlavorPaper, Version: "1.21.4", Build: "123"}, "paper_server_1.21.4-123.jar"},
+		{JarSpec{Flavor: FlavorPurpur, Version: "1.21.4", Build: "2388"}, "purpur_server_1.21.4-2388.jar"},
+		{JarSpec{Flavor: FlavorFabric, Version: "1.21.4", Build: "0.16.9"}, "fabric_server_1.21.4-0.16.9.jar"},
+		{JarSpec{Flavor: FlavorPaper, Version: "1.20.5-rc1", Build: "7"}, "paper_server_1.20.5-rc1-7.jar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.FileName(); got != tt.name {
+				t.Errorf("FileName() = %q, want %q", got, tt.name)
+			}
+			spec, ok := ParseJarFileName(tt.name)
+			if !ok {
+				t.Fatalf("ParseJarFileName(%q) failed", tt.name)
+			}
+			if spec != tt.spec {
+				t.Errorf("ParseJarFileName(%q) = %+v, want %+v", tt.name, spec, tt.spec)
+			}
+		})
+	}
+
+	t.Run("unknown prefix", func(t *testing.T) {
+		if _, ok := ParseJarFileName("forge_server_1.21.4-1.jar"); ok {
+			t.Error("Expected unknown flavor to be rejected")
+		}
+	})
+}
+
+func TestParseFlavor(t *testing.T) {
+	if f, err := ParseFlavor(""); err != nil || f != FlavorVanilla {
+		t.Errorf("ParseFlavor(\"\") = %q, %v; want vanilla", f, err)
+	}
+	if f, err := ParseFlavor("Paper"); err != nil || f != FlavorPaper {
+		t.Errorf("ParseFlavor(\"Paper\") = %q, %v; want paper", f, err)
+	}
+	if _, err := ParseFlavor("forge"); err == nil {
+		t.Error("Expected error for unsupported flavor")
+	}
+}
+
+func TestFindFlavorJar(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"minecraft_server_1.21.4.jar",
+		"paper_server_1.21.4-99.jar",
+		"paper_server_1.21.4-123.jar",
+		"paper_server_1.21.3-80.jar",
+		"purpur_server_1.21.4-2388.jar",
+	} {
+		os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	}
+
+	t.Run("newest build", func(t *testing.T) {
+		got, err := FindFlavorJar(FlavorPaper, "1.21.4", dir)
+		if err != nil {
+			t.Fatalf("FindFlavorJar failed: %v", err)
+		}
+		if filepath.Base(got) != "paper_server_1.21.4-123.jar" {
+			t.Errorf("FindFlavorJar = %s, want build 123", filepath.Base(got))
+		}
+	})
+
+	t.Run("specific build", func(t *testing.T) {
+		got, err := FindFlavorJar(FlavorPaper, "1.21.4-99", dir)
+		if err != nil {
+			t.Fatalf("FindFlavorJar failed: %v", err)
+		}
+		if filepath.Base(got) != "paper_server_1.21.4-99.jar" {
+			t.Errorf("FindFlavorJar = %s, want build 99", filepath.Base(got))
+		}
+	})
+
+	t.Run("vanilla", func(t *testing.T) {
+		got, err := FindFlavorJar(FlavorVanilla, "1.21.4", dir)
+		if err != nil {
+			t.Fatalf("FindFlavorJar failed: %v", err)
+		}
+		if filepath.Base(got) != "minecraft_server_1.21.4.jar" {
+			t.Errorf("FindFlavorJar = %s", filepath.Base(got))
+		}
+	})
+
+	t.Run("missing flavor", func(t *testing.T) {
+		if _, err := FindFlavorJar(FlavorFabric, "1.21.4", dir); err == nil {
+			t.Error("Expected error for missing fabric jar")
+		}
+	})
+
+	t.Run("listed with flavor", func(t *testing.T) {
+		jars, err := ListJars(dir)
+		if err != nil {
+			t.Fatalf("ListJars failed: %v", err)
+		}
+		if len(jars) != 5 {
+			t.Fatalf("Expected 5 jars, got %d", len(jars))
+		}
+		flavors := map[Flavor]int{}
+		for _, j := range jars {
+			flavors[j.Flavor]++
+		}
+		if flavors[FlavorPaper] != 3 || flavors[FlavorPurpur] != 1 || flavors[FlavorVanilla] != 1 {
+			t.Errorf("Unexpected flavor counts: %v", flavors)
+		}
+	})
+}
+
+func TestFlavorClient(t *testing.T) {
+	jarContent := "flavored server jar"
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/v2/projects/paper", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"project_id": "paper", "versions": ["1.21.3", "1.21.4"]}`))
+	})
+	mux.HandleFunc("/v2/projects/paper/versions/1.21.4/builds", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"builds": [
+            {"build": 122, "channel": "default", "downloads": {"application": {"name": "paper-1.21.4-122.jar", "sha256": "old"}}},
+            {"build": 123, "channel": "default", "downloads": {"application": {"name": "paper-1.21.4-123.jar", "sha256": %q}}},
+            {"build": 124, "channel": "experimental", "downloads": {"application": {"name": "paper-1.21.4-124.jar", "sha256": "exp"}}}
+        ]}`, sha256sum(jarContent))
+	})
+	mux.HandleFunc("/v2/projects/paper/versions/1.21.4/builds/123/downloads/paper-1.21.4-123.jar", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jarContent))
+	})
+
+	md5Sum := md5.Sum([]byte(jarContent))
+	mux.HandleFunc("/v2/purpur/1.21.4", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"project": "purpur", "version": "1.21.4", "builds": {"latest": "2388", "all": ["2387", "2388"]}}`))
+	})
+	mux.HandleFunc("/v2/purpur/1.21.4/2388", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"build": "2388", "result": "SUCCESS", "md5": %q}`, hex.EncodeToString(md5Sum[:]))
+	})
+	mux.HandleFunc("/v2/purpur/1.21.4/2388/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jarContent))
+	})
+
+	mux.HandleFunc("/v2/versions/loader/1.21.4", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"loader": {"version": "0.17.0-beta.1", "stable": false}}, {"loader": {"version": "0.16.9", "stable": true}}]`))
+	})
+	mux.HandleFunc("/v2/versions/installer", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"version": "1.0.1", "stable": true}]`))
+	})
+
+	client := &FlavorClient{PaperURL: server.URL, PurpurURL: server.URL, FabricURL: server.URL}
+
+	t.Run("paper latest stable build", func(t *testing.T) {
+		download, err := client.Resolve(FlavorPaper, "latest", "")
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if download.Spec.FileName() != "paper_server_1.21.4-123.jar" {
+			t.Errorf("FileName = %q", download.Spec.FileName())
+		}
+
+		dir := t.TempDir()
+		if err := DownloadJarSpec(download.Spec, download.URL, dir, download.Checksums); err != nil {
+			t.Fatalf("DownloadJarSpec failed: %v", err)
+		}
+		checksums, _ := LoadChecksums(dir)
+		if checksums["paper_server_1.21.4-123.jar"] != sha256sum(jarContent) {
+			t.Error("Checksum not saved under flavored file name")
+		}
+	})
+
+	t.Run("paper missing build", func(t *testing.T) {
+		if _, err := client.Resolve(FlavorPaper, "1.21.4", "999"); err == nil {
+			t.Error("Expected error for missing build")
+		}
+	})
+
+	t.Run("purpur md5 verified", func(t *testing.T) {
+		download, err := client.Resolve(FlavorPurpur, "1.21.4", "")
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if download.Spec.Build != "2388" {
+			t.Errorf("Build = %q, want 2388", download.Spec.Build)
+		}
+
+		dir := t.TempDir()
+		if err := DownloadJarSpec(download.Spec, download.URL, dir, download.Checksums); err != nil {
+			t.Fatalf("DownloadJarSpec failed: %v", err)
+		}
+
+		bad := download.Checksums
+		bad.MD5 = strings.Repeat("0", 32)
+		spec := download.Spec
+		spec.Build = "2387"
+		if err := DownloadJarSpec(spec, download.URL, dir, bad); err == nil {
+			t.Error("Expected md5 mismatch")
+		}
+	})
+
+	t.Run("fabric stable loader", func(t *testing.T) {
+		download, err := client.Resolve(FlavorFabric, "1.21.4", "")
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if download.Spec.Build != "0.16.9" {
+			t.Errorf("Build = %q, want 0.16.9", download.Spec.Build)
+		}
+		if !strings.HasSuffix(download.URL, "/v2/versions/loader/1.21.4/0.16.9/1.0.1/server/jar") {
+			t.Errorf("URL = %q", download.URL)
+		}
+	})
+
+	t.Run("vanilla has no build API", func(t *testing.T) {
+		if _, err := client.Resolve(FlavorVanilla, "1.21.4", ""); err == nil {
+			t.Error("Expected error for vanilla")
+		}
+	})
+}
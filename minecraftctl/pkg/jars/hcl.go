@@ -0,0 +1,265 @@
+package jars
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// parseHCLJarList reads the JAR list from a Packer variables file such as
+// minecraft_jars.auto.pkrvars.hcl. Only the subset of HCL those files use is
+// supported: attributes whose values are strings, bare words, lists and
+// objects. The first attribute holding a list of objects is returned.
+func parseHCLJarList(data []byte) ([]LockEntry, error) {
+	p := &hclParser{src: []rune(string(data)), line: 1}
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, fmt.Errorf("no list of jars found")
+		}
+
+		name, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		list, ok := value.([]any)
+		if !ok || len(list) == 0 {
+			continue
+		}
+		var entries []LockEntry
+		for i, elem := range list {
+			obj, ok := elem.(map[string]string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d] is not an object", name, i)
+			}
+			entries = append(entries, LockEntry{
+				Version: obj["version"],
+				Flavor:  Flavor(obj["flavor"]),
+				Build:   obj["build"],
+				URL:     obj["url"],
+				SHA256:  obj["sha256"],
+			})
+		}
+		return entries, nil
+	}
+}
+
+// hclParser is a recursive descent parser over the HCL subset above. Objects
+// are flattened to map[string]string, so nested objects are not supported.
+type hclParser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (p *hclParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *hclParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and #, // and /* */ comments
+func (p *hclParser) skipSpace() {
+	for !p.eof() {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case unicode.IsSpace(c):
+			p.pos++
+		case c == '#' || (c == '/' && p.peek(1) == '/'):
+			for !p.eof() && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.peek(1) == '*':
+			p.pos += 2
+			for !p.eof() && !(p.src[p.pos] == '*' && p.peek(1) == '/') {
+				if p.src[p.pos] == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func (p *hclParser) peek(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *hclParser) expect(c rune) error {
+	if p.eof() || p.src[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// word reads a bare identifier or number
+func (p *hclParser) word() (string, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '-' && c != '.' {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		if p.eof() {
+			return "", p.errorf("unexpected end of file")
+		}
+		return "", p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return string(p.src[start:p.pos]), nil
+}
+
+// str reads a double-quoted string. Template interpolation is not supported.
+func (p *hclParser) str() (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for {
+		if p.eof() || p.src[p.pos] == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(esc)
+			}
+		case '$':
+			if p.peek(0) == '{' {
+				return "", p.errorf("template interpolation is not supported")
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// value reads a string, bare word, list or object
+func (p *hclParser) value() (any, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of file")
+	}
+	switch p.src[p.pos] {
+	case '"':
+		return p.str()
+	case '[':
+		return p.list()
+	case '{':
+		return p.object()
+	default:
+		return p.word()
+	}
+}
+
+func (p *hclParser) list() ([]any, error) {
+	p.pos++ // [
+	var items []any
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated list")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return items, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		p.skipSpace()
+		if !p.eof() && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *hclParser) object() (map[string]string, error) {
+	p.pos++ // {
+	obj := make(map[string]string)
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			return obj, nil
+		}
+
+		var key string
+		var err error
+		if p.src[p.pos] == '"' {
+			key, err = p.str()
+		} else {
+			key, err = p.word()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if !p.eof() && p.src[p.pos] == ':' {
+			p.pos++
+		} else if err := p.expect('='); err != nil {
+			return nil, err
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, p.errorf("nested value for %s is not supported", key)
+		}
+		obj[key] = s
+
+		p.skipSpace()
+		if !p.eof() && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
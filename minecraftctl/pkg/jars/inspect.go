@@ -0,0 +1,186 @@
+package jars
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	versionJSONName  = "version.json"
+	versionsListName = "META-INF/versions.list"
+	versionsDirName  = "META-INF/versions"
+)
+
+// JarMetadata contains version details read from inside a server JAR
+type JarMetadata struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	WorldVersion    int       `json:"world_version"`
+	ProtocolVersion int       `json:"protocol_version"`
+	JavaVersion     int       `json:"java_version"`
+	BuildTime       time.Time `json:"build_time"`
+	Bundler         bool      `json:"bundler"`
+}
+
+// versionJSON mirrors the version.json file shipped in vanilla server JARs.
+// pack_version changed from an int to an object over time, so it is ignored.
+type versionJSON struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	WorldVersion    int    `json:"world_version"`
+	ProtocolVersion int    `json:"protocol_version"`
+	JavaVersion     int    `json:"java_version"`
+	BuildTime       string `json:"build_time"`
+}
+
+// InspectJar opens a server JAR and reads its embedded version metadata.
+// Bundler JARs (1.18+) are detected via META-INF/versions.list; when the
+// outer JAR has no version.json the bundled server JAR is inspected instead.
+func InspectJar(jarPath string) (*JarMetadata, error) {
+	zr, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jar: %w", err)
+	}
+	defer zr.Close()
+
+	return inspectZip(&zr.Reader)
+}
+
+func inspectZip(zr *zip.Reader) (*JarMetadata, error) {
+	bundled, err := readVersionsList(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	if f := findZipFile(zr, versionJSONName); f != nil {
+		meta, err := readVersionJSON(f)
+		if err != nil {
+			return nil, err
+		}
+		meta.Bundler = len(bundled) > 0
+		return meta, nil
+	}
+
+	if len(bundled) == 0 {
+		return nil, fmt.Errorf("%s not found in jar", versionJSONName)
+	}
+
+	// Fall back to the server JAR nested inside the bundler
+	entry := bundled[0]
+	nested := findZipFile(zr, path.Join(versionsDirName, entry.path))
+	if nested == nil {
+		return nil, fmt.Errorf("bundled jar %s not found", entry.path)
+	}
+
+	data, err := readZipFile(nested)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled jar: %w", err)
+	}
+
+	inner, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundled jar: %w", err)
+	}
+
+	meta := &JarMetadata{ID: entry.id, Name: entry.id}
+	if f := findZipFile(inner, versionJSONName); f != nil {
+		meta, err = readVersionJSON(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	meta.Bundler = true
+	return meta, nil
+}
+
+// bundledVersion is a single line of META-INF/versions.list
+type bundledVersion struct {
+	sha256 string
+	id     string
+	path   string
+}
+
+// readVersionsList parses META-INF/versions.list, returning nil if it is absent.
+// Each line has the form "<sha256>\t<id>\t<path>".
+func readVersionsList(zr *zip.Reader) ([]bundledVersion, error) {
+	f := findZipFile(zr, versionsListName)
+	if f == nil {
+		return nil, nil
+	}
+
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", versionsListName, err)
+	}
+
+	var versions []bundledVersion
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(parts) != 3 {
+			continue // Skip malformed lines
+		}
+		versions = append(versions, bundledVersion{
+			sha256: parts[0],
+			id:     parts[1],
+			path:   parts[2],
+		})
+	}
+
+	return versions, scanner.Err()
+}
+
+func readVersionJSON(f *zip.File) (*JarMetadata, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", versionJSONName, err)
+	}
+
+	var v versionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", versionJSONName, err)
+	}
+
+	meta := &JarMetadata{
+		ID:              v.ID,
+		Name:            v.Name,
+		WorldVersion:    v.WorldVersion,
+		ProtocolVersion: v.ProtocolVersion,
+		JavaVersion:     v.JavaVersion,
+	}
+	if meta.Name == "" {
+		meta.Name = meta.ID
+	}
+	if v.BuildTime != "" {
+		if t, err := time.Parse(time.RFC3339, v.BuildTime); err == nil {
+			meta.BuildTime = t
+		}
+	}
+
+	return meta, nil
+}
+
+func findZipFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
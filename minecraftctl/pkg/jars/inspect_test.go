@@ -0,0 +1,249 @@
+package jars
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testVersionJSON = `{
+    "id": "1.20.1",
+    "name": "1.20.1",
+    "world_version": 3465,
+    "series_id": "main",
+    "protocol_version": 763,
+    "pack_version": {"resource": 15, "data": 15},
+    "build_time": "2023-06-12T13:25:51+00:00",
+    "java_component": "java-runtime-gamma",
+    "java_version": 17,
+    "stable": true
+}`
+
+// buildZip returns the bytes of a zip archive containing the given files
+func buildZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeZipJar writes a zip archive to dir/name and returns its path
+func writeZipJar(t *testing.T, dir, name string, files map[string][]byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buildZip(t, files), 0644); err != nil {
+		t.Fatalf("Failed to write jar: %v", err)
+	}
+	return path
+}
+
+func TestInspectJar(t *testing.T) {
+	t.Run("plain jar with version.json", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeZipJar(t, dir, "server.jar", map[string][]byte{
+			"version.json": []byte(testVersionJSON),
+		})
+
+		meta, err := InspectJar(path)
+		if err != nil {
+			t.Fatalf("InspectJar failed: %v", err)
+		}
+		if meta.ID != "1.20.1" {
+			t.Errorf("ID = %q, want 1.20.1", meta.ID)
+		}
+		if meta.WorldVersion != 3465 {
+			t.Errorf("WorldVersion = %d, want 3465", meta.WorldVersion)
+		}
+		if meta.ProtocolVersion != 763 {
+			t.Errorf("ProtocolVersion = %d, want 763", meta.ProtocolVersion)
+		}
+		if meta.JavaVersion != 17 {
+			t.Errorf("JavaVersion = %d, want 17", meta.JavaVersion)
+		}
+		want := time.Date(2023, 6, 12, 13, 25, 51, 0, time.UTC)
+		if !meta.BuildTime.Equal(want) {
+			t.Errorf("BuildTime = %v, want %v", meta.BuildTime, want)
+		}
+		if meta.Bundler {
+			t.Error("Bundler should be false without versions.list")
+		}
+	})
+
+	t.Run("legacy integer pack_version", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeZipJar(t, dir, "server.jar", map[string][]byte{
+			"version.json": []byte(`{"id": "1.16.4", "name": "1.16.4", "world_version": 2584, "protocol_version": 754, "pack_version": 6, "build_time": "2020-10-29T15:49:37+00:00", "stable": true}`),
+		})
+
+		meta, err := InspectJar(path)
+		if err != nil {
+			t.Fatalf("InspectJar failed: %v", err)
+		}
+		if meta.WorldVersion != 2584 {
+			t.Errorf("WorldVersion = %d, want 2584", meta.WorldVersion)
+		}
+		if meta.JavaVersion != 0 {
+			t.Errorf("JavaVersion = %d, want 0 when not present", meta.JavaVersion)
+		}
+	})
+
+	t.Run("bundler jar with nested server jar", func(t *testing.T) {
+		dir := t.TempDir()
+		inner := buildZip(t, map[string][]byte{
+			"version.json": []byte(testVersionJSON),
+		})
+		path := writeZipJar(t, dir, "server.jar", map[string][]byte{
+			"META-INF/versions.list":                     []byte("abc123\t1.20.1\t1.20.1/server-1.20.1.jar\n"),
+			"META-INF/versions/1.20.1/server-1.20.1.jar": inner,
+		})
+
+		meta, err := InspectJar(path)
+		if err != nil {
+			t.Fatalf("InspectJar failed: %v", err)
+		}
+		if !meta.Bundler {
+			t.Error("Bundler should be true")
+		}
+		if meta.ID != "1.20.1" || meta.WorldVersion != 3465 {
+			t.Errorf("Unexpected metadata: %+v", meta)
+		}
+	})
+
+	t.Run("bundler jar with root version.json", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeZipJar(t, dir, "server.jar", map[string][]byte{
+			"version.json":           []byte(testVersionJSON),
+			"META-INF/versions.list": []byte("abc123\t1.20.1\t1.20.1/server-1.20.1.jar\n"),
+		})
+
+		meta, err := InspectJar(path)
+		if err != nil {
+			t.Fatalf("InspectJar failed: %v", err)
+		}
+		if !meta.Bundler {
+			t.Error("Bundler should be true")
+		}
+	})
+
+	t.Run("jar without version.json", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeZipJar(t, dir, "server.jar", map[string][]byte{
+			"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n"),
+		})
+
+		if _, err := InspectJar(path); err == nil {
+			t.Error("Expected error for jar without version.json")
+		}
+	})
+
+	t.Run("not a zip file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "server.jar")
+		os.WriteFile(path, []byte("not a zip"), 0644)
+
+		if _, err := InspectJar(path); err == nil {
+			t.Error("Expected error for non-zip file")
+		}
+	})
+}
+
+func TestListJarsWithMetadata(t *testing.T) {
+	t.Run("renamed jar uses embedded version", func(t *testing.T) {
+		dir := t.TempDir()
+		writeZipJar(t, dir, "server-latest.jar", map[string][]byte{
+			"version.json": []byte(testVersionJSON),
+		})
+
+		jars, err := ListJars(dir)
+		if err != nil {
+			t.Fatalf("ListJars failed: %v", err)
+		}
+		if len(jars) != 1 {
+			t.Fatalf("Expected 1 jar, got %d", len(jars))
+		}
+		if jars[0].Version != "1.20.1" {
+			t.Errorf("Version = %q, want 1.20.1", jars[0].Version)
+		}
+		if jars[0].Metadata == nil || jars[0].Metadata.WorldVersion != 3465 {
+			t.Errorf("Expected metadata with WorldVersion 3465, got %+v", jars[0].Metadata)
+		}
+	})
+
+	t.Run("snapshot jar", func(t *testing.T) {
+		dir := t.TempDir()
+		writeZipJar(t, dir, "minecraft_server_24w14a.jar", map[string][]byte{
+			"version.json": []byte(`{"id": "24w14a", "name": "24w14a", "world_version": 3827, "protocol_version": 1073742010, "java_version": 21, "stable": false}`),
+		})
+
+		info, err := GetJarInfo("24w14a", dir)
+		if err != nil {
+			t.Fatalf("GetJarInfo failed: %v", err)
+		}
+		if info.Metadata == nil || info.Metadata.JavaVersion != 21 {
+			t.Errorf("Expected java version 21, got %+v", info.Metadata)
+		}
+	})
+}
+
+func TestFindJar(t *testing.T) {
+	t.Run("by file name", func(t *testing.T) {
+		dir := t.TempDir()
+		want := createTestJar(t, dir, "1.20.4", "content")
+
+		got, err := FindJar("1.20.4", dir)
+		if err != nil {
+			t.Fatalf("FindJar failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("FindJar = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("by embedded version", func(t *testing.T) {
+		dir := t.TempDir()
+		want := writeZipJar(t, dir, "custom.jar", map[string][]byte{
+			"version.json": []byte(testVersionJSON),
+		})
+
+		got, err := FindJar("1.20.1", dir)
+		if err != nil {
+			t.Fatalf("FindJar failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("FindJar = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("other flavor with embedded version", func(t *testing.T) {
+		dir := t.TempDir()
+		writeZipJar(t, dir, "paper_server_1.20.1-196.jar", map[string][]byte{
+			"version.json": []byte(testVersionJSON),
+		})
+
+		if got, err := FindJar("1.20.1", dir); err == nil {
+			t.Errorf("Expected Paper JAR to be skipped, got %q", got)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := FindJar("1.99", dir); err == nil {
+			t.Error("Expected error for missing jar")
+		}
+	})
+}
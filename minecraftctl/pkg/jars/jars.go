@@ -2,14 +2,16 @@ package jars
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -21,16 +23,27 @@ const (
 	jarSuffix         = ".jar"
 )
 
+// checksumsMu serializes the read-modify-write updates of checksums.txt,
+// which parallel downloads make
+var checksumsMu sync.Mutex
+
 // JarInfo contains information about a Minecraft server JAR
 type JarInfo struct {
-	Version     string
-	Path        string
-	Size        int64
-	Checksum    string
-	InstalledAt time.Time
+	Version     string       `json:"version"`
+	Flavor      Flavor       `json:"flavor"`
+	Build       string       `json:"build,omitempty"`
+	Path        string       `json:"path"`
+	Size        int64        `json:"size"`
+	Checksum    string       `json:"checksum"`
+	InstalledAt time.Time    `json:"installed_at"`
+	Metadata    *JarMetadata `json:"metadata,omitempty"`
 }
 
-// ListJars returns a list of all installed JARs in the jars directory
+// ListJars returns a list of all installed JARs in the jars directory.
+// The version comes from the JAR's embedded metadata when available, and
+// from the file name (minecraft_server_<version>.jar or
+// <flavor>_server_<version>-<build>.jar) otherwise. JARs with other names
+// are only listed if they can be inspected.
 func ListJars(jarsDir string) ([]JarInfo, error) {
 	entries, err := os.ReadDir(jarsDir)
 	if err != nil {
@@ -39,23 +52,20 @@ func ListJars(jarsDir string) ([]JarInfo, error) {
 
 	var jars []JarInfo
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), jarSuffix) {
 			continue
 		}
 
-		name := entry.Name()
-		// Check if it matches the pattern minecraft_server_*.jar
-		if !strings.HasPrefix(name, jarPrefix) || !strings.HasSuffix(name, jarSuffix) {
-			continue
+		jarPath := filepath.Join(jarsDir, entry.Name())
+		if _, named := ParseJarFileName(entry.Name()); !named {
+			if _, err := InspectJar(jarPath); err != nil {
+				continue
+			}
 		}
 
-		// Extract version from filename: minecraft_server_<version>.jar
-		version := strings.TrimPrefix(name, jarPrefix)
-		version = strings.TrimSuffix(version, jarSuffix)
-
-		info, err := GetJarInfo(version, jarsDir)
+		info, err := jarInfoFromPath(jarPath)
 		if err != nil {
-			log.Warn().Err(err).Str("version", version).Msg("failed to get jar info")
+			log.Warn().Err(err).Str("jar", entry.Name()).Msg("failed to get jar info")
 			continue
 		}
 
@@ -67,103 +77,144 @@ func ListJars(jarsDir string) ([]JarInfo, error) {
 
 // GetJarInfo retrieves information about a specific JAR
 func GetJarInfo(version, jarsDir string) (*JarInfo, error) {
-	jarPath := filepath.Join(jarsDir, fmt.Sprintf("%s%s%s", jarPrefix, version, jarSuffix))
+	return GetFlavorJarInfo(FlavorVanilla, version, jarsDir)
+}
 
-	fileInfo, err := os.Stat(jarPath)
+// GetFlavorJarInfo retrieves information about a specific JAR of a flavor
+func GetFlavorJarInfo(flavor Flavor, version, jarsDir string) (*JarInfo, error) {
+	jarPath, err := FindFlavorJar(flavor, version, jarsDir)
 	if err != nil {
-		return nil, fmt.Errorf("jar not found: %s", jarPath)
+		return nil, err
 	}
-
-	// Compute checksum
-	checksum, err := computeSHA256(jarPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute checksum: %w", err)
-	}
-
-	return &JarInfo{
-		Version:     version,
-		Path:        jarPath,
-		Size:        fileInfo.Size(),
-		Checksum:    checksum,
-		InstalledAt: fileInfo.ModTime(),
-	}, nil
+	return jarInfoFromPath(jarPath)
 }
 
-// DownloadJar downloads a Minecraft server JAR from a URL and verifies its checksum
-func DownloadJar(version, url, jarsDir string, expectedSHA256 string) error {
-	jarPath := filepath.Join(jarsDir, fmt.Sprintf("%s%s%s", jarPrefix, version, jarSuffix))
-
-	// Check if JAR already exists
-	if _, err := os.Stat(jarPath); err == nil {
-		return fmt.Errorf("jar already exists: %s", jarPath)
+// FindJar returns the path of the JAR for a version. It first looks for
+// minecraft_server_<version>.jar, then for any JAR whose embedded metadata
+// reports the requested version id. JARs named as another flavor's are
+// skipped: Paper and Purpur JARs carry the version.json of the server they
+// patch.
+func FindJar(version, jarsDir string) (string, error) {
+	jarPath := filepath.Join(jarsDir, jarFileName(version))
+	if fileInfo, err := os.Stat(jarPath); err == nil && !fileInfo.IsDir() {
+		return jarPath, nil
 	}
 
-	// Create temporary file for download
-	tmpFile, err := os.CreateTemp(jarsDir, fmt.Sprintf("%s%s-*.tmp", jarPrefix, version))
-	if err != nil {
-		return fmt.Errorf("failed to create temporary file: %w", err)
+	entries, err := os.ReadDir(jarsDir)
+	if err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), jarSuffix) {
+				continue
+			}
+			if spec, ok := ParseJarFileName(entry.Name()); ok && spec.Flavor != FlavorVanilla {
+				continue
+			}
+			candidate := filepath.Join(jarsDir, entry.Name())
+			if meta, err := InspectJar(candidate); err == nil && meta.ID == version {
+				return candidate, nil
+			}
+		}
 	}
-	tmpPath := tmpFile.Name()
-	defer func() {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-	}()
 
-	// Download the file
-	log.Info().Str("version", version).Str("url", url).Msg("downloading jar")
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download jar: %w", err)
+	return "", fmt.Errorf("jar not found: %s", jarPath)
+}
+
+// VersionFromJar returns the version of the JAR at jarPath, preferring the
+// embedded metadata and falling back to the file name.
+func VersionFromJar(jarPath string) (string, error) {
+	if meta, err := InspectJar(jarPath); err == nil && meta.ID != "" {
+		return meta.ID, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	base := filepath.Base(jarPath)
+	spec, ok := ParseJarFileName(base)
+	if !ok {
+		return "", fmt.Errorf("unexpected jar filename format: %s", base)
 	}
+	return spec.Version, nil
+}
 
-	// Write to temporary file
-	_, err = io.Copy(tmpFile, resp.Body)
+// jarInfoFromPath builds a JarInfo for the JAR at jarPath
+func jarInfoFromPath(jarPath string) (*JarInfo, error) {
+	fileInfo, err := os.Stat(jarPath)
 	if err != nil {
-		return fmt.Errorf("failed to write jar file: %w", err)
+		return nil, fmt.Errorf("jar not found: %s", jarPath)
 	}
-	tmpFile.Close()
 
 	// Compute checksum
-	actualSHA256, err := computeSHA256(tmpPath)
+	checksum, err := computeSHA256(jarPath)
 	if err != nil {
-		return fmt.Errorf("failed to compute checksum: %w", err)
+		return nil, fmt.Errorf("failed to compute checksum: %w", err)
 	}
 
-	// Verify checksum if expected is provided
-	if expectedSHA256 != "" {
-		if actualSHA256 != expectedSHA256 {
-			return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedSHA256, actualSHA256)
+	// Metadata is optional; old or non-vanilla JARs may not carry version.json
+	spec, named := ParseJarFileName(filepath.Base(jarPath))
+	meta, err := InspectJar(jarPath)
+	if err == nil && meta.ID != "" {
+		spec.Version = meta.ID
+	} else {
+		log.Debug().Err(err).Str("jar", jarPath).Msg("no embedded jar metadata")
+		meta = nil
+		if !named {
+			return nil, fmt.Errorf("unexpected jar filename format: %s", filepath.Base(jarPath))
 		}
-		log.Info().Str("version", version).Msg("checksum verified")
 	}
-
-	// Move temporary file to final location
-	if err := os.Rename(tmpPath, jarPath); err != nil {
-		return fmt.Errorf("failed to move jar to final location: %w", err)
+	if spec.Flavor == "" {
+		spec.Flavor = FlavorVanilla
 	}
 
-	// Update checksums.txt
-	if err := SaveChecksum(version, actualSHA256, jarsDir); err != nil {
-		log.Warn().Err(err).Msg("failed to update checksums.txt")
-		// Don't fail the download if checksum file update fails
-	}
+	return &JarInfo{
+		Version:     spec.Version,
+		Flavor:      spec.Flavor,
+		Build:       spec.Build,
+		Path:        jarPath,
+		Size:        fileInfo.Size(),
+		Checksum:    checksum,
+		InstalledAt: fileInfo.ModTime(),
+		Metadata:    meta,
+	}, nil
+}
 
-	log.Info().Str("version", version).Str("path", jarPath).Msg("jar downloaded successfully")
-	return nil
+// jarFileName returns the canonical file name for a vanilla version
+func jarFileName(version string) string {
+	return JarSpec{Flavor: FlavorVanilla, Version: version}.FileName()
+}
+
+// DownloadJar downloads a Minecraft server JAR from a URL and verifies its checksum
+func DownloadJar(version, url, jarsDir string, expectedSHA256 string) error {
+	return DownloadJarVerified(version, url, jarsDir, Checksums{SHA256: expectedSHA256})
+}
+
+// Checksums holds the expected digests of a download. Empty fields are not checked.
+type Checksums struct {
+	SHA256 string
+	SHA1   string
+	MD5    string // only published by some build APIs (Purpur)
+}
+
+// DownloadJarVerified downloads a vanilla JAR and verifies it against every
+// provided checksum before moving it into place
+func DownloadJarVerified(version, url, jarsDir string, expected Checksums) error {
+	return DownloadJarSpec(JarSpec{Flavor: FlavorVanilla, Version: version}, url, jarsDir, expected)
+}
+
+// DownloadJarSpec downloads the JAR described by spec to its canonical file
+// name with the default retry and timeout settings, and verifies it against
+// every provided checksum before moving it into place
+func DownloadJarSpec(spec JarSpec, url, jarsDir string, expected Checksums) error {
+	return DownloadJarContext(context.Background(), spec, url, jarsDir, expected, DownloadOptions{})
 }
 
 // VerifyJar verifies a JAR's checksum against the checksums.txt file
 func VerifyJar(version, jarsDir string) error {
-	jarPath := filepath.Join(jarsDir, fmt.Sprintf("%s%s%s", jarPrefix, version, jarSuffix))
+	return VerifyFlavorJar(FlavorVanilla, version, jarsDir)
+}
 
-	// Check if JAR exists
-	if _, err := os.Stat(jarPath); err != nil {
-		return fmt.Errorf("jar not found: %s", jarPath)
+// VerifyFlavorJar verifies the checksum of a JAR of a flavor against the checksums.txt file
+func VerifyFlavorJar(flavor Flavor, version, jarsDir string) error {
+	jarPath, err := FindFlavorJar(flavor, version, jarsDir)
+	if err != nil {
+		return err
 	}
 
 	// Load checksums
@@ -240,8 +291,13 @@ func LoadChecksums(jarsDir string) (map[string]string, error) {
 
 // SaveChecksum saves or updates a checksum in the checksums.txt file
 func SaveChecksum(version, sha256, jarsDir string) error {
-	checksumsPath := filepath.Join(jarsDir, checksumsFileName)
-	jarFileName := fmt.Sprintf("%s%s%s", jarPrefix, version, jarSuffix)
+	return saveChecksum(jarFileName(version), sha256, jarsDir)
+}
+
+// saveChecksum saves or updates the checksum for a JAR file name
+func saveChecksum(fileName, sha256, jarsDir string) error {
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
 
 	// Load existing checksums
 	checksums, err := LoadChecksums(jarsDir)
@@ -250,25 +306,63 @@ func SaveChecksum(version, sha256, jarsDir string) error {
 	}
 
 	// Update or add the checksum
-	checksums[jarFileName] = sha256
+	checksums[fileName] = sha256
 
-	// Write back to file
-	file, err := os.Create(checksumsPath)
+	return writeChecksums(jarsDir, checksums)
+}
+
+// RemoveChecksums drops the entries for the given JAR file names from checksums.txt
+func RemoveChecksums(jarsDir string, fileNames ...string) error {
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
+
+	checksums, err := LoadChecksums(jarsDir)
 	if err != nil {
-		return fmt.Errorf("failed to create checksums file: %w", err)
+		return fmt.Errorf("failed to load existing checksums: %w", err)
 	}
-	defer file.Close()
+
+	changed := false
+	for _, name := range fileNames {
+		if _, ok := checksums[name]; ok {
+			delete(checksums, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return writeChecksums(jarsDir, checksums)
+}
+
+// writeChecksums rewrites checksums.txt, sorted by file name. The file is
+// replaced atomically so readers never see it half written; callers hold
+// checksumsMu.
+func writeChecksums(jarsDir string, checksums map[string]string) error {
+	checksumsPath := filepath.Join(jarsDir, checksumsFileName)
+
+	fileNames := make([]string, 0, len(checksums))
+	for name := range checksums {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
 
 	// Write all checksums in sha256sum format
-	for filename, checksum := range checksums {
+	var sb strings.Builder
+	for _, filename := range fileNames {
 		// sha256sum format: <checksum>  <filename>
 		// Use two spaces for compatibility
-		_, err := fmt.Fprintf(file, "%s  %s\n", checksum, filename)
-		if err != nil {
-			return fmt.Errorf("failed to write checksum: %w", err)
-		}
+		fmt.Fprintf(&sb, "%s  %s\n", checksums[filename], filename)
 	}
 
+	tmpPath := checksumsPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checksums file: %w", err)
+	}
+	if err := os.Rename(tmpPath, checksumsPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace checksums file: %w", err)
+	}
 	return nil
 }
 
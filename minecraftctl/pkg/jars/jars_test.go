@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -321,6 +322,29 @@ func TestSaveChecksum(t *testing.T) {
 			t.Errorf("Checksum not updated")
 		}
 	})
+
+	t.Run("concurrent saves", func(t *testing.T) {
+		dir := t.TempDir()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := SaveChecksum(fmt.Sprintf("1.20.%d", i), fmt.Sprintf("sum%d", i), dir); err != nil {
+					t.Errorf("SaveChecksum failed: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		checksums, err := LoadChecksums(dir)
+		if err != nil {
+			t.Fatalf("LoadChecksums failed: %v", err)
+		}
+		if len(checksums) != 20 {
+			t.Errorf("Expected 20 checksums, got %d", len(checksums))
+		}
+	})
 }
 
 func TestVerifyJar(t *testing.T) {
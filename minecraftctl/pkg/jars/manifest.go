@@ -0,0 +1,260 @@
+package jars
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	manifestCacheFile = "version_manifest_v2.json"
+	manifestCacheDir  = "manifests"
+
+	// Aliases accepted in place of a version id
+	LatestRelease  = "latest"
+	LatestSnapshot = "latest-snapshot"
+)
+
+// VersionManifest is the launcher version_manifest_v2.json document
+type VersionManifest struct {
+	Latest struct {
+		Release  string `json:"release"`
+		Snapshot string `json:"snapshot"`
+	} `json:"latest"`
+	Versions []ManifestVersion `json:"versions"`
+}
+
+// ManifestVersion is a single entry in the version manifest
+type ManifestVersion struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	URL         string    `json:"url"`
+	Time        time.Time `json:"time"`
+	ReleaseTime time.Time `json:"releaseTime"`
+	SHA1        string    `json:"sha1"`
+}
+
+// VersionDetails is the subset of a per-version JSON document that minecraftctl uses
+type VersionDetails struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Downloads struct {
+		Server *Download `json:"server,omitempty"`
+	} `json:"downloads"`
+	JavaVersion struct {
+		Component    string `json:"component"`
+		MajorVersion int    `json:"majorVersion"`
+	} `json:"javaVersion"`
+}
+
+// Download describes a downloadable artifact in a per-version JSON document
+type Download struct {
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"`
+}
+
+// ManifestClient fetches the launcher version manifest and per-version JSON,
+// caching both on disk so they remain available offline
+type ManifestClient struct {
+	ManifestURL string
+	CacheDir    string // empty disables caching
+	Offline     bool   // only use cached documents
+	HTTPClient  *http.Client
+}
+
+// NewManifestClient creates a manifest client for the given manifest URL
+func NewManifestClient(manifestURL, cacheDir string) *ManifestClient {
+	return &ManifestClient{
+		ManifestURL: manifestURL,
+		CacheDir:    cacheDir,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Manifest returns the version manifest, falling back to the cached copy
+// when it cannot be fetched
+func (c *ManifestClient) Manifest() (*VersionManifest, error) {
+	cachePath := c.cachePath(manifestCacheFile)
+
+	var data []byte
+	var fetchErr error
+	if !c.Offline {
+		data, fetchErr = c.fetch(c.ManifestURL)
+		if fetchErr == nil {
+			c.writeCache(cachePath, data)
+		}
+	}
+
+	if data == nil {
+		cached, err := c.readCache(cachePath)
+		if err != nil {
+			if fetchErr != nil {
+				return nil, fmt.Errorf("failed to fetch version manifest: %w", fetchErr)
+			}
+			return nil, fmt.Errorf("no cached version manifest available: %w", err)
+		}
+		if fetchErr != nil {
+			log.Warn().Err(fetchErr).Msg("failed to fetch version manifest, using cached copy")
+		}
+		data = cached
+	}
+
+	var manifest VersionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse version manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// Resolve finds the manifest entry for a version id or one of the
+// LatestRelease/LatestSnapshot aliases
+func (m *VersionManifest) Resolve(version string) (*ManifestVersion, error) {
+	id := version
+	switch version {
+	case LatestRelease:
+		id = m.Latest.Release
+	case LatestSnapshot:
+		id = m.Latest.Snapshot
+	}
+
+	for i := range m.Versions {
+		if m.Versions[i].ID == id {
+			return &m.Versions[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("version %s not found in version manifest", version)
+}
+
+// Filter returns the manifest versions, newest first. Snapshots and other
+// pre-release types are only included when includeSnapshots is set.
+func (m *VersionManifest) Filter(includeSnapshots bool) []ManifestVersion {
+	var versions []ManifestVersion
+	for _, v := range m.Versions {
+		if v.Type != "release" && !includeSnapshots {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// VersionDetails returns the per-version JSON for a manifest entry. Cached
+// documents are used when their SHA1 matches the manifest.
+func (c *ManifestClient) VersionDetails(v *ManifestVersion) (*VersionDetails, error) {
+	cachePath := c.cachePath(v.ID + ".json")
+
+	data, err := c.readCache(cachePath)
+	if err != nil || (v.SHA1 != "" && sha1Hex(data) != v.SHA1) {
+		if c.Offline {
+			return nil, fmt.Errorf("no cached metadata for version %s", v.ID)
+		}
+
+		data, err = c.fetch(v.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch metadata for version %s: %w", v.ID, err)
+		}
+		if v.SHA1 != "" && sha1Hex(data) != v.SHA1 {
+			return nil, fmt.Errorf("metadata checksum mismatch for version %s", v.ID)
+		}
+		c.writeCache(cachePath, data)
+	}
+
+	var details VersionDetails
+	if err := json.Unmarshal(data, &details); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata for version %s: %w", v.ID, err)
+	}
+
+	return &details, nil
+}
+
+// ResolveServerDownload resolves a version or alias to its id and server JAR download
+func (c *ManifestClient) ResolveServerDownload(version string) (string, *Download, error) {
+	manifest, err := c.Manifest()
+	if err != nil {
+		return "", nil, err
+	}
+
+	entry, err := manifest.Resolve(version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	details, err := c.VersionDetails(entry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if details.Downloads.Server == nil || details.Downloads.Server.URL == "" {
+		return "", nil, fmt.Errorf("version %s has no server download", entry.ID)
+	}
+
+	return entry.ID, details.Downloads.Server, nil
+}
+
+func (c *ManifestClient) fetch(url string) ([]byte, error) {
+	return httpGet(c.HTTPClient, url)
+}
+
+// httpGet fetches url and returns the response body, failing on non-200 responses
+func httpGet(client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %s failed with status %d", url, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (c *ManifestClient) cachePath(name string) string {
+	if c.CacheDir == "" {
+		return ""
+	}
+	return filepath.Join(c.CacheDir, manifestCacheDir, name)
+}
+
+func (c *ManifestClient) readCache(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("caching disabled")
+	}
+	return os.ReadFile(path)
+}
+
+// writeCache stores a document in the cache. Failures are logged and ignored
+// since the cache is only an offline fallback.
+func (c *ManifestClient) writeCache(path string, data []byte) {
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Debug().Err(err).Msg("failed to create manifest cache directory")
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("failed to write manifest cache")
+	}
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,201 @@
+package jars
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1sum(content string) string {
+	sum := sha1.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// newManifestServer serves a version manifest fixture with one release and one
+// snapshot, their per-version JSON and the release server JAR
+func newManifestServer(t *testing.T, jarContent string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	releaseJSON := fmt.Sprintf(`{"id": "1.21.4", "type": "release", "downloads": {"server": {"sha1": %q, "size": %d, "url": "%s/server.jar"}}, "javaVersion": {"component": "java-runtime-delta", "majorVersion": 21}}`,
+		sha1sum(jarContent), len(jarContent), server.URL)
+	snapshotJSON := `{"id": "25w02a", "type": "snapshot", "downloads": {}}`
+
+	manifest := fmt.Sprintf(`{
+    "latest": {"release": "1.21.4", "snapshot": "25w02a"},
+    "versions": [
+        {"id": "25w02a", "type": "snapshot", "url": "%[1]s/v1/25w02a.json", "releaseTime": "2025-01-08T12:00:00+00:00", "sha1": %[2]q},
+        {"id": "1.21.4", "type": "release", "url": "%[1]s/v1/1.21.4.json", "releaseTime": "2024-12-03T10:12:57+00:00", "sha1": %[3]q}
+    ]
+}`, server.URL, sha1sum(snapshotJSON), sha1sum(releaseJSON))
+
+	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(manifest))
+	})
+	mux.HandleFunc("/v1/1.21.4.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(releaseJSON))
+	})
+	mux.HandleFunc("/v1/25w02a.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(snapshotJSON))
+	})
+	mux.HandleFunc("/server.jar", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jarContent))
+	})
+	return server
+}
+
+func TestManifestClient(t *testing.T) {
+	t.Run("resolve aliases", func(t *testing.T) {
+		server := newManifestServer(t, "jar")
+		client := NewManifestClient(server.URL+"/manifest.json", "")
+
+		manifest, err := client.Manifest()
+		if err != nil {
+			t.Fatalf("Manifest failed: %v", err)
+		}
+
+		for alias, want := range map[string]string{
+			LatestRelease:  "1.21.4",
+			LatestSnapshot: "25w02a",
+			"1.21.4":       "1.21.4",
+		} {
+			v, err := manifest.Resolve(alias)
+			if err != nil {
+				t.Fatalf("Resolve(%q) failed: %v", alias, err)
+			}
+			if v.ID != want {
+				t.Errorf("Resolve(%q) = %q, want %q", alias, v.ID, want)
+			}
+		}
+
+		if _, err := manifest.Resolve("1.99"); err == nil {
+			t.Error("Expected error for unknown version")
+		}
+	})
+
+	t.Run("filter snapshots", func(t *testing.T) {
+		server := newManifestServer(t, "jar")
+		manifest, err := NewManifestClient(server.URL+"/manifest.json", "").Manifest()
+		if err != nil {
+			t.Fatalf("Manifest failed: %v", err)
+		}
+
+		if got := manifest.Filter(false); len(got) != 1 || got[0].ID != "1.21.4" {
+			t.Errorf("Filter(false) = %+v, want only 1.21.4", got)
+		}
+		if got := manifest.Filter(true); len(got) != 2 {
+			t.Errorf("Filter(true) returned %d versions, want 2", len(got))
+		}
+	})
+
+	t.Run("resolve server download", func(t *testing.T) {
+		server := newManifestServer(t, "server jar")
+		client := NewManifestClient(server.URL+"/manifest.json", "")
+
+		id, download, err := client.ResolveServerDownload(LatestRelease)
+		if err != nil {
+			t.Fatalf("ResolveServerDownload failed: %v", err)
+		}
+		if id != "1.21.4" {
+			t.Errorf("id = %q, want 1.21.4", id)
+		}
+		if download.SHA1 != sha1sum("server jar") {
+			t.Errorf("SHA1 = %q", download.SHA1)
+		}
+		if !strings.HasSuffix(download.URL, "/server.jar") {
+			t.Errorf("URL = %q", download.URL)
+		}
+	})
+
+	t.Run("version without server download", func(t *testing.T) {
+		server := newManifestServer(t, "jar")
+		client := NewManifestClient(server.URL+"/manifest.json", "")
+
+		if _, _, err := client.ResolveServerDownload(LatestSnapshot); err == nil {
+			t.Error("Expected error for version without server download")
+		}
+	})
+
+	t.Run("cached manifest used offline", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		server := newManifestServer(t, "jar")
+		client := NewManifestClient(server.URL+"/manifest.json", cacheDir)
+
+		if _, _, err := client.ResolveServerDownload("1.21.4"); err != nil {
+			t.Fatalf("ResolveServerDownload failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(cacheDir, "manifests", "version_manifest_v2.json")); err != nil {
+			t.Errorf("Manifest not cached: %v", err)
+		}
+
+		server.Close()
+
+		// Unreachable server falls back to the cache
+		id, _, err := client.ResolveServerDownload(LatestRelease)
+		if err != nil {
+			t.Fatalf("ResolveServerDownload with cache failed: %v", err)
+		}
+		if id != "1.21.4" {
+			t.Errorf("id = %q, want 1.21.4", id)
+		}
+
+		client.Offline = true
+		if _, err := client.Manifest(); err != nil {
+			t.Errorf("Offline manifest failed: %v", err)
+		}
+	})
+
+	t.Run("no cache and unreachable", func(t *testing.T) {
+		server := newManifestServer(t, "jar")
+		server.Close()
+
+		client := NewManifestClient(server.URL+"/manifest.json", t.TempDir())
+		if _, err := client.Manifest(); err == nil {
+			t.Error("Expected error without network or cache")
+		}
+	})
+}
+
+func TestDownloadJarVerified(t *testing.T) {
+	t.Run("download resolved from manifest", func(t *testing.T) {
+		content := "vanilla server jar"
+		server := newManifestServer(t, content)
+		client := NewManifestClient(server.URL+"/manifest.json", "")
+
+		id, download, err := client.ResolveServerDownload(LatestRelease)
+		if err != nil {
+			t.Fatalf("ResolveServerDownload failed: %v", err)
+		}
+
+		dir := t.TempDir()
+		if err := DownloadJarVerified(id, download.URL, dir, Checksums{SHA1: download.SHA1}); err != nil {
+			t.Fatalf("DownloadJarVerified failed: %v", err)
+		}
+
+		checksums, _ := LoadChecksums(dir)
+		if checksums["minecraft_server_1.21.4.jar"] != sha256sum(content) {
+			t.Error("SHA256 should be recorded in checksums.txt")
+		}
+	})
+
+	t.Run("sha1 mismatch", func(t *testing.T) {
+		server := newManifestServer(t, "jar")
+		dir := t.TempDir()
+
+		err := DownloadJarVerified("1.21.4", server.URL+"/server.jar", dir, Checksums{SHA1: sha1sum("other")})
+		if err == nil || !strings.Contains(err.Error(), "mismatch") {
+			t.Fatalf("Expected sha1 mismatch, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "minecraft_server_1.21.4.jar")); !os.IsNotExist(err) {
+			t.Error("Jar should not exist after sha1 failure")
+		}
+	})
+}
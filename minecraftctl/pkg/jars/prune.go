@@ -0,0 +1,110 @@
+package jars
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PruneOptions controls which JARs PruneJars removes
+type PruneOptions struct {
+	// Keep is the number of newest unused JARs kept per flavor
+	Keep int
+	// DryRun reports what would be removed without removing anything
+	DryRun bool
+	// Force also removes JARs needed to roll back a world's upgrade
+	Force bool
+	// InUse maps JAR file names to the worlds currently running them.
+	// These JARs are never removed.
+	InUse map[string][]string
+	// Rollback maps JAR file names to the worlds whose upgrade history
+	// references them. These JARs are only removed with Force.
+	Rollback map[string][]string
+}
+
+// PruneDecision records what PruneJars did with a single JAR
+type PruneDecision struct {
+	Jar    JarInfo
+	Remove bool
+	Reason string
+}
+
+// PruneJars removes JARs no world references and drops their checksums.txt
+// entries. Decisions are returned for every JAR, newest first per flavor.
+func PruneJars(jarsDir string, opts PruneOptions) ([]PruneDecision, error) {
+	jarList, err := ListJars(jarsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	sortJarsNewestFirst(jarList)
+
+	var decisions []PruneDecision
+	kept := make(map[Flavor]int)
+	for _, jar := range jarList {
+		name := filepath.Base(jar.Path)
+		decision := PruneDecision{Jar: jar}
+
+		switch {
+		case len(opts.InUse[name]) > 0:
+			decision.Reason = fmt.Sprintf("in use by %v", opts.InUse[name])
+		case kept[jar.Flavor] < opts.Keep:
+			decision.Reason = fmt.Sprintf("kept as one of the %d newest", opts.Keep)
+			kept[jar.Flavor]++
+		case len(opts.Rollback[name]) > 0 && !opts.Force:
+			decision.Reason = fmt.Sprintf("needed for rollback by %v (use --force to remove)", opts.Rollback[name])
+		default:
+			decision.Remove = true
+			decision.Reason = "unused"
+			if len(opts.Rollback[name]) > 0 {
+				decision.Reason = fmt.Sprintf("unused, rollback for %v forced", opts.Rollback[name])
+			}
+		}
+
+		decisions = append(decisions, decision)
+	}
+
+	if opts.DryRun {
+		return decisions, nil
+	}
+
+	var removed []string
+	for _, d := range decisions {
+		if !d.Remove {
+			continue
+		}
+		if err := os.Remove(d.Jar.Path); err != nil {
+			return decisions, fmt.Errorf("failed to remove %s: %w", d.Jar.Path, err)
+		}
+		log.Info().Str("jar", d.Jar.Path).Msg("removed jar")
+		removed = append(removed, filepath.Base(d.Jar.Path))
+	}
+
+	if err := RemoveChecksums(jarsDir, removed...); err != nil {
+		return decisions, fmt.Errorf("failed to update checksums.txt: %w", err)
+	}
+
+	return decisions, nil
+}
+
+// sortJarsNewestFirst orders JARs by flavor, then newest first using the
+// embedded DataVersion, the build, and finally the modification time
+func sortJarsNewestFirst(jarList []JarInfo) {
+	sort.SliceStable(jarList, func(i, j int) bool {
+		a, b := jarList[i], jarList[j]
+		if a.Flavor != b.Flavor {
+			return a.Flavor < b.Flavor
+		}
+		if a.Metadata != nil && b.Metadata != nil && a.Metadata.WorldVersion != b.Metadata.WorldVersion &&
+			a.Metadata.WorldVersion > 0 && b.Metadata.WorldVersion > 0 {
+			return a.Metadata.WorldVersion > b.Metadata.WorldVersion
+		}
+		if a.Version == b.Version && a.Build != b.Build {
+			return CompareBuilds(a.Build, b.Build) > 0
+		}
+		return a.InstalledAt.After(b.InstalledAt)
+	})
+}
@@ -0,0 +1,107 @@
+package jars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneJars(t *testing.T) {
+	// setup creates jars with increasing modification times, oldest first
+	setup := func(t *testing.T, versions ...string) string {
+		t.Helper()
+		dir := t.TempDir()
+		base := time.Now().Add(-time.Hour)
+		for i, v := range versions {
+			path := createTestJar(t, dir, v, "content "+v)
+			mtime := base.Add(time.Duration(i) * time.Minute)
+			os.Chtimes(path, mtime, mtime)
+			SaveChecksum(v, sha256sum("content "+v), dir)
+		}
+		return dir
+	}
+
+	exists := func(dir, version string) bool {
+		_, err := os.Stat(filepath.Join(dir, jarFileName(version)))
+		return err == nil
+	}
+
+	t.Run("removes unused jars and checksums", func(t *testing.T) {
+		dir := setup(t, "1.20.1", "1.20.4", "1.21")
+
+		_, err := PruneJars(dir, PruneOptions{
+			InUse: map[string][]string{"minecraft_server_1.21.jar": {"survival"}},
+		})
+		if err != nil {
+			t.Fatalf("PruneJars failed: %v", err)
+		}
+
+		if exists(dir, "1.20.1") || exists(dir, "1.20.4") {
+			t.Error("Unused jars should be removed")
+		}
+		if !exists(dir, "1.21") {
+			t.Error("In-use jar should be kept")
+		}
+
+		checksums, _ := LoadChecksums(dir)
+		if len(checksums) != 1 || checksums["minecraft_server_1.21.jar"] == "" {
+			t.Errorf("checksums.txt should only list the kept jar, got %v", checksums)
+		}
+	})
+
+	t.Run("dry run removes nothing", func(t *testing.T) {
+		dir := setup(t, "1.20.1", "1.21")
+
+		decisions, err := PruneJars(dir, PruneOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("PruneJars failed: %v", err)
+		}
+		if len(decisions) != 2 || !decisions[0].Remove || !decisions[1].Remove {
+			t.Errorf("Expected both jars marked for removal, got %+v", decisions)
+		}
+		if !exists(dir, "1.20.1") || !exists(dir, "1.21") {
+			t.Error("Dry run should not remove jars")
+		}
+	})
+
+	t.Run("keep newest", func(t *testing.T) {
+		dir := setup(t, "1.20.1", "1.20.4", "1.21")
+
+		if _, err := PruneJars(dir, PruneOptions{Keep: 2}); err != nil {
+			t.Fatalf("PruneJars failed: %v", err)
+		}
+		if exists(dir, "1.20.1") {
+			t.Error("Oldest jar should be removed")
+		}
+		if !exists(dir, "1.20.4") || !exists(dir, "1.21") {
+			t.Error("Two newest jars should be kept")
+		}
+	})
+
+	t.Run("rollback jars need force", func(t *testing.T) {
+		dir := setup(t, "1.20.4", "1.21")
+		opts := PruneOptions{
+			InUse:    map[string][]string{"minecraft_server_1.21.jar": {"survival"}},
+			Rollback: map[string][]string{"minecraft_server_1.20.4.jar": {"survival"}},
+		}
+
+		if _, err := PruneJars(dir, opts); err != nil {
+			t.Fatalf("PruneJars failed: %v", err)
+		}
+		if !exists(dir, "1.20.4") {
+			t.Fatal("Rollback jar should be kept without --force")
+		}
+
+		opts.Force = true
+		if _, err := PruneJars(dir, opts); err != nil {
+			t.Fatalf("PruneJars failed: %v", err)
+		}
+		if exists(dir, "1.20.4") {
+			t.Error("Rollback jar should be removed with --force")
+		}
+		if !exists(dir, "1.21") {
+			t.Error("In-use jar must never be removed")
+		}
+	})
+}
@@ -0,0 +1,291 @@
+package jars
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// LockEntry is a JAR listed in a jar lock file
+type LockEntry struct {
+	Version string `yaml:"version"`
+	Flavor  Flavor `yaml:"flavor,omitempty"`
+	Build   string `yaml:"build,omitempty"`
+	URL     string `yaml:"url"`
+	SHA256  string `yaml:"sha256"`
+}
+
+// Spec returns the JarSpec of the entry
+func (e LockEntry) Spec() JarSpec {
+	flavor := e.Flavor
+	if flavor == "" {
+		flavor = FlavorVanilla
+	}
+	return JarSpec{Flavor: flavor, Version: e.Version, Build: e.Build}
+}
+
+// JarLock is the declarative set of JARs a jars directory should contain
+type JarLock struct {
+	Jars []LockEntry `yaml:"jars"`
+}
+
+// LoadJarLock reads a jar lock file. Files ending in .hcl are read as a
+// Packer variables file whose first list variable (e.g. minecraft_jars)
+// holds the entries; anything else is read as YAML with a top-level "jars" list.
+func LoadJarLock(path string) (*JarLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jar lock file: %w", err)
+	}
+
+	lock := &JarLock{}
+	if strings.HasSuffix(path, ".hcl") {
+		lock.Jars, err = parseHCLJarList(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range lock.Jars {
+		if entry.Version == "" {
+			return nil, fmt.Errorf("%s: entry %d has no version", path, i+1)
+		}
+		flavor, err := ParseFlavor(string(entry.Flavor))
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+		if flavor != FlavorVanilla && entry.Build == "" {
+			return nil, fmt.Errorf("%s: entry %d: %s JARs need a build", path, i+1, flavor)
+		}
+		lock.Jars[i].Flavor = flavor
+		lock.Jars[i].SHA256 = strings.ToLower(entry.SHA256)
+
+		name := lock.Jars[i].Spec().FileName()
+		if seen[name] {
+			return nil, fmt.Errorf("%s: %s is listed more than once", path, name)
+		}
+		seen[name] = true
+	}
+
+	return lock, nil
+}
+
+// Sync actions reported for each JAR
+const (
+	SyncOK         = "ok"
+	SyncDownload   = "download"
+	SyncRedownload = "redownload"
+	SyncRemove     = "remove"
+	SyncUnlisted   = "unlisted"
+	SyncChecksums  = "checksums"
+)
+
+// SyncOptions controls SyncJars
+type SyncOptions struct {
+	// Check only reports drift without changing anything
+	Check bool
+	// Prune removes JARs not listed in the lock file
+	Prune bool
+	// Protected maps JAR file names to the reason they must not be pruned,
+	// e.g. because a world uses them
+	Protected map[string]string
+	// Download configures JAR downloads. Progress is set per JAR by Track.
+	Download DownloadOptions
+	// Track, if set, returns the progress callback for a JAR download
+	Track func(name string) ProgressFunc
+}
+
+// SyncItem is the outcome of syncing a single JAR or checksums.txt
+type SyncItem struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+	// Drift is true when the jars directory did not match the lock file
+	Drift bool   `json:"drift"`
+	Error string `json:"error,omitempty"`
+}
+
+// SyncResult is the outcome of SyncJars
+type SyncResult struct {
+	Items []SyncItem `json:"items"`
+	Drift bool       `json:"drift"`
+}
+
+func (r *SyncResult) add(item SyncItem) {
+	r.Items = append(r.Items, item)
+	if item.Drift {
+		r.Drift = true
+	}
+}
+
+// SyncJars makes jarsDir match lock: missing JARs are downloaded, JARs whose
+// checksum does not match are downloaded again, checksums.txt is rewritten
+// from the lock file and the JARs on disk, and with Prune unlisted JARs are
+// removed. With Check nothing is changed and the result only reports drift.
+// Download failures are recorded per item and returned as one error.
+func SyncJars(ctx context.Context, jarsDir string, lock *JarLock, opts SyncOptions) (*SyncResult, error) {
+	result := &SyncResult{}
+	checksums := make(map[string]string)
+	listed := make(map[string]bool)
+	var failed []string
+
+	for _, entry := range lock.Jars {
+		spec := entry.Spec()
+		name := spec.FileName()
+		jarPath := filepath.Join(jarsDir, name)
+		listed[name] = true
+		item := SyncItem{Name: name, Action: SyncOK}
+
+		if _, err := os.Stat(jarPath); err == nil {
+			actual, err := computeSHA256(jarPath)
+			if err != nil {
+				return result, fmt.Errorf("failed to compute checksum of %s: %w", name, err)
+			}
+			if entry.SHA256 == "" || actual == entry.SHA256 {
+				checksums[name] = actual
+				result.add(item)
+				continue
+			}
+			item.Action = SyncRedownload
+			item.Detail = fmt.Sprintf("checksum mismatch: expected %s, got %s", entry.SHA256, actual)
+		} else {
+			item.Action = SyncDownload
+			item.Detail = "missing"
+		}
+		item.Drift = true
+
+		if opts.Check {
+			if entry.SHA256 != "" {
+				checksums[name] = entry.SHA256
+			}
+			result.add(item)
+			continue
+		}
+
+		if err := syncDownload(ctx, jarsDir, entry, item.Action == SyncRedownload, opts); err != nil {
+			item.Error = err.Error()
+			failed = append(failed, name)
+		} else if sum, err := computeSHA256(jarPath); err == nil {
+			checksums[name] = sum
+		}
+		result.add(item)
+	}
+
+	entries, err := os.ReadDir(jarsDir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read jars directory: %w", err)
+	}
+	existing, err := LoadChecksums(jarsDir)
+	if err != nil {
+		return result, err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, jarSuffix) || listed[name] {
+			continue
+		}
+		jarPath := filepath.Join(jarsDir, name)
+
+		item := SyncItem{Name: name, Action: SyncUnlisted, Detail: "not in lock file"}
+		reason, protected := opts.Protected[name]
+		switch {
+		case !opts.Prune:
+		case protected:
+			item.Detail = "not in lock file, kept: " + reason
+		default:
+			item.Action = SyncRemove
+			item.Drift = true
+		}
+		result.add(item)
+
+		if item.Action == SyncRemove {
+			if !opts.Check {
+				if err := os.Remove(jarPath); err != nil {
+					return result, fmt.Errorf("failed to remove %s: %w", jarPath, err)
+				}
+				log.Info().Str("jar", jarPath).Msg("removed unlisted jar")
+			}
+			continue
+		}
+
+		sum, err := computeSHA256(jarPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to compute checksum of %s: %w", name, err)
+		}
+		checksums[name] = sum
+	}
+
+	if diff := diffChecksums(existing, checksums); diff != "" {
+		result.add(SyncItem{Name: checksumsFileName, Action: SyncChecksums, Detail: diff, Drift: true})
+		if !opts.Check {
+			checksumsMu.Lock()
+			err := writeChecksums(jarsDir, checksums)
+			checksumsMu.Unlock()
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to download %s", strings.Join(failed, ", "))
+	}
+	return result, nil
+}
+
+// syncDownload downloads a lock entry, replacing a JAR with the wrong
+// checksum only once the download is verified, so worlds linked to it keep
+// a JAR if the download fails
+func syncDownload(ctx context.Context, jarsDir string, entry LockEntry, replace bool, opts SyncOptions) error {
+	spec := entry.Spec()
+	if entry.URL == "" {
+		return fmt.Errorf("no url for %s in lock file", spec.Label())
+	}
+
+	dlOpts := opts.Download
+	dlOpts.Replace = replace
+	if opts.Track != nil {
+		dlOpts.Progress = opts.Track(spec.Label())
+	}
+	return DownloadJarContext(ctx, spec, entry.URL, jarsDir, Checksums{SHA256: entry.SHA256}, dlOpts)
+}
+
+// diffChecksums describes how want differs from have, or returns "" if they match
+func diffChecksums(have, want map[string]string) string {
+	var added, changed, dropped int
+	for name, sum := range want {
+		if old, ok := have[name]; !ok {
+			added++
+		} else if old != sum {
+			changed++
+		}
+	}
+	for name := range have {
+		if _, ok := want[name]; !ok {
+			dropped++
+		}
+	}
+	if added+changed+dropped == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, p := range []struct {
+		n    int
+		verb string
+	}{{added, "added"}, {changed, "changed"}, {dropped, "removed"}} {
+		if p.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", p.n, p.verb))
+		}
+	}
+	return "entries " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,261 @@
+package jars
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHCLJars = `# Managed by hand
+minecraft_jars = [
+  {
+    version = "1.21.3"
+    url     = "https://example.com/1.21.3/server.jar"
+    sha256  = "AAAA"
+  },
+  // paper build
+  {
+    version = "1.21.4"
+    flavor  = "paper"
+    build   = "232"
+    url     = "https://example.com/paper.jar"
+    sha256  = "bbbb"
+  }
+]
+`
+
+func TestLoadJarLock(t *testing.T) {
+	t.Run("hcl variables file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "minecraft_jars.auto.pkrvars.hcl")
+		os.WriteFile(path, []byte(testHCLJars), 0644)
+
+		lock, err := LoadJarLock(path)
+		if err != nil {
+			t.Fatalf("LoadJarLock failed: %v", err)
+		}
+		if len(lock.Jars) != 2 {
+			t.Fatalf("Expected 2 jars, got %d", len(lock.Jars))
+		}
+		if lock.Jars[0].Spec().FileName() != "minecraft_server_1.21.3.jar" || lock.Jars[0].SHA256 != "aaaa" {
+			t.Errorf("Unexpected first entry: %+v", lock.Jars[0])
+		}
+		if lock.Jars[1].Spec().FileName() != "paper_server_1.21.4-232.jar" {
+			t.Errorf("Unexpected second entry: %+v", lock.Jars[1])
+		}
+	})
+
+	t.Run("packer vars file in repo", func(t *testing.T) {
+		lock, err := LoadJarLock("../../../packer/minecraft_jars.auto.pkrvars.hcl")
+		if err != nil {
+			t.Fatalf("LoadJarLock failed: %v", err)
+		}
+		if len(lock.Jars) == 0 {
+			t.Fatal("Expected jars in packer vars file")
+		}
+		for _, entry := range lock.Jars {
+			if entry.URL == "" || len(entry.SHA256) != 64 {
+				t.Errorf("Incomplete entry: %+v", entry)
+			}
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jars.yml")
+		os.WriteFile(path, []byte("jars:\n  - version: 1.21.4\n    url: https://example.com/a.jar\n    sha256: cccc\n"), 0644)
+
+		lock, err := LoadJarLock(path)
+		if err != nil {
+			t.Fatalf("LoadJarLock failed: %v", err)
+		}
+		if len(lock.Jars) != 1 || lock.Jars[0].Flavor != FlavorVanilla {
+			t.Errorf("Unexpected lock: %+v", lock.Jars)
+		}
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		tests := map[string]string{
+			"no version":    "jars:\n  - url: x\n",
+			"no build":      "jars:\n  - version: 1.21.4\n    flavor: paper\n",
+			"bad flavor":    "jars:\n  - version: 1.21.4\n    flavor: forge\n",
+			"duplicate jar": "jars:\n  - version: 1.21.4\n  - version: 1.21.4\n",
+		}
+		for name, content := range tests {
+			t.Run(name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "jars.yml")
+				os.WriteFile(path, []byte(content), 0644)
+				if _, err := LoadJarLock(path); err == nil {
+					t.Error("Expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("hcl syntax errors", func(t *testing.T) {
+		for _, content := range []string{`jars = [ { version = "1.21 } ]`, `jars = [ { version = "${var.v}" } ]`, `jars [`} {
+			if _, err := parseHCLJarList([]byte(content)); err == nil {
+				t.Errorf("Expected error for %q", content)
+			}
+		}
+	})
+}
+
+func TestSyncJars(t *testing.T) {
+	contents := map[string]string{
+		"/1.21.3.jar": "jar 1.21.3",
+		"/1.21.4.jar": "jar 1.21.4",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := contents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	lock := &JarLock{Jars: []LockEntry{
+		{Version: "1.21.3", URL: server.URL + "/1.21.3.jar", SHA256: sha256sum("jar 1.21.3")},
+		{Version: "1.21.4", URL: server.URL + "/1.21.4.jar", SHA256: sha256sum("jar 1.21.4")},
+	}}
+	opts := SyncOptions{Download: DownloadOptions{Backoff: time.Millisecond}}
+
+	setup := func(t *testing.T) string {
+		t.Helper()
+		dir := t.TempDir()
+		// 1.21.3 is corrupt, 1.21.4 is missing, 1.20.1 is unlisted
+		createTestJar(t, dir, "1.21.3", "corrupt")
+		createTestJar(t, dir, "1.20.1", "old")
+		os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(sha256sum("gone")+"  minecraft_server_1.19.jar\n"), 0644)
+		return dir
+	}
+
+	actions := func(result *SyncResult) map[string]string {
+		m := make(map[string]string)
+		for _, item := range result.Items {
+			m[item.Name] = item.Action
+		}
+		return m
+	}
+
+	t.Run("check reports drift without changes", func(t *testing.T) {
+		dir := setup(t)
+		checkOpts := opts
+		checkOpts.Check = true
+		checkOpts.Prune = true
+
+		result, err := SyncJars(context.Background(), dir, lock, checkOpts)
+		if err != nil {
+			t.Fatalf("SyncJars failed: %v", err)
+		}
+		if !result.Drift {
+			t.Error("Expected drift")
+		}
+		got := actions(result)
+		want := map[string]string{
+			"minecraft_server_1.21.3.jar": SyncRedownload,
+			"minecraft_server_1.21.4.jar": SyncDownload,
+			"minecraft_server_1.20.1.jar": SyncRemove,
+			"checksums.txt":               SyncChecksums,
+		}
+		for name, action := range want {
+			if got[name] != action {
+				t.Errorf("%s: action %q, want %q", name, got[name], action)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "minecraft_server_1.21.4.jar")); !os.IsNotExist(err) {
+			t.Error("Check mode should not download")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "minecraft_server_1.20.1.jar")); err != nil {
+			t.Error("Check mode should not prune")
+		}
+	})
+
+	t.Run("sync fixes drift", func(t *testing.T) {
+		dir := setup(t)
+
+		if _, err := SyncJars(context.Background(), dir, lock, opts); err != nil {
+			t.Fatalf("SyncJars failed: %v", err)
+		}
+
+		for version, content := range map[string]string{"1.21.3": "jar 1.21.3", "1.21.4": "jar 1.21.4"} {
+			data, _ := os.ReadFile(filepath.Join(dir, "minecraft_server_"+version+".jar"))
+			if string(data) != content {
+				t.Errorf("%s content = %q, want %q", version, data, content)
+			}
+		}
+
+		checksums, _ := LoadChecksums(dir)
+		if len(checksums) != 3 || checksums["minecraft_server_1.20.1.jar"] != sha256sum("old") {
+			t.Errorf("Unexpected checksums: %v", checksums)
+		}
+
+		// A second check finds nothing to do
+		checkOpts := opts
+		checkOpts.Check = true
+		result, err := SyncJars(context.Background(), dir, lock, checkOpts)
+		if err != nil {
+			t.Fatalf("SyncJars check failed: %v", err)
+		}
+		if result.Drift {
+			t.Errorf("Expected no drift after sync: %+v", result.Items)
+		}
+	})
+
+	t.Run("prune keeps protected jars", func(t *testing.T) {
+		dir := setup(t)
+		createTestJar(t, dir, "1.19", "in use")
+
+		pruneOpts := opts
+		pruneOpts.Prune = true
+		pruneOpts.Protected = map[string]string{"minecraft_server_1.19.jar": "in use by [survival]"}
+		if _, err := SyncJars(context.Background(), dir, lock, pruneOpts); err != nil {
+			t.Fatalf("SyncJars failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "minecraft_server_1.20.1.jar")); !os.IsNotExist(err) {
+			t.Error("Unlisted jar should be pruned")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "minecraft_server_1.19.jar")); err != nil {
+			t.Error("Protected jar should be kept")
+		}
+		checksums, _ := LoadChecksums(dir)
+		if _, ok := checksums["minecraft_server_1.20.1.jar"]; ok {
+			t.Error("Pruned jar should be dropped from checksums.txt")
+		}
+		if checksums["minecraft_server_1.19.jar"] != sha256sum("in use") {
+			t.Error("Protected jar should keep its checksum")
+		}
+	})
+
+	t.Run("download failure is reported", func(t *testing.T) {
+		dir := t.TempDir()
+		badLock := &JarLock{Jars: []LockEntry{{Version: "1.18", URL: server.URL + "/missing.jar"}}}
+
+		result, err := SyncJars(context.Background(), dir, badLock, opts)
+		if err == nil || !strings.Contains(err.Error(), "minecraft_server_1.18.jar") {
+			t.Fatalf("Expected download error, got %v", err)
+		}
+		if result.Items[0].Error == "" {
+			t.Error("Expected item error")
+		}
+	})
+
+	t.Run("failed redownload keeps the old jar", func(t *testing.T) {
+		dir := setup(t)
+		badLock := &JarLock{Jars: []LockEntry{{Version: "1.21.3", URL: server.URL + "/missing.jar", SHA256: sha256sum("jar 1.21.3")}}}
+
+		if _, err := SyncJars(context.Background(), dir, badLock, opts); err == nil {
+			t.Fatal("Expected download error")
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "minecraft_server_1.21.3.jar")); string(data) != "corrupt" {
+			t.Errorf("Old jar should be kept, got %q", data)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+// Package java discovers installed Java runtimes and matches them to the
+// Java version a Minecraft server JAR requires.
+package java
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultSearchPaths are the directories scanned for JDK/JRE installations.
+// Each entry may contain glob characters.
+var DefaultSearchPaths = []string{
+	"/usr/lib/jvm/*",
+	"/usr/java/*",
+	"/opt/java/*",
+	"/opt/jdk*",
+}
+
+// Runtime is an installed Java runtime
+type Runtime struct {
+	Home    string `json:"home"`
+	Path    string `json:"path"` // path to bin/java
+	Version string `json:"version"`
+	Major   int    `json:"major"`
+	Vendor  string `json:"vendor,omitempty"`
+}
+
+// Discover returns the Java runtimes found under searchPaths (or
+// DefaultSearchPaths when empty), JAVA_HOME and the java on PATH, sorted by
+// major version. Runtimes reached through different symlinks are listed once.
+func Discover(searchPaths []string) ([]Runtime, error) {
+	if len(searchPaths) == 0 {
+		searchPaths = DefaultSearchPaths
+	}
+
+	var homes []string
+	for _, pattern := range searchPaths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid java search path %s: %w", pattern, err)
+		}
+		homes = append(homes, matches...)
+	}
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		homes = append(homes, javaHome)
+	}
+	if javaPath, err := exec.LookPath("java"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(javaPath); err == nil {
+			homes = append(homes, filepath.Dir(filepath.Dir(resolved)))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var runtimes []Runtime
+	for _, home := range homes {
+		javaPath := filepath.Join(home, "bin", "java")
+		resolved, err := filepath.EvalSymlinks(javaPath)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		rt, err := Inspect(javaPath)
+		if err != nil {
+			continue
+		}
+		runtimes = append(runtimes, *rt)
+	}
+
+	sort.SliceStable(runtimes, func(i, j int) bool {
+		if runtimes[i].Major != runtimes[j].Major {
+			return runtimes[i].Major < runtimes[j].Major
+		}
+		return runtimes[i].Path < runtimes[j].Path
+	})
+
+	return runtimes, nil
+}
+
+// Inspect determines the version of the java binary at javaPath. The JDK
+// "release" file is read when present; otherwise "java -version" is run.
+func Inspect(javaPath string) (*Runtime, error) {
+	if info, err := os.Stat(javaPath); err != nil || info.IsDir() {
+		return nil, fmt.Errorf("java binary not found: %s", javaPath)
+	}
+
+	home := filepath.Dir(filepath.Dir(javaPath))
+	if resolved, err := filepath.EvalSymlinks(javaPath); err == nil {
+		home = filepath.Dir(filepath.Dir(resolved))
+	}
+
+	rt := &Runtime{Home: home, Path: javaPath}
+	if release, err := readRelease(filepath.Join(home, "release")); err == nil && release["JAVA_VERSION"] != "" {
+		rt.Version = release["JAVA_VERSION"]
+		rt.Vendor = release["IMPLEMENTOR"]
+	} else {
+		out, err := exec.Command(javaPath, "-version").CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s -version: %w", javaPath, err)
+		}
+		version, ok := parseVersionOutput(out)
+		if !ok {
+			return nil, fmt.Errorf("could not determine version of %s", javaPath)
+		}
+		rt.Version = version
+	}
+
+	major, err := MajorVersion(rt.Version)
+	if err != nil {
+		return nil, err
+	}
+	rt.Major = major
+
+	return rt, nil
+}
+
+// MajorVersion returns the feature release of a Java version string,
+// handling both "21.0.2" and the legacy "1.8.0_392" scheme
+func MajorVersion(version string) (int, error) {
+	parts := strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_' || r == '+' || r == '-'
+	})
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid java version: %q", version)
+	}
+	first := parts[0]
+	if first == "1" && len(parts) > 1 {
+		first = parts[1]
+	}
+	major, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, fmt.Errorf("invalid java version: %q", version)
+	}
+	return major, nil
+}
+
+// Select picks the runtime for a required major version: an exact match
+// if installed, otherwise the oldest newer runtime. Java runtimes run JARs
+// built for older versions, so any runtime >= required is compatible.
+func Select(runtimes []Runtime, required int) (*Runtime, error) {
+	var best *Runtime
+	for i := range runtimes {
+		rt := &runtimes[i]
+		if rt.Major < required {
+			continue
+		}
+		if best == nil || rt.Major < best.Major {
+			best = rt
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no Java %d+ runtime installed (use 'minecraftctl java list' to see installed runtimes)", required)
+	}
+	return best, nil
+}
+
+var versionOutputPattern = regexp.MustCompile(`version "([^"]+)"`)
+
+// parseVersionOutput extracts the version from "java -version" output such as
+// `openjdk version "21.0.2" 2024-01-16`
+func parseVersionOutput(out []byte) (string, bool) {
+	m := versionOutputPattern.FindSubmatch(out)
+	if m == nil {
+		return "", false
+	}
+	return string(m[1]), true
+}
+
+// readRelease parses a JDK release file of KEY="value" lines
+func readRelease(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return values, scanner.Err()
+}
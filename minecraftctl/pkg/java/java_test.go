@@ -0,0 +1,106 @@
+package java
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeJDK creates a JDK home with a bin/java stub. With a release version
+// the version is read from the release file, otherwise the stub prints it.
+func writeJDK(t *testing.T, dir, name, releaseVersion, scriptVersion string) string {
+	t.Helper()
+	home := filepath.Join(dir, name)
+	os.MkdirAll(filepath.Join(home, "bin"), 0755)
+	script := "#!/bin/sh\nexit 1\n"
+	if scriptVersion != "" {
+		script = fmt.Sprintf("#!/bin/sh\necho 'openjdk version \"%s\" 2024-01-16' >&2\n", scriptVersion)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", "java"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write java stub: %v", err)
+	}
+	if releaseVersion != "" {
+		release := fmt.Sprintf("IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\"%s\"\n", releaseVersion)
+		os.WriteFile(filepath.Join(home, "release"), []byte(release), 0644)
+	}
+	return home
+}
+
+func TestMajorVersion(t *testing.T) {
+	tests := map[string]int{
+		"21.0.2":    21,
+		"17":        17,
+		"1.8.0_392": 8,
+		"22-ea":     22,
+		"11.0.21+9": 11,
+	}
+	for version, want := range tests {
+		got, err := MajorVersion(version)
+		if err != nil {
+			t.Errorf("MajorVersion(%q) failed: %v", version, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("MajorVersion(%q) = %d, want %d", version, got, want)
+		}
+	}
+
+	if _, err := MajorVersion("abc"); err == nil {
+		t.Error("Expected error for invalid version")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Setenv("JAVA_HOME", "")
+	t.Setenv("PATH", t.TempDir())
+
+	dir := t.TempDir()
+	writeJDK(t, dir, "temurin-21", "21.0.2", "")
+	writeJDK(t, dir, "openjdk-17", "", "17.0.9")
+	os.MkdirAll(filepath.Join(dir, "not-a-jdk"), 0755)
+	// Symlinked alias of the Java 21 install should be listed once
+	os.Symlink(filepath.Join(dir, "temurin-21"), filepath.Join(dir, "default-java"))
+
+	runtimes, err := Discover([]string{filepath.Join(dir, "*")})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(runtimes) != 2 {
+		t.Fatalf("Expected 2 runtimes, got %d: %+v", len(runtimes), runtimes)
+	}
+	if runtimes[0].Major != 17 || runtimes[0].Version != "17.0.9" {
+		t.Errorf("runtimes[0] = %+v, want Java 17 from java -version", runtimes[0])
+	}
+	if runtimes[1].Major != 21 || runtimes[1].Vendor != "Eclipse Adoptium" {
+		t.Errorf("runtimes[1] = %+v, want Java 21 from release file", runtimes[1])
+	}
+}
+
+func TestSelect(t *testing.T) {
+	runtimes := []Runtime{
+		{Path: "/jvm/17/bin/java", Major: 17},
+		{Path: "/jvm/21/bin/java", Major: 21},
+		{Path: "/jvm/25/bin/java", Major: 25},
+	}
+
+	t.Run("exact match", func(t *testing.T) {
+		rt, err := Select(runtimes, 21)
+		if err != nil || rt.Major != 21 {
+			t.Errorf("Select(21) = %+v, %v; want Java 21", rt, err)
+		}
+	})
+
+	t.Run("oldest newer runtime", func(t *testing.T) {
+		rt, err := Select(runtimes, 18)
+		if err != nil || rt.Major != 21 {
+			t.Errorf("Select(18) = %+v, %v; want Java 21", rt, err)
+		}
+	})
+
+	t.Run("none available", func(t *testing.T) {
+		if _, err := Select(runtimes, 26); err == nil {
+			t.Error("Expected error when no runtime is new enough")
+		}
+	})
+}
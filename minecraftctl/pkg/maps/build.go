@@ -3,35 +3,34 @@ package maps
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/paul/minecraftctl/pkg/config"
 	"github.com/paul/minecraftctl/pkg/lock"
+	"github.com/paul/minecraftctl/pkg/region"
 	"github.com/rs/zerolog/log"
 )
 
-const (
-	DefaultUnminedPath = "/opt/unmined/unmined-cli"
-)
-
-// Builder handles building maps using uNmINeD
+// Builder builds the maps of a world's map-config.yml with its renderers
 type Builder struct {
-	unminedPath string
-	worldsDir   string
-	mapsDir     string
+	worldsDir string
+	mapsDir   string
+	// renderers are the map backends by the name map-config.yml selects
+	// them with
+	renderers map[string]Renderer
 }
 
 // NewBuilder creates a new map builder
 func NewBuilder() *Builder {
 	cfg := config.Get()
 	return &Builder{
-		unminedPath: DefaultUnminedPath,
-		worldsDir:   cfg.WorldsDir,
-		mapsDir:     cfg.MapsDir,
+		worldsDir: cfg.WorldsDir,
+		mapsDir:   cfg.MapsDir,
+		renderers: map[string]Renderer{
+			"unmined":          NewUnminedRenderer(cfg.UnminedPath),
+			NativeRendererName: NewNativeRenderer(),
+		},
 	}
 }
 
@@ -45,11 +44,16 @@ type BuildOptions struct {
 	LockTimeout time.Duration // Lock timeout (0 = block forever)
 	NoLock      bool          // Disable file locking
 	NonBlocking bool          // Exit immediately if lock is held
-	LogLevel    string        // unmined log level (verbose, debug, information, warning, error, fatal)
+	LogLevel    string        // Renderer log level (unmined: verbose, debug, information, warning, error, fatal)
+	KeepGoing   bool          // Build the remaining maps after a map fails
 }
 
-// Build builds maps for a world according to its map-config.yml
-func (b *Builder) Build(opts BuildOptions) error {
+// Build builds maps for a world according to its map-config.yml. It
+// returns a report of every map, also written to build-report.json in the
+// world's maps directory, and an error if any map or range failed. Unless
+// KeepGoing is set the maps after a failed one are skipped. The report is
+// nil if the build did not start.
+func (b *Builder) Build(opts BuildOptions) (*BuildReport, error) {
 	// Acquire lock if not disabled
 	var fileLock *lock.FileLock
 	if !opts.NoLock {
@@ -67,9 +71,9 @@ func (b *Builder) Build(opts BuildOptions) error {
 		if err := fileLock.LockWithOptions(lockOpts); err != nil {
 			if opts.NonBlocking {
 				log.Info().Msg("Another map build is running, skipping (non-blocking)")
-				return nil
+				return nil, nil
 			}
-			return fmt.Errorf("failed to acquire lock: %w", err)
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
 		}
 
 		defer func() {
@@ -82,22 +86,18 @@ func (b *Builder) Build(opts BuildOptions) error {
 	worldPath := filepath.Join(b.worldsDir, opts.WorldName)
 	mapConfig, err := config.LoadMapConfig(worldPath)
 	if err != nil {
-		return fmt.Errorf("failed to load map config: %w", err)
+		return nil, fmt.Errorf("failed to load map config: %w", err)
 	}
 
 	worldDir := filepath.Join(worldPath, "world")
 	levelDatPath := filepath.Join(worldDir, "level.dat")
-
-	// Get world mtime for up-to-date checks
-	worldStat, err := os.Stat(levelDatPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat level.dat: %w", err)
+	if _, err := os.Stat(levelDatPath); err != nil {
+		return nil, fmt.Errorf("failed to stat level.dat: %w", err)
 	}
-	worldMtime := worldStat.ModTime().Unix()
 
 	worldMapsDir := filepath.Join(b.mapsDir, opts.WorldName)
 	if err := os.MkdirAll(worldMapsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create maps directory: %w", err)
+		return nil, fmt.Errorf("failed to create maps directory: %w", err)
 	}
 
 	// Default log level to warning if not specified
@@ -105,60 +105,122 @@ func (b *Builder) Build(opts BuildOptions) error {
 		opts.LogLevel = "warning"
 	}
 
+	report := &BuildReport{World: opts.WorldName, Started: time.Now()}
+	stopped := false
+
 	// Build each map
 	for _, mapDef := range mapConfig.Maps {
 		if opts.MapName != "" && mapDef.Name != opts.MapName {
 			continue
 		}
 
-		if err := b.buildMap(mapDef, mapConfig.Defaults, worldDir, worldMapsDir, worldMtime, opts); err != nil {
-			log.Error().Err(err).Str("map", mapDef.Name).Msg("failed to build map")
+		mapReport := MapReport{Name: mapDef.Name}
+		if stopped {
+			mapReport.Status = BuildStatusSkipped
+			report.Maps = append(report.Maps, mapReport)
 			continue
 		}
+
+		start := time.Now()
+		if err := b.buildMap(mapDef, mapConfig.Defaults, worldDir, worldMapsDir, opts, &mapReport); err != nil {
+			log.Error().Err(err).Str("map", mapDef.Name).Msg("failed to build map")
+			mapReport.Status = BuildStatusFailed
+			mapReport.Error = err.Error()
+			for i := range mapReport.Ranges {
+				if mapReport.Ranges[i].Status == BuildStatusUpToDate {
+					mapReport.Ranges[i].Status = BuildStatusSkipped
+				}
+			}
+		} else if err := b.writeMarkers(opts.WorldName, mapDef, worldDir, worldMapsDir); err != nil {
+			// Markers change without any chunk changing, so they are
+			// written on every build, also of maps that are up to date
+			log.Warn().Err(err).Str("map", mapDef.Name).Msg("failed to write markers")
+		}
+		mapReport.Duration = time.Since(start)
+
+		outputSubdir := mapDef.OutputSubdir
+		if outputSubdir == "" {
+			outputSubdir = mapDef.Name
+		}
+		if err := mapReport.countTiles(filepath.Join(worldMapsDir, outputSubdir), mapDef, mapConfig.Defaults); err != nil {
+			log.Warn().Err(err).Str("map", mapDef.Name).Msg("failed to count tiles")
+		}
+
+		report.Maps = append(report.Maps, mapReport)
+		if mapReport.Failed() && !opts.KeepGoing {
+			stopped = true
+		}
 	}
 
-	return nil
+	report.Duration = time.Since(report.Started)
+	if err := writeBuildReport(worldMapsDir, report); err != nil {
+		log.Warn().Err(err).Msg("failed to write build report")
+	}
+	return report, report.Err()
 }
 
+// buildMap renders a map. Without Force only the areas whose chunks changed
+// since the last render are rendered into the existing tiles; see planRender.
 func (b *Builder) buildMap(
 	mapDef config.MapDefinition,
 	defaults config.MapDefaults,
 	worldDir string,
 	worldMapsDir string,
-	worldMtime int64,
 	opts BuildOptions,
+	report *MapReport,
 ) error {
-	// Check if rebuild is needed
-	if !opts.Force {
-		outputSubdir := mapDef.OutputSubdir
-		if outputSubdir == "" {
-			outputSubdir = mapDef.Name
-		}
-		mapOutput := filepath.Join(worldMapsDir, outputSubdir)
-		manifestPath := filepath.Join(mapOutput, "manifest.json")
-
-		if manifest, err := readManifest(manifestPath); err == nil {
-			if manifest.LastRenderedEpoch >= worldMtime {
-				log.Info().Str("map", mapDef.Name).Msg("map is up to date, skipping")
-				return nil
-			}
+	renderer, rendererName, err := b.renderer(mapDef)
+	if err != nil {
+		return err
+	}
+	report.Renderer = rendererName
+	caps := renderer.Capabilities()
+	if !caps.WebMap {
+		return fmt.Errorf("renderer %s cannot render web maps", rendererName)
+	}
+	for _, r := range mapDef.Ranges {
+		rangeReport := report.rangeReport(r.Name)
+		if !caps.Areas {
+			rangeReport.Status = BuildStatusSkipped
 		}
 	}
 
-	// Verify dimension exists
-	dimDir := worldDir
-	switch mapDef.Dimension {
-	case "nether", "-1":
-		dimDir = filepath.Join(worldDir, "DIM-1")
-	case "end", "1":
-		dimDir = filepath.Join(worldDir, "DIM1")
+	outputSubdir := mapDef.OutputSubdir
+	if outputSubdir == "" {
+		outputSubdir = mapDef.Name
 	}
+	mapOutput := filepath.Join(worldMapsDir, outputSubdir)
 
-	regionDir := filepath.Join(dimDir, "region")
+	// Verify dimension exists
+	regionDir := filepath.Join(dimensionDir(worldDir, mapDef.Dimension), "region")
 	if _, err := os.Stat(regionDir); os.IsNotExist(err) {
 		return fmt.Errorf("dimension %s has no region data", mapDef.Dimension)
 	}
 
+	// Scan before rendering so chunks saved during the render are picked
+	// up by the next build
+	regions, err := scanRegions(regionDir)
+	if err != nil {
+		return err
+	}
+
+	plan := renderPlan{Full: true, Reason: "forced"}
+	if !opts.Force {
+		manifest, err := readManifest(filepath.Join(mapOutput, "manifest.json"))
+		if err != nil {
+			manifest = nil
+		}
+		plan = planRender(regionDir, manifest, regions)
+		if !plan.Full && len(plan.Areas) == 0 {
+			log.Info().Str("map", mapDef.Name).Msg("map is up to date, skipping")
+			report.Status = BuildStatusUpToDate
+			return nil
+		}
+		if !plan.Full && !caps.Areas {
+			plan = renderPlan{Full: true, Reason: fmt.Sprintf("%s; renderer %s cannot render areas", plan.Reason, rendererName)}
+		}
+	}
+
 	// Determine zoom levels
 	zoomout := defaults.Zoomout
 	if mapDef.Zoomout != nil {
@@ -169,176 +231,140 @@ func (b *Builder) buildMap(
 		zoomin = *mapDef.Zoomin
 	}
 
-	// Build base command
-	outputSubdir := mapDef.OutputSubdir
-	if outputSubdir == "" {
-		outputSubdir = mapDef.Name
+	job := MapJob{
+		WorldDir:        worldDir,
+		Dimension:       mapDef.Dimension,
+		Output:          mapOutput,
+		ImageFormat:     defaults.ImageFormat,
+		ChunkProcessors: defaults.ChunkProcessors,
+		Zoomout:         zoomout,
+		Zoomin:          zoomin,
+		MaxZoomin:       zoomin,
+		Options:         mapDef.Options,
+		LogLevel:        opts.LogLevel,
 	}
-	mapOutput := filepath.Join(worldMapsDir, outputSubdir)
-
-	baseArgs := []string{
-		"web", "render",
-		"--world", worldDir,
-		"--dimension", mapDef.Dimension,
-		"--output", mapOutput,
-		"--imageformat", defaults.ImageFormat,
-		"--chunkprocessors", strconv.Itoa(defaults.ChunkProcessors),
-		"--log-level", opts.LogLevel,
-		"--zoomout", strconv.Itoa(zoomout),
-		"--zoomin", strconv.Itoa(zoomin),
+	if caps.Areas {
+		for _, r := range mapDef.Ranges {
+			if r.Zoomin != nil && *r.Zoomin > job.MaxZoomin {
+				job.MaxZoomin = *r.Zoomin
+			}
+		}
+	} else if len(mapDef.Ranges) > 0 {
+		log.Warn().Str("map", mapDef.Name).Str("renderer", rendererName).Msg("renderer cannot render areas, skipping ranges")
 	}
 
-	// Add optional map options
-	baseArgs = b.addMapOptions(baseArgs, mapDef.Options)
+	report.Reason = plan.Reason
+	if !plan.Full {
+		report.Status = BuildStatusUpdated
+		log.Info().Str("map", mapDef.Name).Str("renderer", rendererName).Str("reason", plan.Reason).
+			Int("areas", len(plan.Areas)).Msg("rendering changed areas")
+		rangesFailed := false
+		for _, area := range plan.Areas {
+			// As in full renders, ranges first so the base map's tiles win
+			// at the zoom levels they share
+			if !b.buildRanges(renderer, job, mapDef, &area, report) {
+				rangesFailed = true
+			}
+			log.Info().Str("map", mapDef.Name).Str("area", area.String()).Msg("rendering area")
+			if err := renderer.RenderArea(job, area); err != nil {
+				return fmt.Errorf("failed to render area %s: %w", area, err)
+			}
+		}
+		return b.finishRender(mapOutput, opts.WorldName, mapDef, regions, rangesFailed)
+	}
 
 	// Build ranges FIRST so their properties don't overwrite the base map bounds
 	// The base map render must come last to set the correct full-world bounds
-	// in unmined.map.properties.js
-	//
-	// Track max zoomin across all ranges so we can patch the properties file
-	maxZoomin := zoomin
-	for _, r := range mapDef.Ranges {
-		rangeZoomin := zoomin
-		if r.Zoomin != nil {
-			rangeZoomin = *r.Zoomin
-		}
-		if rangeZoomin > maxZoomin {
-			maxZoomin = rangeZoomin
-		}
-		if err := b.buildRange(r, mapDef, defaults, worldDir, mapOutput, zoomout, zoomin, opts.LogLevel); err != nil {
-			log.Error().Err(err).Str("range", r.Name).Msg("failed to build range")
-			continue
-		}
+	report.Status = BuildStatusRendered
+	rangesFailed := false
+	if caps.Areas {
+		rangesFailed = !b.buildRanges(renderer, job, mapDef, nil, report)
 	}
 
 	log.Info().Str("map", mapDef.Name).Str("dimension", mapDef.Dimension).
-		Strs("args", baseArgs).Msg("rendering base map")
+		Str("renderer", rendererName).Str("reason", plan.Reason).Msg("rendering base map")
 
 	// Build base map LAST so it sets the correct full-world bounds
-	cmd := exec.Command(b.unminedPath, baseArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := renderer.RenderMap(job); err != nil {
 		return fmt.Errorf("failed to render base map: %w", err)
 	}
 
-	// Patch maxZoom in properties file if ranges have higher zoom levels
-	if maxZoomin > zoomin {
-		if err := b.patchPropertiesMaxZoom(mapOutput, maxZoomin); err != nil {
-			log.Warn().Err(err).Msg("failed to patch maxZoom in properties file")
-		}
-	}
-
-	// Update manifest
-	return b.updateManifest(mapOutput, opts.WorldName, mapDef.Name, mapDef.Dimension)
+	return b.finishRender(mapOutput, opts.WorldName, mapDef, regions, rangesFailed)
 }
 
-func (b *Builder) buildRange(
-	r config.MapRange,
-	mapDef config.MapDefinition,
-	defaults config.MapDefaults,
-	worldDir string,
-	mapOutput string,
-	defaultZoomout int,
-	defaultZoomin int,
-	logLevel string,
-) error {
-	// Calculate area bounds
-	x1 := r.Center[0] - r.Radius
-	z1 := r.Center[1] - r.Radius
-	x2 := r.Center[0] + r.Radius
-	z2 := r.Center[1] + r.Radius
-
-	if x1 >= x2 || z1 >= z2 {
-		return fmt.Errorf("invalid range bounds")
-	}
-
-	areaArg := fmt.Sprintf("--area=b((%d,%d),(%d,%d))", x1, z1, x2, z2)
-
-	zoomout := defaultZoomout
-	if r.Zoomout != nil {
-		zoomout = *r.Zoomout
-	}
-	zoomin := defaultZoomin
-	if r.Zoomin != nil {
-		zoomin = *r.Zoomin
-	}
-
-	args := []string{
-		"web", "render",
-		"--world", worldDir,
-		"--dimension", mapDef.Dimension,
-		"--output", mapOutput,
-		"--imageformat", defaults.ImageFormat,
-		"--chunkprocessors", strconv.Itoa(defaults.ChunkProcessors),
-		"--log-level", logLevel,
-		"--zoomout", strconv.Itoa(zoomout),
-		"--zoomin", strconv.Itoa(zoomin),
-		areaArg,
+// finishRender records the rendered region files in the manifest. After a
+// range failed to render the manifest keeps the previous region files, so
+// the next build renders the same areas again.
+func (b *Builder) finishRender(mapOutput, worldName string, mapDef config.MapDefinition, regions map[string]int64, rangesFailed bool) error {
+	if rangesFailed {
+		log.Warn().Str("map", mapDef.Name).Msg("ranges failed to render, keeping the manifest's region state to render them again")
+		return nil
 	}
-
-	// Apply map-specific options (gndxray, topY, bottomY, shadows, etc.)
-	args = b.addMapOptions(args, mapDef.Options)
-
-	log.Info().Str("range", r.Name).Strs("args", args).Msg("rendering range")
-
-	cmd := exec.Command(b.unminedPath, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return b.updateManifest(mapOutput, worldName, mapDef.Name, mapDef.Dimension, regions)
 }
 
-func (b *Builder) addMapOptions(args []string, opts config.MapOptions) []string {
-	if opts.TopY != nil {
-		args = append(args, "--topY", strconv.Itoa(*opts.TopY))
-	}
-	if opts.BottomY != nil {
-		args = append(args, "--bottomY", strconv.Itoa(*opts.BottomY))
-	}
-	if opts.GndXray != nil && *opts.GndXray {
-		args = append(args, "--gndxray", "true")
-	}
-	if opts.Night != nil && *opts.Night {
-		args = append(args, "--night", "true")
+// buildRanges renders a map's ranges at their own zoom levels. With an area
+// only the part of each range inside it is rendered. Failed ranges are
+// logged and recorded in the report, and the other ranges still rendered.
+// It reports whether every render succeeded; an invalid range is not
+// rendered and does not count, as rendering again cannot fix it.
+func (b *Builder) buildRanges(renderer Renderer, job MapJob, mapDef config.MapDefinition, area *region.Box, report *MapReport) bool {
+	ok := true
+	status := BuildStatusRendered
+	if area != nil {
+		status = BuildStatusUpdated
 	}
-	if opts.Shadows != nil {
-		var shadowArg string
-		switch v := opts.Shadows.(type) {
-		case bool:
-			if v {
-				shadowArg = "true"
-			} else {
-				shadowArg = "false"
-			}
-		case string:
-			shadowArg = v
+	for _, r := range mapDef.Ranges {
+		rangeReport := report.rangeReport(r.Name)
+		box, err := rangeBox(r)
+		if err != nil {
+			log.Error().Err(err).Str("range", r.Name).Msg("failed to build range")
+			rangeReport.Status = BuildStatusFailed
+			rangeReport.Error = err.Error()
+			continue
 		}
-		if shadowArg != "" {
-			args = append(args, "--shadows", shadowArg)
+		if area != nil {
+			overlap, ok := area.Intersect(box)
+			if !ok {
+				continue
+			}
+			box = overlap
 		}
-	}
-	return args
-}
 
-// patchPropertiesMaxZoom updates the maxZoom value in unmined.map.properties.js
-// This is needed because ranges may have higher zoom levels than the base map,
-// but the base map render (which runs last) overwrites the properties file.
-func (b *Builder) patchPropertiesMaxZoom(mapOutput string, maxZoom int) error {
-	propsPath := filepath.Join(mapOutput, "unmined.map.properties.js")
+		rangeJob := job
+		if r.Zoomout != nil {
+			rangeJob.Zoomout = *r.Zoomout
+		}
+		if r.Zoomin != nil {
+			rangeJob.Zoomin = *r.Zoomin
+		}
 
-	data, err := os.ReadFile(propsPath)
-	if err != nil {
-		return fmt.Errorf("failed to read properties file: %w", err)
+		log.Info().Str("range", r.Name).Str("area", box.String()).Msg("rendering range")
+		start := time.Now()
+		err = renderer.RenderArea(rangeJob, box)
+		rangeReport.Duration += time.Since(start)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Str("range", r.Name).Msg("failed to build range")
+			rangeReport.Status = BuildStatusFailed
+			rangeReport.Error = err.Error()
+			ok = false
+		case rangeReport.Status != BuildStatusFailed:
+			rangeReport.Status = status
+		}
 	}
+	return ok
+}
 
-	// Replace maxZoom value
-	re := regexp.MustCompile(`maxZoom:\s*\d+`)
-	newData := re.ReplaceAll(data, []byte(fmt.Sprintf("maxZoom: %d", maxZoom)))
+// rangeBox returns the area a range covers
+func rangeBox(r config.MapRange) (region.Box, error) {
+	x1 := r.Center[0] - r.Radius
+	z1 := r.Center[1] - r.Radius
+	x2 := r.Center[0] + r.Radius
+	z2 := r.Center[1] + r.Radius
 
-	if err := os.WriteFile(propsPath, newData, 0644); err != nil {
-		return fmt.Errorf("failed to write properties file: %w", err)
+	if x1 >= x2 || z1 >= z2 {
+		return region.Box{}, fmt.Errorf("invalid range bounds")
 	}
-
-	log.Info().Int("maxZoom", maxZoom).Msg("patched maxZoom in properties file")
-	return nil
+	return region.Box{MinX: x1, MinZ: z1, MaxX: x2, MaxZ: z2}, nil
 }
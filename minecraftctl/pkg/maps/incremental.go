@@ -0,0 +1,117 @@
+package maps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// maxIncrementalAreas is the number of changed regions above which a full
+// render is cheaper than one area render each
+const maxIncrementalAreas = 16
+
+// renderPlan is what a map build has to render
+type renderPlan struct {
+	// Full re-renders the whole map; otherwise only Areas are rendered and
+	// an empty Areas means the map is up to date
+	Full   bool
+	Areas  []region.Box
+	Reason string
+}
+
+// scanRegions returns the modification times of a dimension's region files
+// in Unix nanoseconds, keyed by file name
+func scanRegions(regionDir string) (map[string]int64, error) {
+	entries, err := os.ReadDir(regionDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region directory: %w", err)
+	}
+
+	regions := make(map[string]int64)
+	for _, entry := range entries {
+		if _, _, ok := region.ParseFileName(entry.Name()); !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		regions[entry.Name()] = info.ModTime().UnixNano()
+	}
+	return regions, nil
+}
+
+// planRender compares the region files of the last render, recorded in the
+// map's manifest, with the current ones. Changed files are narrowed to their
+// chunks modified since, by the timestamps in the region header. Added or
+// removed region files change the map's extent, which only a full render
+// updates.
+func planRender(regionDir string, manifest *Manifest, current map[string]int64) renderPlan {
+	if manifest == nil || manifest.Regions == nil {
+		return renderPlan{Full: true, Reason: "no region state from a previous render"}
+	}
+	for name := range manifest.Regions {
+		if _, ok := current[name]; !ok {
+			return renderPlan{Full: true, Reason: "region files removed"}
+		}
+	}
+
+	var areas []region.Box
+	for name, mtime := range current {
+		prev, ok := manifest.Regions[name]
+		if !ok {
+			return renderPlan{Full: true, Reason: "region files added"}
+		}
+		if mtime == prev {
+			continue
+		}
+		rx, rz, _ := region.ParseFileName(name)
+		areas = append(areas, changedArea(filepath.Join(regionDir, name), rx, rz, prev))
+	}
+
+	if len(areas) > maxIncrementalAreas {
+		return renderPlan{Full: true, Reason: fmt.Sprintf("%d regions changed", len(areas))}
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		if areas[i].MinZ != areas[j].MinZ {
+			return areas[i].MinZ < areas[j].MinZ
+		}
+		return areas[i].MinX < areas[j].MinX
+	})
+	return renderPlan{Areas: areas, Reason: fmt.Sprintf("%d regions changed", len(areas))}
+}
+
+// changedArea returns the blocks covering the chunks of a region file saved
+// at or after since, in Unix nanoseconds. If the header cannot be read or no
+// chunk qualifies, the whole region is returned.
+func changedArea(path string, rx, rz int, since int64) region.Box {
+	whole := region.RegionBox(rx, rz)
+	timestamps, err := region.ReadTimestamps(path)
+	if err != nil {
+		return whole
+	}
+
+	// Chunk timestamps are whole seconds
+	sinceSec := since / 1e9
+	var area *region.Box
+	for i, ts := range timestamps {
+		if ts == 0 || int64(ts) < sinceSec {
+			continue
+		}
+		cx := rx*region.RegionChunks + i%region.RegionChunks
+		cz := rz*region.RegionChunks + i/region.RegionChunks
+		chunk := region.Box{MinX: cx * 16, MinZ: cz * 16, MaxX: cx*16 + 15, MaxZ: cz*16 + 15}
+		if area == nil {
+			area = &chunk
+		} else {
+			*area = area.Union(chunk)
+		}
+	}
+	if area == nil {
+		return whole
+	}
+	return *area
+}
@@ -0,0 +1,86 @@
+package maps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// writeRegion writes a region file with chunks at the given indexes saved at ts
+func writeRegion(t *testing.T, path string, ts time.Time, indexes ...int) {
+	t.Helper()
+	f := &region.File{}
+	for _, i := range indexes {
+		f.Chunks[i] = &region.Chunk{Timestamp: uint32(ts.Unix()), Data: []byte{2, 0x78, 0x9c}}
+	}
+	if err := f.Write(path); err != nil {
+		t.Fatalf("Failed to write region: %v", err)
+	}
+	os.Chtimes(path, ts, ts)
+}
+
+func TestPlanRender(t *testing.T) {
+	regionDir := t.TempDir()
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), old, 0, 1)
+	writeRegion(t, filepath.Join(regionDir, "r.-1.0.mca"), old, 0)
+	// The files were last written a minute after their chunks were saved;
+	// chunks saved in the same second are treated as changed
+	saved := old.Add(time.Minute)
+	os.Chtimes(filepath.Join(regionDir, "r.0.0.mca"), saved, saved)
+	os.Chtimes(filepath.Join(regionDir, "r.-1.0.mca"), saved, saved)
+
+	regions, err := scanRegions(regionDir)
+	if err != nil {
+		t.Fatalf("scanRegions failed: %v", err)
+	}
+	manifest := &Manifest{Regions: regions}
+
+	t.Run("no state", func(t *testing.T) {
+		if plan := planRender(regionDir, &Manifest{}, regions); !plan.Full {
+			t.Errorf("Expected full render without region state, got %+v", plan)
+		}
+		if plan := planRender(regionDir, nil, regions); !plan.Full {
+			t.Errorf("Expected full render without manifest, got %+v", plan)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		if plan := planRender(regionDir, manifest, regions); plan.Full || len(plan.Areas) != 0 {
+			t.Errorf("Expected nothing to render, got %+v", plan)
+		}
+	})
+
+	t.Run("changed chunk", func(t *testing.T) {
+		// Chunk (1,0) saved again; chunk (0,0) keeps its old timestamp
+		f, _ := region.Read(filepath.Join(regionDir, "r.0.0.mca"))
+		now := time.Now().Truncate(time.Second)
+		f.Chunks[1].Timestamp = uint32(now.Unix())
+		f.Write(filepath.Join(regionDir, "r.0.0.mca"))
+		current, _ := scanRegions(regionDir)
+
+		plan := planRender(regionDir, manifest, current)
+		if plan.Full || len(plan.Areas) != 1 {
+			t.Fatalf("Expected one area, got %+v", plan)
+		}
+		if want := (region.Box{MinX: 16, MinZ: 0, MaxX: 31, MaxZ: 15}); plan.Areas[0] != want {
+			t.Errorf("Area = %v, want the changed chunk %v", plan.Areas[0], want)
+		}
+	})
+
+	t.Run("added and removed regions", func(t *testing.T) {
+		added := map[string]int64{"r.5.5.mca": 1}
+		for name, mtime := range regions {
+			added[name] = mtime
+		}
+		if plan := planRender(regionDir, manifest, added); !plan.Full {
+			t.Errorf("Expected full render for added region, got %+v", plan)
+		}
+		if plan := planRender(regionDir, manifest, map[string]int64{"r.0.0.mca": regions["r.0.0.mca"]}); !plan.Full {
+			t.Errorf("Expected full render for removed region, got %+v", plan)
+		}
+	})
+}
@@ -16,6 +16,10 @@ type Manifest struct {
 	Path              string `json:"path,omitempty"`
 	LastRendered      string `json:"last_rendered"`
 	LastRenderedEpoch int64  `json:"last_rendered_epoch"`
+	// Regions holds the modification times of the dimension's region files
+	// at the last render, in Unix nanoseconds keyed by file name, to find
+	// what changed since
+	Regions map[string]int64 `json:"regions,omitempty"`
 }
 
 // readManifest reads a manifest.json file
@@ -34,7 +38,7 @@ func readManifest(path string) (*Manifest, error) {
 }
 
 // updateManifest updates or creates a manifest.json file
-func (b *Builder) updateManifest(mapOutput, worldName, mapName, dimension string) error {
+func (b *Builder) updateManifest(mapOutput, worldName, mapName, dimension string, regions map[string]int64) error {
 	manifestPath := filepath.Join(mapOutput, "manifest.json")
 
 	now := time.Now()
@@ -44,6 +48,11 @@ func (b *Builder) updateManifest(mapOutput, worldName, mapName, dimension string
 		Dimension:         dimension,
 		LastRendered:      now.Format(time.RFC3339),
 		LastRenderedEpoch: now.Unix(),
+		Regions:           regions,
+	}
+	// Keep the path written by the manifest builder
+	if existing, err := readManifest(manifestPath); err == nil {
+		manifest.Path = existing.Path
 	}
 
 	data, err := json.MarshalIndent(manifest, "", "  ")
@@ -123,6 +123,12 @@ func (mb *ManifestBuilder) BuildManifests(worldName string, opts ManifestOptions
 		}
 
 		manifestPath := filepath.Join(mapOutput, "manifest.json")
+		// Keep the render state map builds use to find changed regions
+		if existing, err := readManifest(manifestPath); err == nil && existing.LastRenderedEpoch != 0 {
+			manifest.LastRendered = existing.LastRendered
+			manifest.LastRenderedEpoch = existing.LastRenderedEpoch
+			manifest.Regions = existing.Regions
+		}
 		if err := mb.writeManifest(manifestPath, manifest); err != nil {
 			log.Error().Err(err).Str("map", mapDef.Name).Msg("failed to write map manifest")
 			continue
@@ -144,13 +144,17 @@ func TestReadManifest(t *testing.T) {
 
 func TestBuilderStruct(t *testing.T) {
 	b := &Builder{
-		unminedPath: "/custom/unmined",
-		worldsDir:   "/srv/worlds",
-		mapsDir:     "/srv/maps",
+		worldsDir: "/srv/worlds",
+		mapsDir:   "/srv/maps",
+		renderers: map[string]Renderer{"unmined": NewUnminedRenderer("/custom/unmined")},
 	}
 
-	if b.unminedPath != "/custom/unmined" {
-		t.Errorf("unminedPath = %q, unexpected", b.unminedPath)
+	r, name, err := b.renderer(config.MapDefinition{})
+	if err != nil || name != DefaultRenderer || r.(*UnminedRenderer).Path != "/custom/unmined" {
+		t.Errorf("renderer() = %v, %q, %v, want the custom unmined", r, name, err)
+	}
+	if _, _, err := b.renderer(config.MapDefinition{Renderer: "bluemap"}); err == nil {
+		t.Error("Expected error for unknown renderer")
 	}
 	if b.worldsDir != "/srv/worlds" {
 		t.Errorf("worldsDir = %q, unexpected", b.worldsDir)
@@ -188,12 +192,10 @@ func TestBuildOptionsStruct(t *testing.T) {
 }
 
 func TestAddMapOptions(t *testing.T) {
-	b := &Builder{}
-
 	t.Run("empty options", func(t *testing.T) {
 		args := []string{"web", "render"}
 		opts := config.MapOptions{}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 2 {
 			t.Errorf("Expected no additional args, got %v", result)
@@ -204,7 +206,7 @@ func TestAddMapOptions(t *testing.T) {
 		args := []string{"web", "render"}
 		topY := 64
 		opts := config.MapOptions{TopY: &topY}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -218,7 +220,7 @@ func TestAddMapOptions(t *testing.T) {
 		args := []string{"web", "render"}
 		bottomY := -64
 		opts := config.MapOptions{BottomY: &bottomY}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -232,7 +234,7 @@ func TestAddMapOptions(t *testing.T) {
 		args := []string{"web", "render"}
 		gndxray := true
 		opts := config.MapOptions{GndXray: &gndxray}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -246,7 +248,7 @@ func TestAddMapOptions(t *testing.T) {
 		args := []string{"web", "render"}
 		gndxray := false
 		opts := config.MapOptions{GndXray: &gndxray}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		// GndXray false should not add the arg
 		if len(result) != 2 {
@@ -258,7 +260,7 @@ func TestAddMapOptions(t *testing.T) {
 		args := []string{"web", "render"}
 		night := true
 		opts := config.MapOptions{Night: &night}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -271,7 +273,7 @@ func TestAddMapOptions(t *testing.T) {
 	t.Run("shadows bool true", func(t *testing.T) {
 		args := []string{"web", "render"}
 		opts := config.MapOptions{Shadows: true}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -284,7 +286,7 @@ func TestAddMapOptions(t *testing.T) {
 	t.Run("shadows bool false", func(t *testing.T) {
 		args := []string{"web", "render"}
 		opts := config.MapOptions{Shadows: false}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -297,7 +299,7 @@ func TestAddMapOptions(t *testing.T) {
 	t.Run("shadows string 3d", func(t *testing.T) {
 		args := []string{"web", "render"}
 		opts := config.MapOptions{Shadows: "3d"}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if len(result) != 4 {
 			t.Fatalf("Expected 4 args, got %d", len(result))
@@ -310,7 +312,7 @@ func TestAddMapOptions(t *testing.T) {
 	t.Run("shadows string 2d", func(t *testing.T) {
 		args := []string{"web", "render"}
 		opts := config.MapOptions{Shadows: "2d"}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		if result[2] != "--shadows" || result[3] != "2d" {
 			t.Errorf("Shadows string 2d not added correctly: %v", result)
@@ -328,7 +330,7 @@ func TestAddMapOptions(t *testing.T) {
 			GndXray: &gndxray,
 			Shadows: "3d",
 		}
-		result := b.addMapOptions(args, opts)
+		result := addMapOptions(args, opts)
 
 		// Should have: web, render, --topY, 64, --bottomY, -64, --gndxray, true, --shadows, 3d
 		if len(result) != 10 {
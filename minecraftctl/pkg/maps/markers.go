@@ -0,0 +1,460 @@
+package maps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tnze/go-mc/save"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/nbt"
+	"github.com/paul/minecraftctl/pkg/region"
+	"github.com/rs/zerolog/log"
+)
+
+// Marker kinds
+const (
+	MarkerSpawn  = "spawn"
+	MarkerPlayer = "player"
+	MarkerPOI    = "poi"
+	MarkerSign   = "sign"
+	MarkerBanner = "banner"
+)
+
+// markerOrder sorts markers by kind in the files
+var markerOrder = map[string]int{MarkerSpawn: 0, MarkerPOI: 1, MarkerPlayer: 2, MarkerBanner: 3, MarkerSign: 4}
+
+// Marker is a labelled point on a map
+type Marker struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	X    int    `json:"x"`
+	// Y is omitted for points of interest, which are configured without it
+	Y *int `json:"y,omitempty"`
+	Z int  `json:"z"`
+}
+
+// MarkersFile is a map's markers.json, for viewers other than unmined's
+type MarkersFile struct {
+	World     string   `json:"world"`
+	Map       string   `json:"map"`
+	Dimension string   `json:"dimension"`
+	Generated string   `json:"generated"`
+	Markers   []Marker `json:"markers"`
+}
+
+// writeMarkers collects a map's markers and writes them to markers.json and
+// unmined's custom.markers.js in the map's directory
+func (b *Builder) writeMarkers(worldName string, mapDef config.MapDefinition, worldDir, worldMapsDir string) error {
+	outputSubdir := mapDef.OutputSubdir
+	if outputSubdir == "" {
+		outputSubdir = mapDef.Name
+	}
+	mapOutput := filepath.Join(worldMapsDir, outputSubdir)
+
+	markers, err := collectMarkers(mapDef, worldDir, filepath.Join(mapOutput, signCacheFile))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(MarkersFile{
+		World:     worldName,
+		Map:       mapDef.Name,
+		Dimension: mapDef.Dimension,
+		Generated: time.Now().Format(time.RFC3339),
+		Markers:   markers,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal markers: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(mapOutput, "markers.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write markers: %w", err)
+	}
+
+	script, err := unminedMarkers(markers)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(mapOutput, "custom.markers.js"), script, 0644); err != nil {
+		return fmt.Errorf("failed to write custom markers: %w", err)
+	}
+
+	log.Info().Str("map", mapDef.Name).Int("markers", len(markers)).Msg("markers written")
+	return nil
+}
+
+// collectMarkers returns the markers in a map's dimension: the world spawn,
+// the configured points of interest, with options.players the players' last
+// positions and with markers.signs the signs and named banners. Sign markers
+// are cached in signCache, if given.
+func collectMarkers(mapDef config.MapDefinition, worldDir, signCache string) ([]Marker, error) {
+	dimension := resourceLocation(mapDef.Dimension)
+	var markers []Marker
+
+	level, err := nbt.ReadLevelDat(filepath.Join(worldDir, "level.dat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read level.dat: %w", err)
+	}
+	spawnDimension := level.Spawn.Dimension
+	if spawnDimension == "" {
+		spawnDimension = "minecraft:overworld"
+	}
+	if spawnDimension == dimension {
+		y := int(level.GetSpawnY())
+		markers = append(markers, Marker{Kind: MarkerSpawn, Name: "Spawn", X: int(level.GetSpawnX()), Y: &y, Z: int(level.GetSpawnZ())})
+	}
+
+	for _, poi := range mapDef.Markers.POIs {
+		markers = append(markers, Marker{Kind: MarkerPOI, Name: poi.Name, X: poi.Position[0], Z: poi.Position[1]})
+	}
+
+	if mapDef.Options.Players != nil && *mapDef.Options.Players {
+		players, err := playerMarkers(worldDir, dimension)
+		if err != nil {
+			return nil, err
+		}
+		markers = append(markers, players...)
+	}
+
+	if mapDef.Markers.Signs {
+		regionDir := filepath.Join(dimensionDir(worldDir, mapDef.Dimension), "region")
+		signs, err := signMarkers(regionDir, signCache)
+		if err != nil {
+			return nil, err
+		}
+		markers = append(markers, signs...)
+	}
+
+	sort.SliceStable(markers, func(i, j int) bool {
+		if markers[i].Kind != markers[j].Kind {
+			return markerOrder[markers[i].Kind] < markerOrder[markers[j].Kind]
+		}
+		return markers[i].Name < markers[j].Name
+	})
+	return markers, nil
+}
+
+// resourceLocation returns the id of a map-config.yml dimension
+func resourceLocation(dimension string) string {
+	switch dimension {
+	case "nether", "-1":
+		return "minecraft:the_nether"
+	case "end", "1":
+		return "minecraft:the_end"
+	}
+	return "minecraft:overworld"
+}
+
+// playerMarkers returns the last positions of the players in a dimension,
+// named from the server's usercache.json next to the world/ directory
+func playerMarkers(worldDir, dimension string) ([]Marker, error) {
+	paths, err := filepath.Glob(filepath.Join(worldDir, "playerdata", "*.dat"))
+	if err != nil {
+		return nil, err
+	}
+	names := playerNames(filepath.Dir(worldDir))
+
+	var markers []Marker
+	for _, path := range paths {
+		player, err := nbt.ReadPlayerDat(path)
+		if err != nil {
+			log.Warn().Err(err).Str("file", filepath.Base(path)).Msg("skipping unreadable player data")
+			continue
+		}
+		if player.GetDimension() != dimension {
+			continue
+		}
+		uuid := strings.TrimSuffix(filepath.Base(path), ".dat")
+		name, ok := names[strings.ToLower(uuid)]
+		if !ok {
+			name = uuid
+		}
+		y := int(math.Floor(player.Pos[1]))
+		markers = append(markers, Marker{
+			Kind: MarkerPlayer,
+			Name: name,
+			X:    int(math.Floor(player.Pos[0])),
+			Y:    &y,
+			Z:    int(math.Floor(player.Pos[2])),
+		})
+	}
+	return markers, nil
+}
+
+// playerNames maps player UUIDs to names from usercache.json. A missing or
+// unreadable cache yields no names.
+func playerNames(worldPath string) map[string]string {
+	names := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(worldPath, "usercache.json"))
+	if err != nil {
+		return names
+	}
+	var cache []struct {
+		Name string `json:"name"`
+		UUID string `json:"uuid"`
+	}
+	if json.Unmarshal(data, &cache) != nil {
+		return names
+	}
+	for _, entry := range cache {
+		names[strings.ToLower(entry.UUID)] = entry.Name
+	}
+	return names
+}
+
+// blockEntity holds the tags of signs and banners that markers are made of
+type blockEntity struct {
+	ID string `nbt:"id"`
+	X  int32  `nbt:"x"`
+	Y  int32  `nbt:"y"`
+	Z  int32  `nbt:"z"`
+	// FrontText holds the lines of signs since 1.20
+	FrontText struct {
+		Messages []any `nbt:"messages"`
+	} `nbt:"front_text"`
+	// Text1 to Text4 are the lines of older signs
+	Text1 any `nbt:"Text1"`
+	Text2 any `nbt:"Text2"`
+	Text3 any `nbt:"Text3"`
+	Text4 any `nbt:"Text4"`
+	// CustomName is a banner's name given in an anvil
+	CustomName any `nbt:"CustomName"`
+}
+
+// signCacheFile is the name of the sign marker cache in a map's directory.
+// Hidden files are not published.
+const signCacheFile = ".sign-markers.json"
+
+// signCacheEntry holds the sign markers of a region file as of its
+// modification time in Unix nanoseconds
+type signCacheEntry struct {
+	ModTime int64    `json:"mtime"`
+	Markers []Marker `json:"markers"`
+}
+
+// readSignCache reads a sign marker cache, keyed by region file name. A
+// missing or unreadable cache is empty.
+func readSignCache(path string) map[string]signCacheEntry {
+	cache := make(map[string]signCacheEntry)
+	if path == "" {
+		return cache
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Warn().Err(err).Str("file", path).Msg("ignoring unreadable sign marker cache")
+		return make(map[string]signCacheEntry)
+	}
+	return cache
+}
+
+// signMarkers returns markers for signs with text and for named banners in
+// a dimension. Only the region files modified since they were cached in
+// cachePath are read; without a cachePath every region file is.
+func signMarkers(regionDir, cachePath string) ([]Marker, error) {
+	regions, err := scanRegions(regionDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(regions))
+	for name := range regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cache := readSignCache(cachePath)
+	updated := make(map[string]signCacheEntry, len(regions))
+	var markers []Marker
+	read := 0
+	for _, name := range names {
+		entry, ok := cache[name]
+		if !ok || entry.ModTime != regions[name] {
+			regionMarkers, err := regionSignMarkers(regionDir, name)
+			if err != nil {
+				return nil, err
+			}
+			entry = signCacheEntry{ModTime: regions[name], Markers: regionMarkers}
+			read++
+		}
+		updated[name] = entry
+		markers = append(markers, entry.Markers...)
+	}
+	log.Debug().Int("regions", len(regions)).Int("read", read).Msg("collected sign markers")
+
+	if cachePath != "" && (read > 0 || len(updated) != len(cache)) {
+		data, err := json.Marshal(updated)
+		if err == nil {
+			err = os.WriteFile(cachePath, data, 0644)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to write sign marker cache")
+		}
+	}
+	return markers, nil
+}
+
+// regionSignMarkers reads the block entities of every chunk in a region
+// file and returns markers for signs with text and for named banners
+func regionSignMarkers(regionDir, name string) ([]Marker, error) {
+	rx, rz, _ := region.ParseFileName(name)
+	f, err := region.Read(filepath.Join(regionDir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var markers []Marker
+	for i, rchunk := range f.Chunks {
+		if rchunk == nil {
+			continue
+		}
+		cx := rx*region.RegionChunks + i%region.RegionChunks
+		cz := rz*region.RegionChunks + i/region.RegionChunks
+		data, err := chunkData(rchunk, regionDir, cx, cz)
+		if err != nil {
+			return nil, err
+		}
+		var c save.Chunk
+		if err := c.Load(data); err != nil {
+			log.Warn().Err(err).Int("chunk_x", cx).Int("chunk_z", cz).Msg("skipping unreadable chunk")
+			continue
+		}
+		for _, raw := range c.BlockEntities {
+			var be blockEntity
+			if err := raw.Unmarshal(&be); err != nil {
+				continue
+			}
+			if m, ok := be.marker(); ok {
+				markers = append(markers, m)
+			}
+		}
+	}
+	return markers, nil
+}
+
+// marker returns the marker of a sign with text or a named banner
+func (be *blockEntity) marker() (Marker, bool) {
+	var kind, name string
+	switch be.ID {
+	case "minecraft:sign", "minecraft:hanging_sign":
+		lines := be.FrontText.Messages
+		if len(lines) == 0 {
+			lines = []any{be.Text1, be.Text2, be.Text3, be.Text4}
+		}
+		var text []string
+		for _, line := range lines {
+			if t := strings.TrimSpace(textOf(line)); t != "" {
+				text = append(text, t)
+			}
+		}
+		kind, name = MarkerSign, strings.Join(text, " ")
+	case "minecraft:banner":
+		kind, name = MarkerBanner, strings.TrimSpace(textOf(be.CustomName))
+	}
+	if name == "" {
+		return Marker{}, false
+	}
+	y := int(be.Y)
+	return Marker{Kind: kind, Name: name, X: int(be.X), Y: &y, Z: int(be.Z)}, true
+}
+
+// textOf returns the plain text of a text component. Components are JSON
+// strings before 1.21.5 and NBT strings or compounds since.
+func textOf(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") || strings.HasPrefix(t, `"`) {
+			var component any
+			if json.Unmarshal([]byte(t), &component) == nil {
+				return componentText(component)
+			}
+		}
+		return t
+	}
+	return componentText(v)
+}
+
+// componentText concatenates the text of a decoded text component and its
+// extra components
+func componentText(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []any:
+		var sb strings.Builder
+		for _, part := range t {
+			sb.WriteString(componentText(part))
+		}
+		return sb.String()
+	case map[string]any:
+		text := componentText(t["text"])
+		if extra, ok := t["extra"]; ok {
+			text += componentText(extra)
+		}
+		return text
+	}
+	return ""
+}
+
+// unminedMarker is an entry of unmined's UnminedCustomMarkers
+type unminedMarker struct {
+	X           int        `json:"x"`
+	Z           int        `json:"z"`
+	Image       string     `json:"image"`
+	ImageAnchor [2]float64 `json:"imageAnchor"`
+	ImageScale  float64    `json:"imageScale"`
+	Text        string     `json:"text"`
+	TextColor   string     `json:"textColor"`
+	OffsetX     int        `json:"offsetX"`
+	OffsetY     int        `json:"offsetY"`
+	Font        string     `json:"font"`
+}
+
+// markerColors are the label colours of the marker kinds in unmined's viewer
+var markerColors = map[string]string{
+	MarkerSpawn:  "white",
+	MarkerPOI:    "yellow",
+	MarkerPlayer: "lightgreen",
+	MarkerBanner: "orange",
+	MarkerSign:   "lightgray",
+}
+
+// unminedMarkers formats markers as unmined's custom.markers.js, which its
+// web viewer loads, pinned with the custom.pin.png it ships
+func unminedMarkers(markers []Marker) ([]byte, error) {
+	entries := make([]unminedMarker, 0, len(markers))
+	for _, m := range markers {
+		entries = append(entries, unminedMarker{
+			X:           m.X,
+			Z:           m.Z,
+			Image:       "custom.pin.png",
+			ImageAnchor: [2]float64{0.5, 1},
+			ImageScale:  0.5,
+			Text:        m.Name,
+			TextColor:   markerColors[m.Kind],
+			OffsetY:     20,
+			Font:        "bold 14px sans-serif",
+		})
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"isEnabled": true,
+		"markers":   entries,
+	}, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom markers: %w", err)
+	}
+	return []byte(fmt.Sprintf("UnminedCustomMarkers = %s;\n", data)), nil
+}
@@ -0,0 +1,251 @@
+package maps
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	mcnbt "github.com/Tnze/go-mc/nbt"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// writePlayer writes a playerdata file for a player at pos in dimension
+func writePlayer(t *testing.T, worldDir, uuid string, pos []float64, dimension string) {
+	t.Helper()
+	dir := filepath.Join(worldDir, "playerdata")
+	os.MkdirAll(dir, 0755)
+	f, err := os.Create(filepath.Join(dir, uuid+".dat"))
+	if err != nil {
+		t.Fatalf("Failed to create player data: %v", err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	player := struct {
+		Pos       []float64 `nbt:"Pos"`
+		Dimension string    `nbt:"Dimension"`
+	}{pos, dimension}
+	if err := mcnbt.NewEncoder(w).Encode(player, ""); err != nil {
+		t.Fatalf("Failed to encode player data: %v", err)
+	}
+	w.Close()
+}
+
+// signChunk returns a chunk holding the given block entities
+func signChunk(t *testing.T, cx, cz int32, entities ...any) []byte {
+	t.Helper()
+	c := struct {
+		XPos          int32  `nbt:"xPos"`
+		ZPos          int32  `nbt:"zPos"`
+		Status        string `nbt:"Status"`
+		BlockEntities []any  `nbt:"block_entities"`
+	}{cx, cz, "minecraft:full", entities}
+	var buf bytes.Buffer
+	buf.WriteByte(3) // Uncompressed
+	if err := mcnbt.NewEncoder(&buf).Encode(c, ""); err != nil {
+		t.Fatalf("Failed to encode chunk: %v", err)
+	}
+	return buf.Bytes()
+}
+
+type testSign struct {
+	ID        string `nbt:"id"`
+	X         int32  `nbt:"x"`
+	Y         int32  `nbt:"y"`
+	Z         int32  `nbt:"z"`
+	FrontText struct {
+		Messages []string `nbt:"messages"`
+	} `nbt:"front_text"`
+}
+
+type testLegacySign struct {
+	ID    string `nbt:"id"`
+	X     int32  `nbt:"x"`
+	Y     int32  `nbt:"y"`
+	Z     int32  `nbt:"z"`
+	Text1 string `nbt:"Text1"`
+	Text2 string `nbt:"Text2"`
+}
+
+type testBanner struct {
+	ID         string `nbt:"id"`
+	X          int32  `nbt:"x"`
+	Y          int32  `nbt:"y"`
+	Z          int32  `nbt:"z"`
+	CustomName string `nbt:"CustomName,omitempty"`
+}
+
+func TestCollectMarkers(t *testing.T) {
+	worldPath := t.TempDir()
+	worldDir := filepath.Join(worldPath, "world")
+	regionDir := filepath.Join(worldDir, "region")
+	os.MkdirAll(regionDir, 0755)
+	writeLevelDat(t, filepath.Join(worldDir, "level.dat"), 100, -50)
+
+	alice := "11111111-2222-3333-4444-555555555555"
+	bob := "66666666-7777-8888-9999-000000000000"
+	writePlayer(t, worldDir, alice, []float64{10.7, 70.2, -3.5}, "minecraft:overworld")
+	writePlayer(t, worldDir, bob, []float64{1, 40, 1}, "minecraft:the_nether")
+	os.WriteFile(filepath.Join(worldPath, "usercache.json"),
+		[]byte(`[{"name":"Alice","uuid":"`+alice+`"},{"name":"Bob","uuid":"`+bob+`"}]`), 0644)
+
+	sign := testSign{ID: "minecraft:sign", X: 5, Y: 64, Z: 6}
+	sign.FrontText.Messages = []string{`"Welcome"`, `{"text":"home"}`, `""`, `""`}
+	blank := testSign{ID: "minecraft:sign", X: 7, Y: 64, Z: 7}
+	blank.FrontText.Messages = []string{`""`, `""`, `""`, `""`}
+	f := &region.File{}
+	f.Chunks[0] = &region.Chunk{Timestamp: 1, Data: signChunk(t, 0, 0,
+		sign,
+		blank,
+		testLegacySign{ID: "minecraft:sign", X: 8, Y: 65, Z: 9, Text1: `{"text":"Old"}`, Text2: `{"text":"road","extra":[{"text":"s"}]}`},
+		testBanner{ID: "minecraft:banner", X: 3, Y: 66, Z: 4, CustomName: `{"text":"Base"}`},
+		testBanner{ID: "minecraft:banner", X: 3, Y: 66, Z: 5},
+	)}
+	f.Write(filepath.Join(regionDir, "r.0.0.mca"))
+
+	intp := func(v int) *int { return &v }
+	players := true
+
+	t.Run("all kinds", func(t *testing.T) {
+		mapDef := config.MapDefinition{
+			Name:      "overworld",
+			Dimension: "overworld",
+			Options:   config.MapOptions{Players: &players},
+			Markers: config.MapMarkers{
+				Signs: true,
+				POIs:  []config.MapPOI{{Name: "Village", Position: [2]int{200, 300}}},
+			},
+		}
+		markers, err := collectMarkers(mapDef, worldDir, "")
+		if err != nil {
+			t.Fatalf("collectMarkers failed: %v", err)
+		}
+		want := []Marker{
+			{Kind: MarkerSpawn, Name: "Spawn", X: 100, Y: intp(0), Z: -50},
+			{Kind: MarkerPOI, Name: "Village", X: 200, Z: 300},
+			{Kind: MarkerPlayer, Name: "Alice", X: 10, Y: intp(70), Z: -4},
+			{Kind: MarkerBanner, Name: "Base", X: 3, Y: intp(66), Z: 4},
+			{Kind: MarkerSign, Name: "Old roads", X: 8, Y: intp(65), Z: 9},
+			{Kind: MarkerSign, Name: "Welcome home", X: 5, Y: intp(64), Z: 6},
+		}
+		if !reflect.DeepEqual(markers, want) {
+			got, _ := json.Marshal(markers)
+			t.Errorf("Markers = %s", got)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		// Without options.players and markers.signs only the spawn is shown
+		markers, err := collectMarkers(config.MapDefinition{Name: "overworld", Dimension: "overworld"}, worldDir, "")
+		if err != nil {
+			t.Fatalf("collectMarkers failed: %v", err)
+		}
+		if len(markers) != 1 || markers[0].Kind != MarkerSpawn {
+			t.Errorf("Expected only the spawn marker, got %+v", markers)
+		}
+	})
+
+	t.Run("sign cache", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), signCacheFile)
+		if _, err := signMarkers(regionDir, cachePath); err != nil {
+			t.Fatalf("signMarkers failed: %v", err)
+		}
+		if _, err := os.Stat(cachePath); err != nil {
+			t.Fatalf("Expected sign marker cache: %v", err)
+		}
+
+		// A region file with its cached mtime is not read again
+		regionPath := filepath.Join(regionDir, "r.0.0.mca")
+		info, _ := os.Stat(regionPath)
+		moved := testSign{ID: "minecraft:sign", X: 20, Y: 64, Z: 20}
+		moved.FrontText.Messages = []string{`"Moved"`, `""`, `""`, `""`}
+		changed := &region.File{}
+		changed.Chunks[1] = &region.Chunk{Timestamp: 2, Data: signChunk(t, 1, 0, moved)}
+		changed.Write(regionPath)
+		os.Chtimes(regionPath, info.ModTime(), info.ModTime())
+
+		markers, err := signMarkers(regionDir, cachePath)
+		if err != nil {
+			t.Fatalf("signMarkers failed: %v", err)
+		}
+		if len(markers) != 3 {
+			t.Errorf("Expected the 3 cached markers, got %+v", markers)
+		}
+
+		// A modified region file is read again
+		later := info.ModTime().Add(time.Second)
+		os.Chtimes(regionPath, later, later)
+		markers, err = signMarkers(regionDir, cachePath)
+		if err != nil {
+			t.Fatalf("signMarkers failed: %v", err)
+		}
+		if len(markers) != 1 || markers[0].Name != "Moved" {
+			t.Errorf("Expected the changed region's marker, got %+v", markers)
+		}
+	})
+
+	t.Run("other dimension", func(t *testing.T) {
+		mapDef := config.MapDefinition{Name: "nether", Dimension: "nether", Options: config.MapOptions{Players: &players}}
+		markers, err := collectMarkers(mapDef, worldDir, "")
+		if err != nil {
+			t.Fatalf("collectMarkers failed: %v", err)
+		}
+		want := []Marker{{Kind: MarkerPlayer, Name: "Bob", X: 1, Y: intp(40), Z: 1}}
+		if !reflect.DeepEqual(markers, want) {
+			t.Errorf("Markers = %+v, want %+v", markers, want)
+		}
+	})
+}
+
+func TestBuildWritesMarkers(t *testing.T) {
+	fake := &fakeRenderer{caps: Capabilities{WebMap: true, Areas: true, Preview: true}}
+	worldsDir, mapsDir := t.TempDir(), t.TempDir()
+	writeWorld(t, filepath.Join(worldsDir, "survival"))
+	b := &Builder{worldsDir: worldsDir, mapsDir: mapsDir, renderers: map[string]Renderer{"fake": fake}}
+	writeLevelDat(t, filepath.Join(b.worldsDir, "survival", "world", "level.dat"), 8, 8)
+
+	opts := BuildOptions{WorldName: "survival", NoLock: true}
+	if _, err := b.Build(opts); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	mapOutput := filepath.Join(b.mapsDir, "survival", "overworld")
+	markersPath := filepath.Join(mapOutput, "markers.json")
+	if _, err := os.Stat(markersPath); err != nil {
+		t.Fatalf("Expected markers.json: %v", err)
+	}
+
+	// The map is up to date; markers are written again anyway
+	os.Remove(markersPath)
+	fake.calls = nil
+	if _, err := b.Build(opts); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("Expected no renders, got %v", fake.calls)
+	}
+	data, err := os.ReadFile(markersPath)
+	if err != nil {
+		t.Fatalf("Expected markers.json after up-to-date build: %v", err)
+	}
+	var file MarkersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Invalid markers.json: %v", err)
+	}
+	if file.World != "survival" || file.Map != "overworld" || len(file.Markers) != 1 {
+		t.Errorf("Unexpected markers.json: %+v", file)
+	}
+
+	script, err := os.ReadFile(filepath.Join(mapOutput, "custom.markers.js"))
+	if err != nil {
+		t.Fatalf("Expected custom.markers.js: %v", err)
+	}
+	if !strings.HasPrefix(string(script), "UnminedCustomMarkers = {") || !strings.Contains(string(script), `"text": "Spawn"`) {
+		t.Errorf("Unexpected custom.markers.js:\n%s", script)
+	}
+}
@@ -0,0 +1,298 @@
+package maps
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/bits"
+	"os"
+	"path/filepath"
+
+	"github.com/Tnze/go-mc/save"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// NativeRenderer draws top-down preview images in Go, reading region files
+// directly, so previews work on hosts without unmined. It cannot render web
+// maps.
+type NativeRenderer struct {
+	// Scale is the width and height in pixels of each block column
+	Scale int
+}
+
+// NewNativeRenderer returns a native renderer drawing 2x2 pixels per block,
+// the size unmined's preview zoom produces
+func NewNativeRenderer() *NativeRenderer {
+	return &NativeRenderer{Scale: 2}
+}
+
+// Capabilities implements Renderer
+func (n *NativeRenderer) Capabilities() Capabilities {
+	return Capabilities{Preview: true}
+}
+
+// RenderMap implements Renderer; web maps are not supported
+func (n *NativeRenderer) RenderMap(job MapJob) error {
+	return fmt.Errorf("native renderer cannot render web maps")
+}
+
+// RenderArea implements Renderer; web maps are not supported
+func (n *NativeRenderer) RenderArea(job MapJob, area region.Box) error {
+	return fmt.Errorf("native renderer cannot render web maps")
+}
+
+// column is the top visible block of a block column
+type column struct {
+	y     int
+	block string
+}
+
+// RenderPreview implements Renderer. Each column is coloured by its top
+// non-air block from blockColor and shaded by its height relative to the
+// column to the north, like in-game maps. Columns in ungenerated chunks are
+// transparent. topY and bottomY limit the blocks considered.
+func (n *NativeRenderer) RenderPreview(job PreviewJob) error {
+	area := job.Area
+	width, height := area.MaxX-area.MinX+1, area.MaxZ-area.MinZ+1
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid preview area %s", area)
+	}
+	columns := make([]*column, width*height)
+
+	regionDir := filepath.Join(dimensionDir(job.WorldDir, job.Dimension), "region")
+	for _, rc := range area.Regions() {
+		path := filepath.Join(regionDir, region.FileName(rc[0], rc[1]))
+		f, err := region.Read(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		for i, rchunk := range f.Chunks {
+			cx := rc[0]*region.RegionChunks + i%region.RegionChunks
+			cz := rc[1]*region.RegionChunks + i/region.RegionChunks
+			if rchunk == nil || !area.ContainsChunk(cx, cz) {
+				continue
+			}
+			data, err := chunkData(rchunk, regionDir, cx, cz)
+			if err != nil {
+				return err
+			}
+			var c save.Chunk
+			if err := c.Load(data); err != nil {
+				return fmt.Errorf("chunk %d,%d: %w", cx, cz, err)
+			}
+
+			tops := topBlocks(&c, job.Dimension, job)
+			for j, top := range tops {
+				if top == nil {
+					continue
+				}
+				x, z := cx*16+j%16-area.MinX, cz*16+j/16-area.MinZ
+				if x >= 0 && x < width && z >= 0 && z < height {
+					columns[z*width+x] = top
+				}
+			}
+		}
+	}
+
+	scale := max(n.Scale, 1)
+	img := image.NewNRGBA(image.Rect(0, 0, width*scale, height*scale))
+	for z := 0; z < height; z++ {
+		for x := 0; x < width; x++ {
+			col := columns[z*width+x]
+			if col == nil {
+				continue
+			}
+			c := blockColor(col.block)
+			if z > 0 {
+				if north := columns[(z-1)*width+x]; north != nil {
+					switch {
+					case col.y > north.y:
+						c = shade(c, 1.12)
+					case col.y < north.y:
+						c = shade(c, 0.84)
+					}
+				}
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetNRGBA(x*scale+px, z*scale+py, c)
+				}
+			}
+		}
+	}
+
+	out, err := os.Create(job.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create preview: %w", err)
+	}
+	if err := png.Encode(out, img); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode preview: %w", err)
+	}
+	return out.Close()
+}
+
+// chunkData returns a chunk's compression type and compressed NBT, reading
+// oversized chunks from their c.<x>.<z>.mcc file
+func chunkData(c *region.Chunk, regionDir string, cx, cz int) ([]byte, error) {
+	if !c.External() {
+		return c.Data, nil
+	}
+	ext, err := os.ReadFile(filepath.Join(regionDir, region.ExternalFileName(cx, cz)))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{c.Data[0] &^ 0x80}, ext...), nil
+}
+
+// topBlocks finds the top visible block of each of a chunk's columns,
+// indexed by x + z*16. It starts at the WORLD_SURFACE heightmap, or at the
+// top of the highest section without one. In the nether without topY the
+// bedrock roof is skipped: a column shows the first block below air.
+func topBlocks(c *save.Chunk, dimension string, job PreviewJob) [256]*column {
+	var tops [256]*column
+	if len(c.Sections) == 0 {
+		return tops
+	}
+
+	sections := make(map[int]*save.Section, len(c.Sections))
+	minSection, maxSection := int(c.Sections[0].Y), int(c.Sections[0].Y)
+	for i := range c.Sections {
+		s := &c.Sections[i]
+		sections[int(s.Y)] = s
+		minSection = min(minSection, int(s.Y))
+		maxSection = max(maxSection, int(s.Y))
+	}
+	// Heightmaps count from the world's bottom, the chunk's yPos section
+	baseY := int(c.YPos) * 16
+	minY, maxY := minSection*16, maxSection*16+15
+
+	heights := unpackHeightmap(c.Heightmaps["WORLD_SURFACE"])
+	states := make(map[int][]int)
+	blockAt := func(x, y, z int) string {
+		sy := y >> 4
+		s, ok := sections[sy]
+		if !ok || len(s.BlockStates.Palette) == 0 {
+			return "minecraft:air"
+		}
+		idx, ok := states[sy]
+		if !ok {
+			idx = unpackStates(s.BlockStates.Data, len(s.BlockStates.Palette))
+			states[sy] = idx
+		}
+		i := 0
+		if idx != nil {
+			i = idx[(y&15)*256+z*16+x]
+		}
+		if i >= len(s.BlockStates.Palette) {
+			return "minecraft:air"
+		}
+		return s.BlockStates.Palette[i].Name
+	}
+
+	skipRoof := (dimension == "nether" || dimension == "-1") && job.Options.TopY == nil
+	for z := 0; z < 16; z++ {
+		for x := 0; x < 16; x++ {
+			top := maxY
+			if heights != nil && !skipRoof {
+				top = baseY + heights[z*16+x] - 1
+			}
+			if job.Options.TopY != nil {
+				top = min(top, *job.Options.TopY)
+			}
+			bottom := minY
+			if job.Options.BottomY != nil {
+				bottom = max(bottom, *job.Options.BottomY)
+			}
+
+			sawAir := !skipRoof
+			for y := top; y >= bottom; y-- {
+				name := blockAt(x, y, z)
+				if isAir(name) {
+					sawAir = true
+					continue
+				}
+				if sawAir {
+					tops[z*16+x] = &column{y: y, block: name}
+					break
+				}
+			}
+		}
+	}
+	return tops
+}
+
+// unpackHeightmap decodes a 256-entry heightmap. Entries do not span longs;
+// their width follows from the number of longs.
+func unpackHeightmap(data []uint64) []int {
+	if len(data) == 0 {
+		return nil
+	}
+	perLong := (256 + len(data) - 1) / len(data)
+	width := 64 / perLong
+	mask := uint64(1)<<width - 1
+	heights := make([]int, 256)
+	for i := range heights {
+		long := i / perLong
+		if long >= len(data) {
+			return nil
+		}
+		heights[i] = int(data[long] >> (uint(i%perLong) * uint(width)) & mask)
+	}
+	return heights
+}
+
+// unpackStates decodes a section's 4096 palette indexes, at least 4 bits
+// each and not spanning longs. A single-entry palette has no data and nil
+// is returned.
+func unpackStates(data []uint64, paletteSize int) []int {
+	if len(data) == 0 {
+		return nil
+	}
+	width := max(4, bits.Len(uint(paletteSize-1)))
+	perLong := 64 / width
+	mask := uint64(1)<<width - 1
+	states := make([]int, 4096)
+	for i := range states {
+		long := i / perLong
+		if long >= len(data) {
+			break
+		}
+		states[i] = int(data[long] >> (uint(i%perLong) * uint(width)) & mask)
+	}
+	return states
+}
+
+// isAir reports whether a block is invisible from above
+func isAir(name string) bool {
+	switch name {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air",
+		"minecraft:light", "minecraft:barrier", "minecraft:structure_void":
+		return true
+	}
+	return false
+}
+
+// shade scales a colour's brightness
+func shade(c color.NRGBA, f float64) color.NRGBA {
+	scale := func(v uint8) uint8 {
+		return uint8(min(255, float64(v)*f))
+	}
+	return color.NRGBA{R: scale(c.R), G: scale(c.G), B: scale(c.B), A: c.A}
+}
+
+// dimensionDir returns the directory of a dimension below a world/ directory
+func dimensionDir(worldDir, dimension string) string {
+	switch dimension {
+	case "nether", "-1":
+		return filepath.Join(worldDir, "DIM-1")
+	case "end", "1":
+		return filepath.Join(worldDir, "DIM1")
+	}
+	return worldDir
+}
@@ -0,0 +1,146 @@
+package maps
+
+import (
+	"image/color"
+	"strings"
+)
+
+// blockColors are the top-down colours of common blocks, mostly from the
+// in-game map colours. Blocks not listed are coloured by blockColor's name
+// rules.
+var blockColors = map[string]color.NRGBA{
+	"minecraft:grass_block":       {R: 127, G: 178, B: 56, A: 255},
+	"minecraft:short_grass":       {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:grass":             {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:tall_grass":        {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:fern":              {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:dirt":              {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:coarse_dirt":       {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:rooted_dirt":       {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:dirt_path":         {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:farmland":          {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:podzol":            {R: 129, G: 86, B: 49, A: 255},
+	"minecraft:mud":               {R: 87, G: 92, B: 92, A: 255},
+	"minecraft:mycelium":          {R: 127, G: 63, B: 178, A: 255},
+	"minecraft:sand":              {R: 247, G: 233, B: 163, A: 255},
+	"minecraft:sandstone":         {R: 247, G: 233, B: 163, A: 255},
+	"minecraft:red_sand":          {R: 216, G: 127, B: 51, A: 255},
+	"minecraft:red_sandstone":     {R: 216, G: 127, B: 51, A: 255},
+	"minecraft:gravel":            {R: 112, G: 112, B: 112, A: 255},
+	"minecraft:clay":              {R: 164, G: 168, B: 184, A: 255},
+	"minecraft:stone":             {R: 112, G: 112, B: 112, A: 255},
+	"minecraft:cobblestone":       {R: 112, G: 112, B: 112, A: 255},
+	"minecraft:andesite":          {R: 112, G: 112, B: 112, A: 255},
+	"minecraft:granite":           {R: 151, G: 109, B: 77, A: 255},
+	"minecraft:diorite":           {R: 255, G: 252, B: 245, A: 255},
+	"minecraft:deepslate":         {R: 100, G: 100, B: 100, A: 255},
+	"minecraft:tuff":              {R: 57, G: 41, B: 35, A: 255},
+	"minecraft:calcite":           {R: 209, G: 177, B: 161, A: 255},
+	"minecraft:bedrock":           {R: 85, G: 85, B: 85, A: 255},
+	"minecraft:water":             {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:bubble_column":     {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:seagrass":          {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:tall_seagrass":     {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:kelp":              {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:kelp_plant":        {R: 64, G: 64, B: 255, A: 255},
+	"minecraft:lava":              {R: 255, G: 0, B: 0, A: 255},
+	"minecraft:ice":               {R: 160, G: 160, B: 255, A: 255},
+	"minecraft:packed_ice":        {R: 160, G: 160, B: 255, A: 255},
+	"minecraft:blue_ice":          {R: 160, G: 160, B: 255, A: 255},
+	"minecraft:snow":              {R: 255, G: 255, B: 255, A: 255},
+	"minecraft:snow_block":        {R: 255, G: 255, B: 255, A: 255},
+	"minecraft:powder_snow":       {R: 255, G: 255, B: 255, A: 255},
+	"minecraft:netherrack":        {R: 112, G: 2, B: 0, A: 255},
+	"minecraft:nether_wart_block": {R: 153, G: 51, B: 51, A: 255},
+	"minecraft:crimson_nylium":    {R: 189, G: 48, B: 49, A: 255},
+	"minecraft:warped_nylium":     {R: 22, G: 126, B: 134, A: 255},
+	"minecraft:warped_wart_block": {R: 20, G: 180, B: 133, A: 255},
+	"minecraft:soul_sand":         {R: 102, G: 76, B: 51, A: 255},
+	"minecraft:soul_soil":         {R: 102, G: 76, B: 51, A: 255},
+	"minecraft:basalt":            {R: 25, G: 25, B: 25, A: 255},
+	"minecraft:blackstone":        {R: 25, G: 25, B: 25, A: 255},
+	"minecraft:glowstone":         {R: 247, G: 233, B: 163, A: 255},
+	"minecraft:magma_block":       {R: 112, G: 2, B: 0, A: 255},
+	"minecraft:end_stone":         {R: 247, G: 233, B: 163, A: 255},
+	"minecraft:obsidian":          {R: 25, G: 25, B: 25, A: 255},
+	"minecraft:purpur_block":      {R: 178, G: 76, B: 216, A: 255},
+	"minecraft:chorus_plant":      {R: 127, G: 63, B: 178, A: 255},
+	"minecraft:chorus_flower":     {R: 127, G: 63, B: 178, A: 255},
+	"minecraft:moss_block":        {R: 102, G: 127, B: 51, A: 255},
+	"minecraft:moss_carpet":       {R: 102, G: 127, B: 51, A: 255},
+	"minecraft:vine":              {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:lily_pad":          {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:cactus":            {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:sugar_cane":        {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:bamboo":            {R: 0, G: 124, B: 0, A: 255},
+	"minecraft:pumpkin":           {R: 216, G: 127, B: 51, A: 255},
+	"minecraft:melon":             {R: 127, G: 204, B: 25, A: 255},
+	"minecraft:hay_block":         {R: 229, G: 229, B: 51, A: 255},
+	"minecraft:bricks":            {R: 153, G: 51, B: 51, A: 255},
+	"minecraft:torch":             {R: 255, G: 216, B: 0, A: 255},
+	"minecraft:glass":             {R: 200, G: 220, B: 230, A: 255},
+}
+
+// dyeColors are the map colours of the dye colour prefixes of wool,
+// concrete, terracotta, carpet and glass
+var dyeColors = map[string]color.NRGBA{
+	"white":      {R: 255, G: 255, B: 255, A: 255},
+	"orange":     {R: 216, G: 127, B: 51, A: 255},
+	"magenta":    {R: 178, G: 76, B: 216, A: 255},
+	"light_blue": {R: 102, G: 153, B: 216, A: 255},
+	"yellow":     {R: 229, G: 229, B: 51, A: 255},
+	"lime":       {R: 127, G: 204, B: 25, A: 255},
+	"pink":       {R: 242, G: 127, B: 165, A: 255},
+	"gray":       {R: 76, G: 76, B: 76, A: 255},
+	"light_gray": {R: 153, G: 153, B: 153, A: 255},
+	"cyan":       {R: 76, G: 127, B: 153, A: 255},
+	"purple":     {R: 127, G: 63, B: 178, A: 255},
+	"blue":       {R: 51, G: 76, B: 178, A: 255},
+	"brown":      {R: 102, G: 76, B: 51, A: 255},
+	"green":      {R: 102, G: 127, B: 51, A: 255},
+	"red":        {R: 153, G: 51, B: 51, A: 255},
+	"black":      {R: 25, G: 25, B: 25, A: 255},
+}
+
+// Fallback colours by kind of block
+var (
+	leavesColor  = color.NRGBA{R: 0, G: 124, B: 0, A: 255}
+	woodColor    = color.NRGBA{R: 143, G: 119, B: 72, A: 255}
+	stoneColor   = color.NRGBA{R: 112, G: 112, B: 112, A: 255}
+	plantColor   = color.NRGBA{R: 0, G: 124, B: 0, A: 255}
+	unknownColor = color.NRGBA{R: 160, G: 160, B: 160, A: 255}
+)
+
+// blockColor returns the colour of a block seen from above. Unlisted blocks
+// are matched by name: dyed blocks by their colour prefix, then leaves,
+// wood, stone-like blocks and plants.
+func blockColor(name string) color.NRGBA {
+	if c, ok := blockColors[name]; ok {
+		return c
+	}
+
+	short := strings.TrimPrefix(name, "minecraft:")
+	for _, suffix := range []string{"_wool", "_concrete", "_concrete_powder", "_terracotta", "_glazed_terracotta", "_carpet", "_stained_glass", "_bed", "_shulker_box"} {
+		if dye, ok := strings.CutSuffix(short, suffix); ok {
+			if c, ok := dyeColors[dye]; ok {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case strings.Contains(short, "leaves"):
+		return leavesColor
+	case strings.Contains(short, "log"), strings.Contains(short, "wood"), strings.Contains(short, "planks"),
+		strings.Contains(short, "stem"), strings.Contains(short, "hyphae"), strings.HasSuffix(short, "_stairs"),
+		strings.HasSuffix(short, "_slab"), strings.HasSuffix(short, "_fence"), strings.HasSuffix(short, "_door"):
+		return woodColor
+	case strings.Contains(short, "stone"), strings.Contains(short, "deepslate"), strings.Contains(short, "ore"),
+		strings.Contains(short, "brick"), strings.HasSuffix(short, "_wall"):
+		return stoneColor
+	case strings.Contains(short, "flower"), strings.Contains(short, "tulip"), strings.Contains(short, "sapling"),
+		strings.Contains(short, "bush"), strings.Contains(short, "roots"), strings.Contains(short, "fungus"):
+		return plantColor
+	}
+	return unknownColor
+}
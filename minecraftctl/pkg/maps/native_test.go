@@ -0,0 +1,260 @@
+package maps
+
+import (
+	"bytes"
+	"compress/gzip"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mcnbt "github.com/Tnze/go-mc/nbt"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/nbt"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// packLongs packs values of width bits into longs, not spanning longs
+func packLongs(values []int, width int) []uint64 {
+	perLong := 64 / width
+	data := make([]uint64, (len(values)+perLong-1)/perLong)
+	for i, v := range values {
+		data[i/perLong] |= uint64(v) << (uint(i%perLong) * uint(width))
+	}
+	return data
+}
+
+// testChunk returns chunk 0,0 of a world starting at y=-64 with grass at
+// y=64, raised to y=65 in row z=1. The rest of the chunk is air.
+func testChunk(t *testing.T) []byte {
+	t.Helper()
+	type blockState struct {
+		Name string `nbt:"Name"`
+	}
+	palette := []blockState{{Name: "minecraft:air"}, {Name: "minecraft:grass_block"}}
+	indexes := make([]int, 4096)
+	heights := make([]int, 256)
+	for z := 0; z < 16; z++ {
+		for x := 0; x < 16; x++ {
+			y := 0 // Section y=64
+			if z == 1 {
+				y = 1
+			}
+			indexes[y*256+z*16+x] = 1
+			heights[z*16+x] = 64 + y + 1 + 64
+		}
+	}
+
+	// save.Chunk cannot encode its raw fields, so only the fields the
+	// renderer reads are written
+	type states struct {
+		Palette []blockState `nbt:"palette"`
+		Data    []uint64     `nbt:"data,omitempty"`
+	}
+	type section struct {
+		Y           int8   `nbt:"Y"`
+		BlockStates states `nbt:"block_states"`
+	}
+	c := struct {
+		XPos       int32               `nbt:"xPos"`
+		YPos       int32               `nbt:"yPos"`
+		ZPos       int32               `nbt:"zPos"`
+		Status     string              `nbt:"Status"`
+		Heightmaps map[string][]uint64 `nbt:"Heightmaps"`
+		Sections   []section           `nbt:"sections"`
+	}{
+		YPos:       -4,
+		Status:     "minecraft:full",
+		Heightmaps: map[string][]uint64{"WORLD_SURFACE": packLongs(heights, 9)},
+		Sections: []section{
+			{Y: 4, BlockStates: states{Palette: palette, Data: packLongs(indexes, 4)}},
+			{Y: 5, BlockStates: states{Palette: palette[:1]}},
+		},
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(3) // Uncompressed
+	if err := mcnbt.NewEncoder(&buf).Encode(c, ""); err != nil {
+		t.Fatalf("Failed to encode chunk: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeLevelDat writes a level.dat with the given spawn
+func writeLevelDat(t *testing.T, path string, spawnX, spawnZ int32) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create level.dat: %v", err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	level := nbt.LevelData{Data: nbt.LevelInfo{SpawnX: spawnX, SpawnZ: spawnZ, LevelName: "world"}}
+	if err := mcnbt.NewEncoder(w).Encode(level, ""); err != nil {
+		t.Fatalf("Failed to encode level.dat: %v", err)
+	}
+	w.Close()
+}
+
+func TestNativeRenderer(t *testing.T) {
+	worldDir := t.TempDir()
+	regionDir := filepath.Join(worldDir, "region")
+	os.MkdirAll(regionDir, 0755)
+	f := &region.File{}
+	f.Chunks[0] = &region.Chunk{Timestamp: 1, Data: testChunk(t)}
+	if err := f.Write(filepath.Join(regionDir, "r.0.0.mca")); err != nil {
+		t.Fatalf("Failed to write region: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "preview.png")
+	n := &NativeRenderer{Scale: 1}
+	err := n.RenderPreview(PreviewJob{
+		WorldDir:  worldDir,
+		Dimension: "overworld",
+		Area:      region.Box{MinX: 0, MinZ: 0, MaxX: 16, MaxZ: 15},
+		Output:    output,
+	})
+	if err != nil {
+		t.Fatalf("RenderPreview failed: %v", err)
+	}
+
+	out, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("Preview not written: %v", err)
+	}
+	defer out.Close()
+	img, err := png.Decode(out)
+	if err != nil {
+		t.Fatalf("Failed to decode preview: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 17 || b.Dy() != 16 {
+		t.Fatalf("Expected 17x16 image, got %v", b)
+	}
+
+	grass := blockColor("minecraft:grass_block")
+	tests := []struct {
+		name string
+		x, z int
+		want color.NRGBA
+	}{
+		{"flat", 0, 0, grass},
+		{"higher than north", 3, 1, shade(grass, 1.12)},
+		{"lower than north", 3, 2, shade(grass, 0.84)},
+		{"level with north", 3, 5, grass},
+		{"ungenerated", 16, 0, color.NRGBA{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := color.NRGBAModel.Convert(img.At(tt.x, tt.z)); got != tt.want {
+				t.Errorf("Pixel %d,%d = %v, want %v", tt.x, tt.z, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("bottomY", func(t *testing.T) {
+		// Nothing at or above y=66: the image is empty
+		bottom := 66
+		err := n.RenderPreview(PreviewJob{
+			WorldDir: worldDir,
+			Area:     region.Box{MinX: 0, MinZ: 0, MaxX: 15, MaxZ: 15},
+			Output:   output,
+			Options:  config.MapOptions{BottomY: &bottom},
+		})
+		if err != nil {
+			t.Fatalf("RenderPreview failed: %v", err)
+		}
+		out, _ := os.Open(output)
+		defer out.Close()
+		img, _ := png.Decode(out)
+		if got := color.NRGBAModel.Convert(img.At(0, 0)); got != (color.NRGBA{}) {
+			t.Errorf("Expected transparent pixel, got %v", got)
+		}
+	})
+
+	t.Run("web maps", func(t *testing.T) {
+		if err := n.RenderMap(MapJob{}); err == nil {
+			t.Error("Expected error rendering web map")
+		}
+	})
+}
+
+func TestUnpack(t *testing.T) {
+	t.Run("heightmap", func(t *testing.T) {
+		heights := make([]int, 256)
+		for i := range heights {
+			heights[i] = i + 100
+		}
+		got := unpackHeightmap(packLongs(heights, 9))
+		for i := range heights {
+			if got[i] != heights[i] {
+				t.Fatalf("Height %d = %d, want %d", i, got[i], heights[i])
+			}
+		}
+		if unpackHeightmap(nil) != nil {
+			t.Error("Expected nil heights without data")
+		}
+	})
+
+	t.Run("states", func(t *testing.T) {
+		// 17 palette entries need 5 bits
+		states := make([]int, 4096)
+		for i := range states {
+			states[i] = i % 17
+		}
+		got := unpackStates(packLongs(states, 5), 17)
+		for i := range states {
+			if got[i] != states[i] {
+				t.Fatalf("State %d = %d, want %d", i, got[i], states[i])
+			}
+		}
+	})
+}
+
+func TestBlockColor(t *testing.T) {
+	tests := []struct {
+		name string
+		want color.NRGBA
+	}{
+		{"minecraft:water", blockColors["minecraft:water"]},
+		{"minecraft:red_wool", dyeColors["red"]},
+		{"minecraft:light_blue_concrete", dyeColors["light_blue"]},
+		{"minecraft:oak_leaves", leavesColor},
+		{"minecraft:spruce_log", woodColor},
+		{"minecraft:deepslate_iron_ore", stoneColor},
+		{"mod:unknown_block", unknownColor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockColor(tt.name); got != tt.want {
+				t.Errorf("blockColor(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewFallback(t *testing.T) {
+	worldsDir, mapsDir := t.TempDir(), t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival", "world")
+	regionDir := filepath.Join(worldDir, "region")
+	os.MkdirAll(regionDir, 0755)
+	writeLevelDat(t, filepath.Join(worldDir, "level.dat"), 8, 8)
+	f := &region.File{}
+	f.Chunks[0] = &region.Chunk{Timestamp: 1, Data: testChunk(t)}
+	f.Write(filepath.Join(regionDir, "r.0.0.mca"))
+	os.WriteFile(filepath.Join(worldsDir, "survival", "map-config.yml"), []byte(`
+maps:
+  - name: overworld
+    dimension: overworld
+`), 0644)
+
+	b := &Builder{worldsDir: worldsDir, mapsDir: mapsDir, renderers: map[string]Renderer{
+		"unmined":          NewUnminedRenderer(filepath.Join(t.TempDir(), "unmined-cli")),
+		NativeRendererName: NewNativeRenderer(),
+	}}
+	if err := b.GeneratePreview("survival", "overworld", ""); err != nil {
+		t.Fatalf("GeneratePreview failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mapsDir, "survival", "overworld", "preview.png")); err != nil {
+		t.Errorf("Expected native preview: %v", err)
+	}
+}
@@ -3,13 +3,13 @@ package maps
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 
 	"github.com/paul/minecraftctl/pkg/config"
 	"github.com/paul/minecraftctl/pkg/nbt"
+	"github.com/paul/minecraftctl/pkg/region"
 	"github.com/rs/zerolog/log"
 )
 
@@ -36,6 +36,11 @@ func (b *Builder) GeneratePreview(worldName, mapName, logLevel string) error {
 		return fmt.Errorf("map '%s' not found in map-config.yml", mapName)
 	}
 
+	renderer, rendererName, err := b.previewRenderer(*mapDef)
+	if err != nil {
+		return err
+	}
+
 	worldDir := filepath.Join(worldPath, "world")
 	levelDatPath := filepath.Join(worldDir, "level.dat")
 
@@ -46,15 +51,7 @@ func (b *Builder) GeneratePreview(worldName, mapName, logLevel string) error {
 	}
 
 	// Verify dimension exists
-	dimDir := worldDir
-	switch mapDef.Dimension {
-	case "nether", "-1":
-		dimDir = filepath.Join(worldDir, "DIM-1")
-	case "end", "1":
-		dimDir = filepath.Join(worldDir, "DIM1")
-	}
-
-	regionDir := filepath.Join(dimDir, "region")
+	regionDir := filepath.Join(dimensionDir(worldDir, mapDef.Dimension), "region")
 	if _, err := os.Stat(regionDir); os.IsNotExist(err) {
 		return fmt.Errorf("dimension %s has no region data", mapDef.Dimension)
 	}
@@ -82,12 +79,12 @@ func (b *Builder) GeneratePreview(worldName, mapName, logLevel string) error {
 
 	// Calculate preview area (64 blocks around center)
 	const previewRange = 64
-	x1 := centerX - previewRange
-	z1 := centerZ - previewRange
-	x2 := centerX + previewRange
-	z2 := centerZ + previewRange
-
-	area := fmt.Sprintf("b((%d,%d),(%d,%d))", x1, z1, x2, z2)
+	area := region.Box{
+		MinX: centerX - previewRange,
+		MinZ: centerZ - previewRange,
+		MaxX: centerX + previewRange,
+		MaxZ: centerZ + previewRange,
+	}
 
 	outputSubdir := mapDef.OutputSubdir
 	if outputSubdir == "" {
@@ -104,27 +101,20 @@ func (b *Builder) GeneratePreview(worldName, mapName, logLevel string) error {
 	log.Info().
 		Str("map", mapName).
 		Str("dimension", mapDef.Dimension).
+		Str("renderer", rendererName).
 		Int("center_x", centerX).
 		Int("center_z", centerZ).
 		Msg("generating preview")
 
-	args := []string{
-		"image", "render",
-		"--world", worldDir,
-		"--dimension", mapDef.Dimension,
-		"--area", area,
-		"--zoom", "2",
-		"--log-level", logLevel,
-		"--output", previewPath,
-	}
-
-	// Apply map-specific options (gndxray, topY, bottomY, shadows, etc.)
-	args = b.addMapOptions(args, mapDef.Options)
-
-	cmd := exec.Command(b.unminedPath, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	err = renderer.RenderPreview(PreviewJob{
+		WorldDir:  worldDir,
+		Dimension: mapDef.Dimension,
+		Area:      area,
+		Output:    previewPath,
+		Options:   mapDef.Options,
+		LogLevel:  logLevel,
+	})
+	if err != nil {
 		return fmt.Errorf("failed to render preview: %w", err)
 	}
 
@@ -132,6 +122,35 @@ func (b *Builder) GeneratePreview(worldName, mapName, logLevel string) error {
 	return nil
 }
 
+// previewRenderer returns the renderer for a map's preview. Previews fall
+// back to the native renderer when the map's renderer cannot render them or
+// is not installed.
+func (b *Builder) previewRenderer(mapDef config.MapDefinition) (Renderer, string, error) {
+	renderer, name, err := b.renderer(mapDef)
+	if err != nil {
+		return nil, name, err
+	}
+
+	reason := ""
+	if !renderer.Capabilities().Preview {
+		reason = "renderer cannot render previews"
+	} else if a, ok := renderer.(availability); ok {
+		if err := a.Available(); err != nil {
+			reason = err.Error()
+		}
+	}
+	if reason == "" {
+		return renderer, name, nil
+	}
+
+	native, ok := b.renderers[NativeRendererName]
+	if !ok || name == NativeRendererName {
+		return nil, name, fmt.Errorf("renderer %s cannot render previews: %s", name, reason)
+	}
+	log.Warn().Str("renderer", name).Str("reason", reason).Msg("falling back to native preview renderer")
+	return native, NativeRendererName, nil
+}
+
 // calculateRegionCenter calculates the center block coordinates from region files.
 // Region files are named r.X.Z.mca where X and Z are region coordinates.
 // Each region is 512x512 blocks (32x32 chunks, each chunk is 16x16 blocks).
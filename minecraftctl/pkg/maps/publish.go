@@ -0,0 +1,430 @@
+package maps
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/s3"
+	"github.com/rs/zerolog/log"
+)
+
+// Cache-Control of published files. Tiles keep their keys when re-rendered,
+// so caches revalidate them hourly; the files pointing at tiles are always
+// revalidated.
+const (
+	tileCacheControl     = "public, max-age=3600"
+	metadataCacheControl = "no-cache"
+)
+
+// rootFiles are the files of the maps directory itself, uploaded last so a
+// visitor never sees a manifest pointing at tiles not uploaded yet
+var rootFiles = []string{"world_manifest.json", "index.html"}
+
+// contentTypes are the Content-Types of map files; other extensions use the
+// system's MIME types
+var contentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".js":   "text/javascript; charset=utf-8",
+	".json": "application/json",
+	".html": "text/html; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".svg":  "image/svg+xml",
+}
+
+// ObjectStore is a bucket maps are published to; *s3.Client implements it
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, data []byte, contentType, cacheControl string) error
+	DeleteObject(ctx context.Context, key string) error
+	ListObjects(ctx context.Context, prefix string) ([]s3.Object, error)
+}
+
+// Publisher uploads the maps directory to an object store
+type Publisher struct {
+	mapsDir string
+	store   ObjectStore
+}
+
+// NewPublisher creates a publisher of the configured maps directory
+func NewPublisher(store ObjectStore) *Publisher {
+	return &Publisher{mapsDir: config.Get().MapsDir, store: store}
+}
+
+// PublishOptions control map publishing
+type PublishOptions struct {
+	WorldName string // If empty, publish all worlds
+	Prefix    string // Key prefix, like the maps/ of maps/<world>/...
+	// IndexPath is the local publish index recording what was uploaded
+	IndexPath   string
+	Concurrency int
+	DryRun      bool
+	// Resync compares with a listing of the bucket instead of the index
+	Resync bool
+}
+
+// PublishResult summarizes a publish
+type PublishResult struct {
+	Uploaded      int
+	UploadedBytes int64
+	Deleted       int
+	Unchanged     int
+}
+
+// publishIndex records the files last published to a destination, so a
+// publish only compares local hashes instead of listing the bucket
+type publishIndex struct {
+	Objects map[string]publishedObject `json:"objects"`
+}
+
+// publishedObject is a published file. Size and ModTime are of the local
+// file the hash was computed from, to skip hashing unchanged files; they
+// are zero for entries taken from a bucket listing.
+type publishedObject struct {
+	MD5     string `json:"md5"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime,omitempty"`
+}
+
+// localFile is a file of the maps directory to publish
+type localFile struct {
+	path string
+	key  string
+	obj  publishedObject
+}
+
+// PublishIndexPath returns where the index of a destination is kept below
+// the cache directory
+func PublishIndexPath(cacheDir, endpoint, bucket, prefix string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + bucket + "\x00" + prefix))
+	return filepath.Join(cacheDir, "publish", fmt.Sprintf("%s-%s.json", bucket, hex.EncodeToString(sum[:6])))
+}
+
+// Publish uploads the files changed since the last publish, in three
+// phases: images, then the other files of the worlds' maps, then the root
+// world_manifest.json and index.html. Files removed locally are deleted
+// from the bucket last. The index is saved with whatever succeeded, also
+// when a phase fails.
+func (p *Publisher) Publish(ctx context.Context, opts PublishOptions) (*PublishResult, error) {
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	scope := prefix
+	scopeDir := p.mapsDir
+	if opts.WorldName != "" {
+		scope = prefix + opts.WorldName + "/"
+		scopeDir = filepath.Join(p.mapsDir, opts.WorldName)
+		if _, err := os.Stat(scopeDir); err != nil {
+			return nil, fmt.Errorf("no maps for world %s: %w", opts.WorldName, err)
+		}
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	index, err := p.loadIndex(ctx, opts, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := p.localFiles(scopeDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if opts.WorldName != "" {
+		for _, name := range rootFiles {
+			info, err := os.Stat(filepath.Join(p.mapsDir, name))
+			if err != nil {
+				continue
+			}
+			files = append(files, localFile{
+				path: filepath.Join(p.mapsDir, name),
+				key:  prefix + name,
+				obj:  publishedObject{Size: info.Size(), ModTime: info.ModTime().UnixNano()},
+			})
+		}
+	}
+
+	result := &PublishResult{}
+	local := make(map[string]bool, len(files))
+	var images, others, roots []localFile
+	for _, f := range files {
+		local[f.key] = true
+		prev, ok := index.Objects[f.key]
+		if ok && prev.Size == f.obj.Size && prev.ModTime == f.obj.ModTime && prev.ModTime != 0 {
+			f.obj.MD5 = prev.MD5
+		} else {
+			sum, err := fileMD5(f.path)
+			if err != nil {
+				return nil, err
+			}
+			f.obj.MD5 = sum
+			if ok && prev.MD5 == sum {
+				// Same content with a new mtime; remember the new mtime
+				index.Objects[f.key] = f.obj
+			}
+		}
+		if ok && prev.MD5 == f.obj.MD5 {
+			result.Unchanged++
+			continue
+		}
+
+		switch {
+		case isRootKey(f.key, prefix):
+			roots = append(roots, f)
+		case isImage(f.key):
+			images = append(images, f)
+		default:
+			others = append(others, f)
+		}
+	}
+
+	var removed []string
+	for key := range index.Objects {
+		if strings.HasPrefix(key, scope) && !local[key] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+
+	var mu sync.Mutex
+	upload := func(f localFile) error {
+		if opts.DryRun {
+			log.Info().Str("key", f.key).Msg("would upload")
+			return nil
+		}
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+		if err := p.store.PutObject(ctx, f.key, data, contentType(f.key), cacheControl(f.key)); err != nil {
+			return err
+		}
+		log.Debug().Str("key", f.key).Int("bytes", len(data)).Msg("uploaded")
+		mu.Lock()
+		index.Objects[f.key] = f.obj
+		result.Uploaded++
+		result.UploadedBytes += int64(len(data))
+		mu.Unlock()
+		return nil
+	}
+	remove := func(key string) error {
+		if opts.DryRun {
+			log.Info().Str("key", key).Msg("would delete")
+			return nil
+		}
+		if err := p.store.DeleteObject(ctx, key); err != nil {
+			return err
+		}
+		log.Debug().Str("key", key).Msg("deleted")
+		mu.Lock()
+		delete(index.Objects, key)
+		result.Deleted++
+		mu.Unlock()
+		return nil
+	}
+
+	err = nil
+	for _, phase := range [][]localFile{images, others, roots} {
+		if err = forEachParallel(ctx, opts.Concurrency, phase, upload); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = forEachParallel(ctx, opts.Concurrency, removed, remove)
+	}
+
+	if !opts.DryRun {
+		if saveErr := saveIndex(opts.IndexPath, index); saveErr != nil {
+			log.Warn().Err(saveErr).Msg("failed to save publish index")
+		}
+	} else {
+		result.Uploaded = len(images) + len(others) + len(roots)
+		result.Deleted = len(removed)
+	}
+	if err != nil {
+		return result, fmt.Errorf("publish failed: %w", err)
+	}
+	return result, nil
+}
+
+// loadIndex reads the publish index. Without one, or with Resync, the
+// scope's objects are listed from the bucket; their ETags are the MD5s of
+// files uploaded in one part, so unchanged files are not uploaded again.
+func (p *Publisher) loadIndex(ctx context.Context, opts PublishOptions, scope string) (*publishIndex, error) {
+	index := &publishIndex{Objects: make(map[string]publishedObject)}
+	if !opts.Resync && opts.IndexPath != "" {
+		data, err := os.ReadFile(opts.IndexPath)
+		if err == nil {
+			if err := json.Unmarshal(data, index); err == nil && index.Objects != nil {
+				return index, nil
+			}
+			log.Warn().Str("index", opts.IndexPath).Msg("ignoring unreadable publish index")
+			index.Objects = make(map[string]publishedObject)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read publish index: %w", err)
+		}
+	}
+
+	log.Info().Str("prefix", scope).Msg("listing bucket")
+	objects, err := p.store.ListObjects(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		index.Objects[o.Key] = publishedObject{MD5: o.ETag, Size: o.Size}
+	}
+	return index, nil
+}
+
+// localFiles returns the regular files below dir, keyed by their path
+// relative to the maps directory. Hidden files are skipped.
+func (p *Publisher) localFiles(dir, prefix string) ([]localFile, error) {
+	var files []localFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(p.mapsDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, localFile{
+			path: path,
+			key:  prefix + filepath.ToSlash(rel),
+			obj:  publishedObject{Size: info.Size(), ModTime: info.ModTime().UnixNano()},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk maps directory: %w", err)
+	}
+	return files, nil
+}
+
+// saveIndex writes the publish index atomically
+func saveIndex(indexPath string, index *publishIndex) error {
+	if indexPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	tmp := indexPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, indexPath)
+}
+
+// forEachParallel calls fn for each item with up to n calls at a time and
+// returns the first error. Items not started when an error occurs are
+// skipped.
+func forEachParallel[T any](ctx context.Context, n int, items []T, fn func(T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan T)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := 0; i < min(n, len(items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				if err := fn(item); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case work <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+func fileMD5(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// isRootKey reports whether a key is a file of the maps directory itself
+func isRootKey(key, prefix string) bool {
+	return !strings.Contains(strings.TrimPrefix(key, prefix), "/")
+}
+
+func isImage(key string) bool {
+	return strings.HasPrefix(contentType(key), "image/")
+}
+
+func contentType(key string) string {
+	ext := strings.ToLower(path.Ext(key))
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func cacheControl(key string) string {
+	if isImage(key) {
+		return tileCacheControl
+	}
+	return metadataCacheControl
+}
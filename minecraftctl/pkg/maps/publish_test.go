@@ -0,0 +1,209 @@
+package maps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/s3"
+	"github.com/paul/minecraftctl/pkg/s3/s3test"
+)
+
+// requestKinds returns the methods of requests in order, with consecutive
+// repeats merged, and the keys of each method
+func requestKinds(requests []string) ([]string, map[string][]string) {
+	var order []string
+	keys := make(map[string][]string)
+	for _, r := range requests {
+		method, key, _ := strings.Cut(r, " ")
+		kind := method
+		if method == "PUT" {
+			switch {
+			case !strings.Contains(strings.TrimPrefix(key, "maps/"), "/"):
+				kind = "PUT root"
+			case isImage(key):
+				kind = "PUT image"
+			default:
+				kind = "PUT other"
+			}
+		}
+		if len(order) == 0 || order[len(order)-1] != kind {
+			order = append(order, kind)
+		}
+		keys[method] = append(keys[method], key)
+	}
+	for _, k := range keys {
+		sort.Strings(k)
+	}
+	return order, keys
+}
+
+func TestPublish(t *testing.T) {
+	// Two worlds and the root index files, published to an S3 stand-in
+	mapsDir := t.TempDir()
+	files := map[string]string{
+		"survival/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg": "tile a",
+		"survival/overworld/tiles/zoom.0/0/0/tile.1.0.jpeg": "tile b",
+		"survival/overworld/unmined.map.properties.js":      "props",
+		"survival/overworld/manifest.json":                  "{}",
+		"survival/overworld/.hidden":                        "skip",
+		"creative/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg": "tile c",
+		"world_manifest.json":                               "[]",
+		"index.html":                                        "<html>",
+	}
+	for name, content := range files {
+		path := filepath.Join(mapsDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	srv := s3test.NewServer("maps")
+	defer srv.Close()
+	client := &s3.Client{Bucket: "maps", Endpoint: srv.URL, Credentials: s3.Credentials{AccessKeyID: "id", SecretAccessKey: "secret"}}
+	opts := PublishOptions{
+		Prefix:      "maps/",
+		IndexPath:   filepath.Join(t.TempDir(), "index.json"),
+		Concurrency: 4,
+	}
+	p := &Publisher{mapsDir: mapsDir, store: client}
+	ctx := context.Background()
+
+	// A stale tile and an already published one from before the index
+	srv.Put("maps/survival/overworld/tiles/zoom.0/0/0/tile.9.9.jpeg", []byte("stale"))
+	srv.Put("maps/creative/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg", []byte("tile c"))
+
+	t.Run("first publish", func(t *testing.T) {
+		result, err := p.Publish(ctx, opts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		if result.Uploaded != 6 || result.Unchanged != 1 || result.Deleted != 1 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+
+		order, keys := requestKinds(srv.Requests())
+		want := []string{"LIST", "PUT image", "PUT other", "PUT root", "DELETE"}
+		if !reflect.DeepEqual(order, want) {
+			t.Errorf("Request order = %v, want %v", order, want)
+		}
+		if !reflect.DeepEqual(keys["DELETE"], []string{"maps/survival/overworld/tiles/zoom.0/0/0/tile.9.9.jpeg"}) {
+			t.Errorf("Deleted %v", keys["DELETE"])
+		}
+
+		objects := srv.Objects()
+		if _, ok := objects["maps/survival/overworld/.hidden"]; ok {
+			t.Error("Hidden file was published")
+		}
+		tile := objects["maps/survival/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg"]
+		if tile.ContentType != "image/jpeg" || tile.CacheControl != tileCacheControl {
+			t.Errorf("Unexpected tile headers: %+v", tile)
+		}
+		index := objects["maps/index.html"]
+		if index.ContentType != "text/html; charset=utf-8" || index.CacheControl != metadataCacheControl {
+			t.Errorf("Unexpected index headers: %+v", index)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		srv.ResetRequests()
+		result, err := p.Publish(ctx, opts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		if result.Uploaded != 0 || result.Deleted != 0 || result.Unchanged != 7 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if requests := srv.Requests(); len(requests) != 0 {
+			t.Errorf("Expected no requests with an up-to-date index, got %v", requests)
+		}
+	})
+
+	t.Run("changed and removed tiles", func(t *testing.T) {
+		srv.ResetRequests()
+		tiles := filepath.Join(p.mapsDir, "survival", "overworld", "tiles", "zoom.0", "0", "0")
+		os.WriteFile(filepath.Join(tiles, "tile.0.0.jpeg"), []byte("tile a2"), 0644)
+		os.Remove(filepath.Join(tiles, "tile.1.0.jpeg"))
+
+		result, err := p.Publish(ctx, opts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		if result.Uploaded != 1 || result.Deleted != 1 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		_, keys := requestKinds(srv.Requests())
+		if !reflect.DeepEqual(keys["PUT"], []string{"maps/survival/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg"}) ||
+			!reflect.DeepEqual(keys["DELETE"], []string{"maps/survival/overworld/tiles/zoom.0/0/0/tile.1.0.jpeg"}) {
+			t.Errorf("Unexpected requests: %v", srv.Requests())
+		}
+		if got := string(srv.Objects()["maps/survival/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg"].Data); got != "tile a2" {
+			t.Errorf("Tile content = %q", got)
+		}
+	})
+
+	t.Run("one world", func(t *testing.T) {
+		srv.ResetRequests()
+		os.RemoveAll(filepath.Join(p.mapsDir, "creative"))
+		os.WriteFile(filepath.Join(p.mapsDir, "survival", "overworld", "manifest.json"), []byte(`{"map":"overworld"}`), 0644)
+
+		worldOpts := opts
+		worldOpts.WorldName = "survival"
+		result, err := p.Publish(ctx, worldOpts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		// The other world's removed maps are left alone
+		if result.Uploaded != 1 || result.Deleted != 0 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if _, ok := srv.Objects()["maps/creative/overworld/tiles/zoom.0/0/0/tile.0.0.jpeg"]; !ok {
+			t.Error("Other world's tile was deleted")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		srv.ResetRequests()
+		dryOpts := opts
+		dryOpts.DryRun = true
+		result, err := p.Publish(ctx, dryOpts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		if result.Deleted != 1 {
+			t.Errorf("Expected creative's tile to be deleted, got %+v", result)
+		}
+		if requests := srv.Requests(); len(requests) != 0 {
+			t.Errorf("Expected no requests in a dry run, got %v", requests)
+		}
+	})
+
+	t.Run("resync", func(t *testing.T) {
+		srv.ResetRequests()
+		srv.Put("maps/survival/overworld/orphan.js", []byte("x"))
+		resyncOpts := opts
+		resyncOpts.Resync = true
+		result, err := p.Publish(ctx, resyncOpts)
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		// The orphan and creative's tile are deleted; nothing is uploaded
+		if result.Uploaded != 0 || result.Deleted != 2 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestPublishIndexPath(t *testing.T) {
+	a := PublishIndexPath("/var/cache/minecraftctl", "", "maps", "maps")
+	b := PublishIndexPath("/var/cache/minecraftctl", "http://minio:9000", "maps", "maps")
+	if a == b {
+		t.Error("Expected different indexes for different endpoints")
+	}
+	if filepath.Dir(a) != "/var/cache/minecraftctl/publish" || !strings.HasPrefix(filepath.Base(a), "maps-") {
+		t.Errorf("Unexpected index path %s", a)
+	}
+}
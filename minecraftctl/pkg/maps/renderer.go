@@ -0,0 +1,93 @@
+package maps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// DefaultRenderer renders maps whose map-config.yml entry names no renderer
+const DefaultRenderer = "unmined"
+
+// NativeRendererName is the name of the built-in Go renderer, which
+// previews fall back to
+const NativeRendererName = "native"
+
+// Renderer is a map backend. The Builder decides what to render; the
+// renderer turns a world's region files into tiles and images.
+type Renderer interface {
+	// Capabilities reports which of the render methods are supported
+	Capabilities() Capabilities
+	// RenderMap renders the whole web map of job's dimension
+	RenderMap(job MapJob) error
+	// RenderArea renders the tiles of an area into an existing web map,
+	// leaving the rest of the map as it is
+	RenderArea(job MapJob, area region.Box) error
+	// RenderPreview renders an area into a single image
+	RenderPreview(job PreviewJob) error
+}
+
+// availability is implemented by renderers depending on external tools
+type availability interface {
+	// Available returns an error if the renderer cannot run on this host
+	Available() error
+}
+
+// Capabilities describe what a renderer can do. Builds fall back to full
+// renders without Areas, and skip ranges.
+type Capabilities struct {
+	WebMap  bool
+	Areas   bool
+	Preview bool
+}
+
+// MapJob describes a web map render
+type MapJob struct {
+	// WorldDir is the world's world/ directory
+	WorldDir  string
+	Dimension string
+	// Output is the map's directory in the maps directory
+	Output          string
+	ImageFormat     string
+	ChunkProcessors int
+	Zoomout         int
+	Zoomin          int
+	// MaxZoomin is the highest zoom of the map and its ranges, which the
+	// map's viewer must allow
+	MaxZoomin int
+	Options   config.MapOptions
+	// LogLevel is passed on to renderers with their own logging
+	LogLevel string
+}
+
+// PreviewJob describes a preview image render
+type PreviewJob struct {
+	WorldDir  string
+	Dimension string
+	Area      region.Box
+	// Output is the image file to write
+	Output   string
+	Options  config.MapOptions
+	LogLevel string
+}
+
+// renderer returns the renderer a map definition selects
+func (b *Builder) renderer(mapDef config.MapDefinition) (Renderer, string, error) {
+	name := mapDef.Renderer
+	if name == "" {
+		name = DefaultRenderer
+	}
+	r, ok := b.renderers[name]
+	if !ok {
+		names := make([]string, 0, len(b.renderers))
+		for n := range b.renderers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, name, fmt.Errorf("unknown renderer %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return r, name, nil
+}
@@ -0,0 +1,180 @@
+package maps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// fakeRenderer records the renders it is asked for. Area renders at
+// failZoomin fail, if set.
+type fakeRenderer struct {
+	caps       Capabilities
+	calls      []string
+	failZoomin int
+}
+
+func (f *fakeRenderer) Capabilities() Capabilities { return f.caps }
+
+func (f *fakeRenderer) RenderMap(job MapJob) error {
+	f.calls = append(f.calls, fmt.Sprintf("map zoomin=%d max=%d", job.Zoomin, job.MaxZoomin))
+	return writeTile(job, 0, 0)
+}
+
+func (f *fakeRenderer) RenderArea(job MapJob, area region.Box) error {
+	f.calls = append(f.calls, fmt.Sprintf("area %s zoomin=%d", area, job.Zoomin))
+	if f.failZoomin != 0 && job.Zoomin == f.failZoomin {
+		return fmt.Errorf("render failed")
+	}
+	return writeTile(job, area.MinX, area.MinZ)
+}
+
+// writeTile writes the tile covering a block at the job's zoom level, in
+// unmined's layout
+func writeTile(job MapJob, blockX, blockZ int) error {
+	blocks := tileSize >> job.Zoomin
+	x, z := floorDiv(blockX, blocks), floorDiv(blockZ, blocks)
+	dir := filepath.Join(job.Output, "tiles", fmt.Sprintf("zoom.%d", job.Zoomin),
+		strconv.Itoa(floorDiv(x, 10)), strconv.Itoa(floorDiv(z, 10)))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fmt.Sprintf("tile.%d.%d.png", x, z)), []byte("tile"), 0644)
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+func (f *fakeRenderer) RenderPreview(job PreviewJob) error {
+	f.calls = append(f.calls, fmt.Sprintf("preview %s", job.Area))
+	return nil
+}
+
+// writeWorld writes a world with two region files saved an hour ago and a
+// map-config.yml with an overworld map and a spawn range
+func writeWorld(t *testing.T, worldPath string) {
+	t.Helper()
+	regionDir := filepath.Join(worldPath, "world", "region")
+	os.MkdirAll(regionDir, 0755)
+	os.WriteFile(filepath.Join(worldPath, "world", "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(worldPath, "map-config.yml"), []byte(`
+maps:
+  - name: overworld
+    dimension: overworld
+    renderer: fake
+    ranges:
+      - name: spawn
+        center: [0, 0]
+        radius: 64
+        zoomin: 3
+`), 0644)
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), old, 0)
+	writeRegion(t, filepath.Join(regionDir, "r.2.2.mca"), old, 0)
+}
+
+func TestBuildIncremental(t *testing.T) {
+	fake := &fakeRenderer{caps: Capabilities{WebMap: true, Areas: true, Preview: true}}
+	worldsDir, mapsDir := t.TempDir(), t.TempDir()
+	writeWorld(t, filepath.Join(worldsDir, "survival"))
+	b := &Builder{worldsDir: worldsDir, mapsDir: mapsDir, renderers: map[string]Renderer{"fake": fake}}
+	regionDir := filepath.Join(worldsDir, "survival", "world", "region")
+	opts := BuildOptions{WorldName: "survival", NoLock: true}
+
+	build := func() []string {
+		t.Helper()
+		fake.calls = nil
+		if _, err := b.Build(opts); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		return fake.calls
+	}
+
+	// First build renders the range, then the whole map
+	want := []string{"area -64,-64,64,64 zoomin=3", "map zoomin=1 max=3"}
+	if calls := build(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("First build = %v, want %v", calls, want)
+	}
+
+	if calls := build(); len(calls) != 0 {
+		t.Errorf("Unchanged world should not render, got %v", calls)
+	}
+
+	// Save chunk (0,0) again: only the range's part of its area, then the
+	// area itself so the base map's tiles win
+	now := time.Now().Truncate(time.Second)
+	writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), now.Add(time.Second), 0)
+	want = []string{"area 0,0,15,15 zoomin=3", "area 0,0,15,15 zoomin=1"}
+	if calls := build(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Incremental build = %v, want %v", calls, want)
+	}
+
+	// Each changed area is rendered after the ranges overlapping it
+	now = now.Add(2 * time.Second)
+	writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), now, 0)
+	writeRegion(t, filepath.Join(regionDir, "r.2.2.mca"), now, 0)
+	want = []string{"area 0,0,15,15 zoomin=3", "area 0,0,15,15 zoomin=1", "area 1024,1024,1039,1039 zoomin=1"}
+	if calls := build(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Build of two areas = %v, want %v", calls, want)
+	}
+
+	// A failed range is rendered again by the next build
+	now = now.Add(2 * time.Second)
+	writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), now, 0)
+	fake.calls = nil
+	fake.failZoomin = 3
+	if _, err := b.Build(opts); err == nil {
+		t.Error("Expected error for failed range")
+	}
+	fake.failZoomin = 0
+	want = []string{"area 0,0,15,15 zoomin=3", "area 0,0,15,15 zoomin=1"}
+	if calls := build(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Build after failed range = %v, want %v", calls, want)
+	}
+	if calls := build(); len(calls) != 0 {
+		t.Errorf("Expected no renders after the range recovered, got %v", calls)
+	}
+
+	// --force renders everything again
+	opts.Force = true
+	want = []string{"area -64,-64,64,64 zoomin=3", "map zoomin=1 max=3"}
+	if calls := build(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Forced build = %v, want %v", calls, want)
+	}
+}
+
+func TestBuildWithoutAreas(t *testing.T) {
+	fake := &fakeRenderer{caps: Capabilities{WebMap: true}}
+	worldsDir, mapsDir := t.TempDir(), t.TempDir()
+	writeWorld(t, filepath.Join(worldsDir, "survival"))
+	b := &Builder{worldsDir: worldsDir, mapsDir: mapsDir, renderers: map[string]Renderer{"fake": fake}}
+	regionDir := filepath.Join(worldsDir, "survival", "world", "region")
+	opts := BuildOptions{WorldName: "survival", NoLock: true}
+
+	// Ranges are skipped and changes render the whole map
+	for i := 0; i < 2; i++ {
+		fake.calls = nil
+		if _, err := b.Build(opts); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if want := []string{"map zoomin=1 max=1"}; !reflect.DeepEqual(fake.calls, want) {
+			t.Errorf("Build %d = %v, want %v", i, fake.calls, want)
+		}
+		writeRegion(t, filepath.Join(regionDir, "r.0.0.mca"), time.Now().Add(time.Second), 0)
+	}
+
+	if err := b.GeneratePreview("survival", "overworld", ""); err == nil {
+		t.Error("Expected error for renderer without previews")
+	}
+}
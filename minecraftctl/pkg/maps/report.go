@@ -0,0 +1,210 @@
+package maps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// BuildReportFile is the name of the build report written next to a
+// world's manifest.json
+const BuildReportFile = "build-report.json"
+
+// Build statuses of maps and ranges
+const (
+	// BuildStatusRendered is a map or range rendered in full
+	BuildStatusRendered = "rendered"
+	// BuildStatusUpdated is a map or range whose changed areas were rendered
+	BuildStatusUpdated = "updated"
+	// BuildStatusUpToDate is a map or range without changes to render
+	BuildStatusUpToDate = "up-to-date"
+	// BuildStatusSkipped is a map not built after an earlier map failed,
+	// or a range its renderer cannot render
+	BuildStatusSkipped = "skipped"
+	BuildStatusFailed  = "failed"
+)
+
+// BuildReport is the outcome of building a world's maps
+type BuildReport struct {
+	World    string        `json:"world"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Maps     []MapReport   `json:"maps"`
+}
+
+// MapReport is the outcome of building one map. Tiles and Size are of the
+// map's whole output directory after the build.
+type MapReport struct {
+	Name     string        `json:"name"`
+	Renderer string        `json:"renderer,omitempty"`
+	Status   string        `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Tiles    int           `json:"tiles"`
+	Size     int64         `json:"size"`
+	Error    string        `json:"error,omitempty"`
+	Ranges   []RangeReport `json:"ranges,omitempty"`
+}
+
+// RangeReport is the outcome of rendering a range of a map. Tiles and Size
+// are of the range's tiles above the map's highest zoom level, the detail
+// the range adds to the map.
+type RangeReport struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Tiles    int           `json:"tiles"`
+	Size     int64         `json:"size"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Failed reports whether the map or any of its ranges failed
+func (m *MapReport) Failed() bool {
+	if m.Status == BuildStatusFailed {
+		return true
+	}
+	for _, r := range m.Ranges {
+		if r.Status == BuildStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed returns the number of failed maps
+func (r *BuildReport) Failed() int {
+	failed := 0
+	for i := range r.Maps {
+		if r.Maps[i].Failed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Err returns an error if any map failed, for the exit status of a build
+func (r *BuildReport) Err() error {
+	if failed := r.Failed(); failed > 0 {
+		return fmt.Errorf("%s: %d of %d maps failed", r.World, failed, len(r.Maps))
+	}
+	return nil
+}
+
+// rangeReport returns the report of the named range, adding it. An
+// incremental build renders a range once for every changed area.
+func (m *MapReport) rangeReport(name string) *RangeReport {
+	for i := range m.Ranges {
+		if m.Ranges[i].Name == name {
+			return &m.Ranges[i]
+		}
+	}
+	m.Ranges = append(m.Ranges, RangeReport{Name: name, Status: BuildStatusUpToDate})
+	return &m.Ranges[len(m.Ranges)-1]
+}
+
+// tilePattern matches the tiles of unmined's web map layout,
+// tiles/zoom.<zoom>/<x/10>/<z/10>/tile.<x>.<z>.<ext>
+var tilePattern = regexp.MustCompile(`^tiles/zoom\.(-?\d+)/-?\d+/-?\d+/tile\.(-?\d+)\.(-?\d+)\.\w+$`)
+
+// tileSize is the width of a tile in pixels; at zoom 0 a pixel is a block
+const tileSize = 256
+
+// countTiles fills in the tiles and sizes of a map and its ranges from the
+// map's output directory. A tile counts for a range when its zoom level is
+// above the map's and up to the range's, and it covers part of the range.
+func (m *MapReport) countTiles(mapOutput string, mapDef config.MapDefinition, defaults config.MapDefaults) error {
+	m.Tiles, m.Size = 0, 0
+	for i := range m.Ranges {
+		m.Ranges[i].Tiles, m.Ranges[i].Size = 0, 0
+	}
+
+	mapZoomin := defaults.Zoomin
+	if mapDef.Zoomin != nil {
+		mapZoomin = *mapDef.Zoomin
+	}
+	type rangeTiles struct {
+		report *RangeReport
+		box    region.Box
+		zoomin int
+	}
+	var ranges []rangeTiles
+	for _, r := range mapDef.Ranges {
+		box, err := rangeBox(r)
+		if err != nil || r.Zoomin == nil || *r.Zoomin <= mapZoomin {
+			continue
+		}
+		ranges = append(ranges, rangeTiles{report: m.rangeReport(r.Name), box: box, zoomin: *r.Zoomin})
+	}
+
+	err := filepath.WalkDir(mapOutput, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		m.Size += info.Size()
+		if !isImage(path) {
+			return nil
+		}
+		m.Tiles++
+
+		rel, err := filepath.Rel(mapOutput, path)
+		if err != nil {
+			return nil
+		}
+		match := tilePattern.FindStringSubmatch(filepath.ToSlash(rel))
+		if match == nil {
+			return nil
+		}
+		zoom, _ := strconv.Atoi(match[1])
+		x, _ := strconv.Atoi(match[2])
+		z, _ := strconv.Atoi(match[3])
+		if zoom <= mapZoomin {
+			return nil
+		}
+		// The blocks the tile covers
+		blocks := tileSize / math.Pow(2, float64(zoom))
+		minX, minZ := float64(x)*blocks, float64(z)*blocks
+		for _, r := range ranges {
+			if zoom > r.zoomin ||
+				minX > float64(r.box.MaxX) || minX+blocks <= float64(r.box.MinX) ||
+				minZ > float64(r.box.MaxZ) || minZ+blocks <= float64(r.box.MinZ) {
+				continue
+			}
+			r.report.Tiles++
+			r.report.Size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// writeBuildReport writes the report to the world's maps directory
+func writeBuildReport(worldMapsDir string, report *BuildReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build report: %w", err)
+	}
+	path := filepath.Join(worldMapsDir, BuildReportFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write build report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package maps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildReport(t *testing.T) {
+	fake := &fakeRenderer{caps: Capabilities{WebMap: true, Areas: true, Preview: true}}
+	worldsDir, mapsDir := t.TempDir(), t.TempDir()
+	writeWorld(t, filepath.Join(worldsDir, "survival"))
+	b := &Builder{worldsDir: worldsDir, mapsDir: mapsDir, renderers: map[string]Renderer{"fake": fake}}
+	opts := BuildOptions{WorldName: "survival", NoLock: true}
+	reportPath := filepath.Join(b.mapsDir, "survival", BuildReportFile)
+
+	t.Run("rendered", func(t *testing.T) {
+		report, err := b.Build(opts)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if len(report.Maps) != 1 {
+			t.Fatalf("Expected 1 map, got %+v", report.Maps)
+		}
+		m := report.Maps[0]
+		if m.Status != BuildStatusRendered || m.Renderer != "fake" || m.Tiles != 2 || m.Size == 0 {
+			t.Errorf("Unexpected map report: %+v", m)
+		}
+		if len(m.Ranges) != 1 || m.Ranges[0].Status != BuildStatusRendered || m.Ranges[0].Tiles != 1 {
+			t.Errorf("Unexpected range reports: %+v", m.Ranges)
+		}
+
+		data, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatalf("Expected %s: %v", BuildReportFile, err)
+		}
+		var written BuildReport
+		if err := json.Unmarshal(data, &written); err != nil {
+			t.Fatalf("Invalid build report: %v", err)
+		}
+		if written.World != "survival" || len(written.Maps) != 1 || written.Maps[0].Status != BuildStatusRendered {
+			t.Errorf("Unexpected build report: %+v", written)
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		report, err := b.Build(opts)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		m := report.Maps[0]
+		if m.Status != BuildStatusUpToDate || m.Ranges[0].Status != BuildStatusUpToDate || m.Tiles != 2 || m.Ranges[0].Tiles != 1 {
+			t.Errorf("Unexpected map report: %+v", m)
+		}
+	})
+
+	// The nether has no region data, so it fails before the overworld
+	os.WriteFile(filepath.Join(b.worldsDir, "survival", "map-config.yml"), []byte(`
+maps:
+  - name: nether
+    dimension: nether
+    renderer: fake
+  - name: overworld
+    dimension: overworld
+    renderer: fake
+    ranges:
+      - name: empty
+        center: [0, 0]
+        radius: 0
+`), 0644)
+
+	t.Run("failed map", func(t *testing.T) {
+		report, err := b.Build(opts)
+		if err == nil {
+			t.Fatal("Expected error for failed map")
+		}
+		if report.Failed() != 1 || report.Maps[0].Status != BuildStatusFailed || report.Maps[0].Error == "" {
+			t.Errorf("Unexpected nether report: %+v", report.Maps[0])
+		}
+		if report.Maps[1].Status != BuildStatusSkipped {
+			t.Errorf("Expected overworld to be skipped, got %+v", report.Maps[1])
+		}
+	})
+
+	t.Run("keep going", func(t *testing.T) {
+		keepGoing := opts
+		keepGoing.KeepGoing = true
+		keepGoing.Force = true
+		report, err := b.Build(keepGoing)
+		if err == nil {
+			t.Fatal("Expected error for failed map")
+		}
+		// The overworld renders, but its invalid range fails
+		m := report.Maps[1]
+		if m.Status != BuildStatusRendered || len(m.Ranges) != 1 || m.Ranges[0].Status != BuildStatusFailed {
+			t.Errorf("Unexpected overworld report: %+v", m)
+		}
+		if report.Failed() != 2 {
+			t.Errorf("Expected 2 failed maps, got %d", report.Failed())
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package maps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultUnminedPath is where the packer images install unmined-cli
+const DefaultUnminedPath = config.DefaultUnminedPath
+
+// UnminedRenderer renders maps with uNmINeD's command line tool
+type UnminedRenderer struct {
+	// Path is the unmined-cli binary
+	Path string
+}
+
+// NewUnminedRenderer returns a renderer running the unmined-cli at path
+func NewUnminedRenderer(path string) *UnminedRenderer {
+	if path == "" {
+		path = DefaultUnminedPath
+	}
+	return &UnminedRenderer{Path: path}
+}
+
+// Capabilities implements Renderer
+func (u *UnminedRenderer) Capabilities() Capabilities {
+	return Capabilities{WebMap: true, Areas: true, Preview: true}
+}
+
+// Available reports whether the unmined-cli binary is installed
+func (u *UnminedRenderer) Available() error {
+	if _, err := exec.LookPath(u.Path); err != nil {
+		return fmt.Errorf("unmined-cli not found: %w", err)
+	}
+	return nil
+}
+
+// RenderMap implements Renderer. unmined writes the map's bounds and zoom
+// levels to unmined.map.properties.js; maxZoom is raised to the job's
+// MaxZoomin when ranges zoom in further than the map.
+func (u *UnminedRenderer) RenderMap(job MapJob) error {
+	if err := u.run(webArgs(job)); err != nil {
+		return err
+	}
+
+	// Patch maxZoom in properties file if ranges have higher zoom levels
+	if job.MaxZoomin > job.Zoomin {
+		if err := patchPropertiesMaxZoom(job.Output, job.MaxZoomin); err != nil {
+			log.Warn().Err(err).Msg("failed to patch maxZoom in properties file")
+		}
+	}
+	return nil
+}
+
+// RenderArea implements Renderer. An area render rewrites unmined's map
+// scripts for the area alone, so they are put back afterwards to keep the
+// whole map's bounds and region list.
+func (u *UnminedRenderer) RenderArea(job MapJob, area region.Box) error {
+	scripts, err := saveMapScripts(job.Output)
+	if err != nil {
+		return fmt.Errorf("failed to read map scripts: %w", err)
+	}
+	defer func() {
+		if err := restoreMapScripts(scripts); err != nil {
+			log.Error().Err(err).Msg("failed to restore map scripts")
+		}
+	}()
+
+	return u.run(append(webArgs(job), areaArg(area)))
+}
+
+// RenderPreview implements Renderer
+func (u *UnminedRenderer) RenderPreview(job PreviewJob) error {
+	args := []string{
+		"image", "render",
+		"--world", job.WorldDir,
+		"--dimension", job.Dimension,
+		"--area", fmt.Sprintf("b((%d,%d),(%d,%d))", job.Area.MinX, job.Area.MinZ, job.Area.MaxX, job.Area.MaxZ),
+		"--zoom", "2",
+		"--log-level", job.LogLevel,
+		"--output", job.Output,
+	}
+
+	// Apply map-specific options (gndxray, topY, bottomY, shadows, etc.)
+	return u.run(addMapOptions(args, job.Options))
+}
+
+func (u *UnminedRenderer) run(args []string) error {
+	log.Debug().Str("unmined", u.Path).Strs("args", args).Msg("running unmined")
+	cmd := exec.Command(u.Path, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// webArgs returns the unmined web render arguments of a job
+func webArgs(job MapJob) []string {
+	args := []string{
+		"web", "render",
+		"--world", job.WorldDir,
+		"--dimension", job.Dimension,
+		"--output", job.Output,
+		"--imageformat", job.ImageFormat,
+		"--chunkprocessors", strconv.Itoa(job.ChunkProcessors),
+		"--log-level", job.LogLevel,
+		"--zoomout", strconv.Itoa(job.Zoomout),
+		"--zoomin", strconv.Itoa(job.Zoomin),
+	}
+
+	// Apply map-specific options (gndxray, topY, bottomY, shadows, etc.)
+	return addMapOptions(args, job.Options)
+}
+
+// areaArg formats an area as unmined's --area option
+func areaArg(area region.Box) string {
+	return fmt.Sprintf("--area=b((%d,%d),(%d,%d))", area.MinX, area.MinZ, area.MaxX, area.MaxZ)
+}
+
+func addMapOptions(args []string, opts config.MapOptions) []string {
+	if opts.TopY != nil {
+		args = append(args, "--topY", strconv.Itoa(*opts.TopY))
+	}
+	if opts.BottomY != nil {
+		args = append(args, "--bottomY", strconv.Itoa(*opts.BottomY))
+	}
+	if opts.GndXray != nil && *opts.GndXray {
+		args = append(args, "--gndxray", "true")
+	}
+	if opts.Night != nil && *opts.Night {
+		args = append(args, "--night", "true")
+	}
+	if opts.Shadows != nil {
+		var shadowArg string
+		switch v := opts.Shadows.(type) {
+		case bool:
+			if v {
+				shadowArg = "true"
+			} else {
+				shadowArg = "false"
+			}
+		case string:
+			shadowArg = v
+		}
+		if shadowArg != "" {
+			args = append(args, "--shadows", shadowArg)
+		}
+	}
+	return args
+}
+
+// patchPropertiesMaxZoom updates the maxZoom value in unmined.map.properties.js
+// This is needed because ranges may have higher zoom levels than the base map,
+// but the base map render (which runs last) overwrites the properties file.
+func patchPropertiesMaxZoom(mapOutput string, maxZoom int) error {
+	propsPath := filepath.Join(mapOutput, "unmined.map.properties.js")
+
+	data, err := os.ReadFile(propsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read properties file: %w", err)
+	}
+
+	// Replace maxZoom value
+	re := regexp.MustCompile(`maxZoom:\s*\d+`)
+	newData := re.ReplaceAll(data, []byte(fmt.Sprintf("maxZoom: %d", maxZoom)))
+
+	if err := os.WriteFile(propsPath, newData, 0644); err != nil {
+		return fmt.Errorf("failed to write properties file: %w", err)
+	}
+
+	log.Info().Int("maxZoom", maxZoom).Msg("patched maxZoom in properties file")
+	return nil
+}
+
+// saveMapScripts reads unmined's unmined.map.*.js files
+func saveMapScripts(mapOutput string) (map[string][]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(mapOutput, "unmined.map.*.js"))
+	if err != nil {
+		return nil, err
+	}
+	scripts := make(map[string][]byte)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		scripts[path] = data
+	}
+	return scripts, nil
+}
+
+// restoreMapScripts writes back files read by saveMapScripts
+func restoreMapScripts(scripts map[string][]byte) error {
+	for path, data := range scripts {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
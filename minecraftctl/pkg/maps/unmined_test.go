@@ -0,0 +1,92 @@
+package maps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/region"
+)
+
+// fakeUnmined returns a renderer running a script that logs its arguments
+// and writes a properties file like unmined's, and the log's path
+func fakeUnmined(t *testing.T) (*UnminedRenderer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "unmined.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+out=""
+prev=""
+for arg in "$@"; do
+	[ "$prev" = "--output" ] && out="$arg"
+	prev="$arg"
+done
+[ "$1" = "web" ] || exit 0
+mkdir -p "$out"
+echo "maxZoom: 1, args: $*" > "$out/unmined.map.properties.js"
+`
+	path := filepath.Join(dir, "unmined-cli")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake unmined: %v", err)
+	}
+	return NewUnminedRenderer(path), logPath
+}
+
+func TestUnminedRenderer(t *testing.T) {
+	u, logPath := fakeUnmined(t)
+	output := filepath.Join(t.TempDir(), "overworld")
+	propsPath := filepath.Join(output, "unmined.map.properties.js")
+	job := MapJob{
+		WorldDir:        "/srv/worlds/survival/world",
+		Dimension:       "overworld",
+		Output:          output,
+		ImageFormat:     "jpeg",
+		ChunkProcessors: 2,
+		Zoomout:         2,
+		Zoomin:          1,
+		MaxZoomin:       3,
+		LogLevel:        "warning",
+	}
+	lastArgs := func() string {
+		data, _ := os.ReadFile(logPath)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		return lines[len(lines)-1]
+	}
+
+	if err := u.RenderMap(job); err != nil {
+		t.Fatalf("RenderMap failed: %v", err)
+	}
+	if args := lastArgs(); !strings.HasPrefix(args, "web render --world /srv/worlds/survival/world --dimension overworld") {
+		t.Errorf("Unexpected web render args: %s", args)
+	}
+	props, _ := os.ReadFile(propsPath)
+	if !strings.Contains(string(props), "maxZoom: 3") {
+		t.Errorf("maxZoom should be raised to the ranges' zoom: %s", props)
+	}
+
+	if err := u.RenderArea(job, region.Box{MinX: 0, MinZ: 0, MaxX: 15, MaxZ: 15}); err != nil {
+		t.Fatalf("RenderArea failed: %v", err)
+	}
+	if args := lastArgs(); !strings.HasSuffix(args, "--area=b((0,0),(15,15))") {
+		t.Errorf("Unexpected area render args: %s", args)
+	}
+	if after, _ := os.ReadFile(propsPath); string(after) != string(props) {
+		t.Errorf("Area render should keep the map's properties, got %s", after)
+	}
+
+	err := u.RenderPreview(PreviewJob{
+		WorldDir:  job.WorldDir,
+		Dimension: "nether",
+		Area:      region.Box{MinX: -64, MinZ: -64, MaxX: 64, MaxZ: 64},
+		Output:    filepath.Join(output, "preview.png"),
+		LogLevel:  "warning",
+	})
+	if err != nil {
+		t.Fatalf("RenderPreview failed: %v", err)
+	}
+	if args := lastArgs(); !strings.HasPrefix(args, "image render --world /srv/worlds/survival/world --dimension nether --area b((-64,-64),(64,64)) --zoom 2") {
+		t.Errorf("Unexpected preview args: %s", args)
+	}
+}
@@ -0,0 +1,61 @@
+package nbt
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+
+	nbtlib "github.com/Tnze/go-mc/nbt"
+)
+
+// ReadPlayerDat reads a player's position from their playerdata/<uuid>.dat
+// file, which is gzip-compressed NBT like level.dat
+func ReadPlayerDat(playerDatPath string) (*PlayerInfo, error) {
+	file, err := os.Open(playerDatPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open player data: %w", err)
+	}
+	defer file.Close()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzReader.Close()
+
+	var info PlayerInfo
+	if _, err := nbtlib.NewDecoder(gzReader).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode NBT: %w", err)
+	}
+	if len(info.Pos) < 3 {
+		return nil, fmt.Errorf("player data has no position")
+	}
+
+	return &info, nil
+}
+
+// PlayerInfo represents the position tags of a playerdata file
+type PlayerInfo struct {
+	// Pos is the player's x, y, z position
+	Pos []float64 `nbt:"Pos"`
+	// Dimension is a resource location like "minecraft:the_nether" since
+	// 1.16, and -1, 0 or 1 before
+	Dimension any `nbt:"Dimension"`
+}
+
+// GetDimension returns the player's dimension as a resource location,
+// handling both old and new formats
+func (p *PlayerInfo) GetDimension() string {
+	switch d := p.Dimension.(type) {
+	case string:
+		return d
+	case int32:
+		switch d {
+		case -1:
+			return "minecraft:the_nether"
+		case 1:
+			return "minecraft:the_end"
+		}
+	}
+	return "minecraft:overworld"
+}
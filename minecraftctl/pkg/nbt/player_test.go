@@ -0,0 +1,78 @@
+package nbt
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	nbtlib "github.com/Tnze/go-mc/nbt"
+)
+
+// writePlayerDat writes a gzip-compressed playerdata file with v as its root
+func writePlayerDat(t *testing.T, v any) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "player.dat")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create player data: %v", err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	if err := nbtlib.NewEncoder(w).Encode(v, ""); err != nil {
+		t.Fatalf("Failed to encode player data: %v", err)
+	}
+	w.Close()
+	return path
+}
+
+func TestReadPlayerDat(t *testing.T) {
+	t.Run("resource location dimension", func(t *testing.T) {
+		path := writePlayerDat(t, struct {
+			Pos       []float64 `nbt:"Pos"`
+			Dimension string    `nbt:"Dimension"`
+			Health    float32   `nbt:"Health"`
+		}{[]float64{10.5, 64, -20.25}, "minecraft:the_nether", 20})
+
+		info, err := ReadPlayerDat(path)
+		if err != nil {
+			t.Fatalf("ReadPlayerDat failed: %v", err)
+		}
+		if info.Pos[0] != 10.5 || info.Pos[1] != 64 || info.Pos[2] != -20.25 {
+			t.Errorf("Pos = %v, want [10.5 64 -20.25]", info.Pos)
+		}
+		if got := info.GetDimension(); got != "minecraft:the_nether" {
+			t.Errorf("GetDimension() = %q, want minecraft:the_nether", got)
+		}
+	})
+
+	t.Run("legacy dimension", func(t *testing.T) {
+		path := writePlayerDat(t, struct {
+			Pos       []float64 `nbt:"Pos"`
+			Dimension int32     `nbt:"Dimension"`
+		}{[]float64{0, 70, 0}, 1})
+
+		info, err := ReadPlayerDat(path)
+		if err != nil {
+			t.Fatalf("ReadPlayerDat failed: %v", err)
+		}
+		if got := info.GetDimension(); got != "minecraft:the_end" {
+			t.Errorf("GetDimension() = %q, want minecraft:the_end", got)
+		}
+	})
+
+	t.Run("no position", func(t *testing.T) {
+		path := writePlayerDat(t, struct {
+			Health float32 `nbt:"Health"`
+		}{20})
+		if _, err := ReadPlayerDat(path); err == nil {
+			t.Error("Expected error for player data without position")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := ReadPlayerDat("/nonexistent/player.dat"); err == nil {
+			t.Error("Expected error for missing file, got nil")
+		}
+	})
+}
@@ -2,11 +2,19 @@ package rcon
 
 import (
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/gorcon/rcon"
 	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/properties"
 )
 
+// worldCommandTimeout bounds a single command on a world's RCON connection.
+// It is longer than the library default because save-all flush only answers
+// once the world has been written to disk.
+const worldCommandTimeout = 2 * time.Minute
+
 // Client wraps an RCON connection
 type Client struct {
 	conn *rcon.Conn
@@ -33,6 +41,36 @@ func NewClientWithConfig(host string, port int, password string) (*Client, error
 	return &Client{conn: conn}, nil
 }
 
+// NewWorldClient connects to the RCON endpoint of the world in worldDir,
+// using rcon.port and rcon.password from its server.properties and the
+// configured RCON host
+func NewWorldClient(worldDir string) (*Client, error) {
+	props, err := properties.Load(filepath.Join(worldDir, "server.properties"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server.properties: %w", err)
+	}
+	if enabled, err := props.GetBool("enable-rcon"); err != nil || !enabled {
+		return nil, fmt.Errorf("RCON is not enabled in %s", props.Path())
+	}
+
+	port, err := props.GetInt("rcon.port")
+	if err != nil {
+		port = config.DefaultRconPort
+	}
+	password, _ := props.Get("rcon.password")
+	if password == "" {
+		return nil, fmt.Errorf("rcon.password not set in %s", props.Path())
+	}
+
+	addr := fmt.Sprintf("%s:%d", config.Get().Rcon.Host, port)
+	conn, err := rcon.Dial(addr, password, rcon.SetDeadline(worldCommandTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RCON at %s: %w", addr, err)
+	}
+
+	return &Client{conn: conn}, nil
+}
+
 // Send executes a command via RCON and returns the response
 func (c *Client) Send(command string) (string, error) {
 	resp, err := c.conn.Execute(command)
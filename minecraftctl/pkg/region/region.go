@@ -0,0 +1,320 @@
+// Package region reads, writes and splices Anvil region files (r.<x>.<z>.mca)
+package region
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SectorSize is the allocation unit of region files
+	SectorSize = 4096
+	// RegionChunks is the width of a region in chunks
+	RegionChunks = 32
+	// ChunksPerRegion is the number of chunks a region file holds
+	ChunksPerRegion = RegionChunks * RegionChunks
+
+	headerSize = 2 * SectorSize
+	// externalFlag marks a chunk stored in a separate c.<x>.<z>.mcc file
+	externalFlag = 0x80
+)
+
+// Chunk is a chunk as stored in a region file
+type Chunk struct {
+	// Timestamp is the last modification time in epoch seconds
+	Timestamp uint32
+	// Data is the chunk payload: its compression type byte followed by
+	// the compressed NBT. The 4-byte length prefix is not included.
+	Data []byte
+}
+
+// External reports whether the chunk's data is in a c.<x>.<z>.mcc file next
+// to the region file
+func (c *Chunk) External() bool {
+	return len(c.Data) > 0 && c.Data[0]&externalFlag != 0
+}
+
+// File is a parsed region file. Chunks are indexed by (x & 31) + (z & 31) * 32
+// and nil where no chunk has been generated.
+type File struct {
+	Chunks [ChunksPerRegion]*Chunk
+}
+
+// Index returns the position of the chunk with absolute coordinates cx, cz
+// in its region file
+func Index(cx, cz int) int {
+	return (cx & (RegionChunks - 1)) + (cz&(RegionChunks-1))*RegionChunks
+}
+
+// FileName returns the name of the region file at region coordinates rx, rz
+func FileName(rx, rz int) string {
+	return fmt.Sprintf("r.%d.%d.mca", rx, rz)
+}
+
+// ParseFileName returns the region coordinates of a region file name such
+// as r.-1.2.mca
+func ParseFileName(name string) (rx, rz int, ok bool) {
+	parts := strings.Split(name, ".")
+	if len(parts) != 4 || parts[0] != "r" || parts[3] != "mca" {
+		return 0, 0, false
+	}
+	rx, errX := strconv.Atoi(parts[1])
+	rz, errZ := strconv.Atoi(parts[2])
+	if errX != nil || errZ != nil {
+		return 0, 0, false
+	}
+	return rx, rz, true
+}
+
+// ExternalFileName returns the name of the file holding an oversized chunk
+// with absolute coordinates cx, cz
+func ExternalFileName(cx, cz int) string {
+	return fmt.Sprintf("c.%d.%d.mcc", cx, cz)
+}
+
+// Read reads and parses a region file
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses region file data. An empty file has no chunks.
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	if len(data) == 0 {
+		return f, nil
+	}
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("region file too short: %d bytes", len(data))
+	}
+
+	for i := 0; i < ChunksPerRegion; i++ {
+		loc := binary.BigEndian.Uint32(data[i*4:])
+		if loc == 0 {
+			continue
+		}
+		offset := int(loc>>8) * SectorSize
+		sectors := int(loc & 0xff)
+		if offset < headerSize || offset+4 > len(data) || sectors == 0 {
+			return nil, fmt.Errorf("chunk %d: invalid location (sector %d, count %d)", i, loc>>8, sectors)
+		}
+
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if length < 1 || length > sectors*SectorSize-4 || offset+4+length > len(data) {
+			return nil, fmt.Errorf("chunk %d: invalid length %d", i, length)
+		}
+
+		chunk := &Chunk{
+			Timestamp: binary.BigEndian.Uint32(data[SectorSize+i*4:]),
+			Data:      make([]byte, length),
+		}
+		copy(chunk.Data, data[offset+4:offset+4+length])
+		f.Chunks[i] = chunk
+	}
+	return f, nil
+}
+
+// ReadTimestamps reads the modification times of a region file's chunks
+// from its header without loading the chunks. Chunks that have not been
+// generated are 0. An empty file has no chunks.
+func ReadTimestamps(path string) ([ChunksPerRegion]uint32, error) {
+	var timestamps [ChunksPerRegion]uint32
+	f, err := os.Open(path)
+	if err != nil {
+		return timestamps, err
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if n == 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+		return timestamps, nil
+	}
+	if err != nil {
+		return timestamps, fmt.Errorf("%s: region file too short: %d bytes", path, n)
+	}
+
+	for i := range timestamps {
+		if binary.BigEndian.Uint32(header[i*4:]) != 0 {
+			timestamps[i] = binary.BigEndian.Uint32(header[SectorSize+i*4:])
+		}
+	}
+	return timestamps, nil
+}
+
+// Bytes encodes the region file, packing chunks in index order
+func (f *File) Bytes() []byte {
+	size := headerSize
+	for _, c := range f.Chunks {
+		if c != nil {
+			size += sectorsFor(len(c.Data)) * SectorSize
+		}
+	}
+
+	data := make([]byte, size)
+	sector := headerSize / SectorSize
+	for i, c := range f.Chunks {
+		if c == nil {
+			continue
+		}
+		sectors := sectorsFor(len(c.Data))
+		offset := sector * SectorSize
+		binary.BigEndian.PutUint32(data[i*4:], uint32(sector)<<8|uint32(sectors))
+		binary.BigEndian.PutUint32(data[SectorSize+i*4:], c.Timestamp)
+		binary.BigEndian.PutUint32(data[offset:], uint32(len(c.Data)))
+		copy(data[offset+4:], c.Data)
+		sector += sectors
+	}
+	return data
+}
+
+// sectorsFor returns the sectors a chunk payload of n bytes occupies with
+// its length prefix
+func sectorsFor(n int) int {
+	return (n + 4 + SectorSize - 1) / SectorSize
+}
+
+// Empty reports whether the file holds no chunks
+func (f *File) Empty() bool {
+	for _, c := range f.Chunks {
+		if c != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes the region file atomically
+func (f *File) Write(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".region-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(f.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Box is an area in block coordinates, bounds inclusive
+type Box struct {
+	MinX int `json:"min_x"`
+	MinZ int `json:"min_z"`
+	MaxX int `json:"max_x"`
+	MaxZ int `json:"max_z"`
+}
+
+// RegionBox returns the blocks covered by the region at rx, rz
+func RegionBox(rx, rz int) Box {
+	const size = RegionChunks * 16
+	return Box{MinX: rx * size, MinZ: rz * size, MaxX: rx*size + size - 1, MaxZ: rz*size + size - 1}
+}
+
+// Union returns the smallest box containing b and o
+func (b Box) Union(o Box) Box {
+	return Box{MinX: min(b.MinX, o.MinX), MinZ: min(b.MinZ, o.MinZ), MaxX: max(b.MaxX, o.MaxX), MaxZ: max(b.MaxZ, o.MaxZ)}
+}
+
+// Intersect returns the blocks in both boxes, and false if they do not
+// overlap
+func (b Box) Intersect(o Box) (Box, bool) {
+	i := Box{MinX: max(b.MinX, o.MinX), MinZ: max(b.MinZ, o.MinZ), MaxX: min(b.MaxX, o.MaxX), MaxZ: min(b.MaxZ, o.MaxZ)}
+	return i, i.MinX <= i.MaxX && i.MinZ <= i.MaxZ
+}
+
+// ParseBox parses "x1,z1,x2,z2" block coordinates of two opposite corners
+func ParseBox(s string) (Box, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return Box{}, fmt.Errorf("invalid box %q: expected x1,z1,x2,z2", s)
+	}
+	var v [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return Box{}, fmt.Errorf("invalid box %q: %w", s, err)
+		}
+		v[i] = n
+	}
+	return Box{
+		MinX: min(v[0], v[2]), MinZ: min(v[1], v[3]),
+		MaxX: max(v[0], v[2]), MaxZ: max(v[1], v[3]),
+	}, nil
+}
+
+// Chunks returns the inclusive chunk coordinate range the box touches
+func (b Box) Chunks() (minCX, minCZ, maxCX, maxCZ int) {
+	// Arithmetic shifts floor negative coordinates
+	return b.MinX >> 4, b.MinZ >> 4, b.MaxX >> 4, b.MaxZ >> 4
+}
+
+// ContainsChunk reports whether the chunk at cx, cz overlaps the box
+func (b Box) ContainsChunk(cx, cz int) bool {
+	minCX, minCZ, maxCX, maxCZ := b.Chunks()
+	return cx >= minCX && cx <= maxCX && cz >= minCZ && cz <= maxCZ
+}
+
+// Regions returns the region coordinates the box touches
+func (b Box) Regions() [][2]int {
+	minCX, minCZ, maxCX, maxCZ := b.Chunks()
+	var regions [][2]int
+	for rz := minCZ >> 5; rz <= maxCZ>>5; rz++ {
+		for rx := minCX >> 5; rx <= maxCX>>5; rx++ {
+			regions = append(regions, [2]int{rx, rz})
+		}
+	}
+	return regions
+}
+
+// String formats the box as x1,z1,x2,z2
+func (b Box) String() string {
+	return fmt.Sprintf("%d,%d,%d,%d", b.MinX, b.MinZ, b.MaxX, b.MaxZ)
+}
+
+// SpliceChunk describes a chunk replaced by Splice, in absolute chunk
+// coordinates
+type SpliceChunk struct {
+	X, Z int
+	// Old and New are the replaced and the spliced-in chunk, nil if absent
+	Old, New *Chunk
+}
+
+// Splice replaces the chunks of dst, the region file at rx, rz, that overlap
+// box with the chunks of src. Chunks outside the box are left untouched.
+// It returns the chunks that were considered, whether or not they differed.
+func Splice(dst, src *File, rx, rz int, box Box) []SpliceChunk {
+	var spliced []SpliceChunk
+	for lz := 0; lz < RegionChunks; lz++ {
+		for lx := 0; lx < RegionChunks; lx++ {
+			cx, cz := rx*RegionChunks+lx, rz*RegionChunks+lz
+			if !box.ContainsChunk(cx, cz) {
+				continue
+			}
+			i := Index(cx, cz)
+			spliced = append(spliced, SpliceChunk{X: cx, Z: cz, Old: dst.Chunks[i], New: src.Chunks[i]})
+			dst.Chunks[i] = src.Chunks[i]
+		}
+	}
+	return spliced
+}
@@ -0,0 +1,155 @@
+package region
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testChunk(ts uint32, size int, fill byte) *Chunk {
+	data := bytes.Repeat([]byte{fill}, size)
+	data[0] = 2 // zlib
+	return &Chunk{Timestamp: ts, Data: data}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f := &File{}
+	f.Chunks[Index(0, 0)] = testChunk(100, 10, 'a')
+	f.Chunks[Index(31, 31)] = testChunk(200, 5000, 'b') // spans two sectors
+	f.Chunks[Index(-1, 3)] = testChunk(300, 4092, 'c')  // exactly one sector with its prefix
+
+	data := f.Bytes()
+	if len(data)%SectorSize != 0 {
+		t.Errorf("File size %d is not sector aligned", len(data))
+	}
+	if want := (2 + 1 + 2 + 1) * SectorSize; len(data) != want {
+		t.Errorf("File size = %d, want %d", len(data), want)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName(0, 0))
+	if err := f.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Error("Region file changed in round trip")
+	}
+	timestamps, err := ReadTimestamps(path)
+	if err != nil {
+		t.Fatalf("ReadTimestamps failed: %v", err)
+	}
+	if timestamps[Index(31, 31)] != 200 || timestamps[Index(-1, 3)] != 300 || timestamps[Index(1, 0)] != 0 {
+		t.Errorf("Unexpected timestamps: %v", timestamps[:4])
+	}
+
+	t.Run("invalid files", func(t *testing.T) {
+		if _, err := Parse(make([]byte, 100)); err == nil {
+			t.Error("Expected error for short file")
+		}
+		bad := append([]byte(nil), data...)
+		bad[0], bad[1], bad[2] = 0xff, 0xff, 0xff
+		if _, err := Parse(bad); err == nil {
+			t.Error("Expected error for chunk outside the file")
+		}
+	})
+
+	if f, err := Parse(nil); err != nil || !f.Empty() {
+		t.Errorf("Empty data should parse as empty file: %v", err)
+	}
+}
+
+func TestBox(t *testing.T) {
+	box, err := ParseBox("40, -20, -100, 600")
+	if err != nil {
+		t.Fatalf("ParseBox failed: %v", err)
+	}
+	if box != (Box{MinX: -100, MinZ: -20, MaxX: 40, MaxZ: 600}) {
+		t.Errorf("ParseBox() = %+v", box)
+	}
+
+	minCX, minCZ, maxCX, maxCZ := box.Chunks()
+	if minCX != -7 || minCZ != -2 || maxCX != 2 || maxCZ != 37 {
+		t.Errorf("Chunks() = %d,%d,%d,%d", minCX, minCZ, maxCX, maxCZ)
+	}
+	want := [][2]int{{-1, -1}, {0, -1}, {-1, 0}, {0, 0}, {-1, 1}, {0, 1}}
+	if got := box.Regions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Regions() = %v, want %v", got, want)
+	}
+	if !box.ContainsChunk(-7, 37) || box.ContainsChunk(-8, 0) || box.ContainsChunk(3, 0) {
+		t.Error("ContainsChunk() wrong at the edges")
+	}
+
+	if got, ok := box.Intersect(RegionBox(0, 0)); !ok || got != (Box{MinX: 0, MinZ: 0, MaxX: 40, MaxZ: 511}) {
+		t.Errorf("Intersect() = %v, %v", got, ok)
+	}
+	if _, ok := box.Intersect(RegionBox(5, 5)); ok {
+		t.Error("Intersect() should report disjoint boxes")
+	}
+
+	for _, s := range []string{"1,2,3", "a,b,c,d", ""} {
+		if _, err := ParseBox(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestSplice(t *testing.T) {
+	dst, src := &File{}, &File{}
+	for cx := 0; cx < 4; cx++ {
+		dst.Chunks[Index(cx, 0)] = testChunk(1, 10, 'd')
+		src.Chunks[Index(cx, 0)] = testChunk(2, 10, 's')
+	}
+	// Generated after the snapshot, inside the box
+	dst.Chunks[Index(1, 1)] = testChunk(1, 10, 'n')
+
+	// Blocks 16..47 cover chunks 1 and 2
+	spliced := Splice(dst, src, 0, 0, Box{MinX: 16, MinZ: 0, MaxX: 47, MaxZ: 31})
+	if len(spliced) != 4 {
+		t.Errorf("Expected 4 chunks in box, got %d", len(spliced))
+	}
+
+	for cx, want := range map[int]uint32{0: 1, 1: 2, 2: 2, 3: 1} {
+		if got := dst.Chunks[Index(cx, 0)].Timestamp; got != want {
+			t.Errorf("chunk %d,0 timestamp = %d, want %d", cx, got, want)
+		}
+	}
+	if dst.Chunks[Index(1, 1)] != nil {
+		t.Error("Chunk missing from the snapshot should be removed")
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		rx, rz int
+		ok     bool
+	}{
+		{"r.0.0.mca", 0, 0, true},
+		{"r.-1.12.mca", -1, 12, true},
+		{"r.0.0.mcr", 0, 0, false},
+		{"c.1.2.mcc", 0, 0, false},
+		{"r.a.0.mca", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx, rz, ok := ParseFileName(tt.name)
+			if ok != tt.ok || rx != tt.rx || rz != tt.rz {
+				t.Errorf("ParseFileName(%q) = %d, %d, %v", tt.name, rx, rz, ok)
+			}
+			if ok && FileName(rx, rz) != tt.name {
+				t.Errorf("FileName(%d, %d) = %q", rx, rz, FileName(rx, rz))
+			}
+		})
+	}
+
+	if got := RegionBox(-1, 2); got != (Box{MinX: -512, MinZ: 1024, MaxX: -1, MaxZ: 1535}) {
+		t.Errorf("RegionBox(-1, 2) = %v", got)
+	}
+	if got := RegionBox(0, 0).Union(RegionBox(-1, 1)); got != (Box{MinX: -512, MinZ: 0, MaxX: 511, MaxZ: 1023}) {
+		t.Errorf("Union = %v", got)
+	}
+}
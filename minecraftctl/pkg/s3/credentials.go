@@ -0,0 +1,170 @@
+package s3
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// imdsEndpoint is the EC2 instance metadata service, replaced in tests
+var imdsEndpoint = "http://169.254.169.254"
+
+// Credentials sign requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// LoadCredentials finds credentials the way the AWS CLI does, in order:
+// the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables,
+// the AWS_PROFILE (or default) profile of the shared credentials file, and
+// the role of the EC2 instance.
+func LoadCredentials(ctx context.Context) (Credentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	if creds, ok := sharedCredentials(); ok {
+		return creds, nil
+	}
+
+	creds, err := instanceCredentials(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("no AWS credentials in the environment, shared credentials file or instance metadata: %w", err)
+	}
+	return creds, nil
+}
+
+// sharedCredentials reads a profile of ~/.aws/credentials, or of the file
+// AWS_SHARED_CREDENTIALS_FILE names
+func sharedCredentials() (Credentials, bool) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Credentials{}, false
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Credentials{}, false
+	}
+	defer f.Close()
+
+	var creds Credentials
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != ""
+}
+
+// instanceCredentials gets the credentials of the EC2 instance's role from
+// the instance metadata service, using an IMDSv2 session token
+func instanceCredentials(ctx context.Context) (Credentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	token, err := imdsRequest(ctx, http.MethodPut, "/latest/api/token", "")
+	if err != nil {
+		return Credentials{}, err
+	}
+	roles, err := imdsRequest(ctx, http.MethodGet, "/latest/meta-data/iam/security-credentials/", token)
+	if err != nil {
+		return Credentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, fmt.Errorf("instance has no IAM role")
+	}
+	data, err := imdsRequest(ctx, http.MethodGet, "/latest/meta-data/iam/security-credentials/"+role, token)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var resp struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse instance credentials: %w", err)
+	}
+	return Credentials{AccessKeyID: resp.AccessKeyID, SecretAccessKey: resp.SecretAccessKey, SessionToken: resp.Token}, nil
+}
+
+// InstanceRegion returns the region of the EC2 instance from the instance
+// metadata service. Buckets of the instance's stack are in its region.
+func InstanceRegion(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	token, err := imdsRequest(ctx, http.MethodPut, "/latest/api/token", "")
+	if err != nil {
+		return "", err
+	}
+	region, err := imdsRequest(ctx, http.MethodGet, "/latest/meta-data/placement/region", token)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(region), nil
+}
+
+func imdsRequest(ctx context.Context, method, path, token string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, imdsEndpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	} else {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("instance metadata unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("instance metadata %s: HTTP %d", path, resp.StatusCode)
+	}
+	return string(data), nil
+}
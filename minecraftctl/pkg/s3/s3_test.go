@@ -0,0 +1,222 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/s3/s3test"
+)
+
+func TestSignature(t *testing.T) {
+	// The GET Object example of the Signature Version 4 documentation
+	req, _ := http.NewRequest(http.MethodGet, "https://examplebucket.s3.amazonaws.com/test.txt", nil)
+	req.Header.Set("Range", "bytes=0-9")
+	req.Header.Set("X-Amz-Content-Sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	req.Header.Set("X-Amz-Date", "20130524T000000Z")
+
+	sig, signedHeaders, scope := signature(req, "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", "us-east-1",
+		time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+
+	if want := "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41"; sig != want {
+		t.Errorf("signature = %s, want %s", sig, want)
+	}
+	if want := "host;range;x-amz-content-sha256;x-amz-date"; signedHeaders != want {
+		t.Errorf("signed headers = %s, want %s", signedHeaders, want)
+	}
+	if want := "20130524/us-east-1/s3/aws4_request"; scope != want {
+		t.Errorf("scope = %s, want %s", scope, want)
+	}
+}
+
+func TestObjectURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		client Client
+		key    string
+		want   string
+	}{
+		{"aws", Client{Bucket: "maps", Region: "us-east-2"}, "maps/a b+c.png", "https://maps.s3.us-east-2.amazonaws.com/maps/a%20b%2Bc.png"},
+		{"aws dotted bucket", Client{Bucket: "maps.example.com"}, "index.html", "https://s3.us-east-1.amazonaws.com/maps.example.com/index.html"},
+		{"endpoint", Client{Bucket: "maps", Endpoint: "http://localhost:9000/"}, "x/y.js", "http://localhost:9000/maps/x/y.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.client.objectURL(tt.key, nil); got != tt.want {
+				t.Errorf("objectURL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := s3test.NewServer("maps")
+	defer srv.Close()
+	c := &Client{Bucket: "maps", Endpoint: srv.URL, Credentials: Credentials{AccessKeyID: "id", SecretAccessKey: "secret"}}
+	ctx := context.Background()
+
+	t.Run("put", func(t *testing.T) {
+		if err := c.PutObject(ctx, "w/tiles/a b.png", []byte("tile"), "image/png", "max-age=60"); err != nil {
+			t.Fatalf("PutObject failed: %v", err)
+		}
+		o, ok := srv.Objects()["w/tiles/a b.png"]
+		if !ok {
+			t.Fatalf("Object not stored: %v", srv.Objects())
+		}
+		if string(o.Data) != "tile" || o.ContentType != "image/png" || o.CacheControl != "max-age=60" {
+			t.Errorf("Unexpected object: %+v", o)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		c.PutObject(ctx, "w/index.html", []byte("<html>"), "text/html", "")
+		c.PutObject(ctx, "other/x", []byte("x"), "", "")
+		objects, err := c.ListObjects(ctx, "w/")
+		if err != nil {
+			t.Fatalf("ListObjects failed: %v", err)
+		}
+		if len(objects) != 2 || objects[0].Key != "w/index.html" || objects[1].Key != "w/tiles/a b.png" {
+			t.Fatalf("Unexpected listing: %+v", objects)
+		}
+		// ETags are the hex MD5 of the content
+		if sum := md5.Sum([]byte("tile")); objects[1].ETag != hex.EncodeToString(sum[:]) {
+			t.Errorf("Unexpected ETag %q", objects[1].ETag)
+		}
+		if objects[1].Size != 4 {
+			t.Errorf("Size = %d, want 4", objects[1].Size)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := c.DeleteObject(ctx, "other/x"); err != nil {
+			t.Fatalf("DeleteObject failed: %v", err)
+		}
+		if _, ok := srv.Objects()["other/x"]; ok {
+			t.Error("Object not deleted")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		wrong := *c
+		wrong.Bucket = "missing"
+		err := wrong.PutObject(ctx, "k", []byte("x"), "", "")
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.Code != "NoSuchBucket" || apiErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected NoSuchBucket error, got %v", err)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		oldDelay := retryDelay
+		retryDelay = time.Millisecond
+		defer func() { retryDelay = oldDelay }()
+
+		calls := 0
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer flaky.Close()
+
+		fc := &Client{Bucket: "maps", Endpoint: flaky.URL}
+		if err := fc.PutObject(ctx, "k", []byte("x"), "", ""); err != nil {
+			t.Fatalf("Expected success after retries, got %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+}
+
+func TestLoadCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "envid")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+		t.Setenv("AWS_SESSION_TOKEN", "envtoken")
+		creds, err := LoadCredentials(ctx)
+		if err != nil {
+			t.Fatalf("LoadCredentials failed: %v", err)
+		}
+		if creds != (Credentials{"envid", "envsecret", "envtoken"}) {
+			t.Errorf("Unexpected credentials: %+v", creds)
+		}
+	})
+
+	t.Run("shared file", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+		path := filepath.Join(t.TempDir(), "credentials")
+		os.WriteFile(path, []byte(`
+[default]
+aws_access_key_id = defaultid
+aws_secret_access_key = defaultsecret
+
+[maps]
+aws_access_key_id = mapsid
+aws_secret_access_key = mapssecret
+`), 0600)
+		t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+		t.Setenv("AWS_PROFILE", "maps")
+		creds, err := LoadCredentials(ctx)
+		if err != nil {
+			t.Fatalf("LoadCredentials failed: %v", err)
+		}
+		if creds.AccessKeyID != "mapsid" || creds.SecretAccessKey != "mapssecret" {
+			t.Errorf("Unexpected credentials: %+v", creds)
+		}
+	})
+
+	t.Run("instance role", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "")
+		t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+		imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+				w.Write([]byte("session"))
+			case r.Header.Get("X-aws-ec2-metadata-token") != "session":
+				w.WriteHeader(http.StatusUnauthorized)
+			case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+				w.Write([]byte("minecraft-role\n"))
+			case r.URL.Path == "/latest/meta-data/iam/security-credentials/minecraft-role":
+				w.Write([]byte(`{"AccessKeyId":"roleid","SecretAccessKey":"rolesecret","Token":"roletoken"}`))
+			case r.URL.Path == "/latest/meta-data/placement/region":
+				w.Write([]byte("us-east-2"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer imds.Close()
+		old := imdsEndpoint
+		imdsEndpoint = imds.URL
+		defer func() { imdsEndpoint = old }()
+
+		creds, err := LoadCredentials(ctx)
+		if err != nil {
+			t.Fatalf("LoadCredentials failed: %v", err)
+		}
+		if creds != (Credentials{"roleid", "rolesecret", "roletoken"}) {
+			t.Errorf("Unexpected credentials: %+v", creds)
+		}
+
+		region, err := InstanceRegion(ctx)
+		if err != nil {
+			t.Fatalf("InstanceRegion failed: %v", err)
+		}
+		if region != "us-east-2" {
+			t.Errorf("region = %s, want us-east-2", region)
+		}
+	})
+}
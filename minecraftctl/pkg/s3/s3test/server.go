@@ -0,0 +1,175 @@
+// Package s3test provides an in-memory S3 stand-in for tests, in the way
+// net/http/httptest provides servers. It serves one bucket path-style and
+// implements the requests of the s3 package's client.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Object is a stored object
+type Object struct {
+	Data         []byte
+	ContentType  string
+	CacheControl string
+	ETag         string
+}
+
+// Server is an S3 stand-in listening on a local port
+type Server struct {
+	// URL is the endpoint to configure clients with
+	URL    string
+	Bucket string
+
+	srv      *httptest.Server
+	mu       sync.Mutex
+	objects  map[string]Object
+	requests []string
+}
+
+// NewServer starts a server holding an empty bucket. Requests must carry
+// an AWS4-HMAC-SHA256 Authorization header; signatures are not checked.
+func NewServer(bucket string) *Server {
+	s := &Server{Bucket: bucket, objects: make(map[string]Object)}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Objects returns a copy of the bucket's objects by key
+func (s *Server) Objects() map[string]Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := make(map[string]Object, len(s.objects))
+	for key, o := range s.objects {
+		objects[key] = o
+	}
+	return objects
+}
+
+// Put stores an object directly, as if uploaded earlier
+func (s *Server) Put(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = newObject(data, "", "")
+}
+
+// Requests returns the requests served, as "METHOD key", oldest first.
+// Listings are recorded as "LIST prefix".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ResetRequests forgets the requests served so far
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func newObject(data []byte, contentType, cacheControl string) Object {
+	sum := md5.Sum(data)
+	return Object{Data: data, ContentType: contentType, CacheControl: cacheControl, ETag: hex.EncodeToString(sum[:])}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		writeError(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "no such bucket")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		s.requests = append(s.requests, "LIST "+prefix)
+		s.list(w, prefix)
+	case r.Method == http.MethodPut:
+		s.requests = append(s.requests, "PUT "+key)
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		o := newObject(data, r.Header.Get("Content-Type"), r.Header.Get("Cache-Control"))
+		if want := r.Header.Get("Content-MD5"); want != "" {
+			sum, _ := hex.DecodeString(o.ETag)
+			if base64.StdEncoding.EncodeToString(sum) != want {
+				writeError(w, http.StatusBadRequest, "BadDigest", "Content-MD5 mismatch")
+				return
+			}
+		}
+		s.objects[key] = o
+		w.Header().Set("ETag", `"`+o.ETag+`"`)
+	case r.Method == http.MethodDelete:
+		s.requests = append(s.requests, "DELETE "+key)
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// list writes a ListObjectsV2 result of all matching keys in one page
+func (s *Server) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+		Size int    `xml:"Size"`
+	}
+	result := struct {
+		XMLName     xml.Name  `xml:"ListBucketResult"`
+		Name        string    `xml:"Name"`
+		Prefix      string    `xml:"Prefix"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Contents    []content `xml:"Contents"`
+	}{Name: s.Bucket, Prefix: prefix}
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		o := s.objects[key]
+		result.Contents = append(result.Contents, content{Key: key, ETag: `"` + o.ETag + `"`, Size: len(o.Data)})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+// DefaultRetention keeps two hours of 15-minute snapshots, then hourly
+// snapshots for a day and daily ones for a week. It is used when
+// minecraftctl.yml sets no snapshots.retention.
+var DefaultRetention = config.RetentionPolicy{
+	KeepLast:   8,
+	KeepHourly: 24,
+	KeepDaily:  7,
+}
+
+// RetentionFor returns the configured policy, or DefaultRetention if none
+// of its keep counts is set
+func RetentionFor(policy config.RetentionPolicy) config.RetentionPolicy {
+	if policy.KeepLast == 0 && policy.KeepHourly == 0 && policy.KeepDaily == 0 &&
+		policy.KeepWeekly == 0 && policy.KeepMonthly == 0 && policy.KeepYearly == 0 {
+		return DefaultRetention
+	}
+	return policy
+}
+
+// Keep returns the IDs of the snapshots a policy keeps, with the same
+// semantics as restic forget: each keep-<period> count keeps the newest
+// snapshot of that many most recent periods that have one. -1 keeps all.
+func Keep(snapshots []Snapshot, policy config.RetentionPolicy) map[string]bool {
+	keep := make(map[string]bool)
+	rules := []struct {
+		n      int
+		bucket func(time.Time) string
+	}{
+		{policy.KeepLast, func(t time.Time) string { return t.Format(time.RFC3339Nano) }},
+		{policy.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02 15") }},
+		{policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{policy.KeepYearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	for _, rule := range rules {
+		if rule.n == 0 {
+			continue
+		}
+		kept, last := 0, ""
+		// Newest first
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			if rule.n > 0 && kept >= rule.n {
+				break
+			}
+			bucket := rule.bucket(snapshots[i].Time.Local())
+			if bucket == last {
+				continue
+			}
+			last = bucket
+			keep[snapshots[i].ID] = true
+			kept++
+		}
+	}
+	return keep
+}
+
+// Prune removes a world's snapshots that the policy does not keep and
+// returns them. With dryRun nothing is removed. It holds the lock Create
+// takes, so a snapshot being linked against is never removed.
+func (s *Store) Prune(world string, policy config.RetentionPolicy, dryRun bool) ([]Snapshot, error) {
+	dir := filepath.Join(s.Dir, world)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return []Snapshot{}, nil
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("a snapshot of %s is in progress: %w", world, err)
+	}
+	defer unlock()
+
+	snapshots, err := s.List(world)
+	if err != nil {
+		return nil, err
+	}
+	keep := Keep(snapshots, policy)
+
+	removed := []Snapshot{}
+	for i := range snapshots {
+		if keep[snapshots[i].ID] {
+			continue
+		}
+		if !dryRun {
+			if err := s.Remove(&snapshots[i]); err != nil {
+				return removed, err
+			}
+		}
+		removed = append(removed, snapshots[i])
+	}
+	return removed, nil
+}
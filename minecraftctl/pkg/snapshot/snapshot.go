@@ -0,0 +1,293 @@
+// Package snapshot keeps local snapshots of worlds for quick rollbacks.
+// Each snapshot is a full copy of a world's world/ directory in which files
+// unchanged since the previous snapshot are hard links to it, like rsync
+// --link-dest, so a snapshot only costs the space of the files that changed.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+const (
+	// idFormat is the layout of snapshot IDs, the local creation time
+	idFormat = "20060102-150405"
+	// infoFile is written next to the world copy in each snapshot
+	infoFile = "snapshot.json"
+)
+
+// quiesceWorlds flushes running worlds while they are copied, and now
+// timestamps snapshots; replaced in tests
+var (
+	quiesceWorlds = backup.WithQuiescedWorlds
+	now           = time.Now
+)
+
+// Snapshot is a local copy of a world's world/ directory
+type Snapshot struct {
+	ID    string    `json:"id"`
+	World string    `json:"world"`
+	Time  time.Time `json:"time"`
+	// Path is the copy of world/
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+	// Copied is the data copied for the snapshot; the rest of Size is
+	// shared with the previous snapshot through hard links
+	Copied   int64         `json:"copied"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Store manages the snapshots under a directory, one subdirectory per world
+type Store struct {
+	Dir       string
+	WorldsDir string
+}
+
+// NewStore returns the store configured in minecraftctl.yml
+func NewStore() *Store {
+	cfg := config.Get()
+	return &Store{Dir: cfg.Snapshots.Dir, WorldsDir: cfg.WorldsDir}
+}
+
+// Create snapshots a world. A running world is quiesced over RCON while it
+// is copied. Files whose size and modification time match the latest
+// snapshot are hard-linked to it instead of copied.
+func (s *Store) Create(world string) (*Snapshot, error) {
+	worldDir := filepath.Join(s.WorldsDir, world)
+	worldPath := filepath.Join(worldDir, "world")
+	if _, err := os.Stat(worldPath); err != nil {
+		return nil, fmt.Errorf("world path not found: %s", worldPath)
+	}
+
+	dir := filepath.Join(s.Dir, world)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("another snapshot of %s is in progress: %w", world, err)
+	}
+	defer unlock()
+
+	existing, err := s.List(world)
+	if err != nil {
+		return nil, err
+	}
+	linkDest := ""
+	if len(existing) > 0 {
+		linkDest = existing[len(existing)-1].Path
+	}
+
+	start := now()
+	snap := &Snapshot{ID: start.Format(idFormat), World: world, Time: start}
+	// IDs have one-second resolution; number further snapshots taken in the
+	// same second
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, snap.ID)); os.IsNotExist(err) {
+			break
+		}
+		snap.ID = fmt.Sprintf("%s-%d", start.Format(idFormat), n)
+	}
+	final := filepath.Join(dir, snap.ID)
+
+	// Copy into a partial directory and rename it once complete, so an
+	// interrupted run never leaves a snapshot that looks usable
+	partial := filepath.Join(dir, "."+snap.ID+".partial")
+	defer os.RemoveAll(partial)
+	err = quiesceWorlds([]string{worldDir}, func() error {
+		stats, err := linkCopy(worldPath, filepath.Join(partial, "world"), linkDest)
+		snap.Files, snap.Size, snap.Copied = stats.files, stats.size, stats.copied
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snap.Duration = now().Sub(start).Round(time.Millisecond)
+	snap.Path = filepath.Join(final, "world")
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(partial, infoFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot info: %w", err)
+	}
+	if err := os.Rename(partial, final); err != nil {
+		return nil, fmt.Errorf("failed to finish snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// List returns a world's snapshots, oldest first
+func (s *Store) List(world string) ([]Snapshot, error) {
+	dir := filepath.Join(s.Dir, world)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), infoFile))
+		if err != nil {
+			continue
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot %s: %w", e.Name(), err)
+		}
+		snap.ID = e.Name()
+		snap.Path = filepath.Join(dir, e.Name(), "world")
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID < snapshots[j].ID })
+	return snapshots, nil
+}
+
+// Get returns a world's snapshot by ID, or its newest for "latest"
+func (s *Store) Get(world, id string) (*Snapshot, error) {
+	snapshots, err := s.List(world)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots of %s", world)
+	}
+	if id == "" || id == "latest" {
+		return &snapshots[len(snapshots)-1], nil
+	}
+	for i := range snapshots {
+		if snapshots[i].ID == id {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s of %s not found", id, world)
+}
+
+// Remove deletes a snapshot. Hard links keep files other snapshots share.
+func (s *Store) Remove(snap *Snapshot) error {
+	if err := os.RemoveAll(filepath.Dir(snap.Path)); err != nil {
+		return fmt.Errorf("failed to remove snapshot %s: %w", snap.ID, err)
+	}
+	return nil
+}
+
+// CopyWorld copies a snapshot's world into target, which must not exist.
+// Files are copied rather than linked: the server modifies region files in
+// place, which would change the snapshot too.
+func CopyWorld(snap *Snapshot, target string) error {
+	if _, err := linkCopy(snap.Path, target, ""); err != nil {
+		return fmt.Errorf("failed to copy snapshot %s: %w", snap.ID, err)
+	}
+	return nil
+}
+
+type copyStats struct {
+	files  int
+	size   int64
+	copied int64
+}
+
+// linkCopy copies the tree at src to dst. Regular files with the same size
+// and modification time under linkDest are hard-linked from there instead.
+func linkCopy(src, dst, linkDest string) (copyStats, error) {
+	var stats copyStats
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		stats.files++
+		stats.size += info.Size()
+		if linkDest != "" {
+			prev, err := os.Lstat(filepath.Join(linkDest, rel))
+			if err == nil && prev.Mode().IsRegular() && prev.Size() == info.Size() && prev.ModTime().Equal(info.ModTime()) {
+				if err := os.Link(filepath.Join(linkDest, rel), target); err == nil {
+					return nil
+				}
+			}
+		}
+		stats.copied += info.Size()
+		return copyFile(path, target, info)
+	})
+	return stats, err
+}
+
+// copyFile copies a regular file, keeping its mode and modification time
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// lockDir takes an exclusive lock on a directory's .lock file, failing at
+// once if it is held
+func lockDir(dir string) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,210 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+func TestStore(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldPath := filepath.Join(worldsDir, "survival", "world")
+	os.MkdirAll(filepath.Join(worldPath, "region"), 0755)
+	os.WriteFile(filepath.Join(worldPath, "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(worldPath, "region", "r.0.0.mca"), []byte("region 0 0"), 0644)
+	os.WriteFile(filepath.Join(worldPath, "region", "r.1.0.mca"), []byte("region 1 0"), 0644)
+
+	origQuiesce, origNow := quiesceWorlds, now
+	defer func() { quiesceWorlds, now = origQuiesce, origNow }()
+	quiesceWorlds = func(dirs []string, fn func() error) error {
+		for _, dir := range dirs {
+			if dir != filepath.Dir(worldPath) {
+				t.Errorf("Quiesced %s, want the world's directory", dir)
+			}
+		}
+		return fn()
+	}
+
+	// Snapshots are taken at clock's time, which the tests advance. Each
+	// test starts a store of its own at the same time.
+	var clock time.Time
+	now = func() time.Time { return clock }
+	newStore := func(t *testing.T) *Store {
+		clock = time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+		return &Store{Dir: filepath.Join(t.TempDir(), ".snapshots"), WorldsDir: worldsDir}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		store := newStore(t)
+		first, err := store.Create("survival")
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if first.ID != "20240510-120000" || first.Files != 3 || first.Copied != first.Size {
+			t.Errorf("Unexpected first snapshot: %+v", first)
+		}
+
+		// Change one region file; the rest should be linked to the first snapshot
+		later := time.Now().Add(time.Minute)
+		os.WriteFile(filepath.Join(worldPath, "region", "r.1.0.mca"), []byte("region 1 0 changed"), 0644)
+		os.Chtimes(filepath.Join(worldPath, "region", "r.1.0.mca"), later, later)
+		clock = clock.Add(15 * time.Minute)
+
+		second, err := store.Create("survival")
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if second.Copied != int64(len("region 1 0 changed")) {
+			t.Errorf("Copied = %d, want only the changed region", second.Copied)
+		}
+
+		sameFile := func(rel string) bool {
+			a, _ := os.Stat(filepath.Join(first.Path, rel))
+			b, _ := os.Stat(filepath.Join(second.Path, rel))
+			return a != nil && b != nil && os.SameFile(a, b)
+		}
+		if !sameFile("level.dat") || !sameFile(filepath.Join("region", "r.0.0.mca")) {
+			t.Error("Unchanged files should be hard links to the previous snapshot")
+		}
+		if sameFile(filepath.Join("region", "r.1.0.mca")) {
+			t.Error("Changed file should be copied")
+		}
+		data, _ := os.ReadFile(filepath.Join(first.Path, "region", "r.1.0.mca"))
+		if string(data) != "region 1 0" {
+			t.Errorf("First snapshot changed: %q", data)
+		}
+
+		// A second snapshot in the same second gets a numbered ID
+		third, err := store.Create("survival")
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if third.ID != second.ID+"-2" {
+			t.Errorf("ID = %s, want %s-2", third.ID, second.ID)
+		}
+		if _, err := store.Create("missing"); err == nil {
+			t.Error("Expected error for missing world")
+		}
+
+		snapshots, err := store.List("survival")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(snapshots) != 3 || snapshots[0].ID != first.ID || snapshots[1].ID != second.ID || snapshots[2].ID != third.ID {
+			t.Fatalf("Unexpected snapshots: %+v", snapshots)
+		}
+		if snapshots[1].Copied != second.Copied || snapshots[1].Path != second.Path {
+			t.Errorf("Listed snapshot differs from created: %+v", snapshots[1])
+		}
+
+		latest, err := store.Get("survival", "latest")
+		if err != nil || latest.ID != third.ID {
+			t.Errorf("Get latest = %+v, %v", latest, err)
+		}
+		if _, err := store.Get("survival", "20240101-000000"); err == nil {
+			t.Error("Expected error for unknown snapshot")
+		}
+	})
+
+	t.Run("copy world", func(t *testing.T) {
+		store := newStore(t)
+		snap, err := store.Create("survival")
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		target := filepath.Join(t.TempDir(), "world")
+		if err := CopyWorld(snap, target); err != nil {
+			t.Fatalf("CopyWorld failed: %v", err)
+		}
+
+		// The server writes region files in place; that must not reach the snapshot
+		os.WriteFile(filepath.Join(target, "region", "r.0.0.mca"), []byte("overwritten"), 0644)
+		data, _ := os.ReadFile(filepath.Join(snap.Path, "region", "r.0.0.mca"))
+		if string(data) != "region 0 0" {
+			t.Errorf("Snapshot changed through restored copy: %q", data)
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		store := newStore(t)
+		for i := 0; i < 4; i++ {
+			if _, err := store.Create("survival"); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			clock = clock.Add(15 * time.Minute)
+		}
+		policy := config.RetentionPolicy{KeepLast: 2}
+
+		removed, err := store.Prune("survival", policy, true)
+		if err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+		if len(removed) != 2 {
+			t.Errorf("Dry run should report 2 snapshots, got %d", len(removed))
+		}
+		if snapshots, _ := store.List("survival"); len(snapshots) != 4 {
+			t.Errorf("Dry run removed snapshots: %d left", len(snapshots))
+		}
+
+		// Prune waits for no snapshot to be in progress
+		unlock, err := lockDir(filepath.Join(store.Dir, "survival"))
+		if err != nil {
+			t.Fatalf("lockDir failed: %v", err)
+		}
+		if _, err := store.Prune("survival", policy, false); err == nil {
+			t.Error("Expected error while a snapshot is in progress")
+		}
+		unlock()
+
+		removed, err = store.Prune("survival", policy, false)
+		if err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+		snapshots, _ := store.List("survival")
+		if len(removed) != 2 || len(snapshots) != 2 || snapshots[0].ID != "20240510-123000" {
+			t.Errorf("Unexpected snapshots after prune: %+v", snapshots)
+		}
+		// Files shared with removed snapshots survive through their other links
+		if _, err := os.Stat(filepath.Join(snapshots[0].Path, "level.dat")); err != nil {
+			t.Errorf("Kept snapshot lost a file: %v", err)
+		}
+	})
+}
+
+func TestKeep(t *testing.T) {
+	start := time.Date(2024, 5, 10, 0, 0, 0, 0, time.Local)
+	var snapshots []Snapshot
+	// Every 15 minutes for two days
+	for i := 0; i < 2*24*4; i++ {
+		ts := start.Add(time.Duration(i) * 15 * time.Minute)
+		snapshots = append(snapshots, Snapshot{ID: ts.Format(idFormat), Time: ts})
+	}
+
+	tests := []struct {
+		name   string
+		policy config.RetentionPolicy
+		want   int
+	}{
+		{"last", config.RetentionPolicy{KeepLast: 8}, 8},
+		{"hourly", config.RetentionPolicy{KeepHourly: 24}, 24},
+		{"last and hourly overlap", config.RetentionPolicy{KeepLast: 8, KeepHourly: 24}, 8 + 22},
+		{"daily", config.RetentionPolicy{KeepDaily: 7}, 2},
+		{"unlimited", config.RetentionPolicy{KeepLast: -1}, len(snapshots)},
+		{"default", RetentionFor(config.RetentionPolicy{}), 8 + 22 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := Keep(snapshots, tt.policy)
+			if len(keep) != tt.want {
+				t.Errorf("Kept %d snapshots, want %d", len(keep), tt.want)
+			}
+			if !keep[snapshots[len(snapshots)-1].ID] {
+				t.Error("Newest snapshot should always be kept")
+			}
+		})
+	}
+}
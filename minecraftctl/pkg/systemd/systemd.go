@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // UnitType represents the type of systemd unit
@@ -114,6 +116,33 @@ func GetActiveState(unit string) string {
 	return state
 }
 
+// NextElapse returns when a timer next triggers, or the zero time if it is
+// not scheduled
+func NextElapse(timer string) time.Time {
+	cmd := exec.Command("systemctl", "show", "-p", "NextElapseUSecRealtime", "--value", "--timestamp=unix", timer)
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}
+	}
+	return parseTimestamp(strings.TrimSpace(string(output)))
+}
+
+// parseTimestamp parses a timestamp property as printed by systemctl show
+// --timestamp=unix, e.g. "@1714953600". Seconds since the epoch avoid
+// parsing zone abbreviations, which Go cannot resolve other than UTC and the
+// local zone. Empty, "n/a" and unparsable values yield the zero time.
+func parseTimestamp(value string) time.Time {
+	seconds, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return time.Time{}
+	}
+	n, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0)
+}
+
 // Logs runs journalctl for a unit with the given options
 func Logs(unit string, opts LogOptions) error {
 	args := []string{"-u", unit}
@@ -2,6 +2,7 @@ package systemd
 
 import (
 	"testing"
+	"time"
 )
 
 func TestFormatUnitName(t *testing.T) {
@@ -39,3 +40,32 @@ func TestUnitTypeConstants(t *testing.T) {
 		t.Errorf("UnitTimer = %q, want %q", UnitTimer, "timer")
 	}
 }
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"@1714953600", "2024-05-06T00:00:00Z"},
+		{"@1715045400", "2024-05-07T01:30:00Z"},
+		{"Mon 2024-05-06 00:00:00 CEST", ""},
+		{"n/a", ""},
+		{"@", ""},
+		{"", ""},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := parseTimestamp(tt.value)
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("parseTimestamp(%q) = %v, want zero", tt.value, got)
+				}
+				return
+			}
+			if got.UTC().Format(time.RFC3339) != tt.want {
+				t.Errorf("parseTimestamp(%q) = %v, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
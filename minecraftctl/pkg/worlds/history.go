@@ -0,0 +1,120 @@
+package worlds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+// UpgradeHistoryFile is the per-world file recording server.jar changes
+const UpgradeHistoryFile = "upgrade-history.json"
+
+// UpgradeRecord is a single entry in a world's upgrade history
+type UpgradeRecord struct {
+	Time        time.Time `json:"time"`
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	FromJar     string    `json:"from_jar"`
+	ToJar       string    `json:"to_jar"`
+}
+
+// LoadUpgradeHistory reads a world's upgrade history, oldest first.
+// A missing history file yields an empty history.
+func LoadUpgradeHistory(worldPath string) ([]UpgradeRecord, error) {
+	data, err := os.ReadFile(filepath.Join(worldPath, UpgradeHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read upgrade history: %w", err)
+	}
+
+	var history []UpgradeRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade history: %w", err)
+	}
+	return history, nil
+}
+
+// RecordUpgrade appends an entry to a world's upgrade history
+func RecordUpgrade(worldPath string, record UpgradeRecord) error {
+	history, err := LoadUpgradeHistory(worldPath)
+	if err != nil {
+		return err
+	}
+	history = append(history, record)
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade history: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(worldPath, UpgradeHistoryFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write upgrade history: %w", err)
+	}
+	return nil
+}
+
+// JarReferences maps JAR file names to the worlds that reference them
+type JarReferences struct {
+	// InUse holds the JARs worlds' server.jar symlinks currently point to
+	InUse map[string][]string
+	// Rollback holds JARs worlds ran before an upgrade, needed to roll back
+	Rollback map[string][]string
+}
+
+// CollectJarReferences walks every world's server.jar symlink and upgrade
+// history. JARs are keyed by file name; world lists are sorted. An
+// unreadable history is an error, as the JARs it protects are unknown.
+func CollectJarReferences() (*JarReferences, error) {
+	cfg := config.Get()
+	names, err := GetWorldNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worlds: %w", err)
+	}
+
+	refs := &JarReferences{
+		InUse:    make(map[string][]string),
+		Rollback: make(map[string][]string),
+	}
+
+	for _, name := range names {
+		worldPath := filepath.Join(cfg.WorldsDir, name)
+
+		if jarPath, err := currentJarPath(worldPath); err == nil {
+			jarName := filepath.Base(jarPath)
+			refs.InUse[jarName] = append(refs.InUse[jarName], name)
+		} else {
+			log.Debug().Err(err).Str("world", name).Msg("world has no server.jar symlink")
+		}
+
+		history, err := LoadUpgradeHistory(worldPath)
+		if err != nil {
+			return nil, fmt.Errorf("world %s: %w", name, err)
+		}
+		seen := make(map[string]bool)
+		for _, record := range history {
+			jarName := filepath.Base(record.FromJar)
+			if record.FromJar == "" || seen[jarName] {
+				continue
+			}
+			seen[jarName] = true
+			refs.Rollback[jarName] = append(refs.Rollback[jarName], name)
+		}
+	}
+
+	for _, worlds := range refs.InUse {
+		sort.Strings(worlds)
+	}
+	for _, worlds := range refs.Rollback {
+		sort.Strings(worlds)
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,86 @@
+package worlds
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeHistory(t *testing.T) {
+	t.Run("upgrade is recorded", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+		targetJar := filepath.Join(jarsDir, "minecraft_server_1.21.jar")
+		writeVersionedJar(t, currentJar, "1.20.4", 3700)
+		writeVersionedJar(t, targetJar, "1.21", 3953)
+		worldPath := setupUpgradeWorld(t, jarsDir, currentJar)
+
+		if _, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21"}); err != nil {
+			t.Fatalf("UpgradeWorld failed: %v", err)
+		}
+
+		history, err := LoadUpgradeHistory(worldPath)
+		if err != nil {
+			t.Fatalf("LoadUpgradeHistory failed: %v", err)
+		}
+		if len(history) != 1 {
+			t.Fatalf("Expected 1 history entry, got %d", len(history))
+		}
+		if history[0].FromJar != currentJar || history[0].ToJar != targetJar {
+			t.Errorf("Unexpected history entry: %+v", history[0])
+		}
+		if history[0].FromVersion != "1.20.4" || history[0].ToVersion != "1.21" {
+			t.Errorf("Unexpected versions: %+v", history[0])
+		}
+	})
+
+	t.Run("missing history is empty", func(t *testing.T) {
+		history, err := LoadUpgradeHistory(t.TempDir())
+		if err != nil || len(history) != 0 {
+			t.Errorf("LoadUpgradeHistory = %v, %v; want empty", history, err)
+		}
+	})
+}
+
+func TestCollectJarReferences(t *testing.T) {
+	jarsDir := t.TempDir()
+	oldJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+	newJar := filepath.Join(jarsDir, "minecraft_server_1.21.jar")
+	writeVersionedJar(t, oldJar, "1.20.4", 3700)
+	writeVersionedJar(t, newJar, "1.21", 3953)
+
+	worldPath := setupUpgradeWorld(t, jarsDir, newJar)
+	RecordUpgrade(worldPath, UpgradeRecord{FromJar: oldJar, ToJar: newJar})
+
+	// A second world still on the old jar
+	creative := filepath.Join(filepath.Dir(worldPath), "creative")
+	os.MkdirAll(filepath.Join(creative, "world"), 0755)
+	data, _ := os.ReadFile(filepath.Join(worldPath, "world", "level.dat"))
+	os.WriteFile(filepath.Join(creative, "world", "level.dat"), data, 0644)
+	os.Symlink(oldJar, filepath.Join(creative, "server.jar"))
+
+	refs, err := CollectJarReferences()
+	if err != nil {
+		t.Fatalf("CollectJarReferences failed: %v", err)
+	}
+
+	wantInUse := map[string][]string{
+		"minecraft_server_1.21.jar":   {"survival"},
+		"minecraft_server_1.20.4.jar": {"creative"},
+	}
+	if !reflect.DeepEqual(refs.InUse, wantInUse) {
+		t.Errorf("InUse = %v, want %v", refs.InUse, wantInUse)
+	}
+	wantRollback := map[string][]string{"minecraft_server_1.20.4.jar": {"survival"}}
+	if !reflect.DeepEqual(refs.Rollback, wantRollback) {
+		t.Errorf("Rollback = %v, want %v", refs.Rollback, wantRollback)
+	}
+
+	// An unreadable history must not leave its rollback jars unprotected
+	os.WriteFile(filepath.Join(creative, UpgradeHistoryFile), []byte("{"), 0644)
+	if _, err := CollectJarReferences(); err == nil || !strings.Contains(err.Error(), "creative") {
+		t.Errorf("Expected error for unreadable history, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package worlds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/envfile"
+	"github.com/paul/minecraftctl/pkg/jars"
+	"github.com/paul/minecraftctl/pkg/java"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// JVMEnvFile is the per-world env file read by minecraft@.service
+	JVMEnvFile = "jvm.env"
+
+	// JavaEnvKey selects the java binary for a world
+	JavaEnvKey = "JAVA"
+
+	jvmEnvHeader = "Per-world JVM settings read by minecraft@.service.\nManaged by minecraftctl; edit with 'minecraftctl world java' and 'minecraftctl world jvm'."
+)
+
+// loadJVMEnv loads a world's jvm.env, returning an empty file if it does not exist
+func loadJVMEnv(worldPath string) (*envfile.EnvFile, error) {
+	path := filepath.Join(worldPath, JVMEnvFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return envfile.New(path), nil
+	}
+	ef, err := envfile.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", JVMEnvFile, err)
+	}
+	return ef, nil
+}
+
+// saveJVMEnv writes a world's jvm.env and chowns it to the minecraft user
+// like the rest of the world's files
+func saveJVMEnv(ef *envfile.EnvFile, worldName string) error {
+	if err := ef.Save(jvmEnvHeader); err != nil {
+		return err
+	}
+	if err := fixOwnership(ef.Path()); err != nil {
+		log.Warn().Err(err).Str("world", worldName).Msg("failed to chown jvm.env to minecraft user, continuing")
+	}
+	return nil
+}
+
+// GetWorldJava returns the java binary selected for a world, or "" if the
+// world uses the default java
+func GetWorldJava(worldName string) (string, error) {
+	cfg := config.Get()
+	ef, err := loadJVMEnv(filepath.Join(cfg.WorldsDir, worldName))
+	if err != nil {
+		return "", err
+	}
+	javaPath, _ := ef.Get(JavaEnvKey)
+	return javaPath, nil
+}
+
+// SetWorldJava writes the java binary for a world to its jvm.env. An empty
+// javaPath removes the selection so the default java is used.
+func SetWorldJava(worldName, javaPath string) error {
+	cfg := config.Get()
+	worldPath := filepath.Join(cfg.WorldsDir, worldName)
+	if _, err := os.Stat(worldPath); err != nil {
+		return fmt.Errorf("world not found: %s", worldName)
+	}
+
+	ef, err := loadJVMEnv(worldPath)
+	if err != nil {
+		return err
+	}
+	if javaPath == "" {
+		ef.Delete(JavaEnvKey)
+	} else {
+		ef.Set(JavaEnvKey, javaPath)
+	}
+	return saveJVMEnv(ef, worldName)
+}
+
+// JavaCheck describes whether a world's Java runtime can run its JAR
+type JavaCheck struct {
+	// Required is the Java major version the JAR needs, 0 if unknown
+	Required int
+	// JavaPath is the java binary the world runs with
+	JavaPath string
+	// Selected is true when JavaPath comes from the world's jvm.env
+	Selected bool
+	// Current is the runtime at JavaPath, nil if it is not installed
+	Current *java.Runtime
+	// Suggested is the best installed runtime for Required, nil if none
+	Suggested *java.Runtime
+}
+
+// OK reports whether the world's current runtime satisfies the requirement
+func (c *JavaCheck) OK() bool {
+	if c.Required == 0 {
+		return true
+	}
+	return c.Current != nil && c.Current.Major >= c.Required
+}
+
+// CheckWorldJava checks the Java runtime of a world against the JAR at
+// jarPath (the world's server.jar when empty). It fails only when no
+// installed runtime satisfies the JAR; if one exists but the world does not
+// use it, the check reports it in Suggested.
+func CheckWorldJava(worldName, jarPath string) (*JavaCheck, error) {
+	cfg := config.Get()
+	worldPath := filepath.Join(cfg.WorldsDir, worldName)
+
+	// A world without a server.jar symlink has no known requirement
+	if jarPath == "" {
+		jarPath, _ = currentJarPath(worldPath)
+	}
+
+	check := &JavaCheck{JavaPath: cfg.JavaPath}
+	if jarPath != "" {
+		if meta, err := jars.InspectJar(jarPath); err == nil {
+			check.Required = meta.JavaVersion
+		}
+	}
+
+	ef, err := loadJVMEnv(worldPath)
+	if err != nil {
+		return nil, err
+	}
+	if javaPath, ok := ef.Get(JavaEnvKey); ok && javaPath != "" {
+		check.JavaPath = javaPath
+		check.Selected = true
+	}
+	if rt, err := java.Inspect(check.JavaPath); err == nil {
+		check.Current = rt
+	}
+
+	if check.Required == 0 {
+		return check, nil
+	}
+
+	runtimes, err := java.Discover(cfg.JavaSearchPaths)
+	if err != nil {
+		return nil, err
+	}
+	if check.Current != nil {
+		runtimes = append(runtimes, *check.Current)
+	}
+	suggested, err := java.Select(runtimes, check.Required)
+	if err != nil {
+		return check, fmt.Errorf("%s requires Java %d: %w", filepath.Base(jarPath), check.Required, err)
+	}
+	check.Suggested = suggested
+
+	return check, nil
+}
@@ -0,0 +1,102 @@
+package worlds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWorldJava(t *testing.T) {
+	t.Run("set and get", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		jarPath := filepath.Join(jarsDir, "minecraft_server_1.21.jar")
+		writeVersionedJar(t, jarPath, "1.21", 3953)
+		worldPath := setupUpgradeWorld(t, jarsDir, jarPath)
+
+		if err := SetWorldJava("survival", "/usr/lib/jvm/java-21/bin/java"); err != nil {
+			t.Fatalf("SetWorldJava failed: %v", err)
+		}
+		got, err := GetWorldJava("survival")
+		if err != nil || got != "/usr/lib/jvm/java-21/bin/java" {
+			t.Errorf("GetWorldJava = %q, %v", got, err)
+		}
+
+		data, _ := os.ReadFile(filepath.Join(worldPath, JVMEnvFile))
+		if !strings.Contains(string(data), "JAVA=/usr/lib/jvm/java-21/bin/java") {
+			t.Errorf("Unexpected jvm.env:\n%s", data)
+		}
+
+		if err := SetWorldJava("survival", ""); err != nil {
+			t.Fatalf("SetWorldJava clear failed: %v", err)
+		}
+		if got, _ := GetWorldJava("survival"); got != "" {
+			t.Errorf("GetWorldJava after clear = %q, want empty", got)
+		}
+	})
+
+	t.Run("upgrade fails without required java", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.21.jar")
+		writeVersionedJar(t, currentJar, "1.21", 3953)
+		setupUpgradeWorld(t, jarsDir, currentJar)
+
+		// Pretend a future version needs Java 25 by pointing at a JAR whose
+		// metadata says so
+		target := filepath.Join(jarsDir, "minecraft_server_1.22.jar")
+		writeJavaJar(t, target, "1.22", 4500, 25)
+
+		_, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.22"})
+		if err == nil || !strings.Contains(err.Error(), "Java 25") {
+			t.Fatalf("Expected Java 25 error, got %v", err)
+		}
+	})
+
+	t.Run("upgrade selects newer installed java", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+		writeJavaJar(t, currentJar, "1.20.4", 3700, 17)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_1.21.jar"), "1.21", 3953)
+		setupUpgradeWorld(t, jarsDir, currentJar)
+
+		// Default java is 17; a Java 21 runtime is installed elsewhere
+		java17 := writeFakeJDK(t, t.TempDir(), "17.0.9")
+		viper.Set("java_path", java17)
+
+		result, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21"})
+		if err != nil {
+			t.Fatalf("UpgradeWorld failed: %v", err)
+		}
+		if !strings.HasSuffix(result.JavaSelected, filepath.Join("jdk-21.0.2", "bin", "java")) {
+			t.Errorf("JavaSelected = %q, want the Java 21 runtime", result.JavaSelected)
+		}
+		if got, _ := GetWorldJava("survival"); got != result.JavaSelected {
+			t.Errorf("GetWorldJava = %q, want %q", got, result.JavaSelected)
+		}
+	})
+	t.Run("failed java selection restarts the old version", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+		writeJavaJar(t, currentJar, "1.20.4", 3700, 17)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_1.21.jar"), "1.21", 3953)
+		worldPath := setupUpgradeWorld(t, jarsDir, currentJar)
+		viper.Set("java_path", writeFakeJDK(t, t.TempDir(), "17.0.9"))
+		actions := fakeService(t, true)
+
+		// jvm.env cannot be written through a dangling symlink
+		os.Symlink(filepath.Join(worldPath, "missing", "jvm.env"), filepath.Join(worldPath, JVMEnvFile))
+
+		_, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21", StopService: true})
+		if err == nil || !strings.Contains(err.Error(), "Java 21") {
+			t.Fatalf("Expected Java selection error, got %v", err)
+		}
+		if got := strings.Join(*actions, ", "); got != "stop survival, start survival" {
+			t.Errorf("actions = %s", got)
+		}
+		if target, _ := os.Readlink(filepath.Join(worldPath, "server.jar")); target != currentJar {
+			t.Errorf("server.jar points to %s, want the previous jar", target)
+		}
+	})
+}
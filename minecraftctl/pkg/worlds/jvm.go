@@ -0,0 +1,242 @@
+package worlds
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paul/minecraftctl/pkg/config"
+)
+
+const (
+	// JVMOptsKey holds the JVM options minecraft@.service passes to java
+	JVMOptsKey = "JVM_OPTS"
+	// JVMHeapKey and JVMFlagsKey record the settings JVM_OPTS was built from
+	JVMHeapKey  = "JVM_HEAP"
+	JVMFlagsKey = "JVM_FLAGS"
+	// JVMCustomFlagsKey holds the flags used with the custom preset
+	JVMCustomFlagsKey = "JVM_CUSTOM_FLAGS"
+
+	// DefaultHeap matches the JVM_OPTS default in minecraft@.service
+	DefaultHeap = "1536M"
+
+	// FlagsDefault adds no GC tuning, FlagsAikar uses Aikar's G1 flags and
+	// FlagsCustom uses the world's JVM_CUSTOM_FLAGS
+	FlagsDefault = "default"
+	FlagsAikar   = "aikar"
+	FlagsCustom  = "custom"
+
+	// minHeapMB is the smallest heap SuggestHeaps hands out
+	minHeapMB = 512
+	// jvmOverheadMB approximates per-server memory used outside the heap
+	// (metaspace, thread stacks, direct buffers)
+	jvmOverheadMB = 512
+)
+
+// JVMSettings are the per-world heap and flag settings
+type JVMSettings struct {
+	Heap        string `json:"heap"`
+	Flags       string `json:"flags"`
+	CustomFlags string `json:"custom_flags,omitempty"`
+	// Configured is false when the world uses the unit's defaults
+	Configured bool `json:"configured"`
+}
+
+// Options returns the JVM options for the settings, with Xms equal to Xmx
+func (s JVMSettings) Options() (string, error) {
+	heapMB, err := ParseHeap(s.Heap)
+	if err != nil {
+		return "", err
+	}
+	heap := FormatHeap(heapMB)
+	opts := []string{"-Xms" + heap, "-Xmx" + heap}
+
+	switch s.Flags {
+	case FlagsDefault, "":
+	case FlagsAikar:
+		opts = append(opts, AikarFlags(heapMB)...)
+	case FlagsCustom:
+		if strings.TrimSpace(s.CustomFlags) == "" {
+			return "", fmt.Errorf("custom flags preset requires flags")
+		}
+		opts = append(opts, strings.Fields(s.CustomFlags)...)
+	default:
+		return "", fmt.Errorf("unknown JVM flags preset: %s (supported: %s, %s, %s)", s.Flags, FlagsDefault, FlagsAikar, FlagsCustom)
+	}
+
+	return strings.Join(opts, " "), nil
+}
+
+// ParseHeap parses a heap size such as "4G", "1536M" or "1536m" into megabytes
+func ParseHeap(heap string) (int, error) {
+	heap = strings.TrimSpace(heap)
+	if heap == "" {
+		return 0, fmt.Errorf("heap size is empty")
+	}
+
+	digits, multiplier := heap, 1
+	switch heap[len(heap)-1] {
+	case 'G', 'g':
+		digits, multiplier = heap[:len(heap)-1], 1024
+	case 'M', 'm':
+		digits = heap[:len(heap)-1]
+	}
+
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid heap size: %q (use e.g. 4G or 1536M)", heap)
+	}
+	return n * multiplier, nil
+}
+
+// FormatHeap formats megabytes as a JVM size, using G when it divides evenly
+func FormatHeap(mb int) string {
+	if mb%1024 == 0 {
+		return fmt.Sprintf("%dG", mb/1024)
+	}
+	return fmt.Sprintf("%dM", mb)
+}
+
+// AikarFlags returns Aikar's recommended G1 flags (https://mcflags.emc.gs),
+// using the large-heap variant for heaps of 12G and above
+func AikarFlags(heapMB int) []string {
+	newSize, maxNewSize, regionSize, reserve, ihop := "30", "40", "8M", "20", "15"
+	if heapMB >= 12*1024 {
+		newSize, maxNewSize, regionSize, reserve, ihop = "40", "50", "16M", "15", "20"
+	}
+	return []string{
+		"-XX:+UseG1GC",
+		"-XX:+ParallelRefProcEnabled",
+		"-XX:MaxGCPauseMillis=200",
+		"-XX:+UnlockExperimentalVMOptions",
+		"-XX:+DisableExplicitGC",
+		"-XX:+AlwaysPreTouch",
+		"-XX:G1NewSizePercent=" + newSize,
+		"-XX:G1MaxNewSizePercent=" + maxNewSize,
+		"-XX:G1HeapRegionSize=" + regionSize,
+		"-XX:G1ReservePercent=" + reserve,
+		"-XX:G1HeapWastePercent=5",
+		"-XX:G1MixedGCCountTarget=4",
+		"-XX:InitiatingHeapOccupancyPercent=" + ihop,
+		"-XX:G1MixedGCLiveThresholdPercent=90",
+		"-XX:G1RSetUpdatingPauseTimePercent=5",
+		"-XX:SurvivorRatio=32",
+		"-XX:+PerfDisableSharedMem",
+		"-XX:MaxTenuringThreshold=1",
+		"-Dusing.aikars.flags=https://mcflags.emc.gs",
+		"-Daikars.new.flags=true",
+	}
+}
+
+// GetWorldJVM returns a world's JVM settings, or the unit's defaults if the
+// world has none
+func GetWorldJVM(worldName string) (*JVMSettings, error) {
+	cfg := config.Get()
+	worldPath := filepath.Join(cfg.WorldsDir, worldName)
+	if _, err := os.Stat(worldPath); err != nil {
+		return nil, fmt.Errorf("world not found: %s", worldName)
+	}
+
+	ef, err := loadJVMEnv(worldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &JVMSettings{Heap: DefaultHeap, Flags: FlagsDefault}
+	if _, ok := ef.Get(JVMOptsKey); ok {
+		settings.Configured = true
+	}
+	if heap, ok := ef.Get(JVMHeapKey); ok && heap != "" {
+		settings.Heap = heap
+	}
+	if flags, ok := ef.Get(JVMFlagsKey); ok && flags != "" {
+		settings.Flags = flags
+	}
+	settings.CustomFlags, _ = ef.Get(JVMCustomFlagsKey)
+
+	return settings, nil
+}
+
+// SetWorldJVM validates settings and writes them, along with the JVM_OPTS
+// built from them, to the world's jvm.env
+func SetWorldJVM(worldName string, settings JVMSettings) error {
+	cfg := config.Get()
+	worldPath := filepath.Join(cfg.WorldsDir, worldName)
+	if _, err := os.Stat(worldPath); err != nil {
+		return fmt.Errorf("world not found: %s", worldName)
+	}
+
+	opts, err := settings.Options()
+	if err != nil {
+		return err
+	}
+	heapMB, _ := ParseHeap(settings.Heap)
+	flags := settings.Flags
+	if flags == "" {
+		flags = FlagsDefault
+	}
+
+	ef, err := loadJVMEnv(worldPath)
+	if err != nil {
+		return err
+	}
+	ef.Set(JVMOptsKey, opts)
+	ef.Set(JVMHeapKey, FormatHeap(heapMB))
+	ef.Set(JVMFlagsKey, flags)
+	if flags == FlagsCustom {
+		ef.Set(JVMCustomFlagsKey, strings.Join(strings.Fields(settings.CustomFlags), " "))
+	} else {
+		ef.Delete(JVMCustomFlagsKey)
+	}
+	return saveJVMEnv(ef, worldName)
+}
+
+// SuggestHeaps splits totalMB of host memory evenly across worlds after
+// setting aside reserveMB for the system and jvmOverheadMB per server for
+// memory the JVM uses outside the heap. Heaps are rounded down to 256M.
+func SuggestHeaps(totalMB, reserveMB int, worldNames []string) (map[string]int, error) {
+	if len(worldNames) == 0 {
+		return nil, fmt.Errorf("no worlds to split memory across")
+	}
+
+	available := totalMB - reserveMB - jvmOverheadMB*len(worldNames)
+	heap := available / len(worldNames) / 256 * 256
+	if heap < minHeapMB {
+		return nil, fmt.Errorf("%s of memory is not enough for %d worlds (each needs at least %s heap plus %s overhead after reserving %s)",
+			FormatHeap(totalMB), len(worldNames), FormatHeap(minHeapMB), FormatHeap(jvmOverheadMB), FormatHeap(reserveMB))
+	}
+
+	heaps := make(map[string]int, len(worldNames))
+	for _, name := range worldNames {
+		heaps[name] = heap
+	}
+	return heaps, nil
+}
+
+// HostMemoryMB returns the host's total memory from /proc/meminfo
+func HostMemoryMB() (int, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse MemTotal: %w", err)
+			}
+			return kb / 1024, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
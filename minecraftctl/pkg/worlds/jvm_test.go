@@ -0,0 +1,209 @@
+package worlds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/spf13/viper"
+)
+
+func TestParseHeap(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"4G", 4096, false},
+		{"4g", 4096, false},
+		{"1536M", 1536, false},
+		{"1536m", 1536, false},
+		{"2048", 2048, false},
+		{"", 0, true},
+		{"G", 0, true},
+		{"-1G", 0, true},
+		{"4GB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseHeap(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeap(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHeap(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if got := FormatHeap(4096); got != "4G" {
+		t.Errorf("FormatHeap(4096) = %q, want 4G", got)
+	}
+	if got := FormatHeap(1536); got != "1536M" {
+		t.Errorf("FormatHeap(1536) = %q, want 1536M", got)
+	}
+}
+
+func TestJVMSettingsOptions(t *testing.T) {
+	t.Run("default flags", func(t *testing.T) {
+		opts, err := JVMSettings{Heap: "1536m"}.Options()
+		if err != nil || opts != "-Xms1536M -Xmx1536M" {
+			t.Errorf("Options() = %q, %v", opts, err)
+		}
+	})
+
+	t.Run("aikar flags sized to heap", func(t *testing.T) {
+		small, _ := JVMSettings{Heap: "4G", Flags: FlagsAikar}.Options()
+		if !strings.HasPrefix(small, "-Xms4G -Xmx4G -XX:+UseG1GC") || !strings.Contains(small, "G1HeapRegionSize=8M") {
+			t.Errorf("Unexpected aikar options: %s", small)
+		}
+		large, _ := JVMSettings{Heap: "16G", Flags: FlagsAikar}.Options()
+		if !strings.Contains(large, "G1HeapRegionSize=16M") {
+			t.Errorf("Expected large-heap aikar flags: %s", large)
+		}
+	})
+
+	t.Run("custom flags", func(t *testing.T) {
+		opts, err := JVMSettings{Heap: "2G", Flags: FlagsCustom, CustomFlags: " -XX:+UseZGC  -XX:+ZGenerational"}.Options()
+		if err != nil || opts != "-Xms2G -Xmx2G -XX:+UseZGC -XX:+ZGenerational" {
+			t.Errorf("Options() = %q, %v", opts, err)
+		}
+		if _, err := (JVMSettings{Heap: "2G", Flags: FlagsCustom}).Options(); err == nil {
+			t.Error("Expected error for custom preset without flags")
+		}
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		if _, err := (JVMSettings{Heap: "2G", Flags: "turbo"}).Options(); err == nil {
+			t.Error("Expected error for unknown preset")
+		}
+	})
+}
+
+func TestWorldJVM(t *testing.T) {
+	setup := func(t *testing.T) string {
+		t.Helper()
+		worldsDir := t.TempDir()
+		viper.Reset()
+		viper.Set("worlds_dir", worldsDir)
+		if err := config.Init(""); err != nil {
+			t.Fatalf("config.Init() failed: %v", err)
+		}
+		worldPath := filepath.Join(worldsDir, "survival")
+		if err := os.MkdirAll(worldPath, 0755); err != nil {
+			t.Fatalf("Failed to create world: %v", err)
+		}
+		return worldPath
+	}
+
+	t.Run("defaults without jvm.env", func(t *testing.T) {
+		setup(t)
+		settings, err := GetWorldJVM("survival")
+		if err != nil {
+			t.Fatalf("GetWorldJVM failed: %v", err)
+		}
+		if settings.Configured || settings.Heap != DefaultHeap || settings.Flags != FlagsDefault {
+			t.Errorf("Unexpected defaults: %+v", settings)
+		}
+	})
+
+	t.Run("set keeps java selection", func(t *testing.T) {
+		worldPath := setup(t)
+		if err := SetWorldJava("survival", "/usr/lib/jvm/java-21/bin/java"); err != nil {
+			t.Fatalf("SetWorldJava failed: %v", err)
+		}
+		if err := SetWorldJVM("survival", JVMSettings{Heap: "4096M", Flags: FlagsAikar}); err != nil {
+			t.Fatalf("SetWorldJVM failed: %v", err)
+		}
+
+		settings, err := GetWorldJVM("survival")
+		if err != nil {
+			t.Fatalf("GetWorldJVM failed: %v", err)
+		}
+		if !settings.Configured || settings.Heap != "4G" || settings.Flags != FlagsAikar {
+			t.Errorf("Unexpected settings: %+v", settings)
+		}
+		if javaPath, _ := GetWorldJava("survival"); javaPath != "/usr/lib/jvm/java-21/bin/java" {
+			t.Errorf("Java selection lost: %q", javaPath)
+		}
+
+		data, _ := os.ReadFile(filepath.Join(worldPath, JVMEnvFile))
+		if !strings.Contains(string(data), `JVM_OPTS="-Xms4G -Xmx4G -XX:+UseG1GC`) {
+			t.Errorf("Unexpected jvm.env:\n%s", data)
+		}
+	})
+
+	t.Run("custom flags with quotes", func(t *testing.T) {
+		setup(t)
+		actions := fakeService(t, false)
+		custom := `-Dfoo="a b" -Dbar=$HOME`
+		if err := SetWorldJVM("survival", JVMSettings{Heap: "2G", Flags: FlagsCustom, CustomFlags: custom}); err != nil {
+			t.Fatalf("SetWorldJVM failed: %v", err)
+		}
+		settings, err := GetWorldJVM("survival")
+		if err != nil {
+			t.Fatalf("GetWorldJVM failed: %v", err)
+		}
+		if settings.CustomFlags != custom {
+			t.Errorf("CustomFlags = %q, want %q", settings.CustomFlags, custom)
+		}
+		if got := strings.Join(*actions, ", "); got != "chown jvm.env" {
+			t.Errorf("actions = %s", got)
+		}
+	})
+
+	t.Run("invalid settings are not written", func(t *testing.T) {
+		worldPath := setup(t)
+		if err := SetWorldJVM("survival", JVMSettings{Heap: "lots"}); err == nil {
+			t.Fatal("Expected error for invalid heap")
+		}
+		if _, err := os.Stat(filepath.Join(worldPath, JVMEnvFile)); !os.IsNotExist(err) {
+			t.Error("jvm.env should not be written for invalid settings")
+		}
+	})
+
+	t.Run("missing world", func(t *testing.T) {
+		setup(t)
+		if err := SetWorldJVM("missing", JVMSettings{Heap: "4G"}); err == nil {
+			t.Error("Expected error for missing world")
+		}
+	})
+}
+
+func TestSuggestHeaps(t *testing.T) {
+	t.Run("splits evenly", func(t *testing.T) {
+		// 16G - 2G reserve - 2*512M overhead = 13G, 6.5G each
+		heaps, err := SuggestHeaps(16384, 2048, []string{"lobby", "survival"})
+		if err != nil {
+			t.Fatalf("SuggestHeaps failed: %v", err)
+		}
+		if heaps["lobby"] != 6656 || heaps["survival"] != 6656 {
+			t.Errorf("Unexpected heaps: %v", heaps)
+		}
+	})
+
+	t.Run("rounds down to 256M", func(t *testing.T) {
+		heaps, err := SuggestHeaps(8000, 2048, []string{"a", "b", "c"})
+		if err != nil {
+			t.Fatalf("SuggestHeaps failed: %v", err)
+		}
+		if heaps["a"]%256 != 0 || heaps["a"] != 1280 {
+			t.Errorf("Unexpected heap: %d", heaps["a"])
+		}
+	})
+
+	t.Run("not enough memory", func(t *testing.T) {
+		if _, err := SuggestHeaps(4096, 2048, []string{"a", "b", "c"}); err == nil {
+			t.Error("Expected error when memory is insufficient")
+		}
+	})
+
+	t.Run("no worlds", func(t *testing.T) {
+		if _, err := SuggestHeaps(4096, 2048, nil); err == nil {
+			t.Error("Expected error without worlds")
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package worlds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Service control and ownership fixes used by restores; replaced in tests
+var (
+	serviceRunning = IsServiceRunning
+	stopService    = StopService
+	startService   = StartService
+	fixOwnership   = chownToMinecraftUser
+)
+
+// RestoreResult contains the result of an in-place world restore
+type RestoreResult struct {
+	WorldName string
+	Snapshot  string // ID of the restored snapshot
+	WorldPath string // the restored world/ directory
+	// PreRestorePath is where the previous world/ was moved, empty if the
+	// world had no world/ directory
+	PreRestorePath string
+	WasRunning     bool
+	Restarted      bool
+}
+
+// RestoreWorld replaces a world's world/ directory with the copy in a
+// snapshot. The world's service is stopped first, the current world/ is
+// moved aside to world.pre-restore-<timestamp>, only the world's path is
+// restored from the snapshot, ownership is fixed and the service is started
+// again if it was running. If the restore fails the previous world/ is moved
+// back.
+func RestoreWorld(repo *backup.Config, snapshot, worldName string) (*RestoreResult, error) {
+	worldDir := filepath.Join(config.Get().WorldsDir, worldName)
+	if _, err := os.Stat(filepath.Join(worldDir, "server.properties")); err != nil {
+		return nil, fmt.Errorf("world not found: %s", worldDir)
+	}
+
+	snap, err := repo.WorldSnapshot(worldName, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	snapPath, err := backup.WorldPath(snap, worldName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReplaceWorld(worldName, snap.ShortID, func(worldPath string) error {
+		return repo.RestorePath(snap.ID, snapPath, worldPath)
+	})
+}
+
+// ReplaceWorld replaces a world's world/ directory with one created by
+// restore, as RestoreWorld does for restic snapshots. snapshot names the
+// source in the result and errors.
+func ReplaceWorld(worldName, snapshot string, restore func(worldPath string) error) (*RestoreResult, error) {
+	worldDir := filepath.Join(config.Get().WorldsDir, worldName)
+	if _, err := os.Stat(filepath.Join(worldDir, "server.properties")); err != nil {
+		return nil, fmt.Errorf("world not found: %s", worldDir)
+	}
+
+	running, err := serviceRunning(worldName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check service status: %w", err)
+	}
+
+	result := &RestoreResult{
+		WorldName:  worldName,
+		Snapshot:   snapshot,
+		WorldPath:  filepath.Join(worldDir, "world"),
+		WasRunning: running,
+	}
+
+	if running {
+		log.Info().Str("world", worldName).Msg("stopping world for restore")
+		if err := stopService(worldName); err != nil {
+			return nil, fmt.Errorf("failed to stop service: %w", err)
+		}
+	}
+
+	if _, err := os.Stat(result.WorldPath); err == nil {
+		result.PreRestorePath = fmt.Sprintf("%s.pre-restore-%s", result.WorldPath, time.Now().Format("20060102-150405"))
+		if err := os.Rename(result.WorldPath, result.PreRestorePath); err != nil {
+			return nil, restartAfter(result, fmt.Errorf("failed to move world aside: %w", err))
+		}
+		log.Info().Str("path", result.PreRestorePath).Msg("moved current world aside")
+	}
+
+	if err := restore(result.WorldPath); err != nil {
+		restoreErr := fmt.Errorf("failed to restore snapshot %s: %w", snapshot, err)
+		if result.PreRestorePath != "" {
+			if err := os.RemoveAll(result.WorldPath); err != nil {
+				return nil, fmt.Errorf("%w; previous world left at %s: %v", restoreErr, result.PreRestorePath, err)
+			}
+			if err := os.Rename(result.PreRestorePath, result.WorldPath); err != nil {
+				return nil, fmt.Errorf("%w; previous world left at %s: %v", restoreErr, result.PreRestorePath, err)
+			}
+			result.PreRestorePath = ""
+		}
+		return nil, restartAfter(result, restoreErr)
+	}
+
+	if err := fixOwnership(result.WorldPath); err != nil {
+		log.Warn().Err(err).Str("world", worldName).Msg("failed to chown restored world to minecraft user, continuing")
+	}
+
+	if running {
+		if err := startService(worldName); err != nil {
+			return result, fmt.Errorf("world restored but failed to start service: %w", err)
+		}
+		result.Restarted = true
+	}
+
+	return result, nil
+}
+
+// restartAfter starts a world that RestoreWorld stopped before failing with
+// err, and returns err
+func restartAfter(result *RestoreResult, err error) error {
+	if result.WasRunning {
+		if startErr := startService(result.WorldName); startErr != nil {
+			return fmt.Errorf("%w (and failed to restart service: %v)", err, startErr)
+		}
+	}
+	return err
+}
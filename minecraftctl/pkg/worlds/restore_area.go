@@ -0,0 +1,201 @@
+package worlds
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/region"
+	"github.com/rs/zerolog/log"
+)
+
+// regionKinds are the world/ subdirectories holding region files: terrain,
+// entities (1.17+) and points of interest
+var regionKinds = []string{"region", "entities", "poi"}
+
+// DimensionDir returns the directory of a dimension relative to world/.
+// Dimensions are overworld, nether and end, optionally prefixed with
+// "minecraft:the_" as in commands.
+func DimensionDir(dimension string) (string, error) {
+	switch dimension {
+	case "overworld", "minecraft:overworld", "":
+		return "", nil
+	case "nether", "the_nether", "minecraft:the_nether":
+		return "DIM-1", nil
+	case "end", "the_end", "minecraft:the_end":
+		return "DIM1", nil
+	default:
+		return "", fmt.Errorf("unknown dimension %q (overworld, nether, end)", dimension)
+	}
+}
+
+// AreaRestoreResult contains the result of RestoreArea
+type AreaRestoreResult struct {
+	WorldName string
+	Snapshot  string
+	Box       region.Box
+	// Chunks is the number of terrain chunks replaced or removed
+	Chunks int
+	// Modified lists changed files relative to world/; their previous
+	// versions are in BackupDir under the same relative path
+	Modified []string
+	// Created lists files that did not exist before, relative to world/
+	Created   []string
+	BackupDir string
+}
+
+// RestoreArea restores the chunks of a stopped world's dimension that overlap
+// box from a snapshot. For every region, entities and poi file the box
+// touches, only the chunks inside the box are replaced; the rest of each file
+// is left as it is. Chunks missing from the snapshot are removed so the
+// server generates them again. Replaced files are moved to
+// world.pre-restore-area-<timestamp> in the world directory.
+func RestoreArea(repo *backup.Config, snapshot, worldName, dimension string, box region.Box) (*AreaRestoreResult, error) {
+	dimDir, err := DimensionDir(dimension)
+	if err != nil {
+		return nil, err
+	}
+	worldDir, snap, snapPath, err := prepareRestore(repo, snapshot, worldName)
+	if err != nil {
+		return nil, err
+	}
+
+	worldPath := filepath.Join(worldDir, "world")
+	result := &AreaRestoreResult{
+		WorldName: worldName,
+		Snapshot:  snap.ShortID,
+		Box:       box,
+		BackupDir: filepath.Join(worldDir, "world.pre-restore-area-"+time.Now().Format("20060102-150405")),
+	}
+
+	for _, kind := range regionKinds {
+		rel := path.Join(filepath.ToSlash(dimDir), kind)
+		nodes, err := repo.ListDir(snap.ID, path.Join(snapPath, rel))
+		if err != nil {
+			return result, err
+		}
+		inSnapshot := make(map[string]bool)
+		for _, node := range nodes {
+			inSnapshot[node.Name] = true
+		}
+
+		for _, r := range box.Regions() {
+			err := restoreRegion(repo, snap.ID, path.Join(snapPath, rel), worldPath, rel, region.FileName(r[0], r[1]),
+				r[0], r[1], inSnapshot, result)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if len(result.Modified) == 0 {
+		os.RemoveAll(result.BackupDir)
+		result.BackupDir = ""
+	}
+	return result, nil
+}
+
+// sameChunk reports whether a live chunk and its snapshot copy are the same.
+// The data of chunks in .mcc files is not compared, so they always differ.
+func sameChunk(a, b *region.Chunk) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return !a.External() && !b.External() && a.Timestamp == b.Timestamp && bytes.Equal(a.Data, b.Data)
+}
+
+// restoreRegion splices the chunks in result.Box from the snapshot's copy of
+// a region file into the live one
+func restoreRegion(repo *backup.Config, snapshotID, snapDir, worldPath, rel, name string, rx, rz int,
+	inSnapshot map[string]bool, result *AreaRestoreResult) error {
+	liveDir := filepath.Join(worldPath, filepath.FromSlash(rel))
+	livePath := filepath.Join(liveDir, name)
+
+	live, err := region.Read(livePath)
+	existed := err == nil
+	if os.IsNotExist(err) {
+		live = &region.File{}
+	} else if err != nil {
+		return err
+	}
+
+	src := &region.File{}
+	if inSnapshot[name] {
+		var buf bytes.Buffer
+		if err := repo.Dump(snapshotID, path.Join(snapDir, name), &buf); err != nil {
+			return err
+		}
+		if src, err = region.Parse(buf.Bytes()); err != nil {
+			return fmt.Errorf("%s in snapshot: %w", path.Join(rel, name), err)
+		}
+	}
+
+	changed := 0
+	var external []region.SpliceChunk
+	for _, c := range region.Splice(live, src, rx, rz, result.Box) {
+		if sameChunk(c.Old, c.New) {
+			continue
+		}
+		changed++
+		if (c.Old != nil && c.Old.External()) || (c.New != nil && c.New.External()) {
+			external = append(external, c)
+		}
+	}
+	if changed == 0 {
+		return nil
+	}
+
+	backupDir := filepath.Join(result.BackupDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.MkdirAll(liveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", liveDir, err)
+	}
+
+	// Oversized chunks live in c.<x>.<z>.mcc files next to the region file
+	for _, c := range external {
+		mcc := region.ExternalFileName(c.X, c.Z)
+		if c.Old != nil && c.Old.External() {
+			if err := os.Rename(filepath.Join(liveDir, mcc), filepath.Join(backupDir, mcc)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to move %s aside: %w", mcc, err)
+			}
+		}
+		if c.New != nil && c.New.External() {
+			var buf bytes.Buffer
+			if err := repo.Dump(snapshotID, path.Join(snapDir, mcc), &buf); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(liveDir, mcc), buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", mcc, err)
+			}
+			if err := fixOwnership(filepath.Join(liveDir, mcc)); err != nil {
+				log.Warn().Err(err).Str("file", mcc).Msg("failed to chown restored file to minecraft user, continuing")
+			}
+		}
+	}
+
+	if existed {
+		if err := os.Rename(livePath, filepath.Join(backupDir, name)); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", name, err)
+		}
+		result.Modified = append(result.Modified, path.Join(rel, name))
+	} else {
+		result.Created = append(result.Created, path.Join(rel, name))
+	}
+
+	if err := live.Write(livePath); err != nil {
+		return err
+	}
+	if err := fixOwnership(livePath); err != nil {
+		log.Warn().Err(err).Str("file", name).Msg("failed to chown restored file to minecraft user, continuing")
+	}
+	if path.Base(rel) == "region" {
+		result.Chunks += changed
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package worlds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// ResolvePlayer returns the UUID of a player given by UUID (with or without
+// dashes) or by name. Names are looked up in the world's usercache.json, so
+// the player must have joined the server.
+func ResolvePlayer(worldDir, player string) (string, error) {
+	if uuidPattern.MatchString(player) {
+		hex := strings.ToLower(strings.ReplaceAll(player, "-", ""))
+		return fmt.Sprintf("%s-%s-%s-%s-%s", hex[0:8], hex[8:12], hex[12:16], hex[16:20], hex[20:32]), nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(worldDir, "usercache.json"))
+	if err != nil {
+		return "", fmt.Errorf("cannot look up player %s, use their UUID: %w", player, err)
+	}
+	var cache []struct {
+		Name string `json:"name"`
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return "", fmt.Errorf("failed to parse usercache.json: %w", err)
+	}
+	for _, entry := range cache {
+		if strings.EqualFold(entry.Name, player) {
+			return strings.ToLower(entry.UUID), nil
+		}
+	}
+	return "", fmt.Errorf("player %s not found in usercache.json, use their UUID", player)
+}
+
+// PlayerRestoreResult contains the result of RestorePlayer
+type PlayerRestoreResult struct {
+	WorldName string
+	Snapshot  string
+	UUID      string
+	// Restored lists the files written, relative to the world/ directory
+	Restored []string
+	// Previous maps restored files to where their previous version was moved
+	Previous map[string]string
+}
+
+// playerFiles are the per-player files in world/, relative to it. Only
+// playerdata is required to be in the snapshot.
+var playerFiles = []struct {
+	dir, ext string
+	required bool
+}{
+	{"playerdata", ".dat", true},
+	{"stats", ".json", false},
+	{"advancements", ".json", false},
+}
+
+// RestorePlayer restores a player's playerdata, stats and advancements from
+// a snapshot into a stopped world. Current files are kept next to the
+// restored ones with a .pre-restore-<timestamp> suffix.
+func RestorePlayer(repo *backup.Config, snapshot, worldName, player string) (*PlayerRestoreResult, error) {
+	worldDir, snap, snapPath, err := prepareRestore(repo, snapshot, worldName)
+	if err != nil {
+		return nil, err
+	}
+	uuid, err := ResolvePlayer(worldDir, player)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlayerRestoreResult{
+		WorldName: worldName,
+		Snapshot:  snap.ShortID,
+		UUID:      uuid,
+		Previous:  make(map[string]string),
+	}
+	worldPath := filepath.Join(worldDir, "world")
+	suffix := ".pre-restore-" + time.Now().Format("20060102-150405")
+
+	var files []string
+	for _, pf := range playerFiles {
+		name := uuid + pf.ext
+		nodes, err := repo.ListDir(snap.ID, path.Join(snapPath, pf.dir))
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, node := range nodes {
+			if node.Name == name && node.Type == "file" {
+				found = true
+				break
+			}
+		}
+		if found {
+			files = append(files, path.Join(pf.dir, name))
+		} else if pf.required {
+			return nil, fmt.Errorf("snapshot %s has no %s/%s for player %s", snap.ShortID, pf.dir, name, player)
+		}
+	}
+
+	for _, rel := range files {
+		var buf bytes.Buffer
+		if err := repo.Dump(snap.ID, path.Join(snapPath, rel), &buf); err != nil {
+			return result, err
+		}
+
+		dest := filepath.Join(worldPath, filepath.FromSlash(rel))
+		if _, err := os.Stat(dest); err == nil {
+			if err := os.Rename(dest, dest+suffix); err != nil {
+				return result, fmt.Errorf("failed to keep previous %s: %w", rel, err)
+			}
+			result.Previous[rel] = dest + suffix
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return result, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(dest, buf.Bytes(), 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		if err := fixOwnership(dest); err != nil {
+			log.Warn().Err(err).Str("file", rel).Msg("failed to chown restored file to minecraft user, continuing")
+		}
+		result.Restored = append(result.Restored, rel)
+	}
+
+	return result, nil
+}
+
+// prepareRestore checks that a world exists and is stopped, and finds its
+// world/ directory in a snapshot
+func prepareRestore(repo *backup.Config, snapshot, worldName string) (worldDir string, snap *backup.Snapshot, snapPath string, err error) {
+	worldDir = filepath.Join(config.Get().WorldsDir, worldName)
+	if _, err := os.Stat(filepath.Join(worldDir, "server.properties")); err != nil {
+		return "", nil, "", fmt.Errorf("world not found: %s", worldDir)
+	}
+
+	running, err := serviceRunning(worldName)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to check service status: %w", err)
+	}
+	if running {
+		return "", nil, "", fmt.Errorf("world %s is running, stop it first: minecraftctl world stop %s", worldName, worldName)
+	}
+
+	snap, err = repo.WorldSnapshot(worldName, snapshot)
+	if err != nil {
+		return "", nil, "", err
+	}
+	snapPath, err = backup.WorldPath(snap, worldName)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return worldDir, snap, snapPath, nil
+}
@@ -0,0 +1,385 @@
+package worlds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/backup"
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/region"
+	"github.com/spf13/viper"
+)
+
+// fakeRestic puts a restic script on PATH that lists one snapshot of the
+// survival world and restores a level.dat into --target, or fails the
+// restore when FAKE_RESTIC_FAIL is set. ls and dump serve files from tree,
+// which holds the snapshot's paths below it. It returns the argument log
+// path.
+func fakeRestic(t *testing.T, tree string) string {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "restic.log")
+	script := `#!/bin/sh
+echo "$*" >> ` + logPath + `
+case "$1" in
+snapshots)
+	echo '[{"id":"abcdef0123456789","short_id":"abcdef01","time":"2024-05-01T03:00:00Z","tags":["survival"],"paths":["/srv/minecraft-server/survival/world"]}]'
+	;;
+restore)
+	if [ -n "$FAKE_RESTIC_FAIL" ]; then
+		mkdir -p "$4" && echo partial > "$4/partial"
+		exit 1
+	fi
+	mkdir -p "$4" && echo restored > "$4/level.dat"
+	;;
+ls)
+	echo '{"struct_type":"snapshot","paths":["/srv/minecraft-server/survival/world"]}'
+	for f in "` + tree + `$4"/*; do
+		[ -e "$f" ] || continue
+		echo "{\"name\":\"$(basename "$f")\",\"type\":\"file\",\"path\":\"$4/$(basename "$f")\",\"struct_type\":\"node\"}"
+	done
+	;;
+dump)
+	cat "` + tree + `$3"
+	;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "restic"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake restic: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+// fakeService fakes the world's service, running or not, and records the
+// service and ownership actions. The hooks are restored after the test.
+func fakeService(t *testing.T, running bool) *[]string {
+	t.Helper()
+	origRunning, origStop, origStart, origChown := serviceRunning, stopService, startService, fixOwnership
+	t.Cleanup(func() {
+		serviceRunning, stopService, startService, fixOwnership = origRunning, origStop, origStart, origChown
+	})
+
+	var actions []string
+	serviceRunning = func(string) (bool, error) { return running, nil }
+	stopService = func(name string) error {
+		actions = append(actions, "stop "+name)
+		return nil
+	}
+	startService = func(name string) error {
+		actions = append(actions, "start "+name)
+		return nil
+	}
+	fixOwnership = func(path string) error {
+		actions = append(actions, "chown "+filepath.Base(path))
+		return nil
+	}
+	return &actions
+}
+
+func TestRestoreWorld(t *testing.T) {
+	repo := &backup.Config{Name: "test", Repository: "/tmp/repo", Password: "secret"}
+
+	// newWorld creates a survival world in its own worlds directory, as
+	// restores move the world directory aside
+	newWorld := func(t *testing.T) string {
+		worldsDir := t.TempDir()
+		worldDir := filepath.Join(worldsDir, "survival")
+		os.MkdirAll(filepath.Join(worldDir, "world"), 0755)
+		os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("level-name=world\n"), 0644)
+		os.WriteFile(filepath.Join(worldDir, "world", "level.dat"), []byte("current"), 0644)
+		viper.Reset()
+		viper.Set("worlds_dir", worldsDir)
+		if err := config.Init(""); err != nil {
+			t.Fatalf("config.Init() failed: %v", err)
+		}
+		return worldDir
+	}
+
+	t.Run("running world", func(t *testing.T) {
+		worldDir := newWorld(t)
+		actions := fakeService(t, true)
+		logPath := fakeRestic(t, "")
+
+		result, err := RestoreWorld(repo, "latest", "survival")
+		if err != nil {
+			t.Fatalf("RestoreWorld failed: %v", err)
+		}
+
+		if got := strings.Join(*actions, ", "); got != "stop survival, chown world, start survival" {
+			t.Errorf("actions = %s", got)
+		}
+		if !result.WasRunning || !result.Restarted || result.Snapshot != "abcdef01" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if data, _ := os.ReadFile(filepath.Join(worldDir, "world", "level.dat")); string(data) != "restored\n" {
+			t.Errorf("world/level.dat = %q, want restored", data)
+		}
+		if !strings.HasPrefix(filepath.Base(result.PreRestorePath), "world.pre-restore-") {
+			t.Errorf("PreRestorePath = %s", result.PreRestorePath)
+		}
+		if data, _ := os.ReadFile(filepath.Join(result.PreRestorePath, "level.dat")); string(data) != "current" {
+			t.Errorf("previous world not kept: %q", data)
+		}
+
+		data, _ := os.ReadFile(logPath)
+		want := fmt.Sprintf("restore abcdef0123456789:/srv/minecraft-server/survival/world --target %s", filepath.Join(worldDir, "world"))
+		if !strings.Contains(string(data), want) {
+			t.Errorf("restic calls:\n%s\nwant %s", data, want)
+		}
+	})
+
+	t.Run("stopped world stays stopped", func(t *testing.T) {
+		newWorld(t)
+		actions := fakeService(t, false)
+		fakeRestic(t, "")
+
+		result, err := RestoreWorld(repo, "latest", "survival")
+		if err != nil {
+			t.Fatalf("RestoreWorld failed: %v", err)
+		}
+		if got := strings.Join(*actions, ", "); got != "chown world" {
+			t.Errorf("actions = %s", got)
+		}
+		if result.Restarted {
+			t.Error("Stopped world should not be started")
+		}
+	})
+
+	t.Run("failed restore puts world back", func(t *testing.T) {
+		worldDir := newWorld(t)
+		actions := fakeService(t, true)
+		fakeRestic(t, "")
+		t.Setenv("FAKE_RESTIC_FAIL", "1")
+
+		if _, err := RestoreWorld(repo, "latest", "survival"); err == nil {
+			t.Fatal("Expected restore error")
+		}
+		if got := strings.Join(*actions, ", "); got != "stop survival, start survival" {
+			t.Errorf("actions = %s", got)
+		}
+		if data, _ := os.ReadFile(filepath.Join(worldDir, "world", "level.dat")); string(data) != "current" {
+			t.Errorf("previous world not put back: %q", data)
+		}
+		entries, _ := os.ReadDir(worldDir)
+		for _, e := range entries {
+			if strings.Contains(e.Name(), "pre-restore") {
+				t.Errorf("Unexpected leftover %s", e.Name())
+			}
+		}
+	})
+
+	t.Run("snapshot without the world", func(t *testing.T) {
+		worldDir := newWorld(t)
+		actions := fakeService(t, true)
+		fakeRestic(t, "")
+		creativeDir := filepath.Join(filepath.Dir(worldDir), "creative")
+		os.MkdirAll(creativeDir, 0755)
+		os.WriteFile(filepath.Join(creativeDir, "server.properties"), nil, 0644)
+
+		// latest is resolved among the world's own snapshots
+		_, err := RestoreWorld(repo, "latest", "creative")
+		if err == nil || !strings.Contains(err.Error(), "no snapshots of creative") {
+			t.Fatalf("Expected error for world without snapshots, got %v", err)
+		}
+		_, err = RestoreWorld(repo, "abcdef01", "creative")
+		if err == nil || !strings.Contains(err.Error(), "does not contain world creative") {
+			t.Fatalf("Expected error for world missing from snapshot, got %v", err)
+		}
+		if len(*actions) != 0 {
+			t.Errorf("Nothing should happen, got %v", *actions)
+		}
+	})
+}
+
+func TestResolvePlayer(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "usercache.json"),
+		[]byte(`[{"name":"Steve","uuid":"069a79f4-44e9-4726-a5be-fca90e38aaf5","expiresOn":"2024-06-01 00:00:00 +0000"}]`), 0644)
+
+	tests := []struct {
+		player  string
+		want    string
+		wantErr bool
+	}{
+		{"steve", "069a79f4-44e9-4726-a5be-fca90e38aaf5", false},
+		{"069A79F444E94726A5BEFCA90E38AAF5", "069a79f4-44e9-4726-a5be-fca90e38aaf5", false},
+		{"853c80ef-3c37-49fd-aa49-938b674adae6", "853c80ef-3c37-49fd-aa49-938b674adae6", false},
+		{"Alex", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.player, func(t *testing.T) {
+			got, err := ResolvePlayer(dir, tt.player)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePlayer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePlayer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestorePlayer(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival")
+	os.MkdirAll(filepath.Join(worldDir, "world"), 0755)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("level-name=world\n"), 0644)
+	viper.Reset()
+	viper.Set("worlds_dir", worldsDir)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config.Init() failed: %v", err)
+	}
+	repo := &backup.Config{Name: "test", Repository: "/tmp/repo", Password: "secret"}
+	uuid := "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+
+	tree := t.TempDir()
+	snapWorld := filepath.Join(tree, "srv/minecraft-server/survival/world")
+	for _, rel := range []string{"playerdata/" + uuid + ".dat", "stats/" + uuid + ".json", "playerdata/other.dat"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(snapWorld, rel)), 0755)
+		os.WriteFile(filepath.Join(snapWorld, rel), []byte("snapshot "+rel), 0644)
+	}
+
+	t.Run("restores player files", func(t *testing.T) {
+		actions := fakeService(t, false)
+		fakeRestic(t, tree)
+		live := filepath.Join(worldDir, "world", "playerdata", uuid+".dat")
+		os.MkdirAll(filepath.Dir(live), 0755)
+		os.WriteFile(live, []byte("current"), 0644)
+
+		result, err := RestorePlayer(repo, "latest", "survival", uuid)
+		if err != nil {
+			t.Fatalf("RestorePlayer failed: %v", err)
+		}
+
+		want := []string{"playerdata/" + uuid + ".dat", "stats/" + uuid + ".json"}
+		if strings.Join(result.Restored, ",") != strings.Join(want, ",") {
+			t.Errorf("Restored = %v, want %v", result.Restored, want)
+		}
+		if data, _ := os.ReadFile(live); string(data) != "snapshot playerdata/"+uuid+".dat" {
+			t.Errorf("playerdata = %q", data)
+		}
+		previous := result.Previous["playerdata/"+uuid+".dat"]
+		if data, _ := os.ReadFile(previous); string(data) != "current" {
+			t.Errorf("previous playerdata at %s = %q", previous, data)
+		}
+		if _, err := os.Stat(filepath.Join(worldDir, "world", "playerdata", "other.dat")); !os.IsNotExist(err) {
+			t.Error("Other players must not be restored")
+		}
+		if len(*actions) != 2 {
+			t.Errorf("Expected restored files to be chowned, got %v", *actions)
+		}
+	})
+
+	t.Run("running world", func(t *testing.T) {
+		fakeService(t, true)
+		fakeRestic(t, tree)
+		if _, err := RestorePlayer(repo, "latest", "survival", uuid); err == nil || !strings.Contains(err.Error(), "stop it first") {
+			t.Errorf("Expected error for running world, got %v", err)
+		}
+	})
+
+	t.Run("player not in snapshot", func(t *testing.T) {
+		fakeService(t, false)
+		fakeRestic(t, tree)
+		if _, err := RestorePlayer(repo, "latest", "survival", "853c80ef-3c37-49fd-aa49-938b674adae6"); err == nil {
+			t.Error("Expected error for player missing from snapshot")
+		}
+	})
+}
+
+func TestRestoreArea(t *testing.T) {
+	worldsDir := t.TempDir()
+	worldDir := filepath.Join(worldsDir, "survival")
+	os.MkdirAll(filepath.Join(worldDir, "world"), 0755)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("level-name=world\n"), 0644)
+	viper.Reset()
+	viper.Set("worlds_dir", worldsDir)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config.Init() failed: %v", err)
+	}
+	repo := &backup.Config{Name: "test", Repository: "/tmp/repo", Password: "secret"}
+
+	chunk := func(ts uint32) *region.Chunk {
+		return &region.Chunk{Timestamp: ts, Data: []byte{2, byte(ts)}}
+	}
+
+	// The snapshot has chunks -4..3,0 in r.-1.0 and r.0.0 with timestamp 1
+	tree := t.TempDir()
+	snapRegion := filepath.Join(tree, "srv/minecraft-server/survival/world/region")
+	os.MkdirAll(snapRegion, 0755)
+	for _, rx := range []int{0, -1} {
+		f := &region.File{}
+		for cx := rx * 4; cx < rx*4+4; cx++ {
+			f.Chunks[region.Index(cx, 0)] = chunk(1)
+		}
+		f.Write(filepath.Join(snapRegion, region.FileName(rx, 0)))
+	}
+
+	actions := fakeService(t, false)
+	fakeRestic(t, tree)
+
+	// The live r.0.0 has the same chunks with timestamp 2; r.-1.0 is missing
+	liveRegion := filepath.Join(worldDir, "world", "region")
+	os.MkdirAll(liveRegion, 0755)
+	live := &region.File{}
+	for cx := 0; cx < 4; cx++ {
+		live.Chunks[region.Index(cx, 0)] = chunk(2)
+	}
+	live.Write(filepath.Join(liveRegion, "r.0.0.mca"))
+
+	// Blocks -32..31 cover chunks -2..1
+	result, err := RestoreArea(repo, "latest", "survival", "overworld", region.Box{MinX: -32, MinZ: 0, MaxX: 31, MaxZ: 15})
+	if err != nil {
+		t.Fatalf("RestoreArea failed: %v", err)
+	}
+
+	if result.Chunks != 2+2 {
+		t.Errorf("Chunks = %d, want 4", result.Chunks)
+	}
+	if strings.Join(result.Modified, ",") != "region/r.0.0.mca" || strings.Join(result.Created, ",") != "region/r.-1.0.mca" {
+		t.Errorf("Modified = %v, Created = %v", result.Modified, result.Created)
+	}
+
+	restored, err := region.Read(filepath.Join(liveRegion, "r.0.0.mca"))
+	if err != nil {
+		t.Fatalf("Failed to read restored region: %v", err)
+	}
+	for cx, want := range map[int]uint32{0: 1, 1: 1, 2: 2, 3: 2} {
+		if got := restored.Chunks[region.Index(cx, 0)].Timestamp; got != want {
+			t.Errorf("chunk %d,0 timestamp = %d, want %d", cx, got, want)
+		}
+	}
+
+	created, err := region.Read(filepath.Join(liveRegion, "r.-1.0.mca"))
+	if err != nil {
+		t.Fatalf("Failed to read created region: %v", err)
+	}
+	if created.Chunks[region.Index(-4, 0)] != nil || created.Chunks[region.Index(-2, 0)] == nil {
+		t.Error("Only chunks inside the box should be restored into r.-1.0")
+	}
+
+	if previous, err := region.Read(filepath.Join(result.BackupDir, "region", "r.0.0.mca")); err != nil ||
+		previous.Chunks[region.Index(0, 0)].Timestamp != 2 {
+		t.Errorf("Previous region not kept in %s: %v", result.BackupDir, err)
+	}
+	if len(*actions) != 2 {
+		t.Errorf("Expected both region files to be chowned, got %v", *actions)
+	}
+
+	// The area now matches the snapshot, so nothing is restored again
+	again, err := RestoreArea(repo, "latest", "survival", "overworld", result.Box)
+	if err != nil {
+		t.Fatalf("RestoreArea failed: %v", err)
+	}
+	if again.Chunks != 0 || len(again.Modified) != 0 || len(again.Created) != 0 || again.BackupDir != "" {
+		t.Errorf("Expected no changes, got %+v", again)
+	}
+
+	if _, err := RestoreArea(repo, "latest", "survival", "aether", result.Box); err == nil {
+		t.Error("Expected error for unknown dimension")
+	}
+}
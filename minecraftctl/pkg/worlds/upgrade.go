@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/jars"
 	"github.com/paul/minecraftctl/pkg/systemd"
 	"github.com/rs/zerolog/log"
 )
@@ -19,10 +20,14 @@ type UpgradeOptions struct {
 
 // UpgradeResult contains the result of an upgrade operation
 type UpgradeResult struct {
-	WorldName       string
-	PreviousVersion string
-	NewVersion      string
-	ServiceStopped  bool
+	WorldName           string
+	Flavor              jars.Flavor
+	PreviousVersion     string
+	NewVersion          string
+	PreviousDataVersion int // 0 if the previous JAR has no embedded metadata
+	NewDataVersion      int // 0 if the new JAR has no embedded metadata
+	ServiceStopped      bool
+	JavaSelected        string // java binary selected for the new JAR, if it changed
 }
 
 // IsServiceRunning checks if the minecraft service for a world is running
@@ -37,24 +42,81 @@ func StopService(worldName string) error {
 	return systemd.Stop(serviceName)
 }
 
-// GetCurrentVersion reads the current version from the server.jar symlink
+// StartService starts the minecraft service for a world
+func StartService(worldName string) error {
+	serviceName := systemd.FormatUnitName("minecraft", worldName, systemd.UnitService)
+	return systemd.Start(serviceName)
+}
+
+// GetCurrentVersion reads the current version from the server.jar symlink.
+// The version is taken from the JAR's embedded metadata when possible and
+// from the minecraft_server_<version>.jar file name otherwise.
 func GetCurrentVersion(worldPath string) (string, error) {
+	target, err := currentJarPath(worldPath)
+	if err != nil {
+		return "", err
+	}
+	return jars.VersionFromJar(target)
+}
+
+// currentJarPath resolves the target of a world's server.jar symlink
+func currentJarPath(worldPath string) (string, error) {
 	serverJarPath := filepath.Join(worldPath, "server.jar")
 
 	target, err := os.Readlink(serverJarPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read server.jar symlink: %w", err)
 	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(worldPath, target)
+	}
+
+	return target, nil
+}
+
+// compareJars orders two server JARs. When both carry embedded metadata their
+// DataVersions are compared, which also orders snapshots and renamed JARs;
+// otherwise the version names are compared numerically.
+func compareJars(currentJar, currentVersion, targetJar, targetVersion string) (int, error) {
+	current, currentErr := jars.InspectJar(currentJar)
+	target, targetErr := jars.InspectJar(targetJar)
+	if currentErr == nil && targetErr == nil && current.WorldVersion > 0 && target.WorldVersion > 0 {
+		switch {
+		case current.WorldVersion < target.WorldVersion:
+			return -1, nil
+		case current.WorldVersion > target.WorldVersion:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+
+	return CompareVersions(currentVersion, targetVersion)
+}
+
+// compareBuilds orders two flavored JARs of the same Minecraft version by
+// the build in their file names
+func compareBuilds(currentJar, targetJar string) int {
+	current, _ := jars.ParseJarFileName(filepath.Base(currentJar))
+	target, _ := jars.ParseJarFileName(filepath.Base(targetJar))
+	return jars.CompareBuilds(current.Build, target.Build)
+}
 
-	// Extract version from path like /opt/minecraft/jars/minecraft_server_1.21.1.jar
-	base := filepath.Base(target)
-	if !strings.HasPrefix(base, "minecraft_server_") || !strings.HasSuffix(base, ".jar") {
-		return "", fmt.Errorf("unexpected jar filename format: %s", base)
+// displayVersion appends the build of a flavored JAR to its version
+func displayVersion(jarPath, version string) string {
+	if spec, ok := jars.ParseJarFileName(filepath.Base(jarPath)); ok && spec.Build != "" {
+		return version + "-" + spec.Build
 	}
-	version := strings.TrimPrefix(base, "minecraft_server_")
-	version = strings.TrimSuffix(version, ".jar")
+	return version
+}
 
-	return version, nil
+// dataVersion returns the DataVersion embedded in a JAR, or 0 if unknown
+func dataVersion(jarPath string) int {
+	meta, err := jars.InspectJar(jarPath)
+	if err != nil {
+		return 0
+	}
+	return meta.WorldVersion
 }
 
 // UpgradeWorld upgrades a world to a new Minecraft version
@@ -68,41 +130,63 @@ func UpgradeWorld(worldName string, opts UpgradeOptions) (*UpgradeResult, error)
 	}
 
 	// 2. Get current version from symlink
-	currentVersion, err := GetCurrentVersion(worldPath)
+	currentJar, err := currentJarPath(worldPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine current version: %w", err)
 	}
+	currentVersion, err := jars.VersionFromJar(currentJar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine current version: %w", err)
+	}
+
+	// 3. Verify target JAR of the world's flavor exists
+	flavor := jars.FlavorFromJar(currentJar)
+	targetJarPath, err := jars.FindFlavorJar(flavor, opts.TargetVersion, cfg.JarsDir)
+	if err != nil {
+		return nil, fmt.Errorf("target %s JAR not found for version %s in %s (use 'minecraftctl jar download' first)",
+			flavor, opts.TargetVersion, cfg.JarsDir)
+	}
 
-	// 3. Verify target version is newer
-	cmp, err := CompareVersions(currentVersion, opts.TargetVersion)
+	targetVersion, err := jars.VersionFromJar(targetJarPath)
+	if err != nil {
+		targetVersion = opts.TargetVersion
+	}
+
+	// 4. Verify target version is newer
+	cmp, err := compareJars(currentJar, currentVersion, targetJarPath, targetVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compare versions: %w", err)
 	}
+	if cmp == 0 && flavor != jars.FlavorVanilla {
+		// Same Minecraft version: a newer build of the flavor is still an upgrade
+		cmp = compareBuilds(currentJar, targetJarPath)
+	}
 	if cmp >= 0 {
 		return nil, fmt.Errorf("target version %s is not newer than current version %s (downgrades not supported)",
-			opts.TargetVersion, currentVersion)
+			displayVersion(targetJarPath, targetVersion), displayVersion(currentJar, currentVersion))
 	}
 
-	// 4. Verify target JAR exists
-	targetJarPath := filepath.Join(cfg.JarsDir,
-		fmt.Sprintf("minecraft_server_%s.jar", opts.TargetVersion))
-	if _, err := os.Stat(targetJarPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("target JAR not found: %s (use 'minecraftctl jar download' first)",
-			targetJarPath)
+	// 5. Verify a Java runtime for the new JAR is installed
+	javaCheck, err := CheckWorldJava(worldName, targetJarPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// 5. Check if service is running
-	running, err := IsServiceRunning(worldName)
+	// 6. Check if service is running
+	running, err := serviceRunning(worldName)
 	if err != nil {
 		log.Warn().Err(err).Msg("failed to check service status, assuming not running")
 		running = false
 	}
 
 	result := &UpgradeResult{
-		WorldName:       worldName,
-		PreviousVersion: currentVersion,
-		NewVersion:      opts.TargetVersion,
-		ServiceStopped:  false,
+		WorldName:           worldName,
+		Flavor:              flavor,
+		PreviousVersion:     displayVersion(currentJar, currentVersion),
+		NewVersion:          displayVersion(targetJarPath, targetVersion),
+		PreviousDataVersion: dataVersion(currentJar),
+		NewDataVersion:      dataVersion(targetJarPath),
+		ServiceStopped:      false,
 	}
 
 	if running {
@@ -111,27 +195,74 @@ func UpgradeWorld(worldName string, opts UpgradeOptions) (*UpgradeResult, error)
 		}
 
 		log.Info().Str("world", worldName).Msg("stopping minecraft service")
-		if err := StopService(worldName); err != nil {
+		if err := stopService(worldName); err != nil {
 			return nil, fmt.Errorf("failed to stop service: %w", err)
 		}
 		result.ServiceStopped = true
 	}
 
-	// 6. Update server.jar symlink
-	serverJarPath := filepath.Join(worldPath, "server.jar")
+	// fail restarts a stopped world, still on its previous JAR and Java
+	fail := func(err error) (*UpgradeResult, error) {
+		if result.ServiceStopped {
+			log.Info().Str("world", worldName).Msg("upgrade failed, starting minecraft service again")
+			if startErr := startService(worldName); startErr != nil {
+				log.Error().Err(startErr).Str("world", worldName).Msg("failed to start service")
+			}
+		}
+		return nil, err
+	}
+
+	// 7. Switch the world to a compatible Java runtime if needed, before the
+	// JAR, so a failure leaves the world as it was
+	previousJava := ""
+	if javaCheck.Selected {
+		previousJava = javaCheck.JavaPath
+	}
+	if !javaCheck.OK() {
+		if err := SetWorldJava(worldName, javaCheck.Suggested.Path); err != nil {
+			return fail(fmt.Errorf("failed to select Java %d: %w", javaCheck.Required, err))
+		}
+		result.JavaSelected = javaCheck.Suggested.Path
+		log.Info().
+			Str("world", worldName).
+			Str("java", javaCheck.Suggested.Path).
+			Msg("selected java runtime for new version")
+	}
 
-	if err := os.Remove(serverJarPath); err != nil {
-		return nil, fmt.Errorf("failed to remove existing server.jar symlink: %w", err)
+	// 8. Update server.jar symlink, replacing it atomically
+	serverJarPath := filepath.Join(worldPath, "server.jar")
+	newLinkPath := serverJarPath + ".new"
+	os.Remove(newLinkPath)
+	err = os.Symlink(targetJarPath, newLinkPath)
+	if err == nil {
+		err = os.Rename(newLinkPath, serverJarPath)
+	}
+	if err != nil {
+		os.Remove(newLinkPath)
+		if result.JavaSelected != "" {
+			if javaErr := SetWorldJava(worldName, previousJava); javaErr != nil {
+				log.Error().Err(javaErr).Str("world", worldName).Msg("failed to restore previous java selection")
+			}
+		}
+		return fail(fmt.Errorf("failed to update server.jar symlink: %w", err))
 	}
 
-	if err := os.Symlink(targetJarPath, serverJarPath); err != nil {
-		return nil, fmt.Errorf("failed to create server.jar symlink: %w", err)
+	// 9. Record the previous JAR so it is kept for rollback
+	record := UpgradeRecord{
+		Time:        time.Now(),
+		FromVersion: result.PreviousVersion,
+		ToVersion:   result.NewVersion,
+		FromJar:     currentJar,
+		ToJar:       targetJarPath,
+	}
+	if err := RecordUpgrade(worldPath, record); err != nil {
+		log.Warn().Err(err).Str("world", worldName).Msg("failed to record upgrade history")
 	}
 
 	log.Info().
 		Str("world", worldName).
-		Str("from", currentVersion).
-		Str("to", opts.TargetVersion).
+		Str("from", result.PreviousVersion).
+		Str("to", result.NewVersion).
 		Msg("world upgraded successfully")
 
 	return result, nil
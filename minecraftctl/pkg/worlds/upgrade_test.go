@@ -0,0 +1,218 @@
+package worlds
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/jars"
+	"github.com/spf13/viper"
+)
+
+// writeVersionedJar writes a zip JAR with an embedded version.json requiring Java 21
+func writeVersionedJar(t *testing.T, path, id string, dataVersion int) {
+	t.Helper()
+	writeJavaJar(t, path, id, dataVersion, 21)
+}
+
+// writeJavaJar writes a zip JAR with an embedded version.json
+func writeJavaJar(t *testing.T, path, id string, dataVersion, javaVersion int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create jar: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("version.json")
+	if err != nil {
+		t.Fatalf("Failed to create version.json: %v", err)
+	}
+	fmt.Fprintf(w, `{"id": %q, "name": %q, "world_version": %d, "java_version": %d}`, id, id, dataVersion, javaVersion)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close jar: %v", err)
+	}
+}
+
+// writeFakeJDK creates a JDK home with a release file and a bin/java stub
+// and returns the path of bin/java
+func writeFakeJDK(t *testing.T, dir, version string) string {
+	t.Helper()
+	home := filepath.Join(dir, "jdk-"+version)
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create JDK: %v", err)
+	}
+	javaPath := filepath.Join(home, "bin", "java")
+	os.WriteFile(javaPath, []byte("#!/bin/sh\nexit 1\n"), 0755)
+	os.WriteFile(filepath.Join(home, "release"), []byte(fmt.Sprintf("JAVA_VERSION=%q\nIMPLEMENTOR=\"Test\"\n", version)), 0644)
+	return javaPath
+}
+
+// setupUpgradeWorld creates a world linked to the given jar and configures
+// the worlds and jars directories and a Java 21 runtime as the default java
+func setupUpgradeWorld(t *testing.T, jarsDir, jarPath string) string {
+	t.Helper()
+	worldsDir := t.TempDir()
+	jdksDir := t.TempDir()
+	viper.Reset()
+	viper.Set("worlds_dir", worldsDir)
+	viper.Set("jars_dir", jarsDir)
+	viper.Set("java_path", writeFakeJDK(t, jdksDir, "21.0.2"))
+	viper.Set("java_search_paths", []string{filepath.Join(jdksDir, "*")})
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config.Init() failed: %v", err)
+	}
+
+	worldPath := filepath.Join(worldsDir, "survival")
+	if err := os.MkdirAll(filepath.Join(worldPath, "world"), 0755); err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+
+	src, err := os.Open("../../testdata/default/world/level.dat")
+	if err != nil {
+		t.Fatalf("Failed to open testdata level.dat: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(worldPath, "world", "level.dat"))
+	if err != nil {
+		t.Fatalf("Failed to create level.dat: %v", err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatalf("Failed to copy level.dat: %v", err)
+	}
+
+	if err := os.Symlink(jarPath, filepath.Join(worldPath, "server.jar")); err != nil {
+		t.Fatalf("Failed to link server.jar: %v", err)
+	}
+	return worldPath
+}
+
+func TestGetCurrentVersion(t *testing.T) {
+	t.Run("from file name", func(t *testing.T) {
+		worldPath := t.TempDir()
+		os.Symlink("/opt/minecraft/jars/minecraft_server_1.21.1.jar", filepath.Join(worldPath, "server.jar"))
+
+		got, err := GetCurrentVersion(worldPath)
+		if err != nil {
+			t.Fatalf("GetCurrentVersion failed: %v", err)
+		}
+		if got != "1.21.1" {
+			t.Errorf("GetCurrentVersion = %q, want 1.21.1", got)
+		}
+	})
+
+	t.Run("from embedded metadata", func(t *testing.T) {
+		worldPath := t.TempDir()
+		jarPath := filepath.Join(t.TempDir(), "renamed.jar")
+		writeVersionedJar(t, jarPath, "24w14a", 3827)
+		os.Symlink(jarPath, filepath.Join(worldPath, "server.jar"))
+
+		got, err := GetCurrentVersion(worldPath)
+		if err != nil {
+			t.Fatalf("GetCurrentVersion failed: %v", err)
+		}
+		if got != "24w14a" {
+			t.Errorf("GetCurrentVersion = %q, want 24w14a", got)
+		}
+	})
+
+	t.Run("unexpected file name", func(t *testing.T) {
+		worldPath := t.TempDir()
+		os.Symlink("/opt/minecraft/jars/custom.jar", filepath.Join(worldPath, "server.jar"))
+
+		if _, err := GetCurrentVersion(worldPath); err == nil {
+			t.Error("Expected error for unrecognized jar")
+		}
+	})
+}
+
+func TestUpgradeWorldDataVersion(t *testing.T) {
+	t.Run("snapshot upgrade ordered by data version", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+		writeVersionedJar(t, currentJar, "1.20.4", 3700)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_24w14a.jar"), "24w14a", 3827)
+		worldPath := setupUpgradeWorld(t, jarsDir, currentJar)
+
+		result, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "24w14a"})
+		if err != nil {
+			t.Fatalf("UpgradeWorld failed: %v", err)
+		}
+		if result.PreviousDataVersion != 3700 || result.NewDataVersion != 3827 {
+			t.Errorf("Unexpected data versions: %d -> %d", result.PreviousDataVersion, result.NewDataVersion)
+		}
+
+		target, _ := os.Readlink(filepath.Join(worldPath, "server.jar"))
+		if filepath.Base(target) != "minecraft_server_24w14a.jar" {
+			t.Errorf("server.jar points to %s", target)
+		}
+	})
+
+	t.Run("downgrade by data version rejected", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_24w14a.jar")
+		writeVersionedJar(t, currentJar, "24w14a", 3827)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_1.20.4.jar"), "1.20.4", 3700)
+		setupUpgradeWorld(t, jarsDir, currentJar)
+
+		_, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.20.4"})
+		if err == nil {
+			t.Fatal("Expected downgrade to be rejected")
+		}
+	})
+
+	t.Run("missing target jar", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "minecraft_server_1.20.4.jar")
+		writeVersionedJar(t, currentJar, "1.20.4", 3700)
+		setupUpgradeWorld(t, jarsDir, currentJar)
+
+		_, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21"})
+		if err == nil {
+			t.Fatal("Expected error for missing target jar")
+		}
+	})
+
+	t.Run("paper world upgrades to newer build", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "paper_server_1.21.4-120.jar")
+		writeVersionedJar(t, currentJar, "1.21.4", 4189)
+		writeVersionedJar(t, filepath.Join(jarsDir, "paper_server_1.21.4-123.jar"), "1.21.4", 4189)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_1.21.5.jar"), "1.21.5", 4325)
+		worldPath := setupUpgradeWorld(t, jarsDir, currentJar)
+
+		result, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21.4"})
+		if err != nil {
+			t.Fatalf("UpgradeWorld failed: %v", err)
+		}
+		if result.Flavor != jars.FlavorPaper {
+			t.Errorf("Flavor = %q, want paper", result.Flavor)
+		}
+		if result.PreviousVersion != "1.21.4-120" || result.NewVersion != "1.21.4-123" {
+			t.Errorf("Unexpected versions: %s -> %s", result.PreviousVersion, result.NewVersion)
+		}
+
+		target, _ := os.Readlink(filepath.Join(worldPath, "server.jar"))
+		if filepath.Base(target) != "paper_server_1.21.4-123.jar" {
+			t.Errorf("server.jar points to %s", target)
+		}
+	})
+
+	t.Run("paper world ignores vanilla jars", func(t *testing.T) {
+		jarsDir := t.TempDir()
+		currentJar := filepath.Join(jarsDir, "paper_server_1.21.4-123.jar")
+		writeVersionedJar(t, currentJar, "1.21.4", 4189)
+		writeVersionedJar(t, filepath.Join(jarsDir, "minecraft_server_1.21.5.jar"), "1.21.5", 4325)
+		setupUpgradeWorld(t, jarsDir, currentJar)
+
+		if _, err := UpgradeWorld("survival", UpgradeOptions{TargetVersion: "1.21.5"}); err == nil {
+			t.Fatal("Expected error when no paper jar exists for the target version")
+		}
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/paul/minecraftctl/pkg/config"
 	"github.com/paul/minecraftctl/pkg/envfile"
+	"github.com/paul/minecraftctl/pkg/jars"
 	"github.com/paul/minecraftctl/pkg/nbt"
 	"github.com/rs/zerolog/log"
 )
@@ -234,7 +235,9 @@ func ExpandWorldPattern(pattern string) ([]string, error) {
 // CreateWorldOptions holds options for creating a new world
 type CreateWorldOptions struct {
 	Version         string
+	Flavor          jars.Flavor // empty means vanilla
 	Seed            string
+	Heap            string // empty keeps the unit's default heap
 	CreateMapConfig bool
 	EnableSystemd   bool
 }
@@ -243,7 +246,15 @@ type CreateWorldOptions struct {
 func CreateWorld(worldName string, opts CreateWorldOptions) error {
 	cfg := config.Get()
 	worldDir := filepath.Join(cfg.WorldsDir, worldName)
-	jarPath := fmt.Sprintf("/opt/minecraft/jars/minecraft_server_%s.jar", opts.Version)
+	flavor := opts.Flavor
+	if flavor == "" {
+		flavor = jars.FlavorVanilla
+	}
+	if opts.Heap != "" {
+		if _, err := ParseHeap(opts.Heap); err != nil {
+			return err
+		}
+	}
 
 	// Check if world already exists
 	if _, err := os.Stat(worldDir); err == nil {
@@ -255,8 +266,9 @@ func CreateWorld(worldName string, opts CreateWorldOptions) error {
 	}
 
 	// Validate that jar exists
-	if _, err := os.Stat(jarPath); os.IsNotExist(err) {
-		return fmt.Errorf("Minecraft server jar not found: %s", jarPath)
+	jarPath, err := jars.FindFlavorJar(flavor, opts.Version, cfg.JarsDir)
+	if err != nil {
+		return fmt.Errorf("Minecraft server jar not found: %w", err)
 	}
 
 	// Create world directory
@@ -276,6 +288,13 @@ func CreateWorld(worldName string, opts CreateWorldOptions) error {
 		return fmt.Errorf("failed to create symlink to server jar: %w", err)
 	}
 
+	// Create the plugins or mods directory for non-vanilla flavors
+	if dir := flavor.ExtensionsDir(); dir != "" {
+		if err := os.MkdirAll(filepath.Join(worldDir, dir), 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", dir, err)
+		}
+	}
+
 	// Create eula.txt
 	eulaPath := filepath.Join(worldDir, "eula.txt")
 	if err := os.WriteFile(eulaPath, []byte("eula=true\n"), 0644); err != nil {
@@ -372,6 +391,12 @@ maps:
 		}
 	}
 
+	if opts.Heap != "" {
+		if err := SetWorldJVM(worldName, JVMSettings{Heap: opts.Heap, Flags: FlagsDefault}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", JVMEnvFile, err)
+		}
+	}
+
 	// Fix permissions: chown all created files to minecraft:minecraft
 	// This ensures the systemd service (which runs as minecraft user) can write to these files
 	if err := chownToMinecraftUser(worldDir); err != nil {
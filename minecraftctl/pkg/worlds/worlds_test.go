@@ -0,0 +1,82 @@
+package worlds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paul/minecraftctl/pkg/config"
+	"github.com/paul/minecraftctl/pkg/jars"
+	"github.com/spf13/viper"
+)
+
+func TestCreateWorldFlavor(t *testing.T) {
+	setup := func(t *testing.T, jarNames ...string) string {
+		t.Helper()
+		worldsDir := t.TempDir()
+		jarsDir := t.TempDir()
+		for _, name := range jarNames {
+			os.WriteFile(filepath.Join(jarsDir, name), []byte(name), 0644)
+		}
+		viper.Reset()
+		viper.Set("worlds_dir", worldsDir)
+		viper.Set("jars_dir", jarsDir)
+		if err := config.Init(""); err != nil {
+			t.Fatalf("config.Init() failed: %v", err)
+		}
+		return worldsDir
+	}
+
+	t.Run("paper world gets plugins directory", func(t *testing.T) {
+		worldsDir := setup(t, "paper_server_1.21.4-120.jar", "paper_server_1.21.4-123.jar")
+
+		err := CreateWorld("plugins", CreateWorldOptions{Version: "1.21.4", Flavor: jars.FlavorPaper})
+		if err != nil {
+			t.Fatalf("CreateWorld failed: %v", err)
+		}
+
+		if fi, err := os.Stat(filepath.Join(worldsDir, "plugins", "plugins")); err != nil || !fi.IsDir() {
+			t.Errorf("plugins directory not created: %v", err)
+		}
+		target, _ := os.Readlink(filepath.Join(worldsDir, "plugins", "server.jar"))
+		if filepath.Base(target) != "paper_server_1.21.4-123.jar" {
+			t.Errorf("server.jar points to %s, want newest paper build", target)
+		}
+	})
+
+	t.Run("fabric world gets mods directory", func(t *testing.T) {
+		worldsDir := setup(t, "fabric_server_1.21.4-0.16.9.jar")
+
+		if err := CreateWorld("modded", CreateWorldOptions{Version: "1.21.4", Flavor: jars.FlavorFabric}); err != nil {
+			t.Fatalf("CreateWorld failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(worldsDir, "modded", "mods")); err != nil {
+			t.Errorf("mods directory not created: %v", err)
+		}
+	})
+
+	t.Run("vanilla world", func(t *testing.T) {
+		worldsDir := setup(t, "minecraft_server_1.21.4.jar", "paper_server_1.21.4-123.jar")
+
+		if err := CreateWorld("survival", CreateWorldOptions{Version: "1.21.4"}); err != nil {
+			t.Fatalf("CreateWorld failed: %v", err)
+		}
+		target, _ := os.Readlink(filepath.Join(worldsDir, "survival", "server.jar"))
+		if filepath.Base(target) != "minecraft_server_1.21.4.jar" {
+			t.Errorf("server.jar points to %s", target)
+		}
+		for _, dir := range []string{"plugins", "mods"} {
+			if _, err := os.Stat(filepath.Join(worldsDir, "survival", dir)); !os.IsNotExist(err) {
+				t.Errorf("vanilla world should not have %s/", dir)
+			}
+		}
+	})
+
+	t.Run("missing flavor jar", func(t *testing.T) {
+		setup(t, "minecraft_server_1.21.4.jar")
+
+		if err := CreateWorld("survival", CreateWorldOptions{Version: "1.21.4", Flavor: jars.FlavorPurpur}); err == nil {
+			t.Error("Expected error when no purpur jar is installed")
+		}
+	})
+}
@@ -13,6 +13,10 @@ sudo install -Dm644 "${SRC_DIR}/minecraft-world-backup.timer" /etc/systemd/syste
 sudo install -Dm644 "${SRC_DIR}/minecraft-world-prune.service" /etc/systemd/system/minecraft-world-prune.service
 sudo install -Dm644 "${SRC_DIR}/minecraft-world-prune.timer" /etc/systemd/system/minecraft-world-prune.timer
 
+# Local snapshot service and timer
+sudo install -Dm644 "${SRC_DIR}/minecraft-world-snapshot@.service" /etc/systemd/system/minecraft-world-snapshot@.service
+sudo install -Dm644 "${SRC_DIR}/minecraft-world-snapshot@.timer" /etc/systemd/system/minecraft-world-snapshot@.timer
+
 # Hook into minecraft@.service (ExecStopPost to backup on stop)
 sudo mkdir -p /etc/systemd/system/minecraft@.service.d
 sudo install -Dm644 "${SRC_DIR}/minecraft-override-world-backup.conf" /etc/systemd/system/minecraft@.service.d/minecraft-world-backup.conf
@@ -2,7 +2,6 @@
 set -euo pipefail
 
 WORLD="${1:-all}"
-BASE_DIR="/var/www/map"
 BUCKET="${MC_MAP_BUCKET:-}"
 
 if [[ -z "$BUCKET" ]]; then
@@ -10,55 +9,13 @@ if [[ -z "$BUCKET" ]]; then
   exit 0
 fi
 
-if ! command -v aws >/dev/null 2>&1; then
-  echo "AWS CLI not installed; skipping map backup"
-  exit 0
-fi
-
-backup_world() {
-  local world="$1"
-  local dir="${BASE_DIR}/${world}"
-
-  if [[ -d "$dir" ]]; then
-    echo "Backing up maps for $world → s3://$BUCKET/maps/$world/"
-
-
-    ### Replace aws sync
-    # rclone sync /srv/minecraft-server/maps/ s3:minecraft-test-maps/maps \
-    #   --s3-provider AWS \
-    #   --s3-region us-east-2 \
-    #   --fast-list \
-    #   --checkers 16 \
-    #   --transfers 8 \
-    #   --progress
-
-    aws s3 sync "$dir" "s3://$BUCKET/maps/$world/" --delete --no-progress
-    echo "Backup complete for $world."
-  else
-    echo "Skipping missing map directory: $dir"
-  fi
-}
-
-backup_root_files() {
-  for f in "${BASE_DIR}/world_manifest.json" "${BASE_DIR}/index.html"; do
-    if [[ -f "$f" ]]; then
-      echo "Backing up $(basename "$f") → s3://$BUCKET/maps/"
-      aws s3 cp "$f" "s3://$BUCKET/maps/$(basename "$f")" --no-progress
-    fi
-  done
-}
-
+# minecraftctl uploads only changed tiles, deletes removed ones and uploads
+# world_manifest.json and index.html last
 if [[ "$WORLD" == "all" ]]; then
-  echo "Backing up all maps under $BASE_DIR"
-  for d in "${BASE_DIR}"/*; do
-    if [[ -d "$d" ]]; then
-      backup_world "$(basename "$d")"
-    fi
-  done
-  echo "All map backups complete."
+  echo "Publishing all maps → s3://$BUCKET/maps/"
+  /usr/local/bin/minecraftctl map publish --bucket "$BUCKET"
 else
-  backup_world "$WORLD"
+  echo "Publishing maps for $WORLD → s3://$BUCKET/maps/$WORLD/"
+  /usr/local/bin/minecraftctl map publish "$WORLD" --bucket "$BUCKET"
 fi
-
-# Always sync root-level manifest files (small files, updated after each rebuild)
-backup_root_files
+echo "Map backup complete."
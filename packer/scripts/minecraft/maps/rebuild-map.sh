@@ -27,6 +27,7 @@ if [[ "$WORLD_PATH" == *"*"* ]]; then
 fi
 
 # --- Handle wildcard globs ---
+FAILED=false
 if $IS_GLOB; then
   for w in $WORLD_PATH; do
     if [[ -d "$w" ]]; then
@@ -35,9 +36,15 @@ if $IS_GLOB; then
       [[ -n "$MAP_FILTER" ]] && CMD+=("--map" "$MAP_FILTER")
       $FORCE && CMD+=("--force")
       $NONBLOCK && CMD+=("--non-blocking")
-      "${CMD[@]}" || true  # Continue on error for glob processing
+      CMD+=("--keep-going")
+      # Continue with the other worlds, but fail at the end
+      "${CMD[@]}" || FAILED=true
     fi
   done
+  if $FAILED; then
+    echo "❌ Map build failed for one or more worlds" >&2
+    exit 1
+  fi
   exit 0
 fi
 
@@ -64,5 +71,8 @@ if $NONBLOCK; then
   CMD+=("--non-blocking")
 fi
 
+# A failed map doesn't stop the others, but still fails the build
+CMD+=("--keep-going")
+
 # Execute minecraftctl
 exec "${CMD[@]}"
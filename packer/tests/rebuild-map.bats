@@ -76,6 +76,24 @@ teardown() {
     assert_mock_called_with "minecraftctl map build now survival --map nether --force --non-blocking"
 }
 
+@test "rebuild-map: builds the remaining maps after a failed map" {
+    mkdir -p "${TEST_TEMP_DIR}/worlds/survival"
+
+    run bash "$SCRIPT" "${TEST_TEMP_DIR}/worlds/survival" --force
+
+    [ "$status" -eq 0 ]
+    assert_mock_called_with "minecraftctl map build now survival --force --keep-going"
+}
+
+@test "rebuild-map: exits with error when the build fails" {
+    mkdir -p "${TEST_TEMP_DIR}/worlds/survival"
+    create_mock "minecraftctl" 1 "Build failed"
+
+    run bash "$SCRIPT" "${TEST_TEMP_DIR}/worlds/survival"
+
+    [ "$status" -eq 1 ]
+}
+
 @test "rebuild-map: rejects unknown arguments" {
     mkdir -p "${TEST_TEMP_DIR}/worlds/survival"
 
@@ -110,7 +128,7 @@ teardown() {
     assert_mock_called_with "minecraftctl map build now world2 --force"
 }
 
-@test "rebuild-map: glob mode continues on error" {
+@test "rebuild-map: glob mode continues on error and fails at the end" {
     mkdir -p "${TEST_TEMP_DIR}/worlds/world1"
     mkdir -p "${TEST_TEMP_DIR}/worlds/world2"
 
@@ -119,8 +137,11 @@ teardown() {
 
     run bash "$SCRIPT" "${TEST_TEMP_DIR}/worlds/*"
 
-    # Should still exit 0 because glob mode continues on error
-    [ "$status" -eq 0 ]
+    # Every world is built, but the failure is reported
+    [ "$status" -eq 1 ]
+    assert_mock_called_with "minecraftctl map build now world1"
+    assert_mock_called_with "minecraftctl map build now world2"
+    [[ "$output" == *"Map build failed"* ]]
 }
 
 @test "rebuild-map: glob skips non-directories" {